@@ -3,6 +3,7 @@ module github.com/pda0/koanf-winreg/v2
 go 1.18
 
 require (
+	github.com/klauspost/compress v1.17.0
 	github.com/knadh/koanf/v2 v2.1.1
 	golang.org/x/sys v0.0.0-20211113001501-0c823b97ae02
 )
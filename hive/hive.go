@@ -0,0 +1,372 @@
+// Package hive reads registry hive files in the regf format, like SOFTWARE
+// or NTUSER.DAT, without the Windows API, so configuration stored in disk
+// images can be loaded by koanf on any OS:
+//
+//	k.Load(hive.Provider("NTUSER.DAT", hive.Config{Path: "Software\\Vendor\\App"}), nil)
+//
+// Hives are read as they are in the file, changes pending in transaction
+// logs of a dirty hive are not applied.
+package hive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// Registry value types.
+const (
+	NONE      = 0
+	SZ        = 1
+	EXPAND_SZ = 2
+	BINARY    = 3
+	DWORD     = 4
+	MULTI_SZ  = 7
+	QWORD     = 11
+)
+
+// ErrNotExist is returned when a key doesn't exist in the hive.
+var ErrNotExist = errors.New("key doesn't exist")
+
+const (
+	baseBlockSize = 4096
+	bigDataSize   = 16344  // Data larger than this is split into segments of big data records
+	compName      = 0x0020 // Key name is stored in Latin-1 instead of UTF-16
+	valueCompName = 0x0001 // Value name is stored in Latin-1 instead of UTF-16
+	maxListDepth  = 8      // Limit of nested ri lists, which are never nested in valid hives
+)
+
+// Hive is a parsed hive file.
+type Hive struct {
+	data  []byte // Hive bins, cell offsets are relative to their start
+	root  uint32
+	minor uint32
+}
+
+// Key is a key of a hive.
+type Key struct {
+	h      *Hive
+	name   string
+	offset uint32
+}
+
+// Value is a value of a key with its data in the stored form.
+type Value struct {
+	Name string // Empty for the default key value
+	Type uint32
+	Data []byte
+}
+
+// Parse parses the contents of a hive file.
+func Parse(data []byte) (*Hive, error) {
+	if len(data) < baseBlockSize || !bytes.Equal(data[:4], []byte("regf")) {
+		return nil, errors.New("not a registry hive")
+	}
+	if major := binary.LittleEndian.Uint32(data[20:]); major != 1 {
+		return nil, fmt.Errorf("unsupported hive version %d", major)
+	}
+
+	h := &Hive{
+		data:  data[baseBlockSize:],
+		root:  binary.LittleEndian.Uint32(data[36:]),
+		minor: binary.LittleEndian.Uint32(data[24:]),
+	}
+	if size := binary.LittleEndian.Uint32(data[40:]); uint64(size) <= uint64(len(h.data)) {
+		h.data = h.data[:size]
+	}
+
+	return h, nil
+}
+
+// Root returns the root key of the hive.
+func (h *Hive) Root() (*Key, error) {
+	return h.key(h.root)
+}
+
+// cell returns the data of the cell at offset.
+func (h *Hive) cell(offset uint32) ([]byte, error) {
+	if uint64(offset)+4 > uint64(len(h.data)) {
+		return nil, fmt.Errorf("cell offset 0x%X out of hive", offset)
+	}
+	size := int32(binary.LittleEndian.Uint32(h.data[offset:]))
+	if size < 0 {
+		// Allocated cells have a negative size
+		size = -size
+	}
+	if size < 4 || uint64(offset)+uint64(size) > uint64(len(h.data)) {
+		return nil, fmt.Errorf("invalid cell size at 0x%X", offset)
+	}
+
+	return h.data[offset+4 : offset+uint32(size)], nil
+}
+
+// record returns the cell at offset which must start with signature sig
+// and be at least size bytes long.
+func (h *Hive) record(offset uint32, sig string, size int) ([]byte, error) {
+	b, err := h.cell(offset)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < size || string(b[:2]) != sig {
+		return nil, fmt.Errorf("invalid %s record at 0x%X", sig, offset)
+	}
+
+	return b, nil
+}
+
+func (h *Hive) key(offset uint32) (*Key, error) {
+	b, err := h.record(offset, "nk", 76)
+	if err != nil {
+		return nil, err
+	}
+	n := int(binary.LittleEndian.Uint16(b[72:]))
+	if 76+n > len(b) {
+		return nil, fmt.Errorf("invalid nk record at 0x%X", offset)
+	}
+
+	return &Key{h: h, name: decodeName(b[76:76+n], binary.LittleEndian.Uint16(b[2:])&compName != 0), offset: offset}, nil
+}
+
+// Name returns the name of the key.
+func (k *Key) Name() string {
+	return k.name
+}
+
+// SubKeys returns the subkeys of the key.
+func (k *Key) SubKeys() ([]*Key, error) {
+	b, err := k.h.record(k.offset, "nk", 76)
+	if err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(b[20:]) == 0 {
+		return nil, nil
+	}
+
+	var offsets []uint32
+	if err = k.h.subKeyList(binary.LittleEndian.Uint32(b[28:]), 0, &offsets); err != nil {
+		return nil, fmt.Errorf("%s: %w", k.name, err)
+	}
+	retval := make([]*Key, 0, len(offsets))
+	for _, offset := range offsets {
+		sub, err := k.h.key(offset)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k.name, err)
+		}
+		retval = append(retval, sub)
+	}
+
+	return retval, nil
+}
+
+// subKeyList appends the offsets of the keys of the subkey list at offset.
+func (h *Hive) subKeyList(offset uint32, depth int, out *[]uint32) error {
+	b, err := h.cell(offset)
+	if err != nil {
+		return err
+	}
+	if len(b) < 4 {
+		return fmt.Errorf("invalid subkey list at 0x%X", offset)
+	}
+	count := int(binary.LittleEndian.Uint16(b[2:]))
+
+	step := 4
+	switch string(b[:2]) {
+	case "lf", "lh":
+		// Each entry is followed by a hash of the name
+		step = 8
+	case "li":
+	case "ri":
+		if depth >= maxListDepth {
+			return fmt.Errorf("subkey lists nested too deep at 0x%X", offset)
+		}
+		if 4+count*4 > len(b) {
+			return fmt.Errorf("invalid subkey list at 0x%X", offset)
+		}
+		for i := 0; i < count; i++ {
+			if err = h.subKeyList(binary.LittleEndian.Uint32(b[4+i*4:]), depth+1, out); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid subkey list at 0x%X", offset)
+	}
+	if 4+count*step > len(b) {
+		return fmt.Errorf("invalid subkey list at 0x%X", offset)
+	}
+	for i := 0; i < count; i++ {
+		*out = append(*out, binary.LittleEndian.Uint32(b[4+i*step:]))
+	}
+
+	return nil
+}
+
+// SubKey returns the subkey path of the key, names are compared ignoring
+// case as the registry does.
+func (k *Key) SubKey(path string) (*Key, error) {
+	retval := k
+	for _, name := range strings.Split(strings.Trim(path, "\\"), "\\") {
+		if name == "" {
+			continue
+		}
+		subKeys, err := retval.SubKeys()
+		if err != nil {
+			return nil, err
+		}
+		var found *Key
+		for _, sub := range subKeys {
+			if strings.EqualFold(sub.name, name) {
+				found = sub
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("%s: %w", joinPath(retval.name, name), ErrNotExist)
+		}
+		retval = found
+	}
+
+	return retval, nil
+}
+
+// Values returns the values of the key.
+func (k *Key) Values() ([]Value, error) {
+	b, err := k.h.record(k.offset, "nk", 76)
+	if err != nil {
+		return nil, err
+	}
+	count := binary.LittleEndian.Uint32(b[36:])
+	if count == 0 {
+		return nil, nil
+	}
+
+	list, err := k.h.cell(binary.LittleEndian.Uint32(b[40:]))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", k.name, err)
+	}
+	if uint64(count)*4 > uint64(len(list)) {
+		return nil, fmt.Errorf("%s: invalid value list", k.name)
+	}
+
+	retval := make([]Value, 0, count)
+	for i := uint32(0); i < count; i++ {
+		v, err := k.h.value(binary.LittleEndian.Uint32(list[i*4:]))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k.name, err)
+		}
+		retval = append(retval, v)
+	}
+
+	return retval, nil
+}
+
+func (h *Hive) value(offset uint32) (Value, error) {
+	b, err := h.record(offset, "vk", 20)
+	if err != nil {
+		return Value{}, err
+	}
+	n := int(binary.LittleEndian.Uint16(b[2:]))
+	if 20+n > len(b) {
+		return Value{}, fmt.Errorf("invalid vk record at 0x%X", offset)
+	}
+	v := Value{
+		Name: decodeName(b[20:20+n], binary.LittleEndian.Uint16(b[16:])&valueCompName != 0),
+		Type: binary.LittleEndian.Uint32(b[12:]),
+	}
+
+	size := binary.LittleEndian.Uint32(b[4:])
+	if size&0x80000000 != 0 {
+		// Up to 4 bytes are stored in place of the data offset
+		size &^= 0x80000000
+		if size > 4 {
+			return Value{}, fmt.Errorf("%s: invalid resident data size %d", v.Name, size)
+		}
+		v.Data = append([]byte(nil), b[8:8+size]...)
+		return v, nil
+	}
+
+	dataOffset := binary.LittleEndian.Uint32(b[8:])
+	if size > bigDataSize && h.minor >= 4 {
+		v.Data, err = h.bigData(dataOffset, size)
+	} else {
+		var data []byte
+		if data, err = h.cell(dataOffset); err == nil && uint64(size) > uint64(len(data)) {
+			err = fmt.Errorf("invalid data size %d", size)
+		}
+		if err == nil {
+			v.Data = append([]byte(nil), data[:size]...)
+		}
+	}
+	if err != nil {
+		return Value{}, fmt.Errorf("%s: %w", v.Name, err)
+	}
+
+	return v, nil
+}
+
+// bigData joins the segments of the db record at offset.
+func (h *Hive) bigData(offset, size uint32) ([]byte, error) {
+	b, err := h.record(offset, "db", 8)
+	if err != nil {
+		return nil, err
+	}
+	count := int(binary.LittleEndian.Uint16(b[2:]))
+	list, err := h.cell(binary.LittleEndian.Uint32(b[4:]))
+	if err != nil {
+		return nil, err
+	}
+	if count*4 > len(list) {
+		return nil, fmt.Errorf("invalid db record at 0x%X", offset)
+	}
+
+	retval := make([]byte, 0, size)
+	for i := 0; i < count && uint32(len(retval)) < size; i++ {
+		segment, err := h.cell(binary.LittleEndian.Uint32(list[i*4:]))
+		if err != nil {
+			return nil, err
+		}
+		if len(segment) > bigDataSize {
+			segment = segment[:bigDataSize]
+		}
+		if rest := int(size) - len(retval); len(segment) > rest {
+			segment = segment[:rest]
+		}
+		retval = append(retval, segment...)
+	}
+	if uint32(len(retval)) != size {
+		return nil, fmt.Errorf("invalid data size %d", size)
+	}
+
+	return retval, nil
+}
+
+// decodeName decodes a key or value name stored in Latin-1 or UTF-16LE.
+func decodeName(b []byte, latin1 bool) string {
+	if latin1 {
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		return string(runes)
+	}
+
+	return string(utf16.Decode(bytesToUTF16(b)))
+}
+
+func bytesToUTF16(buf []byte) []uint16 {
+	units := make([]uint16, len(buf)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(buf[i*2:])
+	}
+	return units
+}
+
+func joinPath(parent, child string) string {
+	if parent == "" {
+		return child
+	}
+	return parent + "\\" + child
+}
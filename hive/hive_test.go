@@ -0,0 +1,226 @@
+package hive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// hiveBuilder builds a minimal hive with cells allocated one after another.
+type hiveBuilder struct {
+	bins []byte
+}
+
+func newHiveBuilder() *hiveBuilder {
+	// The hbin header precedes the first cell
+	return &hiveBuilder{bins: append([]byte("hbin"), make([]byte, 28)...)}
+}
+
+func (b *hiveBuilder) cell(data []byte) uint32 {
+	offset := uint32(len(b.bins))
+	size := (len(data) + 4 + 7) &^ 7
+	b.bins = appendUint32(b.bins, uint32(-int32(size)))
+	b.bins = append(b.bins, data...)
+	b.bins = append(b.bins, make([]byte, size-4-len(data))...)
+
+	return offset
+}
+
+func (b *hiveBuilder) key(name string, subKeys []uint32, values []uint32) uint32 {
+	nk := make([]byte, 76)
+	copy(nk, "nk")
+	binary.LittleEndian.PutUint16(nk[2:], compName)
+	if len(subKeys) > 0 {
+		// Two lh lists joined by an ri list
+		half := len(subKeys) / 2
+		ri := []byte("ri")
+		ri = appendUint16(ri, 2)
+		for _, part := range [][]uint32{subKeys[:half], subKeys[half:]} {
+			lh := []byte("lh")
+			lh = appendUint16(lh, uint16(len(part)))
+			for _, offset := range part {
+				lh = appendUint32(lh, offset)
+				lh = appendUint32(lh, 0)
+			}
+			ri = appendUint32(ri, b.cell(lh))
+		}
+		binary.LittleEndian.PutUint32(nk[20:], uint32(len(subKeys)))
+		binary.LittleEndian.PutUint32(nk[28:], b.cell(ri))
+	}
+	if len(values) > 0 {
+		var list []byte
+		for _, offset := range values {
+			list = appendUint32(list, offset)
+		}
+		binary.LittleEndian.PutUint32(nk[36:], uint32(len(values)))
+		binary.LittleEndian.PutUint32(nk[40:], b.cell(list))
+	}
+	binary.LittleEndian.PutUint16(nk[72:], uint16(len(name)))
+
+	return b.cell(append(nk, name...))
+}
+
+func (b *hiveBuilder) value(name string, typ uint32, data []byte) uint32 {
+	vk := make([]byte, 20)
+	copy(vk, "vk")
+	// The name is stored in UTF-16
+	encoded := utf16Bytes(name)
+	binary.LittleEndian.PutUint16(vk[2:], uint16(len(encoded)))
+	binary.LittleEndian.PutUint32(vk[4:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(vk[12:], typ)
+	switch {
+	case len(data) <= 4:
+		binary.LittleEndian.PutUint32(vk[4:], uint32(len(data))|0x80000000)
+		copy(vk[8:], data)
+	case len(data) > bigDataSize:
+		var list []byte
+		for rest := data; len(rest) > 0; {
+			n := len(rest)
+			if n > bigDataSize {
+				n = bigDataSize
+			}
+			list = appendUint32(list, b.cell(rest[:n]))
+			rest = rest[n:]
+		}
+		db := []byte("db")
+		db = appendUint16(db, uint16(len(list)/4))
+		db = appendUint32(db, b.cell(list))
+		binary.LittleEndian.PutUint32(vk[8:], b.cell(db))
+	default:
+		binary.LittleEndian.PutUint32(vk[8:], b.cell(data))
+	}
+
+	return b.cell(append(vk, encoded...))
+}
+
+func (b *hiveBuilder) hive(root uint32) []byte {
+	base := make([]byte, baseBlockSize)
+	copy(base, "regf")
+	binary.LittleEndian.PutUint32(base[20:], 1)
+	binary.LittleEndian.PutUint32(base[24:], 5)
+	binary.LittleEndian.PutUint32(base[36:], root)
+	size := (len(b.bins) + baseBlockSize - 1) &^ (baseBlockSize - 1)
+	binary.LittleEndian.PutUint32(base[40:], uint32(size))
+
+	return append(append(base, b.bins...), make([]byte, size-len(b.bins))...)
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v), byte(v>>8))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func utf16Bytes(str string) []byte {
+	var retval []byte
+	for _, unit := range utf16.Encode([]rune(str)) {
+		retval = appendUint16(retval, unit)
+	}
+	return retval
+}
+
+func testHive() []byte {
+	b := newHiveBuilder()
+	big := bytes.Repeat([]byte{1, 2, 3, 4, 5}, 4000)
+	deep := b.key("Deep", nil, []uint32{b.value("Name", SZ, utf16Bytes("deep\x00"))})
+	app := b.key("App", []uint32{deep}, []uint32{
+		b.value("", SZ, utf16Bytes("default\x00")),
+		b.value("Count", DWORD, []byte{5, 0, 0, 0}),
+		b.value("Name", EXPAND_SZ, utf16Bytes("%APPDATA%\\App\x00")),
+		b.value("List", MULTI_SZ, utf16Bytes("a\x00b\x00\x00")),
+		b.value("Big", BINARY, big),
+		b.value("Link", 6, []byte{1, 2}),
+	})
+	other := b.key("Other", nil, nil)
+	software := b.key("Software", []uint32{app, other}, nil)
+	empty := b.key("Empty", nil, nil)
+
+	return b.hive(b.key("ROOT", []uint32{software, empty}, nil))
+}
+
+func TestHive(t *testing.T) {
+	t.Log("Testing hive files.")
+	{
+		file := filepath.Join(t.TempDir(), "NTUSER.DAT")
+		if err := os.WriteFile(file, testHive(), 0o600); err != nil {
+			t.Fatalf("\t%s\tUnable to write test hive: %v", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\treading a key.", testID)
+		{
+			tree, err := Provider(file, Config{Path: "software\\APP", DefaultValue: "Default"}).Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read hive: %v.", failed, err)
+			}
+			expected := map[string]interface{}{
+				"Default": "default",
+				"Count":   uint64(5),
+				"Name":    "%APPDATA%\\App",
+				"List":    []string{"a", "b"},
+				"Big":     bytes.Repeat([]byte{1, 2, 3, 4, 5}, 4000),
+				"Deep":    map[string]interface{}{"Name": "deep"},
+			}
+			if !reflect.DeepEqual(tree, expected) {
+				t.Fatalf("\t%s\tTree is invalid, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tTree is read.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tdepth limit.", testID)
+		{
+			tree, err := Provider(file, Config{MaxDepth: 2}).Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read hive: %v.", failed, err)
+			}
+			expected := map[string]interface{}{
+				"Software": map[string]interface{}{},
+				"Empty":    map[string]interface{}{},
+			}
+			if !reflect.DeepEqual(tree, expected) {
+				t.Fatalf("\t%s\tTree is invalid, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tDepth is limited.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tmissing key.", testID)
+		{
+			if _, err := Provider(file, Config{Path: "Software\\Missing"}).Read(); !errors.Is(err, ErrNotExist) {
+				t.Fatalf("\t%s\tRead() should fail with ErrNotExist, got: %v.", failed, err)
+			}
+			t.Logf("\t%s\tMissing key is reported.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tcorrupted hive.", testID)
+		{
+			data := testHive()
+			binary.LittleEndian.PutUint32(data[36:], 0xFFFFFF)
+			h, err := Parse(data)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to parse hive: %v.", failed, err)
+			}
+			if _, err := h.Tree(Config{}); err == nil {
+				t.Fatalf("\t%s\tReading a corrupted hive should fail.", failed)
+			}
+			if _, err := Parse(data[:100]); err == nil {
+				t.Fatalf("\t%s\tParsing a truncated hive should fail.", failed)
+			}
+			t.Logf("\t%s\tCorruption is reported.", success)
+		}
+	}
+}
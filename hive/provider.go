@@ -0,0 +1,139 @@
+package hive
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/pda0/koanf-winreg/v2/regfile"
+)
+
+// Config selects the part of a hive read into the tree.
+type Config struct {
+	Path         string // A top path in the hive, relative to its root key
+	DefaultValue string // The name of the value to which the default key value will be mapped
+	MaxDepth     uint   // Maximum subkey reading depth, zero means no limit
+}
+
+// File is a koanf.Provider of a hive file.
+type File struct {
+	name string
+	cfg  Config
+}
+
+// Provider returns a provider of the hive file name. The file is read
+// again by each Read().
+func Provider(name string, cfg Config) *File {
+	return &File{name: name, cfg: cfg}
+}
+
+// ReadBytes is not supported by the hive provider.
+func (f *File) ReadBytes() ([]byte, error) {
+	return nil, errors.New("hive provider does not support this method")
+}
+
+// Read parses the hive file and returns the tree of the configured key.
+func (f *File) Read() (map[string]interface{}, error) {
+	data, err := os.ReadFile(f.name)
+	if err != nil {
+		return nil, err
+	}
+	h, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.Tree(f.cfg)
+}
+
+// Tree returns the nested tree of the key cfg.Path. Values are converted
+// as the winreg provider reads them, except that REG_EXPAND_SZ strings are
+// not expanded, since they refer to the environment of the machine the
+// hive belongs to. Values of other types are left out.
+func (h *Hive) Tree(cfg Config) (map[string]interface{}, error) {
+	root, err := h.Root()
+	if err != nil {
+		return nil, err
+	}
+	k, err := root.SubKey(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return readKey(k, cfg, 1, map[uint32]bool{})
+}
+
+// readKey reads the key k at level. ancestors are the offsets of the keys
+// being read, which a corrupted hive may list as subkeys.
+func readKey(k *Key, cfg Config, level uint, ancestors map[uint32]bool) (map[string]interface{}, error) {
+	if ancestors[k.offset] {
+		return nil, fmt.Errorf("%s: key is its own subkey", k.name)
+	}
+	ancestors[k.offset] = true
+	defer delete(ancestors, k.offset)
+
+	values, err := k.Values()
+	if err != nil {
+		return nil, err
+	}
+
+	retval := make(map[string]interface{})
+	for _, v := range values {
+		name := v.Name
+		if name == "" && v.Type == SZ {
+			if cfg.DefaultValue == "" {
+				continue
+			}
+			name = cfg.DefaultValue
+		}
+		if data, ok := convertValue(v.Type, v.Data); ok {
+			retval[name] = data
+		}
+	}
+
+	if cfg.MaxDepth == 0 || level < cfg.MaxDepth {
+		subKeys, err := k.SubKeys()
+		if err != nil {
+			return nil, err
+		}
+		for _, sub := range subKeys {
+			tree, err := readKey(sub, cfg, level+1, ancestors)
+			if err != nil {
+				return nil, err
+			}
+			retval[sub.Name()] = tree
+		}
+	}
+
+	return retval, nil
+}
+
+// convertValue converts stored data to the Go type returned by the winreg
+// provider. Values of unsupported types are reported with ok set to false.
+func convertValue(typ uint32, data []byte) (interface{}, bool) {
+	switch typ {
+	case SZ, EXPAND_SZ:
+		str := string(utf16.Decode(bytesToUTF16(data)))
+		if i := strings.IndexByte(str, 0); i >= 0 {
+			str = str[:i]
+		}
+		return str, true
+	case MULTI_SZ:
+		return regfile.DecodeMultiString(data), true
+	case DWORD:
+		if len(data) == 4 {
+			return uint64(binary.LittleEndian.Uint32(data)), true
+		}
+	case QWORD:
+		if len(data) == 8 {
+			return binary.LittleEndian.Uint64(data), true
+		}
+	case BINARY:
+		return data, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,281 @@
+package regfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// RegFile implements koanf.Parser for .reg files. The first key of a file
+// is the root of the tree, all other keys must be its subkeys. Values are
+// converted as the winreg provider reads them, except that REG_EXPAND_SZ
+// strings are not expanded, since they refer to the environment of the
+// machine the file is applied to.
+type RegFile struct {
+	DefaultValue string // The name of the value to which the default key value will be mapped
+	Key          string // Full name of the root key written by Marshal(), e.g. "HKEY_CURRENT_USER\Software\Vendor"
+}
+
+// Parser returns a .reg file parser.
+func Parser() *RegFile {
+	return &RegFile{}
+}
+
+// Unmarshal parses a .reg file into a nested tree of values.
+func (p *RegFile) Unmarshal(data []byte) (map[string]interface{}, error) {
+	keys, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	retval := make(map[string]interface{})
+	var base string
+	for _, k := range keys {
+		if base == "" {
+			if k.Delete {
+				return nil, fmt.Errorf("line %d: the first key can't be deleted", k.Line)
+			}
+			base = k.Name
+		}
+		path, err := relativePath(base, k.Name)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", k.Line, err)
+		}
+
+		if k.Delete {
+			if path == "" {
+				retval = make(map[string]interface{})
+			} else {
+				parent, name := splitParent(path)
+				if m := subTree(retval, parent, false); m != nil {
+					delete(m, findName(m, name))
+				}
+			}
+			continue
+		}
+
+		m := subTree(retval, path, true)
+		for _, v := range k.Values {
+			name := v.Name
+			if name == "" {
+				if p.DefaultValue == "" || (!v.Delete && v.Type != SZ) {
+					continue
+				}
+				name = p.DefaultValue
+			}
+			if v.Delete {
+				delete(m, findName(m, name))
+				continue
+			}
+			if data, ok := convertValue(v.Type, v.Data); ok {
+				m[name] = data
+			}
+		}
+	}
+
+	return retval, nil
+}
+
+// Marshal writes a tree as a .reg file with its root at Key. Nested maps
+// are written as subkeys, other Go types are stored as by the winreg
+// provider's Apply(): string as REG_SZ, []string as REG_MULTI_SZ, uint32 and
+// bool as REG_DWORD, uint64, int64 and int as REG_QWORD, []byte as
+// REG_BINARY.
+func (p *RegFile) Marshal(tree map[string]interface{}) ([]byte, error) {
+	if p.Key == "" {
+		return nil, errors.New("regfile parser requires Key to marshal")
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := p.marshalKey(w, p.Key, "", tree); err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (p *RegFile) marshalKey(w *Writer, key, path string, tree map[string]interface{}) error {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w.Key(key)
+	var subKeys []string
+	for _, name := range names {
+		if _, ok := tree[name].(map[string]interface{}); ok {
+			subKeys = append(subKeys, name)
+			continue
+		}
+		v := Value{Name: name}
+		if p.DefaultValue != "" && name == p.DefaultValue {
+			v.Name = ""
+		}
+		var err error
+		if v.Type, v.Data, err = encodeValue(tree[name]); err != nil {
+			return fmt.Errorf("%s: %w", joinPath(path, name), err)
+		}
+		w.Value(v)
+	}
+	for _, name := range subKeys {
+		if err := p.marshalKey(w, key+"\\"+name, joinPath(path, name), tree[name].(map[string]interface{})); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeValue converts Go data into a registry type and its stored form.
+func encodeValue(value interface{}) (uint32, []byte, error) {
+	switch v := value.(type) {
+	case string:
+		return SZ, utf16Bytes(utf16.Encode(append([]rune(v), 0))), nil
+	case []string:
+		var buf []uint16
+		for _, str := range v {
+			buf = append(append(buf, utf16.Encode([]rune(str))...), 0)
+		}
+		return MULTI_SZ, utf16Bytes(append(buf, 0)), nil
+	case uint32:
+		return DWORD, uint32Bytes(v), nil
+	case bool:
+		var dw uint32
+		if v {
+			dw = 1
+		}
+		return DWORD, uint32Bytes(dw), nil
+	case uint64:
+		return QWORD, uint64Bytes(v), nil
+	case int64:
+		return QWORD, uint64Bytes(uint64(v)), nil
+	case int:
+		return QWORD, uint64Bytes(uint64(v)), nil
+	case []byte:
+		return BINARY, v, nil
+	default:
+		return 0, nil, fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+func uint32Bytes(v uint32) []byte {
+	retval := make([]byte, 4)
+	binary.LittleEndian.PutUint32(retval, v)
+
+	return retval
+}
+
+func uint64Bytes(v uint64) []byte {
+	retval := make([]byte, 8)
+	binary.LittleEndian.PutUint64(retval, v)
+
+	return retval
+}
+
+func joinPath(parent, child string) string {
+	if parent == "" {
+		return child
+	}
+	return parent + "\\" + child
+}
+
+// relativePath returns name relative to base, which must be name or one
+// of its parents.
+func relativePath(base, name string) (string, error) {
+	if strings.EqualFold(name, base) {
+		return "", nil
+	}
+	if len(name) > len(base) && strings.EqualFold(name[:len(base)], base) && name[len(base)] == '\\' {
+		return name[len(base)+1:], nil
+	}
+
+	return "", fmt.Errorf("%s is not a subkey of %s", name, base)
+}
+
+func splitParent(path string) (string, string) {
+	if i := strings.LastIndexByte(path, '\\'); i >= 0 {
+		return path[:i], path[i+1:]
+	}
+	return "", path
+}
+
+// subTree returns the map of the key path, creating missing maps if create
+// is set. Key names are case-insensitive as in the registry.
+func subTree(tree map[string]interface{}, path string, create bool) map[string]interface{} {
+	if path == "" {
+		return tree
+	}
+	for _, name := range strings.Split(path, "\\") {
+		found := findName(tree, name)
+		sub, ok := tree[found].(map[string]interface{})
+		if !ok {
+			if !create {
+				return nil
+			}
+			sub = make(map[string]interface{})
+			tree[found] = sub
+		}
+		tree = sub
+	}
+
+	return tree
+}
+
+// findName returns the name of the item of tree matching name ignoring
+// case, or name if there is no such item.
+func findName(tree map[string]interface{}, name string) string {
+	if _, ok := tree[name]; ok {
+		return name
+	}
+	for item := range tree {
+		if strings.EqualFold(item, name) {
+			return item
+		}
+	}
+
+	return name
+}
+
+// convertValue converts stored data to the Go type returned by the winreg
+// provider. Values of unsupported types are reported with ok set to false.
+func convertValue(typ uint32, data []byte) (interface{}, bool) {
+	switch typ {
+	case SZ, EXPAND_SZ:
+		str := string(utf16.Decode(bytesToUTF16(data)))
+		if i := strings.IndexByte(str, 0); i >= 0 {
+			str = str[:i]
+		}
+		return str, true
+	case MULTI_SZ:
+		return DecodeMultiString(data), true
+	case DWORD:
+		if len(data) == 4 {
+			return uint64(binary.LittleEndian.Uint32(data)), true
+		}
+	case QWORD:
+		if len(data) == 8 {
+			return binary.LittleEndian.Uint64(data), true
+		}
+	case BINARY:
+		return data, true
+	}
+
+	return nil, false
+}
+
+func bytesToUTF16(buf []byte) []uint16 {
+	units := make([]uint16, len(buf)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(buf[i*2:])
+	}
+	return units
+}
@@ -0,0 +1,198 @@
+package regfile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+var testFile = strings.Join([]string{
+	Header,
+	"",
+	"[HKEY_CURRENT_USER\\Software\\Vendor\\App]",
+	"@=\"default \\\"quoted\\\" C:\\\\\"",
+	"\"on\"=dword:00000001",
+	"\"Binary\"=hex:01,02,03",
+	"\"Int64\"=hex(b):00,f2,05,2a,01,00,00,00",
+	"\"Expand\"=hex(2):25,00,50,00,41,00,54,00,48,00,25,00,00,00",
+	"\"StrList\"=hex(7):61,00,00,00,62,00,20,00,\\",
+	"  63,00,00,00,00,00",
+	"\"Removed\"=-",
+	"",
+	"; A comment",
+	"[HKEY_CURRENT_USER\\Software\\Vendor\\App\\SubKey]",
+	"\"Name\"=\"sub\"",
+	"",
+	"[HKEY_CURRENT_USER\\Software\\Vendor\\App\\Deleted]",
+	"\"Name\"=\"deleted\"",
+	"",
+	"[-HKEY_CURRENT_USER\\Software\\Vendor\\App\\deleted]",
+	"",
+}, "\r\n")
+
+func TestUnmarshal(t *testing.T) {
+	t.Log("Testing parsing of .reg files.")
+	{
+		expected := map[string]interface{}{
+			"Default": "default \"quoted\" C:\\",
+			"on":      uint64(1),
+			"Binary":  []byte{1, 2, 3},
+			"Int64":   uint64(5000000000),
+			"Expand":  "%PATH%",
+			"StrList": []string{"a", "b c"},
+			"SubKey":  map[string]interface{}{"Name": "sub"},
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tUTF-8 file.", testID)
+		{
+			p := &RegFile{DefaultValue: "Default"}
+			tree, err := p.Unmarshal([]byte(testFile))
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to parse file: %v.", failed, err)
+			}
+			if !reflect.DeepEqual(tree, expected) {
+				t.Fatalf("\t%s\tTree is invalid, got %v, expect %v.", failed, tree, expected)
+			}
+			t.Logf("\t%s\tTree is parsed.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tUTF-16 file without default value name.", testID)
+		{
+			data := []byte{0xFF, 0xFE}
+			for _, c := range utf16.Encode([]rune(testFile)) {
+				data = append(data, byte(c), byte(c>>8))
+			}
+			tree, err := Parser().Unmarshal(data)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to parse file: %v.", failed, err)
+			}
+			delete(expected, "Default")
+			if !reflect.DeepEqual(tree, expected) {
+				t.Fatalf("\t%s\tTree is invalid, got %v, expect %v.", failed, tree, expected)
+			}
+			t.Logf("\t%s\tTree is parsed.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tempty strings in REG_MULTI_SZ.", testID)
+		{
+			file := Header + "\r\n\r\n[HKEY_CURRENT_USER\\A]\r\n" +
+				"\"Gap\"=hex(7):61,00,00,00,00,00,62,00,00,00,00,00\r\n" +
+				"\"Empty\"=hex(7):00,00\r\n" +
+				"\"Unterminated\"=hex(7):61,00,00,00,62,00\r\n"
+			tree, err := Parser().Unmarshal([]byte(file))
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to parse file: %v.", failed, err)
+			}
+			expected := map[string]interface{}{"Gap": []string{"a", "", "b"}, "Empty": []string{}, "Unterminated": []string{"a", "b"}}
+			if !reflect.DeepEqual(tree, expected) {
+				t.Fatalf("\t%s\tTree is invalid, got %v, expect %v.", failed, tree, expected)
+			}
+			t.Logf("\t%s\tEmpty strings inside the list are kept.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tinvalid files.", testID)
+		{
+			for _, file := range []string{
+				"REGEDIT4\r\n\r\n[HKEY_CURRENT_USER\\A]\r\n",
+				Header + "\r\n\r\n\"Value\"=\"outside\"\r\n",
+				Header + "\r\n\r\n[HKEY_CURRENT_USER\\A]\r\n[HKEY_CURRENT_USER\\B]\r\n",
+				Header + "\r\n\r\n[HKEY_CURRENT_USER\\A]\r\n\"Value\"=dword:1000000000\r\n",
+				Header + "\r\n\r\n[HKEY_CURRENT_USER\\A]\r\n\"Value\"=hex:1,xx\r\n",
+				Header + "\r\n\r\n[HKEY_CURRENT_USER\\A]\r\n\"Value=\"unterminated\r\n",
+			} {
+				if _, err := Parser().Unmarshal([]byte(file)); err == nil {
+					t.Fatalf("\t%s\tUnmarshal() of an invalid file should fail:\n%s", failed, file)
+				}
+			}
+			t.Logf("\t%s\tInvalid files are rejected.", success)
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	t.Log("Testing writing of .reg files.")
+	{
+		p := &RegFile{DefaultValue: "Default", Key: "HKEY_CURRENT_USER\\Software\\Vendor\\App"}
+		tree := map[string]interface{}{
+			"Default": "C:\\Program Files\\\"App\"",
+			"on":      true,
+			"Size":    uint32(800),
+			"Int64":   uint64(5000000000),
+			"List":    []string{"a", "b"},
+			"Binary":  []byte{1, 2, 3},
+			"Window":  map[string]interface{}{"Title": "main"},
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tencoding.", testID)
+		{
+			data, err := p.Marshal(tree)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to marshal tree: %v.", failed, err)
+			}
+			units := make([]uint16, (len(data)-2)/2)
+			for i := range units {
+				units[i] = uint16(data[2+i*2]) | uint16(data[3+i*2])<<8
+			}
+			expected := strings.Join([]string{
+				Header,
+				"",
+				"[HKEY_CURRENT_USER\\Software\\Vendor\\App]",
+				"\"Binary\"=hex:01,02,03",
+				"@=\"C:\\\\Program Files\\\\\\\"App\\\"\"",
+				"\"Int64\"=hex(b):00,f2,05,2a,01,00,00,00",
+				"\"List\"=hex(7):61,00,00,00,62,00,00,00,00,00",
+				"\"Size\"=dword:00000320",
+				"\"on\"=dword:00000001",
+				"",
+				"[HKEY_CURRENT_USER\\Software\\Vendor\\App\\Window]",
+				"\"Title\"=\"main\"",
+				"",
+				"",
+			}, "\r\n")
+			if text := string(utf16.Decode(units)); data[0] != 0xFF || data[1] != 0xFE || text != expected {
+				t.Fatalf("\t%s\tFile is invalid, got:\n%s\nexpect:\n%s", failed, text, expected)
+			}
+			t.Logf("\t%s\tTree is written.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tround trip.", testID)
+		{
+			data, err := p.Marshal(tree)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to marshal tree: %v.", failed, err)
+			}
+			parsed, err := p.Unmarshal(data)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to parse file: %v.", failed, err)
+			}
+			if parsed["Default"] != tree["Default"] || parsed["Size"] != uint64(800) || parsed["on"] != uint64(1) || !reflect.DeepEqual(parsed["Window"], tree["Window"]) {
+				t.Fatalf("\t%s\tTree is invalid, got %v.", failed, parsed)
+			}
+			t.Logf("\t%s\tTree is parsed back.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tunsupported values.", testID)
+		{
+			if _, err := p.Marshal(map[string]interface{}{"Float": 1.5}); err == nil {
+				t.Fatalf("\t%s\tMarshal() of float should fail.", failed)
+			}
+			if _, err := Parser().Marshal(tree); err == nil {
+				t.Fatalf("\t%s\tMarshal() without Key should fail.", failed)
+			}
+			t.Logf("\t%s\tErrors are reported.", success)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+// Package regfile parses files in Registry Editor 5.00 format (.reg files).
+// It doesn't depend on the Windows API, so .reg files kept in version
+// control can be loaded by koanf on any OS:
+//
+//	k.Load(file.Provider("app.reg"), regfile.Parser())
+package regfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Header is the first line of files in Registry Editor 5.00 format.
+const Header = "Windows Registry Editor Version 5.00"
+
+// Registry value types used in .reg files.
+const (
+	SZ        = 1
+	EXPAND_SZ = 2
+	BINARY    = 3
+	DWORD     = 4
+	MULTI_SZ  = 7
+	QWORD     = 11
+)
+
+// Key is a key section of a .reg file.
+type Key struct {
+	Line   int    // Line number of the section header
+	Name   string // Full key name, e.g. "HKEY_CURRENT_USER\Software\Vendor"
+	Delete bool   // The key is deleted with its subkeys, written as [-name]
+	Values []Value
+}
+
+// Value is a value line of a key section.
+type Value struct {
+	Line   int
+	Name   string // Empty for the default key value, written as @
+	Delete bool   // The value is deleted, written as name=-
+	Type   uint32
+	Data   []byte // Stored form of the value, e.g. NUL terminated UTF-16LE for SZ
+}
+
+// Parse parses a .reg file encoded in UTF-16LE with a byte order mark, as
+// written by Registry Editor, or in UTF-8.
+func Parse(data []byte) ([]Key, error) {
+	var text string
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		units := make([]uint16, (len(data)-2)/2)
+		for i := range units {
+			units[i] = binary.LittleEndian.Uint16(data[2+i*2:])
+		}
+		text = string(utf16.Decode(units))
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		text = string(data[3:])
+	default:
+		text = string(data)
+	}
+
+	lines := splitLines(text)
+	if len(lines) == 0 || lines[0].text != Header {
+		return nil, errors.New("not a Registry Editor 5.00 file")
+	}
+
+	var retval []Key
+	for _, line := range lines[1:] {
+		switch {
+		case line.text == "" || line.text[0] == ';':
+			continue
+		case line.text[0] == '[':
+			if !strings.HasSuffix(line.text, "]") {
+				return nil, fmt.Errorf("line %d: invalid key", line.n)
+			}
+			k := Key{Line: line.n, Name: line.text[1 : len(line.text)-1]}
+			if strings.HasPrefix(k.Name, "-") {
+				k.Name, k.Delete = k.Name[1:], true
+			}
+			retval = append(retval, k)
+		default:
+			if len(retval) == 0 || retval[len(retval)-1].Delete {
+				return nil, fmt.Errorf("line %d: value outside of a key", line.n)
+			}
+			v, err := parseValue(line.text)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line.n, err)
+			}
+			v.Line = line.n
+			k := &retval[len(retval)-1]
+			k.Values = append(k.Values, v)
+		}
+	}
+
+	return retval, nil
+}
+
+type line struct {
+	n    int // Number of the first line
+	text string
+}
+
+// splitLines splits text into lines, joining lines continued by a trailing
+// backslash.
+func splitLines(text string) []line {
+	var (
+		retval []line
+		cont   bool
+	)
+	for i, str := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		str = strings.TrimSpace(str)
+		if cont {
+			retval[len(retval)-1].text += str
+		} else {
+			retval = append(retval, line{n: i + 1, text: str})
+		}
+		last := &retval[len(retval)-1]
+		if cont = strings.HasSuffix(last.text, "\\") && !strings.HasPrefix(last.text, "["); cont {
+			last.text = last.text[:len(last.text)-1]
+		}
+	}
+
+	return retval
+}
+
+// parseValue parses a value line: @=data or "name"=data, where data is
+// a quoted string, dword:, hex: or hex(type): data, or - to delete.
+func parseValue(str string) (Value, error) {
+	var (
+		v    Value
+		rest string
+		err  error
+	)
+	if strings.HasPrefix(str, "@") {
+		rest = str[1:]
+	} else if v.Name, rest, err = unquote(str); err != nil {
+		return v, err
+	}
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(rest, "=") {
+		return v, errors.New("missing =")
+	}
+	data := strings.TrimSpace(rest[1:])
+
+	switch {
+	case data == "-":
+		v.Delete = true
+	case strings.HasPrefix(data, `"`):
+		var tail string
+		if str, tail, err = unquote(data); err != nil {
+			return v, err
+		}
+		if strings.TrimSpace(tail) != "" {
+			return v, fmt.Errorf("unexpected %q after string", tail)
+		}
+		v.Type, v.Data = SZ, utf16Bytes(utf16.Encode(append([]rune(str), 0)))
+	case strings.HasPrefix(data, "dword:"):
+		var dw uint64
+		if dw, err = strconv.ParseUint(data[len("dword:"):], 16, 32); err != nil {
+			return v, fmt.Errorf("invalid dword: %v", err)
+		}
+		v.Type, v.Data = DWORD, make([]byte, 4)
+		binary.LittleEndian.PutUint32(v.Data, uint32(dw))
+	case strings.HasPrefix(data, "hex:"):
+		v.Type = BINARY
+		if v.Data, err = parseHex(data[len("hex:"):]); err != nil {
+			return v, err
+		}
+	case strings.HasPrefix(data, "hex("):
+		end := strings.Index(data, "):")
+		if end < 0 {
+			return v, errors.New("invalid hex type")
+		}
+		var typ uint64
+		if typ, err = strconv.ParseUint(data[len("hex("):end], 16, 32); err != nil {
+			return v, fmt.Errorf("invalid hex type: %v", err)
+		}
+		v.Type = uint32(typ)
+		if v.Data, err = parseHex(data[end+2:]); err != nil {
+			return v, err
+		}
+	default:
+		return v, fmt.Errorf("unsupported data %q", data)
+	}
+
+	return v, nil
+}
+
+// parseHex parses comma separated hex bytes.
+func parseHex(data string) ([]byte, error) {
+	data = strings.TrimSpace(data)
+	if data == "" {
+		return []byte{}, nil
+	}
+	retval := make([]byte, 0, (len(data)+1)/3)
+	for _, item := range strings.Split(data, ",") {
+		b, err := hex.DecodeString(strings.TrimSpace(item))
+		if err != nil || len(b) != 1 {
+			return nil, fmt.Errorf("invalid hex byte %q", item)
+		}
+		retval = append(retval, b[0])
+	}
+
+	return retval, nil
+}
+
+// unquote parses a quoted string at the start of str, returning it and
+// the rest of str.
+func unquote(str string) (string, string, error) {
+	if !strings.HasPrefix(str, `"`) {
+		return "", "", errors.New("missing quote")
+	}
+	var b strings.Builder
+	for i := 1; i < len(str); i++ {
+		switch str[i] {
+		case '"':
+			return b.String(), str[i+1:], nil
+		case '\\':
+			if i++; i == len(str) {
+				return "", "", errors.New("unterminated string")
+			}
+		}
+		b.WriteByte(str[i])
+	}
+
+	return "", "", errors.New("unterminated string")
+}
+
+// DecodeMultiString decodes the stored form of a REG_MULTI_SZ value: NUL
+// terminated UTF-16LE strings followed by the NUL terminating the list.
+// Empty strings inside the list are kept, a string missing its terminator
+// at the end of data is still returned.
+func DecodeMultiString(data []byte) []string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(data[i*2:])
+	}
+	if n := len(units); n > 0 && units[n-1] == 0 {
+		units = units[:n-1]
+	}
+
+	retval := []string{}
+	start := 0
+	for i, c := range units {
+		if c == 0 {
+			retval = append(retval, string(utf16.Decode(units[start:i])))
+			start = i + 1
+		}
+	}
+	if start < len(units) {
+		retval = append(retval, string(utf16.Decode(units[start:])))
+	}
+
+	return retval
+}
+
+func utf16Bytes(s []uint16) []byte {
+	retval := make([]byte, len(s)*2)
+	for i, c := range s {
+		binary.LittleEndian.PutUint16(retval[i*2:], c)
+	}
+
+	return retval
+}
@@ -0,0 +1,146 @@
+package regfile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// lineWidth is the line width at which Registry Editor wraps hex data.
+const lineWidth = 80
+
+// Writer writes a .reg file as UTF-16LE with CRLF line ends, like Registry
+// Editor does. The first write error is kept and returned by Flush().
+type Writer struct {
+	w    *bufio.Writer
+	keys int
+	err  error
+}
+
+// NewWriter returns a writer of a .reg file to w. The header is written
+// with the first key.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Key starts the section of the key name, e.g.
+// "HKEY_CURRENT_USER\Software\Vendor".
+func (w *Writer) Key(name string) {
+	w.section("[" + name + "]")
+}
+
+// DeleteKey writes the deletion of the key name with its subkeys.
+func (w *Writer) DeleteKey(name string) {
+	w.section("[-" + name + "]")
+}
+
+func (w *Writer) section(str string) {
+	if w.keys == 0 {
+		// UTF-16LE byte order mark
+		if _, err := w.w.Write([]byte{0xFF, 0xFE}); err != nil && w.err == nil {
+			w.err = err
+		}
+		w.line(Header)
+	}
+	w.keys++
+	w.line("")
+	w.line(str)
+}
+
+// Value writes a value of the current key, using the quoted string and
+// dword forms where they represent data exactly and hex otherwise.
+func (w *Writer) Value(v Value) {
+	prefix := "@="
+	if v.Name != "" {
+		prefix = quote(v.Name) + "="
+	}
+	if v.Delete {
+		w.line(prefix + "-")
+		return
+	}
+
+	switch v.Type {
+	case SZ:
+		if str, ok := quotable(v.Data); ok {
+			w.line(prefix + quote(str))
+			return
+		}
+	case DWORD:
+		if len(v.Data) == 4 {
+			w.line(fmt.Sprintf("%sdword:%08x", prefix, binary.LittleEndian.Uint32(v.Data)))
+			return
+		}
+	}
+
+	if v.Type == BINARY {
+		prefix += "hex:"
+	} else {
+		prefix += fmt.Sprintf("hex(%x):", v.Type)
+	}
+	w.hex(prefix, v.Data)
+}
+
+// Flush ends the file and writes buffered data, returning the first error
+// of the writer.
+func (w *Writer) Flush() error {
+	if w.keys > 0 {
+		w.line("")
+	}
+	if w.err == nil {
+		w.err = w.w.Flush()
+	}
+
+	return w.err
+}
+
+// hex writes data as comma separated hex bytes, wrapping long lines with
+// a trailing backslash.
+func (w *Writer) hex(prefix string, data []byte) {
+	line := prefix
+	for i, b := range data {
+		item := fmt.Sprintf("%02x", b)
+		if i < len(data)-1 {
+			item += ","
+		}
+		if len(line)+len(item) > lineWidth-3 {
+			w.line(line + "\\")
+			line = "  "
+		}
+		line += item
+	}
+	w.line(line)
+}
+
+func (w *Writer) line(str string) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.w.Write(utf16Bytes(utf16.Encode([]rune(str + "\r\n"))))
+}
+
+// quotable returns SZ data as a string if it can be written quoted:
+// terminated by a single NUL and without other NULs or line breaks.
+func quotable(data []byte) (string, bool) {
+	if len(data) < 2 || len(data)%2 != 0 {
+		return "", false
+	}
+	str := bytesToUTF16(data)
+	if str[len(str)-1] != 0 {
+		return "", false
+	}
+	for _, c := range str[:len(str)-1] {
+		if c == 0 || c == '\r' || c == '\n' {
+			return "", false
+		}
+	}
+
+	return string(utf16.Decode(str[:len(str)-1])), true
+}
+
+// quote quotes str as a .reg file string.
+func quote(str string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(str) + `"`
+}
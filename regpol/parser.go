@@ -0,0 +1,303 @@
+package regpol
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/pda0/koanf-winreg/v2/regfile"
+)
+
+// RegPol implements koanf.Parser for Registry.pol files. Entries are
+// applied in order as the Group Policy client applies them, including the
+// deletion commands, and the keys under Path make the tree. Values are
+// converted as the winreg provider reads them, except that REG_EXPAND_SZ
+// strings are not expanded.
+type RegPol struct {
+	Path         string // Top key of the tree, relative to HKLM or HKCU, the whole file if empty
+	DefaultValue string // The name of the value to which the default key value will be mapped
+}
+
+// Parser returns a Registry.pol parser of the whole file.
+func Parser() *RegPol {
+	return &RegPol{}
+}
+
+// Unmarshal parses a Registry.pol file into a nested tree of values.
+func (p *RegPol) Unmarshal(data []byte) (map[string]interface{}, error) {
+	entries, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	retval := make(map[string]interface{})
+	base := strings.Trim(p.Path, "\\")
+	for _, e := range entries {
+		path, ok := relativePath(base, strings.Trim(e.Key, "\\"))
+		if !ok {
+			continue
+		}
+		m := subTree(retval, path, true)
+
+		switch name := e.Name; {
+		case strings.EqualFold(name, "**delvals."):
+			for item, value := range m {
+				if _, ok := value.(map[string]interface{}); !ok {
+					delete(m, item)
+				}
+			}
+		case len(name) > 6 && strings.EqualFold(name[:6], "**del."):
+			p.deleteValue(m, name[6:])
+		case strings.EqualFold(name, "**DeleteValues"):
+			for _, item := range splitList(e) {
+				p.deleteValue(m, item)
+			}
+		case strings.EqualFold(name, "**DeleteKeys"):
+			for _, item := range splitList(e) {
+				parent, name := splitParent(strings.Trim(item, "\\"))
+				if sub := subTree(m, parent, false); sub != nil {
+					delete(sub, findName(sub, name))
+				}
+			}
+		case len(name) > 7 && strings.EqualFold(name[:7], "**soft."):
+			// The value is set only if it doesn't exist
+			if name = p.valueName(name[7:], e.Type); name != "" {
+				if _, ok := m[findName(m, name)]; !ok {
+					p.setValue(m, name, e)
+				}
+			}
+		case strings.HasPrefix(name, "**"):
+			// Other commands, like **SecureKey, don't change values
+		default:
+			if e.Name == "" && e.Type == NONE && len(e.Data) == 0 {
+				// The entry only creates the key
+				continue
+			}
+			if name = p.valueName(name, e.Type); name != "" {
+				p.setValue(m, name, e)
+			}
+		}
+	}
+
+	return retval, nil
+}
+
+// valueName returns the tree name of the value name, empty if the value
+// is left out.
+func (p *RegPol) valueName(name string, typ uint32) string {
+	if name == "" && typ == SZ {
+		return p.DefaultValue
+	}
+
+	return name
+}
+
+func (p *RegPol) setValue(m map[string]interface{}, name string, e Entry) {
+	if data, ok := convertValue(e.Type, e.Data); ok {
+		delete(m, findName(m, name))
+		m[name] = data
+	}
+}
+
+func (p *RegPol) deleteValue(m map[string]interface{}, name string) {
+	if name = p.valueName(name, SZ); name != "" {
+		if _, ok := m[findName(m, name)].(map[string]interface{}); !ok {
+			delete(m, findName(m, name))
+		}
+	}
+}
+
+// splitList returns the names of a **DeleteValues or **DeleteKeys entry,
+// which are separated by semicolons.
+func splitList(e Entry) []string {
+	str, _ := convertValue(SZ, e.Data)
+	var retval []string
+	for _, name := range strings.Split(str.(string), ";") {
+		if name != "" {
+			retval = append(retval, name)
+		}
+	}
+
+	return retval
+}
+
+// Marshal writes a tree as a Registry.pol file setting its values under
+// Path. Nested maps are written as subkeys, other Go types are stored as
+// by the winreg provider's Apply(): string as REG_SZ, []string as
+// REG_MULTI_SZ, uint32 and bool as REG_DWORD, uint64, int64 and int as
+// REG_QWORD, []byte as REG_BINARY.
+func (p *RegPol) Marshal(tree map[string]interface{}) ([]byte, error) {
+	if strings.Trim(p.Path, "\\") == "" {
+		return nil, errors.New("regpol parser requires Path to marshal")
+	}
+
+	var entries []Entry
+	if err := p.marshalKey(&entries, strings.Trim(p.Path, "\\"), "", tree); err != nil {
+		return nil, err
+	}
+
+	return Write(entries), nil
+}
+
+func (p *RegPol) marshalKey(entries *[]Entry, key, path string, tree map[string]interface{}) error {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Keys are created even if they have no values
+	*entries = append(*entries, Entry{Key: key})
+	var subKeys []string
+	for _, name := range names {
+		if _, ok := tree[name].(map[string]interface{}); ok {
+			subKeys = append(subKeys, name)
+			continue
+		}
+		e := Entry{Key: key, Name: name}
+		if p.DefaultValue != "" && name == p.DefaultValue {
+			e.Name = ""
+		}
+		var err error
+		if e.Type, e.Data, err = encodeValue(tree[name]); err != nil {
+			return fmt.Errorf("%s: %w", joinPath(path, name), err)
+		}
+		*entries = append(*entries, e)
+	}
+	for _, name := range subKeys {
+		if err := p.marshalKey(entries, key+"\\"+name, joinPath(path, name), tree[name].(map[string]interface{})); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeValue converts Go data into a registry type and its stored form.
+func encodeValue(value interface{}) (uint32, []byte, error) {
+	switch v := value.(type) {
+	case string:
+		return SZ, utf16Bytes(utf16.Encode(append([]rune(v), 0))), nil
+	case []string:
+		var buf []uint16
+		for _, str := range v {
+			buf = append(append(buf, utf16.Encode([]rune(str))...), 0)
+		}
+		return MULTI_SZ, utf16Bytes(append(buf, 0)), nil
+	case uint32:
+		return DWORD, uint32Bytes(v), nil
+	case bool:
+		var dw uint32
+		if v {
+			dw = 1
+		}
+		return DWORD, uint32Bytes(dw), nil
+	case uint64:
+		return QWORD, uint64Bytes(v), nil
+	case int64:
+		return QWORD, uint64Bytes(uint64(v)), nil
+	case int:
+		return QWORD, uint64Bytes(uint64(v)), nil
+	case []byte:
+		return BINARY, v, nil
+	default:
+		return 0, nil, fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+func joinPath(parent, child string) string {
+	if parent == "" {
+		return child
+	}
+	return parent + "\\" + child
+}
+
+// relativePath returns name relative to base, ok is false if name isn't
+// base or one of its subkeys.
+func relativePath(base, name string) (string, bool) {
+	if base == "" {
+		return name, true
+	}
+	if strings.EqualFold(name, base) {
+		return "", true
+	}
+	if len(name) > len(base) && strings.EqualFold(name[:len(base)], base) && name[len(base)] == '\\' {
+		return name[len(base)+1:], true
+	}
+
+	return "", false
+}
+
+func splitParent(path string) (string, string) {
+	if i := strings.LastIndexByte(path, '\\'); i >= 0 {
+		return path[:i], path[i+1:]
+	}
+	return "", path
+}
+
+// subTree returns the map of the key path, creating missing maps if create
+// is set. Key names are case-insensitive as in the registry.
+func subTree(tree map[string]interface{}, path string, create bool) map[string]interface{} {
+	if path == "" {
+		return tree
+	}
+	for _, name := range strings.Split(path, "\\") {
+		found := findName(tree, name)
+		sub, ok := tree[found].(map[string]interface{})
+		if !ok {
+			if !create {
+				return nil
+			}
+			sub = make(map[string]interface{})
+			tree[found] = sub
+		}
+		tree = sub
+	}
+
+	return tree
+}
+
+// findName returns the name of the item of tree matching name ignoring
+// case, or name if there is no such item.
+func findName(tree map[string]interface{}, name string) string {
+	if _, ok := tree[name]; ok {
+		return name
+	}
+	for item := range tree {
+		if strings.EqualFold(item, name) {
+			return item
+		}
+	}
+
+	return name
+}
+
+// convertValue converts stored data to the Go type returned by the winreg
+// provider. Values of unsupported types are reported with ok set to false.
+func convertValue(typ uint32, data []byte) (interface{}, bool) {
+	switch typ {
+	case SZ, EXPAND_SZ:
+		str := string(utf16.Decode(bytesToUTF16(data)))
+		if i := strings.IndexByte(str, 0); i >= 0 {
+			str = str[:i]
+		}
+		return str, true
+	case MULTI_SZ:
+		return regfile.DecodeMultiString(data), true
+	case DWORD:
+		if len(data) == 4 {
+			return uint64(binary.LittleEndian.Uint32(data)), true
+		}
+	case QWORD:
+		if len(data) == 8 {
+			return binary.LittleEndian.Uint64(data), true
+		}
+	case BINARY:
+		return data, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,152 @@
+package regpol
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func sz(str string) []byte {
+	return utf16Bytes(utf16.Encode(append([]rune(str), 0)))
+}
+
+var testEntries = []Entry{
+	{Key: "Software\\Policies\\Vendor\\App"},
+	{Key: "Software\\Policies\\Vendor\\App", Type: SZ, Data: sz("default")},
+	{Key: "Software\\Policies\\Vendor\\App", Name: "on", Type: DWORD, Data: uint32Bytes(1)},
+	{Key: "Software\\Policies\\Vendor\\App", Name: "Int64", Type: QWORD, Data: uint64Bytes(5000000000)},
+	{Key: "Software\\Policies\\Vendor\\App", Name: "Removed", Type: SZ, Data: sz("removed")},
+	{Key: "Software\\Policies\\Vendor\\App", Name: "**del.removed", Type: SZ, Data: sz(" ")},
+	{Key: "Software\\Policies\\Vendor\\App", Name: "**soft.on", Type: DWORD, Data: uint32Bytes(0)},
+	{Key: "Software\\Policies\\Vendor\\App", Name: "**soft.Soft", Type: SZ, Data: sz("soft")},
+	{Key: "Software\\Policies\\Vendor\\App\\SubKey", Name: "Name", Type: SZ, Data: sz("sub")},
+	{Key: "Software\\Policies\\Vendor\\App\\SubKey", Name: "**SecureKey", Type: DWORD, Data: uint32Bytes(1)},
+	{Key: "Software\\Policies\\Vendor\\App\\Values", Name: "A", Type: SZ, Data: sz("a")},
+	{Key: "Software\\Policies\\Vendor\\App\\Values", Name: "B", Type: SZ, Data: sz("b")},
+	{Key: "Software\\Policies\\Vendor\\App\\Values", Name: "**delvals.", Type: SZ, Data: sz(" ")},
+	{Key: "Software\\Policies\\Vendor\\App\\Values", Name: "C", Type: SZ, Data: sz("c")},
+	{Key: "Software\\Policies\\Vendor\\App\\Deleted", Name: "Name", Type: SZ, Data: sz("deleted")},
+	{Key: "Software\\Policies\\Vendor\\App", Name: "**DeleteKeys", Type: SZ, Data: sz("deleted;Missing")},
+	{Key: "Software\\Policies\\Other", Name: "Name", Type: SZ, Data: sz("other")},
+}
+
+func TestUnmarshal(t *testing.T) {
+	t.Log("Testing parsing of Registry.pol files.")
+	{
+		data := Write(testEntries)
+
+		testID := 0
+		t.Logf("\tTest %d:\tentries.", testID)
+		{
+			entries, err := Parse(data)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to parse file: %v.", failed, err)
+			}
+			if !reflect.DeepEqual(entries[1:], testEntries[1:]) || entries[0].Key != testEntries[0].Key || len(entries[0].Data) != 0 {
+				t.Fatalf("\t%s\tEntries are invalid, got %+v.", failed, entries)
+			}
+			t.Logf("\t%s\tEntries are read.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tpolicy tree.", testID)
+		{
+			p := &RegPol{Path: "Software\\Policies\\Vendor\\App\\", DefaultValue: "Default"}
+			tree, err := p.Unmarshal(data)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to parse file: %v.", failed, err)
+			}
+			expected := map[string]interface{}{
+				"Default": "default",
+				"on":      uint64(1),
+				"Int64":   uint64(5000000000),
+				"Soft":    "soft",
+				"SubKey":  map[string]interface{}{"Name": "sub"},
+				"Values":  map[string]interface{}{"C": "c"},
+			}
+			if !reflect.DeepEqual(tree, expected) {
+				t.Fatalf("\t%s\tTree is invalid, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tEntries are applied.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\twhole file.", testID)
+		{
+			tree, err := Parser().Unmarshal(data)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to parse file: %v.", failed, err)
+			}
+			other := subTree(tree, "Software\\Policies\\Other", false)
+			if other == nil || other["Name"] != "other" {
+				t.Fatalf("\t%s\tTree is invalid, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tAll keys are read.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tinvalid files.", testID)
+		{
+			if _, err := Parse([]byte("PReg")); err == nil {
+				t.Fatalf("\t%s\tParsing a file without version should fail.", failed)
+			}
+			if _, err := Parse(data[:len(data)-1]); err == nil {
+				t.Fatalf("\t%s\tParsing a truncated file should fail.", failed)
+			}
+			t.Logf("\t%s\tErrors are reported.", success)
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	t.Log("Testing writing of Registry.pol files.")
+	{
+		p := &RegPol{Path: "Software\\Policies\\Vendor\\App", DefaultValue: "Default"}
+		tree := map[string]interface{}{
+			"Default": "default",
+			"on":      true,
+			"List":    []string{"a", "b"},
+			"Window":  map[string]interface{}{"Title": "main"},
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tround trip.", testID)
+		{
+			data, err := p.Marshal(tree)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to marshal tree: %v.", failed, err)
+			}
+			parsed, err := p.Unmarshal(data)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to parse file: %v.", failed, err)
+			}
+			expected := map[string]interface{}{
+				"Default": "default",
+				"on":      uint64(1),
+				"List":    []string{"a", "b"},
+				"Window":  map[string]interface{}{"Title": "main"},
+			}
+			if !reflect.DeepEqual(parsed, expected) {
+				t.Fatalf("\t%s\tTree is invalid, got %v.", failed, parsed)
+			}
+			t.Logf("\t%s\tTree is parsed back.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tunsupported values.", testID)
+		{
+			if _, err := p.Marshal(map[string]interface{}{"Float": 1.5}); err == nil {
+				t.Fatalf("\t%s\tMarshal() of float should fail.", failed)
+			}
+			if _, err := Parser().Marshal(tree); err == nil {
+				t.Fatalf("\t%s\tMarshal() without Path should fail.", failed)
+			}
+			t.Logf("\t%s\tErrors are reported.", success)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Package regpol parses Registry.pol files, the registry settings of Group
+// Policy objects kept in SYSVOL or in the local GroupPolicy folder. It
+// doesn't depend on the Windows API, so policies can be inspected before
+// they are applied, on any OS:
+//
+//	k.Load(file.Provider("Machine\\Registry.pol"), &regpol.RegPol{Path: "Software\\Policies\\Vendor\\App"})
+package regpol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+)
+
+// Signature is the header of Registry.pol files, followed by Version.
+const (
+	Signature = "PReg"
+	Version   = 1
+)
+
+// Registry value types used in Registry.pol files.
+const (
+	NONE      = 0
+	SZ        = 1
+	EXPAND_SZ = 2
+	BINARY    = 3
+	DWORD     = 4
+	MULTI_SZ  = 7
+	QWORD     = 11
+)
+
+// Entry is a setting of a Registry.pol file. Names starting with "**" are
+// commands of the Group Policy client, e.g. "**del.Name" deletes the value
+// Name and "**delvals." deletes all values of the key.
+type Entry struct {
+	Key  string // Key name relative to HKLM or HKCU, e.g. "Software\Policies\Vendor"
+	Name string // Empty for the default key value or an entry creating the key
+	Type uint32
+	Data []byte // Stored form of the value, e.g. NUL terminated UTF-16LE for SZ
+}
+
+// Parse parses the entries of a Registry.pol file.
+func Parse(data []byte) ([]Entry, error) {
+	if len(data) < 8 || !bytes.Equal(data[:4], []byte(Signature)) {
+		return nil, errors.New("not a Registry.pol file")
+	}
+	if version := binary.LittleEndian.Uint32(data[4:]); version != Version {
+		return nil, fmt.Errorf("unsupported Registry.pol version %d", version)
+	}
+
+	r := &reader{data: data, offset: 8}
+	var retval []Entry
+	for r.offset < len(r.data) {
+		start := r.offset
+		e, err := r.entry()
+		if err != nil {
+			return nil, fmt.Errorf("entry at 0x%X: %w", start, err)
+		}
+		retval = append(retval, e)
+	}
+
+	return retval, nil
+}
+
+// reader reads entries, which are written as
+// [key;name;type;size;data] with all but type, size and data in UTF-16LE.
+type reader struct {
+	data   []byte
+	offset int
+}
+
+func (r *reader) entry() (e Entry, err error) {
+	if err = r.char('['); err != nil {
+		return e, err
+	}
+	if e.Key, err = r.str(); err != nil {
+		return e, err
+	}
+	if err = r.char(';'); err != nil {
+		return e, err
+	}
+	if e.Name, err = r.str(); err != nil {
+		return e, err
+	}
+	if err = r.char(';'); err != nil {
+		return e, err
+	}
+	if e.Type, err = r.uint32(); err != nil {
+		return e, err
+	}
+	if err = r.char(';'); err != nil {
+		return e, err
+	}
+	size, err := r.uint32()
+	if err != nil {
+		return e, err
+	}
+	if err = r.char(';'); err != nil {
+		return e, err
+	}
+	if uint64(size) > uint64(len(r.data)-r.offset) {
+		return e, fmt.Errorf("data size %d out of file", size)
+	}
+	e.Data = append([]byte(nil), r.data[r.offset:r.offset+int(size)]...)
+	r.offset += int(size)
+
+	return e, r.char(']')
+}
+
+func (r *reader) unit() (uint16, error) {
+	if r.offset+2 > len(r.data) {
+		return 0, errors.New("unexpected end of file")
+	}
+	retval := binary.LittleEndian.Uint16(r.data[r.offset:])
+	r.offset += 2
+
+	return retval, nil
+}
+
+func (r *reader) char(c rune) error {
+	unit, err := r.unit()
+	if err != nil {
+		return err
+	}
+	if rune(unit) != c {
+		return fmt.Errorf("%q expected at 0x%X", c, r.offset-2)
+	}
+
+	return nil
+}
+
+// str reads a NUL terminated UTF-16LE string.
+func (r *reader) str() (string, error) {
+	var units []uint16
+	for {
+		unit, err := r.unit()
+		if err != nil {
+			return "", err
+		}
+		if unit == 0 {
+			return string(utf16.Decode(units)), nil
+		}
+		units = append(units, unit)
+	}
+}
+
+func (r *reader) uint32() (uint32, error) {
+	if r.offset+4 > len(r.data) {
+		return 0, errors.New("unexpected end of file")
+	}
+	retval := binary.LittleEndian.Uint32(r.data[r.offset:])
+	r.offset += 4
+
+	return retval, nil
+}
+
+// Write returns a Registry.pol file of entries.
+func Write(entries []Entry) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(Signature)
+	buf.Write(uint32Bytes(Version))
+	for _, e := range entries {
+		buf.Write(utf16Bytes(utf16.Encode([]rune("["))))
+		buf.Write(utf16Bytes(utf16.Encode(append([]rune(e.Key), 0, ';'))))
+		buf.Write(utf16Bytes(utf16.Encode(append([]rune(e.Name), 0, ';'))))
+		buf.Write(uint32Bytes(e.Type))
+		buf.Write(utf16Bytes(utf16.Encode([]rune(";"))))
+		buf.Write(uint32Bytes(uint32(len(e.Data))))
+		buf.Write(utf16Bytes(utf16.Encode([]rune(";"))))
+		buf.Write(e.Data)
+		buf.Write(utf16Bytes(utf16.Encode([]rune("]"))))
+	}
+
+	return buf.Bytes()
+}
+
+func utf16Bytes(s []uint16) []byte {
+	retval := make([]byte, len(s)*2)
+	for i, unit := range s {
+		binary.LittleEndian.PutUint16(retval[i*2:], unit)
+	}
+
+	return retval
+}
+
+func uint32Bytes(v uint32) []byte {
+	retval := make([]byte, 4)
+	binary.LittleEndian.PutUint32(retval, v)
+
+	return retval
+}
+
+func uint64Bytes(v uint64) []byte {
+	retval := make([]byte, 8)
+	binary.LittleEndian.PutUint64(retval, v)
+
+	return retval
+}
+
+func bytesToUTF16(buf []byte) []uint16 {
+	units := make([]uint16, len(buf)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(buf[i*2:])
+	}
+	return units
+}
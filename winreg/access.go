@@ -0,0 +1,152 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// AccessError reports an access right to a key which would be denied.
+type AccessError struct {
+	Key   string // Full name of the key
+	Right string // Name of the denied right, e.g. "KEY_SET_VALUE"
+	Err   error
+}
+
+func (e *AccessError) Error() string {
+	return fmt.Sprintf("%s: %s is denied: %v", e.Key, e.Right, e.Err)
+}
+
+func (e *AccessError) Unwrap() error {
+	return e.Err
+}
+
+type accessRight struct {
+	mask uint32
+	name string
+}
+
+var (
+	readRights = []accessRight{
+		{windows.READ_CONTROL, "READ_CONTROL"},
+		{registry.QUERY_VALUE, "KEY_QUERY_VALUE"},
+		{registry.ENUMERATE_SUB_KEYS, "KEY_ENUMERATE_SUB_KEYS"},
+		{registry.NOTIFY, "KEY_NOTIFY"},
+	}
+	writeRights = []accessRight{
+		{registry.SET_VALUE, "KEY_SET_VALUE"},
+		{registry.CREATE_SUB_KEY, "KEY_CREATE_SUB_KEY"},
+	}
+)
+
+// CanRead checks whether the provider's key can be opened with the rights
+// Read() and Watch() need. A denied right is reported as *AccessError.
+// Subkeys are not checked, the keys of Paths are.
+func (s *WinReg) CanRead() error {
+	return s.checkRights(readRights, false)
+}
+
+// CanWrite checks whether values can be written to the provider's key.
+// If the key doesn't exist yet, its nearest existing parent is checked
+// for the right to create subkeys. A denied right is reported as
+// *AccessError.
+func (s *WinReg) CanWrite() error {
+	return s.checkRights(writeRights, true)
+}
+
+// CanReadAs is like CanRead() but checks the rights of the user the token
+// belongs to.
+func (s *WinReg) CanReadAs(token windows.Token) error {
+	return impersonate(token, s.CanRead)
+}
+
+// CanWriteAs is like CanWrite() but checks the rights of the user the
+// token belongs to.
+func (s *WinReg) CanWriteAs(token windows.Token) error {
+	return impersonate(token, s.CanWrite)
+}
+
+func (s *WinReg) checkRights(rights []accessRight, create bool) error {
+	for _, p := range s.paths {
+		if err := p.checkRights(rights, create); err != nil {
+			return err
+		}
+	}
+	if s.paths != nil {
+		return nil
+	}
+
+	root, err := s.connect()
+	if err != nil {
+		return err
+	}
+	if root != s.key {
+		defer s.api.CloseKey(root)
+	}
+
+	path := s.path
+	if create {
+		for !s.keyExists(root, path) {
+			if path == "" {
+				return s.keyError("open", "", "", windows.ERROR_FILE_NOT_FOUND)
+			}
+			path, _ = splitParent(path)
+			rights = []accessRight{{registry.CREATE_SUB_KEY, "KEY_CREATE_SUB_KEY"}}
+		}
+	}
+
+	for _, right := range rights {
+		s.limiter.Wait()
+		k, err := s.api.OpenKey(root, path, s.getAccess(right.mask))
+		if errors.Is(err, windows.ERROR_ACCESS_DENIED) {
+			return &AccessError{Key: s.getKeyName(path), Right: right.name, Err: err}
+		}
+		if err != nil {
+			return s.keyError("open", path, "", err)
+		}
+		s.api.CloseKey(k)
+	}
+
+	return nil
+}
+
+// splitParent splits a key path into its parent path and the last name.
+func splitParent(path string) (string, string) {
+	path = strings.Trim(path, "\\")
+	if i := strings.LastIndexByte(path, '\\'); i >= 0 {
+		return path[:i], path[i+1:]
+	}
+
+	return "", path
+}
+
+// impersonate calls fn on a thread impersonating the token's user.
+func impersonate(token windows.Token, fn func() error) error {
+	var dup windows.Token
+	if err := windows.DuplicateTokenEx(token, windows.TOKEN_IMPERSONATE|windows.TOKEN_QUERY, nil,
+		windows.SecurityImpersonation, windows.TokenImpersonation, &dup); err != nil {
+		return fmt.Errorf("unable to duplicate token: %w", err)
+	}
+	defer dup.Close()
+
+	runtime.LockOSThread()
+	if err := windows.SetThreadToken(nil, dup); err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("unable to impersonate: %w", err)
+	}
+	defer func() {
+		// A thread which can't stop impersonating is left locked, so it
+		// exits with the goroutine instead of being reused.
+		if windows.RevertToSelf() == nil {
+			runtime.UnlockOSThread()
+		}
+	}()
+
+	return fn()
+}
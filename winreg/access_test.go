@@ -0,0 +1,60 @@
+//go:build windows
+
+package winreg
+
+import (
+	"testing"
+
+	"golang.org/x/sys/windows"
+)
+
+func TestAccess(t *testing.T) {
+	t.Log("Testing access checks.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		testID := 0
+		t.Logf("\tTest %d:\towned key.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey})
+			if err := p.CanRead(); err != nil {
+				t.Fatalf("\t%s\tCanRead() failed: %v.", failed, err)
+			}
+			if err := p.CanWrite(); err != nil {
+				t.Fatalf("\t%s\tCanWrite() failed: %v.", failed, err)
+			}
+			t.Logf("\t%s\tAccess is granted.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tmissing key.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey + "\\Missing\\Deeper"})
+			if err := p.CanRead(); err == nil {
+				t.Fatalf("\t%s\tCanRead() of a missing key should fail.", failed)
+			}
+			if err := p.CanWrite(); err != nil {
+				t.Fatalf("\t%s\tCanWrite() failed: %v.", failed, err)
+			}
+			t.Logf("\t%s\tMissing key can be created.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\timpersonation.", testID)
+		{
+			var token windows.Token
+			err := windows.OpenProcessToken(windows.CurrentProcess(), windows.TOKEN_QUERY|windows.TOKEN_DUPLICATE, &token)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to open process token: %v.", failed, err)
+			}
+			defer token.Close()
+
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey})
+			if err := p.CanReadAs(token); err != nil {
+				t.Fatalf("\t%s\tCanReadAs() failed: %v.", failed, err)
+			}
+			t.Logf("\t%s\tAccess is granted.", success)
+		}
+	}
+}
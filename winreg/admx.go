@@ -0,0 +1,459 @@
+//go:build windows
+
+package winreg
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// Policy classes, the Group Policy branch a setting applies to.
+const (
+	PolicyMachine = "Machine"
+	PolicyUser    = "User"
+	PolicyBoth    = "Both"
+)
+
+// Policy setting kinds.
+const (
+	PolicyBool       = iota // REG_DWORD 1/0 set by enabling/disabling the policy
+	PolicyDecimal           // REG_DWORD entered by the administrator
+	PolicyText              // REG_SZ
+	PolicyExpandText        // REG_EXPAND_SZ
+	PolicyEnum              // REG_DWORD or REG_SZ selected from a list
+	PolicyList              // REG_MULTI_SZ
+)
+
+// PolicyTemplate declares the policy settings of an application, which
+// are written to Software\Policies\<Vendor>\<Product>.
+type PolicyTemplate struct {
+	Namespace   string  // ADMX namespace, e.g. "Vendor.Policies.App"
+	Prefix      string  // ADMX namespace prefix, e.g. "vendorapp"
+	Provider    *WinReg // Provider of the application's key the settings are derived from, see PolicySettings()
+	Vendor      string  // Taken from the path of Provider, Software\<Vendor>\<Product>, if empty
+	Product     string
+	Revision    string // Template revision, "1.0" if empty
+	DisplayName string // Category name shown in the Group Policy editor
+	Description string
+	SupportedOn string // Reference to a supportedOn definition, "windows:SUPPORTED_WindowsVista" if empty
+	Settings    []PolicySetting
+}
+
+// PolicySetting declares a single registry value controlled by a policy.
+type PolicySetting struct {
+	Name        string // Policy name, unique in the template
+	Key         string // Subkey under the policy key, may be empty
+	ValueName   string // Registry value name, Name if empty
+	Class       string // One of PolicyMachine/PolicyUser/PolicyBoth, PolicyBoth if empty
+	Kind        int    // One of PolicyBool/PolicyDecimal/... constant
+	DisplayName string
+	Explain     string
+	Min, Max    uint32 // Range of PolicyDecimal, Max of zero is the full REG_DWORD range
+	Items       []PolicyEnumItem
+}
+
+// PolicyEnumItem is one choice of a PolicyEnum setting. Value must be
+// uint32 or string.
+type PolicyEnumItem struct {
+	DisplayName string
+	Value       interface{}
+}
+
+// PolicyKey returns the policy key path of the template relative to
+// LOCAL_MACHINE or CURRENT_USER.
+func (t *PolicyTemplate) PolicyKey() string {
+	return joinPath(joinPath("Software\\Policies", t.Vendor), t.Product)
+}
+
+// PolicySettings derives policy settings from the values of the provider's
+// Defaults, typed as the provider writes them, so TypeRules apply. Boolean
+// defaults become PolicyBool settings. Values Group Policy can't set, e.g.
+// binary data, are left out. Settings are named by their value paths with
+// "_" for other characters than letters and digits, and apply to the class
+// of the provider's key.
+func (s *WinReg) PolicySettings() ([]PolicySetting, error) {
+	class := PolicyBoth
+	switch s.key {
+	case LOCAL_MACHINE:
+		class = PolicyMachine
+	case CURRENT_USER:
+		class = PolicyUser
+	}
+
+	var retval []PolicySetting
+	values := make(map[string]interface{})
+	flattenTree("", s.defaults, values)
+	paths := make([]string, 0, len(values))
+	for path := range values {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		key, name := splitParent(path)
+		typ, _, err := s.encodeWrite(Write{Path: key, Name: name, Value: values[path]}, 0, false)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		setting := PolicySetting{Name: policyName(path), Key: key, ValueName: name, Class: class, DisplayName: name}
+		switch _, isBool := values[path].(bool); {
+		case isBool && typ == registry.DWORD:
+			setting.Kind = PolicyBool
+		case typ == registry.DWORD:
+			setting.Kind = PolicyDecimal
+		case typ == registry.SZ:
+			setting.Kind = PolicyText
+		case typ == registry.EXPAND_SZ:
+			setting.Kind = PolicyExpandText
+		case typ == registry.MULTI_SZ:
+			setting.Kind = PolicyList
+		default:
+			continue
+		}
+		retval = append(retval, setting)
+	}
+
+	return retval, nil
+}
+
+// policyName converts a value path into a policy name.
+func policyName(path string) string {
+	return strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return '_'
+	}, path)
+}
+
+// GenerateADMX writes the ADMX template to admx and its en-US (or any
+// other language) resources to adml. With Provider set, the settings are
+// derived from it by PolicySettings(), declared Settings replace derived
+// ones of the same name, e.g. to add explanations or enum items.
+func GenerateADMX(admx, adml io.Writer, t PolicyTemplate) error {
+	if t.Namespace == "" || t.Prefix == "" {
+		return errors.New("policy template namespace and prefix are required")
+	}
+	if t.Provider != nil {
+		if err := t.deriveSettings(); err != nil {
+			return err
+		}
+	}
+	if t.Revision == "" {
+		t.Revision = "1.0"
+	}
+	if t.SupportedOn == "" {
+		t.SupportedOn = "windows:SUPPORTED_WindowsVista"
+	}
+
+	defs := admxPolicyDefinitions{
+		Xmlns:         admxNamespace,
+		Revision:      t.Revision,
+		SchemaVersion: "1.0",
+		Namespaces: admxNamespaces{
+			Target: admxTarget{Prefix: t.Prefix, Namespace: t.Namespace},
+			Using:  []admxTarget{{Prefix: "windows", Namespace: "Microsoft.Policies.Windows"}},
+		},
+		Resources:  admxResources{MinRequiredRevision: t.Revision},
+		Categories: []admxCategory{{Name: "Category", DisplayName: "$(string.Category)"}},
+	}
+	res := admlResources{
+		Xmlns:         admxNamespace,
+		Revision:      t.Revision,
+		SchemaVersion: "1.0",
+		DisplayName:   t.DisplayName,
+		Description:   t.Description,
+	}
+	res.addString("Category", t.DisplayName)
+
+	names := make(map[string]bool)
+	for _, setting := range t.Settings {
+		if setting.Name == "" || names[setting.Name] {
+			return fmt.Errorf("policy setting name %q is empty or duplicated", setting.Name)
+		}
+		names[setting.Name] = true
+
+		policy, err := t.policy(setting, &res)
+		if err != nil {
+			return err
+		}
+		defs.Policies = append(defs.Policies, policy)
+	}
+
+	if err := writeXML(admx, defs); err != nil {
+		return fmt.Errorf("unable to write ADMX: %w", err)
+	}
+	if err := writeXML(adml, res); err != nil {
+		return fmt.Errorf("unable to write ADML: %w", err)
+	}
+
+	return nil
+}
+
+// deriveSettings completes the template from its provider.
+func (t *PolicyTemplate) deriveSettings() error {
+	if t.Vendor == "" && t.Product == "" {
+		top, rest := splitPath(strings.Trim(t.Provider.path, "\\"))
+		vendor, product := splitPath(rest)
+		if !strings.EqualFold(top, "Software") || vendor == "" || product == "" {
+			return fmt.Errorf("key %s isn't Software\\<Vendor>\\<Product>", t.Provider.getKeyName(t.Provider.path))
+		}
+		t.Vendor, t.Product = vendor, product
+	}
+
+	derived, err := t.Provider.PolicySettings()
+	if err != nil {
+		return fmt.Errorf("unable to derive policy settings, %w", err)
+	}
+	declared := make(map[string]bool, len(t.Settings))
+	for _, setting := range t.Settings {
+		declared[setting.Name] = true
+	}
+	settings := make([]PolicySetting, 0, len(derived)+len(t.Settings))
+	for _, setting := range derived {
+		if !declared[setting.Name] {
+			settings = append(settings, setting)
+		}
+	}
+	t.Settings = append(settings, t.Settings...)
+
+	return nil
+}
+
+func (t *PolicyTemplate) policy(setting PolicySetting, res *admlResources) (admxPolicy, error) {
+	valueName := setting.ValueName
+	if valueName == "" {
+		valueName = setting.Name
+	}
+	class := setting.Class
+	if class == "" {
+		class = PolicyBoth
+	}
+
+	policy := admxPolicy{
+		Name:           setting.Name,
+		Class:          class,
+		DisplayName:    "$(string." + setting.Name + ")",
+		ExplainText:    "$(string." + setting.Name + "_Explain)",
+		Key:            joinPath(t.PolicyKey(), setting.Key),
+		ParentCategory: admxRef{Ref: "Category"},
+		SupportedOn:    admxRef{Ref: t.SupportedOn},
+	}
+	res.addString(setting.Name, setting.DisplayName)
+	res.addString(setting.Name+"_Explain", setting.Explain)
+
+	if setting.Kind == PolicyBool {
+		policy.ValueName = valueName
+		policy.EnabledValue = &admxValue{Decimal: &admxDecimalValue{Value: 1}}
+		policy.DisabledValue = &admxValue{Decimal: &admxDecimalValue{Value: 0}}
+		return policy, nil
+	}
+
+	policy.Presentation = "$(presentation." + setting.Name + ")"
+	presentation := admlPresentation{ID: setting.Name}
+	elements := &admxElements{}
+	label := admlControl{RefID: setting.Name, Label: setting.DisplayName}
+
+	switch setting.Kind {
+	case PolicyDecimal:
+		// The maximum defaults to 9999, so it's always set
+		max := setting.Max
+		if max == 0 {
+			max = math.MaxUint32
+		}
+		elements.Decimal = &admxDecimalElement{
+			ID:        setting.Name,
+			ValueName: valueName,
+			MinValue:  strconv.FormatUint(uint64(setting.Min), 10),
+			MaxValue:  strconv.FormatUint(uint64(max), 10),
+		}
+		presentation.DecimalTextBox = &label
+	case PolicyText, PolicyExpandText:
+		elements.Text = &admxTextElement{ID: setting.Name, ValueName: valueName}
+		if setting.Kind == PolicyExpandText {
+			elements.Text.Expandable = "true"
+		}
+		presentation.TextBox = &admlTextBox{RefID: setting.Name, Label: setting.DisplayName}
+	case PolicyList:
+		elements.MultiText = &admxTextElement{ID: setting.Name, ValueName: valueName}
+		presentation.MultiTextBox = &label
+	case PolicyEnum:
+		enum := &admxEnumElement{ID: setting.Name, ValueName: valueName}
+		for i, item := range setting.Items {
+			id := fmt.Sprintf("%s_Item%d", setting.Name, i)
+			res.addString(id, item.DisplayName)
+
+			value := admxValue{}
+			switch v := item.Value.(type) {
+			case uint32:
+				value.Decimal = &admxDecimalValue{Value: v}
+			case string:
+				value.String = &v
+			default:
+				return admxPolicy{}, fmt.Errorf("policy setting %s: enum value must be uint32 or string, got %T", setting.Name, item.Value)
+			}
+			enum.Items = append(enum.Items, admxEnumItem{DisplayName: "$(string." + id + ")", Value: value})
+		}
+		elements.Enum = enum
+		presentation.DropdownList = &label
+	default:
+		return admxPolicy{}, fmt.Errorf("policy setting %s: unsupported kind %d", setting.Name, setting.Kind)
+	}
+
+	policy.Elements = elements
+	res.Presentations = append(res.Presentations, presentation)
+
+	return policy, nil
+}
+
+func writeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
+
+const admxNamespace = "http://schemas.microsoft.com/GroupPolicy/2006/07/PolicyDefinitions"
+
+type admxPolicyDefinitions struct {
+	XMLName       xml.Name       `xml:"policyDefinitions"`
+	Xmlns         string         `xml:"xmlns,attr"`
+	Revision      string         `xml:"revision,attr"`
+	SchemaVersion string         `xml:"schemaVersion,attr"`
+	Namespaces    admxNamespaces `xml:"policyNamespaces"`
+	Resources     admxResources  `xml:"resources"`
+	Categories    []admxCategory `xml:"categories>category"`
+	Policies      []admxPolicy   `xml:"policies>policy"`
+}
+
+type admxNamespaces struct {
+	Target admxTarget   `xml:"target"`
+	Using  []admxTarget `xml:"using"`
+}
+
+type admxTarget struct {
+	Prefix    string `xml:"prefix,attr"`
+	Namespace string `xml:"namespace,attr"`
+}
+
+type admxResources struct {
+	MinRequiredRevision string `xml:"minRequiredRevision,attr"`
+}
+
+type admxCategory struct {
+	Name        string `xml:"name,attr"`
+	DisplayName string `xml:"displayName,attr"`
+}
+
+type admxRef struct {
+	Ref string `xml:"ref,attr"`
+}
+
+type admxPolicy struct {
+	Name           string        `xml:"name,attr"`
+	Class          string        `xml:"class,attr"`
+	DisplayName    string        `xml:"displayName,attr"`
+	ExplainText    string        `xml:"explainText,attr"`
+	Presentation   string        `xml:"presentation,attr,omitempty"`
+	Key            string        `xml:"key,attr"`
+	ValueName      string        `xml:"valueName,attr,omitempty"`
+	ParentCategory admxRef       `xml:"parentCategory"`
+	SupportedOn    admxRef       `xml:"supportedOn"`
+	EnabledValue   *admxValue    `xml:"enabledValue"`
+	DisabledValue  *admxValue    `xml:"disabledValue"`
+	Elements       *admxElements `xml:"elements"`
+}
+
+type admxValue struct {
+	Decimal *admxDecimalValue `xml:"decimal"`
+	String  *string           `xml:"string"`
+}
+
+type admxDecimalValue struct {
+	Value uint32 `xml:"value,attr"`
+}
+
+type admxElements struct {
+	Decimal   *admxDecimalElement `xml:"decimal"`
+	Text      *admxTextElement    `xml:"text"`
+	MultiText *admxTextElement    `xml:"multiText"`
+	Enum      *admxEnumElement    `xml:"enum"`
+}
+
+type admxDecimalElement struct {
+	ID        string `xml:"id,attr"`
+	ValueName string `xml:"valueName,attr"`
+	MinValue  string `xml:"minValue,attr,omitempty"`
+	MaxValue  string `xml:"maxValue,attr"`
+}
+
+type admxTextElement struct {
+	ID         string `xml:"id,attr"`
+	ValueName  string `xml:"valueName,attr"`
+	Expandable string `xml:"expandable,attr,omitempty"`
+}
+
+type admxEnumElement struct {
+	ID        string         `xml:"id,attr"`
+	ValueName string         `xml:"valueName,attr"`
+	Items     []admxEnumItem `xml:"item"`
+}
+
+type admxEnumItem struct {
+	DisplayName string    `xml:"displayName,attr"`
+	Value       admxValue `xml:"value"`
+}
+
+type admlResources struct {
+	XMLName       xml.Name           `xml:"policyDefinitionResources"`
+	Xmlns         string             `xml:"xmlns,attr"`
+	Revision      string             `xml:"revision,attr"`
+	SchemaVersion string             `xml:"schemaVersion,attr"`
+	DisplayName   string             `xml:"displayName"`
+	Description   string             `xml:"description"`
+	Strings       []admlString       `xml:"resources>stringTable>string"`
+	Presentations []admlPresentation `xml:"resources>presentationTable>presentation"`
+}
+
+func (r *admlResources) addString(id, text string) {
+	r.Strings = append(r.Strings, admlString{ID: id, Text: text})
+}
+
+type admlString struct {
+	ID   string `xml:"id,attr"`
+	Text string `xml:",chardata"`
+}
+
+type admlPresentation struct {
+	ID             string       `xml:"id,attr"`
+	DecimalTextBox *admlControl `xml:"decimalTextBox"`
+	TextBox        *admlTextBox `xml:"textBox"`
+	MultiTextBox   *admlControl `xml:"multiTextBox"`
+	DropdownList   *admlControl `xml:"dropdownList"`
+}
+
+type admlControl struct {
+	RefID string `xml:"refId,attr"`
+	Label string `xml:",chardata"`
+}
+
+type admlTextBox struct {
+	RefID string `xml:"refId,attr"`
+	Label string `xml:"label"`
+}
@@ -0,0 +1,155 @@
+//go:build windows
+
+package winreg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerateADMX(t *testing.T) {
+	t.Log("Testing ADMX/ADML generation.")
+	{
+		var admx, adml bytes.Buffer
+		err := GenerateADMX(&admx, &adml, PolicyTemplate{
+			Namespace:   "Vendor.Policies.App",
+			Prefix:      "vendorapp",
+			Vendor:      "Vendor",
+			Product:     "App",
+			DisplayName: "App",
+			Settings: []PolicySetting{
+				{Name: "Enabled", Kind: PolicyBool, DisplayName: "Enable the app"},
+				{Name: "Level", Kind: PolicyDecimal, Class: PolicyMachine, Key: "Logging", Max: 5, DisplayName: "Log level"},
+				{Name: "Mode", Kind: PolicyEnum, Items: []PolicyEnumItem{{DisplayName: "Fast", Value: uint32(1)}, {DisplayName: "Safe", Value: "safe"}}},
+			},
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to generate templates: %v.", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\twell-formed XML.", testID)
+		{
+			for _, doc := range []*bytes.Buffer{&admx, &adml} {
+				var v struct{}
+				if err := xml.Unmarshal(doc.Bytes(), &v); err != nil {
+					t.Fatalf("\t%s\tInvalid XML: %v.", failed, err)
+				}
+			}
+			t.Logf("\t%s\tTemplates are well-formed.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tpolicy definitions.", testID)
+		{
+			for _, expect := range []string{
+				`<target prefix="vendorapp" namespace="Vendor.Policies.App">`,
+				`key="Software\Policies\Vendor\App" valueName="Enabled"`,
+				`key="Software\Policies\Vendor\App\Logging"`,
+				`<decimal id="Level" valueName="Level" minValue="0" maxValue="5">`,
+				`<string>safe</string>`,
+			} {
+				if !strings.Contains(admx.String(), expect) {
+					t.Fatalf("\t%s\tADMX doesn't contain %s:\n%s", failed, expect, admx.String())
+				}
+			}
+			for _, expect := range []string{
+				`<string id="Mode_Item0">Fast</string>`,
+				`<decimalTextBox refId="Level">Log level</decimalTextBox>`,
+			} {
+				if !strings.Contains(adml.String(), expect) {
+					t.Fatalf("\t%s\tADML doesn't contain %s:\n%s", failed, expect, adml.String())
+				}
+			}
+			t.Logf("\t%s\tTemplates are valid.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tduplicate setting.", testID)
+		{
+			err := GenerateADMX(&admx, &adml, PolicyTemplate{Namespace: "a", Prefix: "a", Settings: []PolicySetting{{Name: "A"}, {Name: "A"}}})
+			if err == nil {
+				t.Fatalf("\t%s\tDuplicate setting was accepted.", failed)
+			}
+			t.Logf("\t%s\tDuplicate setting is rejected.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tunlimited decimal.", testID)
+		{
+			admx.Reset()
+			err := GenerateADMX(&admx, &adml, PolicyTemplate{Namespace: "a", Prefix: "a", Settings: []PolicySetting{{Name: "A", Kind: PolicyDecimal}}})
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to generate templates: %v.", failed, err)
+			}
+			if !strings.Contains(admx.String(), `maxValue="4294967295"`) {
+				t.Fatalf("\t%s\tMaximum is not set:\n%s", failed, admx.String())
+			}
+			t.Logf("\t%s\tMaximum is the full range.", success)
+		}
+	}
+}
+
+func TestPolicySettings(t *testing.T) {
+	t.Log("Testing policy settings derived from a provider.")
+	{
+		p := Provider(Config{
+			Key:  CURRENT_USER,
+			Path: "Software\\Vendor\\App",
+			Defaults: map[string]interface{}{
+				"Enabled": true,
+				"Logging": map[string]interface{}{"Level": uint32(2), "File": "%TEMP%\\app.log"},
+				"Servers": []string{"a", "b"},
+				"Key":     []byte{1},
+			},
+			TypeRules: TypeRules{ExpandVars: true},
+		})
+
+		testID := 0
+		t.Logf("\tTest %d:\tsettings of the defaults.", testID)
+		{
+			settings, err := p.PolicySettings()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to derive settings: %v.", failed, err)
+			}
+			expect := []PolicySetting{
+				{Name: "Enabled", ValueName: "Enabled", Class: PolicyUser, Kind: PolicyBool, DisplayName: "Enabled"},
+				{Name: "Logging_File", Key: "Logging", ValueName: "File", Class: PolicyUser, Kind: PolicyExpandText, DisplayName: "File"},
+				{Name: "Logging_Level", Key: "Logging", ValueName: "Level", Class: PolicyUser, Kind: PolicyDecimal, DisplayName: "Level"},
+				{Name: "Servers", ValueName: "Servers", Class: PolicyUser, Kind: PolicyList, DisplayName: "Servers"},
+			}
+			if !reflect.DeepEqual(settings, expect) {
+				t.Fatalf("\t%s\tUnexpected settings %+v.", failed, settings)
+			}
+			t.Logf("\t%s\tSettings are derived.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\ttemplate of the provider.", testID)
+		{
+			var admx, adml bytes.Buffer
+			err := GenerateADMX(&admx, &adml, PolicyTemplate{
+				Namespace: "Vendor.Policies.App",
+				Prefix:    "vendorapp",
+				Provider:  p,
+				Settings:  []PolicySetting{{Name: "Logging_Level", Key: "Logging", ValueName: "Level", Kind: PolicyDecimal, Max: 5}},
+			})
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to generate templates: %v.", failed, err)
+			}
+			for _, expect := range []string{
+				`key="Software\Policies\Vendor\App" valueName="Enabled"`,
+				`<decimal id="Logging_Level" valueName="Level" minValue="0" maxValue="5">`,
+				`<multiText id="Servers" valueName="Servers">`,
+			} {
+				if !strings.Contains(admx.String(), expect) {
+					t.Fatalf("\t%s\tADMX doesn't contain %s:\n%s", failed, expect, admx.String())
+				}
+			}
+			t.Logf("\t%s\tTemplate is generated.", success)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+//go:build windows
+
+package winreg
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf16"
+
+	"github.com/pda0/koanf-winreg/v2/regfile"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// regAPI is the registry and system API used by providers. The package
+// tests replace it to simulate conditions which are hard to reproduce on a
+// real registry: keys deleted at a particular moment, slow remote calls or
+// storms of notifications.
+type regAPI interface {
+	OpenKey(k registry.Key, path string, access uint32) (registry.Key, error)
+	OpenRemoteKey(host string, k registry.Key) (registry.Key, error)
+	CloseKey(k registry.Key) error
+	ReadValueNames(k registry.Key) ([]string, error)
+	ReadSubKeyNames(k registry.Key) ([]string, error)
+	GetValue(k registry.Key, name string, buf []byte) (n int, valtype uint32, err error)
+	GetStringValue(k registry.Key, name string) (string, uint32, error)
+	GetStringsValue(k registry.Key, name string) ([]string, uint32, error)
+	GetIntegerValue(k registry.Key, name string) (uint64, uint32, error)
+	GetBinaryValue(k registry.Key, name string) ([]byte, uint32, error)
+	GetMUIStringValue(k registry.Key, name string) (string, error)
+	GetKeyClass(k registry.Key) (string, error)
+	NotifyChangeKeyValue(k registry.Key, watchSubtree bool, filter uint32, event windows.Handle) error
+	CreateKey(k registry.Key, path string, options, access uint32) (registry.Key, error)
+	SetValue(k registry.Key, name string, valtype uint32, data []byte) error
+	DeleteValue(k registry.Key, name string) error
+	DeleteKey(k registry.Key, path string, access uint32) error
+	FlushKey(k registry.Key) error
+	CreateLinkKey(k registry.Key, path string, access uint32) (registry.Key, error)
+	DeleteLinkKey(k registry.Key, path string) error
+	LinkTarget(k registry.Key, path string, access uint32) (target string, isLink bool, err error)
+	Now() time.Time
+}
+
+// sysAPI is the regAPI implementation calling the real Windows API.
+type sysAPI struct{}
+
+func (sysAPI) OpenKey(k registry.Key, path string, access uint32) (registry.Key, error) {
+	return registry.OpenKey(k, path, access)
+}
+
+func (sysAPI) OpenRemoteKey(host string, k registry.Key) (registry.Key, error) {
+	return registry.OpenRemoteKey(host, k)
+}
+
+func (sysAPI) CloseKey(k registry.Key) error {
+	return k.Close()
+}
+
+func (sysAPI) ReadValueNames(k registry.Key) ([]string, error) {
+	return k.ReadValueNames(0)
+}
+
+func (sysAPI) ReadSubKeyNames(k registry.Key) ([]string, error) {
+	return k.ReadSubKeyNames(0)
+}
+
+func (sysAPI) GetValue(k registry.Key, name string, buf []byte) (int, uint32, error) {
+	return k.GetValue(name, buf)
+}
+
+func (sysAPI) GetStringValue(k registry.Key, name string) (string, uint32, error) {
+	return k.GetStringValue(name)
+}
+
+func (sysAPI) GetStringsValue(k registry.Key, name string) ([]string, uint32, error) {
+	return k.GetStringsValue(name)
+}
+
+func (sysAPI) GetIntegerValue(k registry.Key, name string) (uint64, uint32, error) {
+	return k.GetIntegerValue(name)
+}
+
+func (sysAPI) GetBinaryValue(k registry.Key, name string) ([]byte, uint32, error) {
+	return k.GetBinaryValue(name)
+}
+
+func (sysAPI) GetMUIStringValue(k registry.Key, name string) (string, error) {
+	return k.GetMUIStringValue(name)
+}
+
+func (sysAPI) GetKeyClass(k registry.Key) (string, error) {
+	buf := make([]uint16, 64)
+	for {
+		n := uint32(len(buf))
+		err := windows.RegQueryInfoKey(windows.Handle(k), &buf[0], &n, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+		if err == nil {
+			return windows.UTF16ToString(buf[:n]), nil
+		}
+		if err != windows.ERROR_MORE_DATA {
+			return "", err
+		}
+		buf = make([]uint16, 2*len(buf))
+	}
+}
+
+func (sysAPI) NotifyChangeKeyValue(k registry.Key, watchSubtree bool, filter uint32, event windows.Handle) error {
+	if SystemCapabilities().ThreadAgnosticNotify {
+		// Otherwise the notification is cancelled when the thread which
+		// registered it exits, so watches lock their threads.
+		filter |= REG_NOTIFY_THREAD_AGNOSTIC
+	}
+	return regNotifyChangeKeyValue(syscall.Handle(k), watchSubtree, filter, event, true)
+}
+
+// CreateKey creates or opens the subkey path, options such as
+// REG_OPTION_VOLATILE apply only to a created key.
+func (sysAPI) CreateKey(k registry.Key, path string, options, access uint32) (registry.Key, error) {
+	retval, _, err := regCreateKeyEx(k, path, options, access)
+	return retval, err
+}
+
+func (sysAPI) SetValue(k registry.Key, name string, valtype uint32, data []byte) error {
+	return regSetValueEx(k, name, valtype, data)
+}
+
+func (sysAPI) DeleteValue(k registry.Key, name string) error {
+	return k.DeleteValue(name)
+}
+
+// DeleteKey deletes the subkey path, which must have no subkeys, in the
+// registry view selected by WOW64 flags of access.
+func (sysAPI) DeleteKey(k registry.Key, path string, access uint32) error {
+	return regDeleteKeyEx(k, path, access)
+}
+
+func (sysAPI) FlushKey(k registry.Key) error {
+	return regFlushKey(k)
+}
+
+func (sysAPI) CreateLinkKey(k registry.Key, path string, access uint32) (registry.Key, error) {
+	retval, disposition, err := regCreateKeyEx(k, path, REG_OPTION_CREATE_LINK, access)
+	if err != nil {
+		return 0, err
+	}
+	if disposition != REG_CREATED_NEW_KEY {
+		retval.Close()
+		return 0, windows.ERROR_ALREADY_EXISTS
+	}
+	return retval, nil
+}
+
+func (sysAPI) DeleteLinkKey(k registry.Key, path string) error {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	var link windows.Handle
+	if err = windows.RegOpenKeyEx(windows.Handle(k), p, REG_OPTION_OPEN_LINK, windows.DELETE, &link); err != nil {
+		return err
+	}
+	defer windows.RegCloseKey(link)
+	// RegDeleteKey would delete the target
+	return ntDeleteKey(registry.Key(link))
+}
+
+// LinkTarget opens the subkey path itself, not the target if it's a link,
+// and returns the native path of the target stored in it.
+func (sysAPI) LinkTarget(k registry.Key, path string, access uint32) (string, bool, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return "", false, err
+	}
+	var link windows.Handle
+	if err = windows.RegOpenKeyEx(windows.Handle(k), p, REG_OPTION_OPEN_LINK, access, &link); err != nil {
+		return "", false, err
+	}
+	defer windows.RegCloseKey(link)
+
+	buf := make([]byte, 512)
+	for {
+		n, typ, err := registry.Key(link).GetValue(symbolicLinkValue, buf)
+		switch {
+		case errors.Is(err, windows.ERROR_FILE_NOT_FOUND):
+			return "", false, nil
+		case errors.Is(err, windows.ERROR_MORE_DATA) || n > len(buf):
+			buf = make([]byte, n)
+			continue
+		case err != nil:
+			return "", false, err
+		case typ != registry.LINK:
+			return "", false, nil
+		}
+		return strings.TrimRight(string(utf16.Decode(bytesToUTF16(buf[:n]))), "\x00"), true, nil
+	}
+}
+
+func (sysAPI) Now() time.Time {
+	return time.Now()
+}
+
+// getValueFunc reads a value in its stored form, as registry.Key.GetValue
+// does. Backends which can't use the registry package read typed values
+// with the stored* functions on top of it.
+type getValueFunc func(k registry.Key, name string, buf []byte) (int, uint32, error)
+
+// storedData returns the data of the value name, which must be of one of
+// the types.
+func storedData(get getValueFunc, k registry.Key, name string, types ...uint32) ([]byte, uint32, error) {
+	var buf []byte
+	for {
+		n, typ, err := get(k, name, buf)
+		if err != nil && err != registry.ErrShortBuffer {
+			return nil, 0, err
+		}
+		if err == nil && n <= len(buf) {
+			for _, t := range types {
+				if t == typ {
+					return buf[:n], typ, nil
+				}
+			}
+			return nil, typ, registry.ErrUnexpectedType
+		}
+		// The value may grow between calls
+		buf = make([]byte, n)
+	}
+}
+
+func storedString(get getValueFunc, k registry.Key, name string) (string, uint32, error) {
+	buf, typ, err := storedData(get, k, name, registry.SZ, registry.EXPAND_SZ)
+	if err != nil {
+		return "", typ, err
+	}
+	return storedStringData(buf), typ, nil
+}
+
+// storedStringData decodes the data of a REG_SZ or REG_EXPAND_SZ value.
+func storedStringData(buf []byte) string {
+	return windows.UTF16ToString(bytesToUTF16(buf))
+}
+
+func storedStrings(get getValueFunc, k registry.Key, name string) ([]string, uint32, error) {
+	buf, typ, err := storedData(get, k, name, registry.MULTI_SZ)
+	if err != nil {
+		return nil, typ, err
+	}
+	return regfile.DecodeMultiString(buf), typ, nil
+}
+
+func storedInteger(get getValueFunc, k registry.Key, name string) (uint64, uint32, error) {
+	buf, typ, err := storedData(get, k, name, registry.DWORD, registry.QWORD)
+	if err != nil {
+		return 0, typ, err
+	}
+	n, err := storedIntegerData(typ, buf)
+	return n, typ, err
+}
+
+// storedIntegerData decodes the data of a REG_DWORD or REG_QWORD value.
+func storedIntegerData(typ uint32, buf []byte) (uint64, error) {
+	switch {
+	case typ == registry.DWORD && len(buf) == 4:
+		return uint64(binary.LittleEndian.Uint32(buf)), nil
+	case typ == registry.QWORD && len(buf) == 8:
+		return binary.LittleEndian.Uint64(buf), nil
+	default:
+		return 0, errors.New("invalid integer value length")
+	}
+}
+
+// bytesToUTF16 converts little-endian bytes of a string value to UTF-16.
+func bytesToUTF16(buf []byte) []uint16 {
+	units := make([]uint16, len(buf)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(buf[i*2:])
+	}
+	return units
+}
@@ -0,0 +1,161 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// faultAPI wraps the real API, failing enumeration of values of keys
+// whose path ends with failKey and reporting a fake time.
+type faultAPI struct {
+	sysAPI
+	mu       sync.Mutex
+	paths    map[registry.Key]string
+	failKey  string
+	failures int
+	now      time.Time
+}
+
+func (a *faultAPI) OpenKey(k registry.Key, path string, access uint32) (registry.Key, error) {
+	retval, err := a.sysAPI.OpenKey(k, path, access)
+	if err == nil {
+		a.mu.Lock()
+		a.paths[retval] = path
+		a.mu.Unlock()
+	}
+	return retval, err
+}
+
+func (a *faultAPI) ReadValueNames(k registry.Key) ([]string, error) {
+	a.mu.Lock()
+	fail := a.failures > 0 && strings.HasSuffix(a.paths[k], a.failKey)
+	if fail {
+		a.failures--
+	}
+	a.mu.Unlock()
+
+	if fail {
+		return nil, windows.ERROR_KEY_DELETED
+	}
+	return a.sysAPI.ReadValueNames(k)
+}
+
+func (a *faultAPI) Now() time.Time {
+	return a.now
+}
+
+func TestInjectedFaults(t *testing.T) {
+	t.Log("Testing simulated registry faults.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		api := &faultAPI{paths: make(map[registry.Key]string), failKey: "SubKeyA", now: time.Now()}
+
+		testID := 0
+		t.Logf("\tTest %d:\tsubkey deleted while being read.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, Retries: 2})
+			p.api = api
+
+			api.failures = 2
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if _, ok := tree["SubKeyA"]; !ok || !p.Torn() {
+				t.Fatalf("\t%s\tSubKeyA should be re-read and the read reported as torn.", failed)
+			}
+
+			api.failures = 3
+			if _, err := p.Read(); !errors.Is(err, windows.ERROR_KEY_DELETED) {
+				t.Fatalf("\t%s\tRead() should fail after retries, got: %v.", failed, err)
+			}
+			t.Logf("\t%s\tRetries are bounded.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tmissing key expiry.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey + "\\Missing", MissingTTL: time.Minute})
+			p.api = api
+			if _, err := p.Read(); err == nil {
+				t.Fatalf("\t%s\tRead() of a missing key should fail.", failed)
+			}
+
+			k, _, err := registry.CreateKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey+"\\Missing", registry.ALL_ACCESS)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to create test key: %v", failed, err)
+			}
+			k.Close()
+
+			api.now = api.now.Add(30 * time.Second)
+			if _, err := p.Read(); err == nil {
+				t.Fatalf("\t%s\tMissing key was not cached.", failed)
+			}
+			api.now = api.now.Add(time.Minute)
+			if _, err := p.Read(); err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			t.Logf("\t%s\tCached result expires.", success)
+		}
+	}
+}
+
+// classAPI wraps the real API, reporting class as the class of every key.
+type classAPI struct {
+	sysAPI
+	class string
+}
+
+func (a *classAPI) GetKeyClass(k registry.Key) (string, error) {
+	return a.class, nil
+}
+
+func TestKeyClass(t *testing.T) {
+	t.Log("Testing key class names.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		testID := 0
+		t.Logf("\tTest %d:\tkeys without class.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, ClassValue: "Class"})
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if _, ok := tree["Class"]; ok {
+				t.Fatalf("\t%s\tEmpty class is mapped to a value.", failed)
+			}
+			t.Logf("\t%s\tNo class values.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tkeys with class.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, ClassValue: "Class"})
+			p.api = &classAPI{class: "Shell"}
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if tree["Class"] != "Shell" {
+				t.Fatalf("\t%s\tClass is invalid, got %v.", failed, tree["Class"])
+			}
+			if sub, ok := tree["SubKeyA"].(map[string]interface{}); !ok || sub["Class"] != "Shell" {
+				t.Fatalf("\t%s\tSubKeyA class is invalid, got %v.", failed, tree["SubKeyA"])
+			}
+			t.Logf("\t%s\tClasses are mapped to values.", success)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+//go:build windows
+
+package winreg
+
+import (
+	"fmt"
+	"sync"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// AppHive is a hive file loaded privately by RegLoadAppKey, like an
+// application hive or a copy of NTUSER.DAT, without mounting it under
+// HKEY_USERS. The hive is unloaded by Close().
+type AppHive struct {
+	file string
+	mu   sync.Mutex
+	k    registry.Key
+}
+
+// OpenAppHive loads the hive file. With writable set, providers can write
+// to it and changes are saved to the file. The file must not be loaded
+// by another process, e.g. NTUSER.DAT of a logged-on user.
+func OpenAppHive(file string, writable bool) (*AppHive, error) {
+
+	k, err := loadAppHive(file, writable)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AppHive{file: file, k: k}, nil
+}
+
+func loadAppHive(file string, writable bool) (registry.Key, error) {
+	access := uint32(registry.READ)
+	if writable {
+		access = registry.ALL_ACCESS
+	}
+	k, err := regLoadAppKey(file, access, 0)
+	if err != nil {
+		return 0, fmt.Errorf("unable to load hive %s: %w", file, err)
+	}
+
+	return k, nil
+}
+
+// File returns the name of the loaded hive file.
+func (h *AppHive) File() string {
+	return h.file
+}
+
+// Provider returns a provider of the hive. Config.Key is ignored,
+// Config.Path is a path in the hive. The provider is valid until the hive
+// is closed.
+func (h *AppHive) Provider(cfg Config) (*WinReg, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.k == 0 {
+		return nil, fmt.Errorf("hive %s is closed", h.file)
+	}
+
+	// Private hives have no WOW64 redirection
+	cfg.Mode = RegAuto
+	cfg.Key = h.k
+
+	return Provider(cfg), nil
+}
+
+// Close unloads the hive.
+func (h *AppHive) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.k == 0 {
+		return nil
+	}
+
+	err := h.k.Close()
+	h.k = 0
+	if err != nil {
+		return fmt.Errorf("unable to unload hive %s: %w", h.file, err)
+	}
+
+	return nil
+}
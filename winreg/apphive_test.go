@@ -0,0 +1,60 @@
+//go:build windows
+
+package winreg
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAppHive(t *testing.T) {
+	t.Log("Testing application hives.")
+	{
+		// RegLoadAppKey creates a missing hive file
+		file := filepath.Join(t.TempDir(), "app.dat")
+
+		testID := 0
+		t.Logf("\tTest %d:\twriting a new hive.", testID)
+		{
+			h, err := OpenAppHive(file, true)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to open hive: %v", failed, err)
+			}
+			p, err := h.Provider(Config{Path: "Settings"})
+			if err != nil {
+				h.Close()
+				t.Fatalf("\t%s\tUnable to create provider: %v.", failed, err)
+			}
+			if err := p.Set("Name", "value"); err != nil {
+				h.Close()
+				t.Fatalf("\t%s\tUnable to write hive: %v.", failed, err)
+			}
+			if err := h.Close(); err != nil {
+				t.Fatalf("\t%s\tUnable to close hive: %v.", failed, err)
+			}
+			t.Logf("\t%s\tHive is written.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\treading the hive.", testID)
+		{
+			h, err := OpenAppHive(file, false)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to open hive: %v", failed, err)
+			}
+			defer h.Close()
+			p, err := h.Provider(Config{Path: "Settings"})
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to create provider: %v.", failed, err)
+			}
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read hive: %v.", failed, err)
+			}
+			if tree["Name"] != "value" {
+				t.Fatalf("\t%s\tHive data is invalid, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tHive is read.", success)
+		}
+	}
+}
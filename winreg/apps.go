@@ -0,0 +1,189 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// UninstallPath is the key where installers register applications.
+const UninstallPath = `SOFTWARE\Microsoft\Windows\CurrentVersion\Uninstall`
+
+// Application is an installed application as registered under an
+// Uninstall key.
+type Application struct {
+	Key         string    // Full name of the application's uninstall key
+	Name        string    // DisplayName
+	Version     string    // DisplayVersion
+	Publisher   string    // Publisher
+	InstallDate time.Time // InstallDate, zero if missing or not recognized
+	User        bool      // Installed for the current user only
+	Bit32       bool      // Registered in the 32-bit view of a 64-bit system
+}
+
+// installDateLayouts are the InstallDate formats found in the wild,
+// YYYYMMDD is the documented one.
+var installDateLayouts = []string{"20060102", "2006-01-02", "1/2/2006", "01/02/2006", "2.1.2006"}
+
+// Applications is a provider of the applications shown in "Programs and
+// Features", read from the Uninstall keys of both views of HKLM and of
+// HKCU. System components, updates and entries without a name are left
+// out. The tree holds the list under the "Applications" key.
+type Applications struct{}
+
+// ReadBytes is not supported by the applications provider.
+func (a *Applications) ReadBytes() ([]byte, error) {
+	return nil, errors.New("winreg applications does not support this method")
+}
+
+// Read returns the installed applications as a list of trees with Key,
+// Name, Version, Publisher, InstallDate ("YYYY-MM-DD" or empty), User
+// and Bit32 keys.
+func (a *Applications) Read() (map[string]interface{}, error) {
+	apps, err := a.List()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]interface{}, 0, len(apps))
+	for _, app := range apps {
+		date := ""
+		if !app.InstallDate.IsZero() {
+			date = app.InstallDate.Format("2006-01-02")
+		}
+		list = append(list, map[string]interface{}{
+			"Key":         app.Key,
+			"Name":        app.Name,
+			"Version":     app.Version,
+			"Publisher":   app.Publisher,
+			"InstallDate": date,
+			"User":        app.User,
+			"Bit32":       app.Bit32,
+		})
+	}
+
+	return map[string]interface{}{"Applications": list}, nil
+}
+
+// List returns the installed applications sorted by name.
+func (a *Applications) List() ([]Application, error) {
+	var retval []Application
+	for _, view := range softwareViews() {
+		view.cfg.Path = UninstallPath
+		view.cfg.MaxDepth = 2
+		// A missing key has no applications
+		view.cfg.Defaults = map[string]interface{}{}
+		p := Provider(view.cfg)
+		tree, err := p.Read()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read applications, %w", err)
+		}
+
+		for name, sub := range tree {
+			values, ok := sub.(map[string]interface{})
+			if !ok || !isApplication(values) {
+				continue
+			}
+			retval = append(retval, Application{
+				Key:         p.getKeyName(joinPath(UninstallPath, name)),
+				Name:        stringField(values, "DisplayName"),
+				Version:     stringField(values, "DisplayVersion"),
+				Publisher:   stringField(values, "Publisher"),
+				InstallDate: parseInstallDate(values["InstallDate"]),
+				User:        view.user,
+				Bit32:       view.bit32,
+			})
+		}
+	}
+
+	sort.SliceStable(retval, func(i, j int) bool {
+		return strings.ToLower(retval[i].Name) < strings.ToLower(retval[j].Name)
+	})
+
+	return retval, nil
+}
+
+// isApplication reports whether values of an uninstall key describe an
+// application shown to users.
+func isApplication(values map[string]interface{}) bool {
+	if stringField(values, "DisplayName") == "" {
+		return false
+	}
+	if system, ok := values["SystemComponent"].(uint64); ok && system != 0 {
+		return false
+	}
+	// Updates and patches refer to the application they belong to
+	return stringField(values, "ParentKeyName") == "" && stringField(values, "ReleaseType") == ""
+}
+
+// stringField returns the trimmed string value name, empty if it's missing
+// or not a string.
+func stringField(values map[string]interface{}, name string) string {
+	str, _ := values[name].(string)
+	return strings.TrimSpace(str)
+}
+
+// parseInstallDate converts an InstallDate value, a string in one of the
+// known layouts or a Unix time stored as a DWORD.
+func parseInstallDate(value interface{}) time.Time {
+	switch value := value.(type) {
+	case string:
+		value = strings.TrimSpace(value)
+		for _, layout := range installDateLayouts {
+			if date, err := time.Parse(layout, value); err == nil {
+				return date
+			}
+		}
+	case uint64:
+		if value != 0 {
+			return time.Unix(int64(value), 0).UTC()
+		}
+	}
+
+	return time.Time{}
+}
+
+// softwareView is a view of the software registered for the machine or
+// the current user.
+type softwareView struct {
+	cfg   Config
+	user  bool
+	bit32 bool
+}
+
+// softwareViews returns HKLM in both views, the 32-bit one only on 64-bit
+// systems, and HKCU, whose Software key is shared by both views.
+func softwareViews() []softwareView {
+	views := []softwareView{
+		{cfg: Config{Key: LOCAL_MACHINE, Mode: Reg64Bit}},
+		{cfg: Config{Key: LOCAL_MACHINE, Mode: Reg32Bit}, bit32: true},
+		{cfg: Config{Key: CURRENT_USER}, user: true},
+	}
+	if !is64BitSystem() {
+		// There is a single view
+		views = append(views[:1], views[2:]...)
+	}
+
+	return views
+}
+
+// is64BitSystem reports whether the running Windows is a 64-bit one.
+func is64BitSystem() bool {
+	if runtime.GOARCH == "amd64" || runtime.GOARCH == "arm64" {
+		return true
+	}
+
+	var wow64 bool
+	if err := windows.IsWow64Process(windows.CurrentProcess(), &wow64); err != nil {
+		return false
+	}
+
+	return wow64
+}
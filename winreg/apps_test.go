@@ -0,0 +1,72 @@
+//go:build windows
+
+package winreg
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestParseInstallDate(t *testing.T) {
+	t.Log("Testing install date formats.")
+	{
+		expect := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+		for testID, value := range []interface{}{"20230115", " 2023-01-15", "1/15/2023", uint64(expect.Unix())} {
+			t.Logf("\tTest %d:\t%v.", testID, value)
+			{
+				if date := parseInstallDate(value); !date.Equal(expect) {
+					t.Fatalf("\t%s\tInvalid date, got %v, expect %v.", failed, date, expect)
+				}
+				t.Logf("\t%s\tDate is parsed.", success)
+			}
+		}
+	}
+}
+
+func TestApplications(t *testing.T) {
+	t.Log("Testing installed applications.")
+	{
+		k, _, err := registry.CreateKey(registry.CURRENT_USER, UninstallPath+"\\"+testKey, registry.ALL_ACCESS)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to create test key: %v", failed, err)
+		}
+		defer registry.DeleteKey(registry.CURRENT_USER, UninstallPath+"\\"+testKey)
+		for name, value := range map[string]string{
+			"DisplayName":    "Koanf WinReg Test ",
+			"DisplayVersion": "1.2.3",
+			"Publisher":      "pda0",
+			"InstallDate":    "20230115",
+		} {
+			if err := k.SetStringValue(name, value); err != nil {
+				k.Close()
+				t.Fatalf("\t%s\tUnable to create test value: %v", failed, err)
+			}
+		}
+		k.Close()
+
+		apps, err := (&Applications{}).List()
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to list applications: %v.", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tuser application.", testID)
+		{
+			var app *Application
+			for i := range apps {
+				if apps[i].Key == "HKCU\\"+UninstallPath+"\\"+testKey {
+					app = &apps[i]
+				}
+			}
+			if app == nil {
+				t.Fatalf("\t%s\tApplication is not found.", failed)
+			}
+			if app.Name != "Koanf WinReg Test" || app.Version != "1.2.3" || !app.User || app.InstallDate.Year() != 2023 {
+				t.Fatalf("\t%s\tApplication is invalid: %+v.", failed, *app)
+			}
+			t.Logf("\t%s\tApplication is normalized.", success)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+//go:build windows
+
+package winreg
+
+import (
+	"sort"
+	"sync"
+)
+
+// AuditReason is why a key or a value was left out of a Read() with Audit.
+type AuditReason int
+
+const (
+	AuditUnreadable  AuditReason = iota // Subkey failing with a Win32 error, like access denied
+	AuditFiltered                       // Left out by IncludeKeys, ExcludeKeys, IncludeValues, ExcludeValues or an unnamed DefaultValue
+	AuditTooLarge                       // REG_BINARY value larger than MaxValueSize
+	AuditUnsupported                    // Value of a type Read() doesn't return, like REG_NONE without RawTypes
+)
+
+func (r AuditReason) String() string {
+	switch r {
+	case AuditUnreadable:
+		return "unreadable"
+	case AuditFiltered:
+		return "filtered"
+	case AuditTooLarge:
+		return "too large"
+	case AuditUnsupported:
+		return "unsupported type"
+	default:
+		return "unknown"
+	}
+}
+
+// AuditEntry is a key or a value left out of a Read() with Audit.
+type AuditEntry struct {
+	Key    string      // Full key name, e.g. "HKLM\SOFTWARE\Vendor\App"
+	Value  string      // Value name, empty for a key and for the default value
+	IsKey  bool        // The entry is a subkey along with its subtree
+	Type   string      // Registry type of a value, e.g. "REG_NONE"
+	Reason AuditReason // Why it was left out
+	Err    error       // *Error of an unreadable subkey
+}
+
+// auditLog are the entries of the last Read() with Audit, shared by the
+// providers of Paths.
+type auditLog struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+func (l *auditLog) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
+}
+
+func (l *auditLog) add(entry AuditEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry)
+}
+
+// auditKey records the subkey path left out for reason.
+func (s *WinReg) auditKey(path string, reason AuditReason, err error) {
+	if s.audit {
+		s.audited.add(AuditEntry{Key: s.getKeyName(path), IsKey: true, Reason: reason, Err: err})
+	}
+}
+
+// auditValue records the value name of type typ of the key path left out
+// for reason.
+func (s *WinReg) auditValue(path, name string, typ uint32, reason AuditReason) {
+	if s.audit {
+		s.audited.add(AuditEntry{Key: s.getKeyName(path), Value: name, Type: valueTypeName(typ), Reason: reason})
+	}
+}
+
+// ReadAudit reads the tree as Read() does and returns the keys and values
+// left out of it alongside, sorted by key and value name. The report is
+// always empty unless Audit is set.
+func (s *WinReg) ReadAudit() (map[string]interface{}, []AuditEntry, error) {
+	retval, err := s.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.audited.mu.Lock()
+	defer s.audited.mu.Unlock()
+	report := append([]AuditEntry(nil), s.audited.entries...)
+	sort.SliceStable(report, func(i, j int) bool {
+		if report[i].Key != report[j].Key {
+			return report[i].Key < report[j].Key
+		}
+		return report[i].Value < report[j].Value
+	})
+
+	return retval, report, nil
+}
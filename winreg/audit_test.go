@@ -0,0 +1,110 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestReadAudit(t *testing.T) {
+	t.Log("Testing audit of keys and values left out of a read.")
+	{
+		m := NewMemory()
+		err := m.Load(LOCAL_MACHINE, "SOFTWARE\\Vendor", map[string]interface{}{
+			"Name":     "vendor",
+			"MRU":      "file",
+			"Blob":     make([]byte, 16),
+			"Denied":   map[string]interface{}{"Secret": "secret"},
+			"Excluded": map[string]interface{}{"Name": "excluded"},
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to load memory registry: %v", failed, err)
+		}
+		k, err := m.CreateKey(LOCAL_MACHINE, "SOFTWARE\\Vendor", 0, registry.SET_VALUE)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to open memory key: %v", failed, err)
+		}
+		if err = m.SetValue(k, "None", registry.NONE, nil); err != nil {
+			t.Fatalf("\t%s\tUnable to set REG_NONE value: %v", failed, err)
+		}
+		m.CloseKey(k)
+
+		cfg := Config{
+			Key:           LOCAL_MACHINE,
+			Path:          "SOFTWARE\\Vendor",
+			ExcludeValues: []string{"MRU"},
+			ExcludeKeys:   []*regexp.Regexp{regexp.MustCompile(`Excluded$`)},
+			MaxValueSize:  8,
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tread without audit.", testID)
+		{
+			p := Provider(cfg)
+			p.api = deniedAPI{m}
+			if _, _, err := p.ReadAudit(); !errors.Is(err, ErrAccessDenied) {
+				t.Fatalf("\t%s\tReadAudit() should fail with ErrAccessDenied, got: %v.", failed, err)
+			}
+			t.Logf("\t%s\tUnreadable keys fail the read.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\taudit read.", testID)
+		{
+			cfg.Audit = true
+			p := Provider(cfg)
+			p.api = deniedAPI{m}
+			tree, report, err := p.ReadAudit()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if !reflect.DeepEqual(tree, map[string]interface{}{"Name": "vendor"}) {
+				t.Fatalf("\t%s\tTree is invalid, got %v.", failed, tree)
+			}
+			var e *Error
+			if len(report) != 5 || !errors.As(report[3].Err, &e) || e.Code != windows.ERROR_ACCESS_DENIED {
+				t.Fatalf("\t%s\tReport is invalid, got %+v.", failed, report)
+			}
+			report[3].Err = nil
+			expected := []AuditEntry{
+				{Key: "HKLM\\SOFTWARE\\Vendor", Value: "Blob", Type: "REG_BINARY", Reason: AuditTooLarge},
+				{Key: "HKLM\\SOFTWARE\\Vendor", Value: "MRU", Type: "REG_SZ", Reason: AuditFiltered},
+				{Key: "HKLM\\SOFTWARE\\Vendor", Value: "None", Type: "REG_NONE", Reason: AuditUnsupported},
+				{Key: "HKLM\\SOFTWARE\\Vendor\\Denied", IsKey: true, Reason: AuditUnreadable},
+				{Key: "HKLM\\SOFTWARE\\Vendor\\Excluded", IsKey: true, Reason: AuditFiltered},
+			}
+			if !reflect.DeepEqual(report, expected) {
+				t.Fatalf("\t%s\tReport is invalid, got %+v.", failed, report)
+			}
+			t.Logf("\t%s\tLeft out keys and values are reported.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\treads of copies.", testID)
+		{
+			p := Provider(cfg)
+			p.api = deniedAPI{m}
+			if _, err := p.Read(); err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			c := p.readCopy()
+			c.audit = false
+			if _, err := c.Read(); err == nil {
+				t.Fatalf("\t%s\tRead() of the copy should fail.", failed)
+			}
+			p.audited.mu.Lock()
+			entries := len(p.audited.entries)
+			p.audited.mu.Unlock()
+			if entries != 5 {
+				t.Fatalf("\t%s\tAudit log is reset by the copy, got %d entries.", failed, entries)
+			}
+			t.Logf("\t%s\tCopies have their own audit log.", success)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// autostartPaths are the autostart keys of the software views, by their
+// locations.
+var autostartPaths = []struct {
+	location string
+	path     string
+}{
+	{"Run", `SOFTWARE\Microsoft\Windows\CurrentVersion\Run`},
+	{"RunOnce", `SOFTWARE\Microsoft\Windows\CurrentVersion\RunOnce`},
+	{"PoliciesRun", `SOFTWARE\Microsoft\Windows\CurrentVersion\Policies\Explorer\Run`},
+}
+
+// AutostartEntry is a program started at logon.
+type AutostartEntry struct {
+	Name     string // Value name
+	Command  string // Expanded command line
+	Source   string // Full name of the key the entry is registered in
+	Location string // One of "Run", "RunOnce", "PoliciesRun"
+	User     bool   // Registered for the current user only
+	Bit32    bool   // Registered in the 32-bit view of a 64-bit system
+}
+
+// Autostart is a provider of the programs started at logon from the Run,
+// RunOnce and policy Run keys of both views of HKLM and of HKCU. The tree
+// holds the list under the "Autostart" key, each entry with the fields of
+// AutostartEntry.
+type Autostart struct{}
+
+// ReadBytes is not supported by the autostart provider.
+func (a *Autostart) ReadBytes() ([]byte, error) {
+	return nil, errors.New("winreg autostart does not support this method")
+}
+
+// Read returns the autostart entries as a list of trees.
+func (a *Autostart) Read() (map[string]interface{}, error) {
+	entries, err := a.List()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		list = append(list, map[string]interface{}{
+			"Name":     entry.Name,
+			"Command":  entry.Command,
+			"Source":   entry.Source,
+			"Location": entry.Location,
+			"User":     entry.User,
+			"Bit32":    entry.Bit32,
+		})
+	}
+
+	return map[string]interface{}{"Autostart": list}, nil
+}
+
+// List returns the autostart entries sorted by source and name.
+func (a *Autostart) List() ([]AutostartEntry, error) {
+	var retval []AutostartEntry
+	for _, view := range softwareViews() {
+		for _, autostart := range autostartPaths {
+			cfg := view.cfg
+			cfg.Path = autostart.path
+			cfg.MaxDepth = 1
+			// A missing key has no entries
+			cfg.Defaults = map[string]interface{}{}
+			p := Provider(cfg)
+			tree, err := p.Read()
+			if err != nil {
+				return nil, fmt.Errorf("unable to read autostart entries, %w", err)
+			}
+
+			source := autostart.path
+			if view.bit32 {
+				// Attributed to the key where it's actually stored
+				source = `SOFTWARE\WOW6432Node` + strings.TrimPrefix(source, "SOFTWARE")
+			}
+			for name := range tree {
+				command := stringField(tree, name)
+				if command == "" {
+					continue
+				}
+				retval = append(retval, AutostartEntry{
+					Name:     name,
+					Command:  command,
+					Source:   p.getKeyName(source),
+					Location: autostart.location,
+					User:     view.user,
+					Bit32:    view.bit32,
+				})
+			}
+		}
+	}
+
+	sort.Slice(retval, func(i, j int) bool {
+		if retval[i].Source != retval[j].Source {
+			return retval[i].Source < retval[j].Source
+		}
+		return strings.ToLower(retval[i].Name) < strings.ToLower(retval[j].Name)
+	})
+
+	return retval, nil
+}
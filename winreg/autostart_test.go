@@ -0,0 +1,48 @@
+//go:build windows
+
+package winreg
+
+import (
+	"testing"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestAutostart(t *testing.T) {
+	t.Log("Testing autostart entries.")
+	{
+		const runPath = `SOFTWARE\Microsoft\Windows\CurrentVersion\Run`
+		k, _, err := registry.CreateKey(registry.CURRENT_USER, runPath, registry.ALL_ACCESS)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to open Run key: %v", failed, err)
+		}
+		defer k.Close()
+		if err := k.SetExpandStringValue(testKey, "%SystemRoot%\\notepad.exe"); err != nil {
+			t.Fatalf("\t%s\tUnable to create test value: %v", failed, err)
+		}
+		defer k.DeleteValue(testKey)
+
+		entries, err := (&Autostart{}).List()
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to list autostart entries: %v.", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tuser Run entry.", testID)
+		{
+			var entry *AutostartEntry
+			for i := range entries {
+				if entries[i].Name == testKey {
+					entry = &entries[i]
+				}
+			}
+			if entry == nil {
+				t.Fatalf("\t%s\tEntry is not found.", failed)
+			}
+			if entry.Source != "HKCU\\"+runPath || entry.Location != "Run" || !entry.User || entry.Command == "%SystemRoot%\\notepad.exe" {
+				t.Fatalf("\t%s\tEntry is invalid: %+v.", failed, *entry)
+			}
+			t.Logf("\t%s\tEntry is attributed.", success)
+		}
+	}
+}
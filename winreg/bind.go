@@ -0,0 +1,86 @@
+//go:build windows
+
+package winreg
+
+import (
+	"sync"
+
+	"github.com/knadh/koanf/v2"
+)
+
+// Binding keeps a koanf instance loaded from a provider, reloading it
+// whenever the provider's key changes.
+type Binding struct {
+	k    *koanf.Koanf
+	p    *WinReg
+	base *koanf.Koanf // Contents of k before it was bound
+
+	mu sync.RWMutex
+	cb func(err error)
+}
+
+// Bind loads the provider of cfg into k and watches its key, reloading k
+// on every change. Values already in k when it is bound, e.g. defaults,
+// stay beneath the registry tree, values deleted from the registry
+// disappear from k. Reads of k concurrent with a reload must be done in
+// View(). The binding lasts until Stop() is called.
+func Bind(k *koanf.Koanf, cfg Config) (*Binding, error) {
+	b := &Binding{k: k, p: Provider(cfg), base: k.Copy()}
+	if err := k.Load(b.p, nil); err != nil {
+		return nil, err
+	}
+
+	err := b.p.Watch(func(event interface{}, err error) {
+		if err == nil {
+			err = b.reload()
+		}
+		b.mu.RLock()
+		cb := b.cb
+		b.mu.RUnlock()
+		if cb != nil {
+			cb(err)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// reload reads the provider and replaces the contents of k.
+func (b *Binding) reload() error {
+	tree, err := b.p.Read()
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.k.Delete("")
+	b.k.Merge(b.base)
+
+	return b.k.Load(&Snapshot{Data: tree}, nil)
+}
+
+// View calls fn with the bound koanf instance, which is not reloaded
+// until fn returns.
+func (b *Binding) View(fn func(k *koanf.Koanf)) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	fn(b.k)
+}
+
+// OnReload sets cb, which is called after each reload with nil, or with
+// the error of reading or watching the key. The previous contents of the
+// koanf instance are kept if a reload fails.
+func (b *Binding) OnReload(cb func(err error)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cb = cb
+}
+
+// Stop stops reloading the koanf instance.
+func (b *Binding) Stop() error {
+	return b.p.Unwatch()
+}
@@ -0,0 +1,65 @@
+//go:build windows
+
+package winreg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/knadh/koanf/v2"
+)
+
+func TestBind(t *testing.T) {
+	t.Log("Testing koanf instance bound to a key.")
+	{
+		const eventTimeout = 5
+		m := NewMemory()
+		if err := m.Load(CURRENT_USER, "SOFTWARE\\Vendor\\App", map[string]interface{}{"Name": "app", "Old": "old"}); err != nil {
+			t.Fatalf("\t%s\tUnable to load memory registry: %v", failed, err)
+		}
+		k := koanf.New(".")
+		k.Set("Default", "default")
+
+		b, err := Bind(k, Config{Key: CURRENT_USER, Path: "SOFTWARE\\Vendor\\App", Memory: m})
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to bind koanf: %v.", failed, err)
+		}
+		defer b.Stop()
+		reloads := make(chan error, 10)
+		b.OnReload(func(err error) { reloads <- err })
+
+		testID := 0
+		t.Logf("\tTest %d:\tinitial load.", testID)
+		{
+			if k.String("Name") != "app" || k.String("Default") != "default" {
+				t.Fatalf("\t%s\tKoanf is not loaded, got %v.", failed, k.All())
+			}
+			t.Logf("\t%s\tKoanf is loaded.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\treload on change.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\Vendor\\App", Memory: m})
+			if err := p.Apply([]Write{{Name: "Name", Value: "changed"}, {Name: "Old"}}); err != nil {
+				t.Fatalf("\t%s\tUnable to write values: %v.", failed, err)
+			}
+			select {
+			case err := <-reloads:
+				if err != nil {
+					t.Fatalf("\t%s\tReload failed: %v.", failed, err)
+				}
+			case <-time.After(eventTimeout * time.Second):
+				t.Fatalf("\t%s\tTimeout exceeded while waiting for reload.", failed)
+			}
+			// Writes of both values may be reported separately
+			time.Sleep(100 * time.Millisecond)
+			b.View(func(k *koanf.Koanf) {
+				if k.String("Name") != "changed" || k.Exists("Old") || k.String("Default") != "default" {
+					t.Fatalf("\t%s\tKoanf is not reloaded, got %v.", failed, k.All())
+				}
+			})
+			t.Logf("\t%s\tKoanf is reloaded.", success)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+//go:build windows
+
+package winreg
+
+import (
+	"fmt"
+	"sync"
+
+	"golang.org/x/sys/windows"
+)
+
+// Capabilities describes the registry features of the running Windows
+// version the package adapts to.
+type Capabilities struct {
+	Major, Minor, Build uint32
+
+	ThreadAgnosticNotify bool // REG_NOTIFY_THREAD_AGNOSTIC, Windows 8 / Server 2012 and later
+}
+
+func (c Capabilities) String() string {
+	return fmt.Sprintf("Windows %d.%d.%d", c.Major, c.Minor, c.Build)
+}
+
+func (c Capabilities) atLeast(major, minor uint32) bool {
+	return c.Major > major || (c.Major == major && c.Minor >= minor)
+}
+
+var (
+	capabilities     Capabilities
+	capabilitiesOnce sync.Once
+)
+
+// SystemCapabilities reports the capabilities of the running system. The
+// version is read ignoring the application compatibility layer.
+func SystemCapabilities() Capabilities {
+	capabilitiesOnce.Do(func() {
+		major, minor, build := windows.RtlGetNtVersionNumbers()
+		c := Capabilities{Major: major, Minor: minor, Build: build & 0xFFFF}
+		c.ThreadAgnosticNotify = c.atLeast(6, 2)
+		capabilities = c
+	})
+
+	return capabilities
+}
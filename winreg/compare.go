@@ -0,0 +1,132 @@
+//go:build windows
+
+package winreg
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// HostDifference is a value which is not the same on all compared hosts.
+type HostDifference struct {
+	Path   string                 // Value path relative to the compared subtree, e.g. "SubKey\Value"
+	Values map[string]interface{} // Value per host, hosts without the value are absent
+}
+
+// HostComparison is the result of CompareHosts().
+type HostComparison struct {
+	Hosts       []string         // Compared hosts, in the requested order
+	Errors      map[string]error // Hosts which could not be read
+	Differences []HostDifference // Differing values, sorted by path
+}
+
+// CompareHosts reads the same subtree from several machines using the
+// provider's settings and reports values which differ between them. The
+// path is relative to the provider's Path, or to each of Paths, empty
+// string compares the whole tree. Hosts that can't be read are reported in Errors and left
+// out of the comparison. Subkeys without values are not compared.
+func (s *WinReg) CompareHosts(path string, hosts []string) *HostComparison {
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		trees = make(map[string]map[string]interface{}, len(hosts))
+	)
+
+	retval := &HostComparison{Hosts: hosts, Errors: make(map[string]error)}
+	for _, host := range hosts {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+
+			tree, err := s.hostCopy(host, path).Read()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				retval.Errors[host] = err
+				return
+			}
+			flat := make(map[string]interface{})
+			flattenTree("", tree, flat)
+			trees[host] = flat
+		}(host)
+	}
+	wg.Wait()
+
+	paths := make(map[string]struct{})
+	for _, tree := range trees {
+		for path := range tree {
+			paths[path] = struct{}{}
+		}
+	}
+
+	for path := range paths {
+		var (
+			values = make(map[string]interface{}, len(trees))
+			first  interface{}
+			differ bool
+		)
+		for _, host := range hosts {
+			tree, ok := trees[host]
+			if !ok {
+				continue
+			}
+			value, ok := tree[path]
+			if !ok {
+				differ = true
+				continue
+			}
+			if len(values) == 0 {
+				first = value
+			} else if !reflect.DeepEqual(first, value) {
+				differ = true
+			}
+			values[host] = value
+		}
+		if differ {
+			retval.Differences = append(retval.Differences, HostDifference{Path: path, Values: values})
+		}
+	}
+	sort.Slice(retval.Differences, func(i, j int) bool {
+		return retval.Differences[i].Path < retval.Differences[j].Path
+	})
+
+	return retval
+}
+
+// flattenTree converts a nested map returned by Read() into a flat map
+// keyed by registry-style paths.
+func flattenTree(prefix string, tree map[string]interface{}, out map[string]interface{}) {
+	flattenPaths(prefix, "\\", tree, out)
+}
+
+// flattenPaths converts a nested map into a flat map keyed by paths of
+// names joined by sep.
+func flattenPaths(prefix, sep string, tree map[string]interface{}, out map[string]interface{}) {
+	for name, value := range tree {
+		path := name
+		if prefix != "" {
+			path = prefix + sep + name
+		}
+		if sub, ok := value.(map[string]interface{}); ok {
+			flattenPaths(path, sep, sub, out)
+			continue
+		}
+		out[path] = value
+	}
+}
+
+// hostCopy returns a copy of the provider reading the subtree path on
+// host, including the providers of Paths.
+func (s *WinReg) hostCopy(host, path string) *WinReg {
+	p := s.readCopy()
+	p.host = host
+	p.path = joinPath(s.path, path)
+	for _, sub := range p.paths {
+		sub.host = host
+		sub.path = joinPath(sub.path, path)
+	}
+
+	return p
+}
@@ -0,0 +1,62 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCompareHosts(t *testing.T) {
+	t.Log("Testing comparison of hosts.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		testID := 0
+		t.Logf("\tTest %d:\tflatten tree.", testID)
+		{
+			flat := make(map[string]interface{})
+			flattenTree("", map[string]interface{}{
+				"a": uint64(1),
+				"b": map[string]interface{}{"c": "d"},
+			}, flat)
+			if len(flat) != 2 || flat["a"] != uint64(1) || flat["b\\c"] != "d" {
+				t.Fatalf("\t%s\tInvalid flattened tree %v.", failed, flat)
+			}
+			t.Logf("\t%s\tTree is flattened.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tlocal host compared with itself.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey})
+			c := p.CompareHosts("SubKeyA", []string{""})
+			if len(c.Errors) != 0 {
+				t.Fatalf("\t%s\tUnable to read local host: %v.", failed, c.Errors[""])
+			}
+			if len(c.Differences) != 0 {
+				t.Fatalf("\t%s\tGot unexpected differences %v.", failed, c.Differences)
+			}
+			t.Logf("\t%s\tNo differences found.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\thosts of Paths.", testID)
+		{
+			m := NewMemory()
+			if err := m.Load(CURRENT_USER, "SOFTWARE\\Vendor\\App", map[string]interface{}{"Name": "app"}); err != nil {
+				t.Fatalf("\t%s\tUnable to load memory registry: %v", failed, err)
+			}
+			p := Provider(Config{Key: CURRENT_USER, Paths: []string{"SOFTWARE\\Vendor"}, Memory: m})
+			c := p.CompareHosts("App", []string{"", "server"})
+			if c.Errors[""] != nil {
+				t.Fatalf("\t%s\tUnable to read local host: %v.", failed, c.Errors[""])
+			}
+			if !errors.Is(c.Errors["server"], ErrMemoryRemote) {
+				t.Fatalf("\t%s\tRemote host should be read remotely, got: %v.", failed, c.Errors["server"])
+			}
+			t.Logf("\t%s\tProviders of Paths read the host.", success)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+//go:build windows
+
+package winreg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// Representations of REG_BINARY values, see Config.BinaryFormat.
+const (
+	BinaryBytes  = iota // []byte
+	BinaryHex           // Lower-case hex string
+	BinaryBase64        // Standard base64 string with padding
+)
+
+// Decoder converts the data of a value read from the registry, e.g. parses
+// a document stored in a string value. A map returned by a decoder becomes
+// a subtree, so its fields are addressable as koanf keys.
+type Decoder func(data interface{}) (interface{}, error)
+
+// DecoderRule applies Decoder to values whose names match Pattern, a glob
+// pattern as understood by path.Match, e.g. "*Time", ignoring case.
+type DecoderRule struct {
+	Pattern string
+	Decoder Decoder
+}
+
+// DecodeJSON is a Decoder parsing a JSON document stored in a string or
+// binary value.
+func DecodeJSON(data interface{}) (interface{}, error) {
+	var doc []byte
+	switch v := data.(type) {
+	case string:
+		doc = []byte(v)
+	case []byte:
+		doc = v
+	default:
+		return nil, fmt.Errorf("unable to decode JSON from %T", data)
+	}
+
+	var retval interface{}
+	if err := json.Unmarshal(doc, &retval); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	return retval, nil
+}
+
+// decodeValue applies the decoder configured for the value name of the
+// key path. Values of keys outside of the provider's path, i.e. included
+// ones, are not decoded.
+func (s *WinReg) decodeValue(keyPath, name string, typ uint32, data interface{}) (interface{}, error) {
+	if s.decoders != nil || s.decoderRules != nil {
+		if rel, ok := s.relativePath(keyPath); ok {
+			if decoder := s.decoders[strings.ToUpper(joinPath(rel, name))]; decoder != nil {
+				return decoder(data)
+			}
+			for _, rule := range s.decoderRules {
+				if ok, err := path.Match(strings.ToUpper(rule.Pattern), strings.ToUpper(name)); err != nil {
+					return nil, err
+				} else if ok {
+					return rule.Decoder(data)
+				}
+			}
+		}
+	}
+
+	if s.signed {
+		switch typ {
+		case registry.DWORD, registry.DWORD_BIG_ENDIAN:
+			return DecodeInt32(data)
+		case registry.QWORD:
+			return DecodeInt64(data)
+		}
+	}
+
+	if str, ok := data.(string); ok && s.detectJSON && typ == registry.SZ && looksLikeJSON(str) {
+		if retval, err := DecodeJSON(str); err == nil {
+			return retval, nil
+		}
+	}
+
+	if s.resources {
+		switch typ {
+		case registry.RESOURCE_LIST:
+			return DecodeResourceList(data)
+		case registry.FULL_RESOURCE_DESCRIPTOR:
+			return DecodeFullResourceDescriptor(data)
+		}
+	}
+
+	if b, ok := data.([]byte); ok {
+		if s.detectUTF16 && typ == registry.BINARY && looksLikeUTF16(b) {
+			return decodeUTF16(b), nil
+		}
+		switch s.binaryFormat {
+		case BinaryHex:
+			return hex.EncodeToString(b), nil
+		case BinaryBase64:
+			return base64.StdEncoding.EncodeToString(b), nil
+		}
+	}
+
+	return data, nil
+}
+
+// binaryData reverses BinaryFormat for a string to be stored as binary
+// data, other values are returned as they are.
+func (s *WinReg) binaryData(value interface{}) (interface{}, error) {
+	str, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+
+	switch s.binaryFormat {
+	case BinaryHex:
+		return hex.DecodeString(str)
+	case BinaryBase64:
+		return base64.StdEncoding.DecodeString(str)
+	default:
+		return value, nil
+	}
+}
+
+// relativePath returns the key path relative to the provider's path.
+func (s *WinReg) relativePath(path string) (string, bool) {
+	path, base := strings.Trim(path, "\\"), strings.Trim(s.path, "\\")
+	switch {
+	case base == "":
+		return path, true
+	case strings.EqualFold(path, base):
+		return "", true
+	case len(path) > len(base) && path[len(base)] == '\\' && strings.EqualFold(path[:len(base)], base):
+		return path[len(base)+1:], true
+	default:
+		return "", false
+	}
+}
+
+// looksLikeJSON reports whether str may be a JSON object or array.
+func looksLikeJSON(str string) bool {
+	str = strings.TrimSpace(str)
+	return len(str) >= 2 && (str[0] == '{' && str[len(str)-1] == '}' || str[0] == '[' && str[len(str)-1] == ']')
+}
+
+// SplitList returns a Decoder splitting a string value on any of the
+// separator characters, e.g. ";" for PATH-like values, into []string.
+// Entries are trimmed of spaces and empty entries are removed, as Windows
+// itself does.
+func SplitList(separators string) Decoder {
+	return func(data interface{}) (interface{}, error) {
+		str, ok := data.(string)
+		if !ok {
+			return nil, fmt.Errorf("unable to split %T", data)
+		}
+
+		retval := []string{}
+		for _, entry := range strings.FieldsFunc(str, func(r rune) bool {
+			return strings.ContainsRune(separators, r)
+		}) {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				retval = append(retval, entry)
+			}
+		}
+
+		return retval, nil
+	}
+}
+
+// DecodeInt32 is a Decoder of a REG_DWORD value storing a signed integer,
+// e.g. 0xFFFFFFFF is returned as int64(-1).
+func DecodeInt32(data interface{}) (interface{}, error) {
+	switch v := data.(type) {
+	case uint64:
+		return int64(int32(uint32(v))), nil
+	case uint32:
+		return int64(int32(v)), nil
+	default:
+		return nil, fmt.Errorf("unable to decode int32 from %T", data)
+	}
+}
+
+// DecodeInt64 is a Decoder of a REG_QWORD value storing a signed integer.
+func DecodeInt64(data interface{}) (interface{}, error) {
+	switch v := data.(type) {
+	case uint64:
+		return int64(v), nil
+	case uint32:
+		return int64(int32(v)), nil
+	default:
+		return nil, fmt.Errorf("unable to decode int64 from %T", data)
+	}
+}
+
+// DecodeGUID is a Decoder of a 16 bytes binary GUID, returned as a string
+// like "{6B29FC40-CA47-1067-B31D-00DD010662DA}".
+func DecodeGUID(data interface{}) (interface{}, error) {
+	b, ok := data.([]byte)
+	if !ok || len(b) != 16 {
+		return nil, fmt.Errorf("unable to decode GUID from %T of %d bytes", data, len(b))
+	}
+
+	guid := windows.GUID{
+		Data1: binary.LittleEndian.Uint32(b),
+		Data2: binary.LittleEndian.Uint16(b[4:]),
+		Data3: binary.LittleEndian.Uint16(b[6:]),
+	}
+	copy(guid.Data4[:], b[8:])
+
+	return guid.String(), nil
+}
+
+// DecodeFILETIME is a Decoder of a FILETIME stored as 8 bytes binary or as
+// REG_QWORD, returned as UTC time.Time.
+func DecodeFILETIME(data interface{}) (interface{}, error) {
+	var ft uint64
+	switch v := data.(type) {
+	case []byte:
+		if len(v) != 8 {
+			return nil, fmt.Errorf("unable to decode FILETIME from %d bytes", len(v))
+		}
+		ft = binary.LittleEndian.Uint64(v)
+	case uint64:
+		ft = v
+	default:
+		return nil, fmt.Errorf("unable to decode FILETIME from %T", data)
+	}
+
+	filetime := windows.Filetime{LowDateTime: uint32(ft), HighDateTime: uint32(ft >> 32)}
+	return time.Unix(0, filetime.Nanoseconds()).UTC(), nil
+}
+
+// DecodeDWORDs is a Decoder of a binary array of little-endian DWORDs,
+// returned as []uint32.
+func DecodeDWORDs(data interface{}) (interface{}, error) {
+	b, ok := data.([]byte)
+	if !ok || len(b)%4 != 0 {
+		return nil, fmt.Errorf("unable to decode DWORD array from %T of %d bytes", data, len(b))
+	}
+
+	retval := make([]uint32, len(b)/4)
+	for i := range retval {
+		retval[i] = binary.LittleEndian.Uint32(b[i*4:])
+	}
+
+	return retval, nil
+}
+
+// DecodeUTF16 is a Decoder converting a binary value holding UTF-16 text to
+// a string. Big-endian text is recognized by its byte order mark, text
+// without one is taken as little-endian. The byte order mark and trailing
+// NUL padding are removed.
+func DecodeUTF16(data interface{}) (interface{}, error) {
+	b, ok := data.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unable to decode UTF-16 from %T", data)
+	}
+
+	return decodeUTF16(b), nil
+}
+
+func decodeUTF16(b []byte) string {
+	var order binary.ByteOrder = binary.LittleEndian
+	switch {
+	case bytes.HasPrefix(b, []byte{0xFF, 0xFE}):
+		b = b[2:]
+	case bytes.HasPrefix(b, []byte{0xFE, 0xFF}):
+		order = binary.BigEndian
+		b = b[2:]
+	}
+
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = order.Uint16(b[i*2:])
+	}
+	for len(units) > 0 && units[len(units)-1] == 0 {
+		units = units[:len(units)-1]
+	}
+
+	return string(utf16.Decode(units))
+}
+
+// looksLikeUTF16 reports whether a binary value may be UTF-16 text: it has
+// a byte order mark, or it is printable little-endian text, at least half
+// of it ASCII, followed by optional NUL padding.
+func looksLikeUTF16(b []byte) bool {
+	if len(b) < 4 || len(b)%2 != 0 {
+		return false
+	}
+	if bytes.HasPrefix(b, []byte{0xFF, 0xFE}) || bytes.HasPrefix(b, []byte{0xFE, 0xFF}) {
+		return true
+	}
+
+	str := decodeUTF16(b)
+	ascii, total := 0, 0
+	for _, r := range str {
+		if r == utf8.RuneError || !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			// Also embedded NULs and unpaired surrogates
+			return false
+		}
+		if r < utf8.RuneSelf {
+			ascii++
+		}
+		total++
+	}
+
+	return total >= 2 && ascii*2 >= total
+}
@@ -0,0 +1,318 @@
+//go:build windows
+
+package winreg
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/knadh/koanf/v2"
+	"golang.org/x/sys/windows/registry"
+)
+
+func setTestStrings(t *testing.T, values map[string]string) {
+	k, err := registry.OpenKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey+"\\SubKeyA", registry.ALL_ACCESS)
+	if err != nil {
+		t.Fatalf("\t%s\tUnable to open test key: %v", failed, err)
+	}
+	defer k.Close()
+
+	for name, value := range values {
+		if err := k.SetStringValue(name, value); err != nil {
+			t.Fatalf("\t%s\tUnable to create test value: %v", failed, err)
+		}
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	t.Log("Testing JSON values.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+		setTestStrings(t, map[string]string{
+			"Json":    `{"a": {"b": 1}}`,
+			"Auto":    ` ["x", "y"] `,
+			"NotJson": `{oops}`,
+		})
+
+		k := koanf.New(".")
+		p := Provider(Config{
+			Key:        CURRENT_USER,
+			Path:       "SOFTWARE\\" + testKey,
+			Decoders:   map[string]Decoder{"subkeya\\JSON": DecodeJSON},
+			DetectJSON: true,
+		})
+		if err := k.Load(p, nil); err != nil {
+			t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tdeclared value.", testID)
+		{
+			if val := k.Int("SubKeyA.Json.a.b"); val != 1 {
+				t.Fatalf("\t%s\tSubKeyA.Json.a.b is invalid, got %d, expect 1.", failed, val)
+			}
+			t.Logf("\t%s\tValue is decoded.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tdetected values.", testID)
+		{
+			if val := k.Strings("SubKeyA.Auto"); len(val) != 2 || val[1] != "y" {
+				t.Fatalf("\t%s\tSubKeyA.Auto is invalid, got %v.", failed, val)
+			}
+			if val := k.String("SubKeyA.NotJson"); val != "{oops}" {
+				t.Fatalf("\t%s\tSubKeyA.NotJson is invalid, got %s.", failed, val)
+			}
+			t.Logf("\t%s\tValues are detected.", success)
+		}
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	t.Log("Testing delimited values.")
+	{
+		split := SplitList(";,")
+
+		testID := 0
+		t.Logf("\tTest %d:\tdelimited string.", testID)
+		{
+			val, err := split(" C:\\Bin ;; D:\\Tools,E:\\;  ")
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to split: %v.", failed, err)
+			}
+			expect := []string{"C:\\Bin", "D:\\Tools", "E:\\"}
+			if !reflect.DeepEqual(val, expect) {
+				t.Fatalf("\t%s\tInvalid list, got %q, expect %q.", failed, val, expect)
+			}
+			if _, err := split(uint64(1)); err == nil {
+				t.Fatalf("\t%s\tSplitting an integer should fail.", failed)
+			}
+			t.Logf("\t%s\tString is split.", success)
+		}
+	}
+}
+
+func TestResolveMUI(t *testing.T) {
+	t.Log("Testing indirect strings.")
+	{
+		const indirect = "@%SystemRoot%\\system32\\shell32.dll,-21770"
+		const missing = "@%SystemRoot%\\system32\\koanf-winreg-missing.dll,-1"
+		createTestData(t)
+		defer deleteTestData(t)
+		setTestStrings(t, map[string]string{
+			"Indirect": indirect,
+			"Missing":  missing,
+			"Plain":    "@plain",
+		})
+
+		k := koanf.New(".")
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, ResolveMUI: true})
+		if err := k.Load(p, nil); err != nil {
+			t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tresolvable string.", testID)
+		{
+			if val := k.String("SubKeyA.Indirect"); val == "" || val == indirect {
+				t.Fatalf("\t%s\tSubKeyA.Indirect is not resolved, got %s.", failed, val)
+			}
+			t.Logf("\t%s\tString is resolved.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tunresolvable strings.", testID)
+		{
+			if val := k.String("SubKeyA.Missing"); val != missing {
+				t.Fatalf("\t%s\tSubKeyA.Missing is invalid, got %s.", failed, val)
+			}
+			if val := k.String("SubKeyA.Plain"); val != "@plain" {
+				t.Fatalf("\t%s\tSubKeyA.Plain is invalid, got %s.", failed, val)
+			}
+			t.Logf("\t%s\tStrings are kept as stored.", success)
+		}
+	}
+}
+
+func TestDecodeUTF16(t *testing.T) {
+	t.Log("Testing UTF-16 binary values.")
+	{
+		testID := 0
+		for _, test := range []struct {
+			data   []byte
+			expect string
+		}{
+			{[]byte{'H', 0, 'i', 0, 0, 0, 0, 0}, "Hi"},
+			{[]byte{0xFF, 0xFE, 'H', 0, 'i', 0}, "Hi"},
+			{[]byte{0xFE, 0xFF, 0, 'H', 0, 'i', 0, 0}, "Hi"},
+		} {
+			t.Logf("\tTest %d:\t% X.", testID, test.data)
+			{
+				val, err := DecodeUTF16(test.data)
+				if err != nil {
+					t.Fatalf("\t%s\tUnable to decode: %v.", failed, err)
+				}
+				if val != test.expect {
+					t.Fatalf("\t%s\tInvalid string, got %q, expect %q.", failed, val, test.expect)
+				}
+				t.Logf("\t%s\tText is decoded.", success)
+			}
+			testID++
+		}
+
+		t.Logf("\tTest %d:\tdetection.", testID)
+		{
+			if !looksLikeUTF16([]byte{'C', 0, ':', 0, '\\', 0, 0, 0}) {
+				t.Fatalf("\t%s\tText is not detected.", failed)
+			}
+			for _, data := range [][]byte{{1, 2, 3}, {1, 2, 3, 4}, {'A', 'B', 'C', 'D'}, {'A', 0, 0, 0, 'B', 0}} {
+				if looksLikeUTF16(data) {
+					t.Fatalf("\t%s\tBinary data % X is detected as text.", failed, data)
+				}
+			}
+			t.Logf("\t%s\tText is detected.", success)
+		}
+	}
+}
+
+func TestSigned(t *testing.T) {
+	t.Log("Testing signed integers.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, Signed: true, TypeRules: TypeRules{
+			Paths: map[string]uint32{"Negative": registry.DWORD},
+		}})
+		if err := p.Set("Negative", -1); err != nil {
+			t.Fatalf("\t%s\tUnable to write negative REG_DWORD: %v.", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tall integers signed.", testID)
+		{
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			sub, _ := tree["SubKeyA"].(map[string]interface{})
+			if tree["Negative"] != int64(-1) || sub["IntVal"] != int64(-294967296) || sub["Int64"] != int64(5000000000) {
+				t.Fatalf("\t%s\tIntegers are invalid, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tIntegers are signed.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tsigned value.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, Decoders: map[string]Decoder{"Negative": DecodeInt32}})
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if tree["Negative"] != int64(-1) || tree["on"] != uint64(1) {
+				t.Fatalf("\t%s\tIntegers are invalid, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tOnly the value is signed.", success)
+		}
+	}
+}
+
+func TestBinaryFormat(t *testing.T) {
+	t.Log("Testing representations of binary values.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		for testID, test := range []struct {
+			format int
+			expect interface{}
+		}{
+			{BinaryBytes, []byte{1, 2, 3}},
+			{BinaryHex, "010203"},
+			{BinaryBase64, "AQID"},
+		} {
+			t.Logf("\tTest %d:\tformat %d.", testID, test.format)
+			{
+				p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, BinaryFormat: test.format, TypesKey: "__types"})
+				tree, err := p.Read()
+				if err != nil {
+					t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+				}
+				sub, _ := tree["SubKeyA"].(map[string]interface{})
+				if !reflect.DeepEqual(sub["Binary"], test.expect) {
+					t.Fatalf("\t%s\tSubKeyA.Binary is invalid, got %v, expect %v.", failed, sub["Binary"], test.expect)
+				}
+
+				sub["Expand"] = "Some %PATH%"
+				report, err := p.Sync(tree)
+				if err != nil {
+					t.Fatalf("\t%s\tUnable to synchronize: %v.", failed, err)
+				}
+				if !reflect.DeepEqual(report, &SyncReport{}) {
+					t.Fatalf("\t%s\tValues are changed, got %+v.", failed, report)
+				}
+				t.Logf("\t%s\tBinary value is represented and written back.", success)
+			}
+		}
+	}
+}
+
+func TestBinaryDecoders(t *testing.T) {
+	t.Log("Testing decoders of structured binary values.")
+	{
+		testID := 0
+		for _, test := range []struct {
+			decoder Decoder
+			data    interface{}
+			expect  interface{}
+		}{
+			{DecodeGUID, []byte{0x40, 0xFC, 0x29, 0x6B, 0x47, 0xCA, 0x67, 0x10, 0xB3, 0x1D, 0x00, 0xDD, 0x01, 0x06, 0x62, 0xDA}, "{6B29FC40-CA47-1067-B31D-00DD010662DA}"},
+			{DecodeFILETIME, []byte{0x00, 0x80, 0x3E, 0xD5, 0xDE, 0xB1, 0x9D, 0x01}, time.Unix(0, 0).UTC()},
+			{DecodeFILETIME, uint64(116444736000000000), time.Unix(0, 0).UTC()},
+			{DecodeDWORDs, []byte{1, 0, 0, 0, 0xFF, 0xFF, 0xFF, 0xFF}, []uint32{1, 0xFFFFFFFF}},
+		} {
+			t.Logf("\tTest %d:\t%v.", testID, test.data)
+			{
+				val, err := test.decoder(test.data)
+				if err != nil {
+					t.Fatalf("\t%s\tUnable to decode: %v.", failed, err)
+				}
+				if !reflect.DeepEqual(val, test.expect) {
+					t.Fatalf("\t%s\tInvalid value, got %v, expect %v.", failed, val, test.expect)
+				}
+				t.Logf("\t%s\tValue is decoded.", success)
+			}
+			testID++
+		}
+
+		t.Logf("\tTest %d:\tinvalid size.", testID)
+		{
+			if _, err := DecodeGUID([]byte{1, 2, 3}); err == nil {
+				t.Fatalf("\t%s\tDecoding 3 bytes as GUID should fail.", failed)
+			}
+			t.Logf("\t%s\tValue is rejected.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tdecoder rules.", testID)
+		{
+			createTestData(t)
+			defer deleteTestData(t)
+
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, DecoderRules: []DecoderRule{
+				{Pattern: "bin*", Decoder: func(data interface{}) (interface{}, error) { return len(data.([]byte)), nil }},
+			}})
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if sub, _ := tree["SubKeyA"].(map[string]interface{}); sub["Binary"] != 3 {
+				t.Fatalf("\t%s\tSubKeyA.Binary is not decoded, got %v.", failed, sub["Binary"])
+			}
+			t.Logf("\t%s\tRule is applied.", success)
+		}
+	}
+}
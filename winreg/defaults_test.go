@@ -0,0 +1,64 @@
+//go:build windows
+
+package winreg
+
+import (
+	"testing"
+
+	"github.com/knadh/koanf/v2"
+)
+
+func TestDefaults(t *testing.T) {
+	t.Log("Testing default values.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		defaults := map[string]interface{}{
+			"on":      uint64(0),
+			"missing": "default",
+			"SubKeyA": map[string]interface{}{
+				"Int64": uint64(1),
+				"Extra": uint64(2),
+			},
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tdefaults beneath registry data.", testID)
+		{
+			k := koanf.New(".")
+			if err := k.Load(Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, Defaults: defaults}), nil); err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if val := k.Int64("on"); val != 1 {
+				t.Fatalf("\t%s\ton is invalid, got %d, expect 1.", failed, val)
+			}
+			if val := k.String("missing"); val != "default" {
+				t.Fatalf("\t%s\tmissing is invalid, got \"%s\", expect \"default\".", failed, val)
+			}
+			if val := k.Int64("SubKeyA.Int64"); val != 5000000000 {
+				t.Fatalf("\t%s\tSubKeyA.Int64 is invalid, got %d, expect 5000000000.", failed, val)
+			}
+			if val := k.Int64("SubKeyA.Extra"); val != 2 {
+				t.Fatalf("\t%s\tSubKeyA.Extra is invalid, got %d, expect 2.", failed, val)
+			}
+			t.Logf("\t%s\tDefaults are merged.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tmissing key.", testID)
+		{
+			k := koanf.New(".")
+			if err := k.Load(Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey + "\\Missing", Defaults: defaults}), nil); err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if val := k.Int64("SubKeyA.Int64"); val != 1 {
+				t.Fatalf("\t%s\tSubKeyA.Int64 is invalid, got %d, expect 1.", failed, val)
+			}
+			if _, err := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey + "\\Missing"}).Read(); err == nil {
+				t.Fatalf("\t%s\tRead() of a missing key without defaults should fail.", failed)
+			}
+			t.Logf("\t%s\tDefaults are returned.", success)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+//go:build windows
+
+package winreg
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// profileListPath holds the profile directories of the system.
+const profileListPath = `SOFTWARE\Microsoft\Windows NT\CurrentVersion\ProfileList`
+
+// DefaultUser provides access to the hive of the Default user profile,
+// usually C:\Users\Default\NTUSER.DAT, which new user profiles are copied
+// from. The hive is loaded privately and unloaded by Close(). Paths of its
+// providers are HKCU paths of new users, e.g. "Software\Vendor\App".
+type DefaultUser struct {
+	AppHive
+}
+
+// OpenDefaultUser loads the Default user hive of the running system. With
+// writable set, providers can write to it, which requires administrator
+// rights; changes are saved to the hive file.
+func OpenDefaultUser(writable bool) (*DefaultUser, error) {
+
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, profileListPath, registry.QUERY_VALUE)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find default user profile: %w", err)
+	}
+	defer k.Close()
+	dir, _, err := k.GetStringValue("Default")
+	if err != nil {
+		return nil, fmt.Errorf("unable to find default user profile: %w", err)
+	}
+	if dir, err = registry.ExpandString(dir); err != nil {
+		return nil, fmt.Errorf("unable to find default user profile: %w", err)
+	}
+
+	file := filepath.Join(dir, offlineUserHive)
+	hive, err := loadAppHive(file, writable)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DefaultUser{AppHive{file: file, k: hive}}, nil
+}
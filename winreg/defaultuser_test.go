@@ -0,0 +1,43 @@
+//go:build windows
+
+package winreg
+
+import (
+	"testing"
+)
+
+func TestDefaultUser(t *testing.T) {
+	t.Log("Testing Default user hive.")
+	{
+		d, err := OpenDefaultUser(false)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to open Default user hive: %v", failed, err)
+		}
+		defer d.Close()
+
+		testID := 0
+		t.Logf("\tTest %d:\treading %s.", testID, d.File())
+		{
+			p, err := d.Provider(Config{Path: "Control Panel", MaxDepth: 1})
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to create provider: %v.", failed, err)
+			}
+			if _, err := p.Read(); err != nil {
+				t.Fatalf("\t%s\tUnable to read hive: %v.", failed, err)
+			}
+			t.Logf("\t%s\tHive is read.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tclosed hive.", testID)
+		{
+			if err := d.Close(); err != nil {
+				t.Fatalf("\t%s\tUnable to close hive: %v.", failed, err)
+			}
+			if _, err := d.Provider(Config{}); err == nil {
+				t.Fatalf("\t%s\tProvider of a closed hive is created.", failed)
+			}
+			t.Logf("\t%s\tClosed hive is rejected.", success)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+//go:build windows
+
+package winreg
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+	"sort"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// digest returns a hash of names, types and stored data of the values of
+// the provider's key and its subkeys read by Read(), as selected by the
+// key and value filters and MaxDepth. Unlike Read(), data is not
+// converted, decoded nor merged with defaults.
+func (s *WinReg) digest() (retval [sha256.Size]byte, err error) {
+	h := sha256.New()
+	if err = s.digestKey(h, s.key, s.path, 1); err != nil {
+		return retval, err
+	}
+	copy(retval[:], h.Sum(nil))
+
+	return retval, nil
+}
+
+func (s *WinReg) digestKey(h hash.Hash, root registry.Key, path string, level uint) error {
+	s.limiter.Wait()
+	k, err := s.api.OpenKey(root, path, s.getAccess(registry.READ))
+	if err != nil {
+		return err
+	}
+	defer s.api.CloseKey(k)
+
+	s.limiter.Wait()
+	values, err := s.api.ReadValueNames(k)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	sort.Strings(values)
+	for _, value := range values {
+		data, typ, err := s.getValueBytes(k, value)
+		if errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+			// The value was deleted after enumeration
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok, err := s.valueSelected(value, typ); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		digestBytes(h, []byte(value))
+		binary.Write(h, binary.LittleEndian, typ)
+		digestBytes(h, data)
+	}
+
+	if !s.readsSubKeys(path, level) {
+		return nil
+	}
+	s.limiter.Wait()
+	subKeys, err := s.api.ReadSubKeyNames(k)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	sort.Strings(subKeys)
+	// Subkeys are marked off values by an invalid value type
+	binary.Write(h, binary.LittleEndian, uint32(0xFFFFFFFF))
+	for _, subKey := range subKeys {
+		if !s.keySelected(joinPath(path, subKey)) {
+			continue
+		}
+		digestBytes(h, []byte(subKey))
+		if err = s.digestKey(h, root, joinPath(path, subKey), level+1); err != nil && !errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// digestBytes writes data prefixed with its length, so concatenations of
+// different items don't hash the same.
+func digestBytes(h hash.Hash, data []byte) {
+	binary.Write(h, binary.LittleEndian, uint32(len(data)))
+	h.Write(data)
+}
@@ -0,0 +1,72 @@
+//go:build windows
+
+package winreg
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestDigest(t *testing.T) {
+	t.Log("Testing digests of the read tree.")
+	{
+		m := NewMemory()
+		err := m.Load(CURRENT_USER, "SOFTWARE\\Vendor", map[string]interface{}{
+			"Name":     "vendor",
+			"MRU":      "file",
+			"Excluded": map[string]interface{}{"Name": "excluded"},
+			"Sub":      map[string]interface{}{"Deep": map[string]interface{}{"Name": "deep"}},
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to load memory registry: %v", failed, err)
+		}
+		p := Provider(Config{
+			Key:           CURRENT_USER,
+			Path:          "SOFTWARE\\Vendor",
+			Memory:        m,
+			ExcludeValues: []string{"MRU"},
+			ExcludeKeys:   []*regexp.Regexp{regexp.MustCompile(`^Excluded$`)},
+			MaxDepths:     map[string]uint{"Sub": 1},
+		})
+		initial, err := p.digest()
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to digest registry: %v.", failed, err)
+		}
+
+		set := func(path, name, value string) {
+			if err := m.Load(CURRENT_USER, path, map[string]interface{}{name: value}); err != nil {
+				t.Fatalf("\t%s\tUnable to set value: %v", failed, err)
+			}
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tchanges left out of the read.", testID)
+		{
+			set("SOFTWARE\\Vendor", "MRU", "other")
+			set("SOFTWARE\\Vendor\\Excluded", "Name", "other")
+			set("SOFTWARE\\Vendor\\Sub\\Deep", "Name", "other")
+			digest, err := p.digest()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to digest registry: %v.", failed, err)
+			}
+			if digest != initial {
+				t.Fatalf("\t%s\tDigest changed.", failed)
+			}
+			t.Logf("\t%s\tDigest is kept.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tchange of the read tree.", testID)
+		{
+			set("SOFTWARE\\Vendor\\Sub", "Name", "other")
+			digest, err := p.digest()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to digest registry: %v.", failed, err)
+			}
+			if digest == initial {
+				t.Fatalf("\t%s\tDigest is kept.", failed)
+			}
+			t.Logf("\t%s\tDigest changed.", success)
+		}
+	}
+}
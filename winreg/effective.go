@@ -0,0 +1,139 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// KeyRights is the effective access of a user or group to a key.
+type KeyRights struct {
+	Key    string // Full name of the key
+	Rights uint32 // Granted access mask, e.g. registry.READ
+	Err    error  // Set if the key's security descriptor could not be read
+}
+
+// CanModify reports whether the rights allow changing the key, its
+// values or its security.
+func (r KeyRights) CanModify() bool {
+	return r.Rights&(registry.SET_VALUE|registry.CREATE_SUB_KEY|windows.DELETE|windows.WRITE_DAC|windows.WRITE_OWNER) != 0
+}
+
+var keyMapping = genericMapping{
+	GenericRead:    registry.READ,
+	GenericWrite:   registry.WRITE,
+	GenericExecute: registry.EXECUTE,
+	GenericAll:     registry.ALL_ACCESS,
+}
+
+// EffectiveRights computes the access the user or group sid has to the
+// provider's key and its subkeys (up to MaxDepth), evaluating the keys'
+// security descriptors with the Authz API. Group memberships of a user
+// are taken into account. Keys the caller itself can't inspect are
+// reported with Err set. With Paths, the rights of all their keys are
+// reported.
+func (s *WinReg) EffectiveRights(sid *windows.SID) ([]KeyRights, error) {
+	rm, err := authzInitializeResourceManager()
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize Authz: %w", err)
+	}
+	defer authzFreeResourceManager(rm)
+	ctx, err := authzInitializeContextFromSid(sid, rm)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize Authz context of %s: %w", sid, err)
+	}
+	defer authzFreeContext(ctx)
+
+	return s.effectiveRights(func(sd *windows.SECURITY_DESCRIPTOR) (uint32, error) {
+		return authzAccessCheck(ctx, sd, windows.MAXIMUM_ALLOWED)
+	})
+}
+
+// EffectiveRightsOfToken is like EffectiveRights() but computes the access
+// of the token's user with AccessCheck.
+func (s *WinReg) EffectiveRightsOfToken(token windows.Token) ([]KeyRights, error) {
+	var dup windows.Token
+	if err := windows.DuplicateTokenEx(token, windows.TOKEN_QUERY, nil,
+		windows.SecurityIdentification, windows.TokenImpersonation, &dup); err != nil {
+		return nil, fmt.Errorf("unable to duplicate token: %w", err)
+	}
+	defer dup.Close()
+
+	return s.effectiveRights(func(sd *windows.SECURITY_DESCRIPTOR) (uint32, error) {
+		return accessCheck(sd, dup, windows.MAXIMUM_ALLOWED, &keyMapping)
+	})
+}
+
+func (s *WinReg) effectiveRights(check func(sd *windows.SECURITY_DESCRIPTOR) (uint32, error)) ([]KeyRights, error) {
+	if s.paths != nil {
+		var retval []KeyRights
+		for _, p := range s.paths {
+			rights, err := p.effectiveRights(check)
+			if err != nil {
+				return nil, err
+			}
+			retval = append(retval, rights...)
+		}
+		return retval, nil
+	}
+
+	root, err := s.connect()
+	if err != nil {
+		return nil, err
+	}
+	if root != s.key {
+		defer s.api.CloseKey(root)
+	}
+
+	var retval []KeyRights
+	if err := s.keyRights(root, s.path, 1, check, &retval); err != nil {
+		return nil, err
+	}
+
+	return retval, nil
+}
+
+func (s *WinReg) keyRights(root registry.Key, path string, level uint, check func(sd *windows.SECURITY_DESCRIPTOR) (uint32, error), out *[]KeyRights) error {
+	rights := KeyRights{Key: s.getKeyName(path)}
+
+	s.limiter.Wait()
+	k, err := s.api.OpenKey(root, path, s.getAccess(windows.READ_CONTROL|registry.ENUMERATE_SUB_KEYS))
+	if err != nil {
+		if level == 1 {
+			return fmt.Errorf("%s: %w", rights.Key, err)
+		}
+		rights.Err = err
+		*out = append(*out, rights)
+		return nil
+	}
+	defer s.api.CloseKey(k)
+
+	s.limiter.Wait()
+	sd, err := windows.GetSecurityInfo(windows.Handle(k), windows.SE_REGISTRY_KEY,
+		windows.OWNER_SECURITY_INFORMATION|windows.GROUP_SECURITY_INFORMATION|windows.DACL_SECURITY_INFORMATION)
+	if err == nil {
+		rights.Rights, err = check(sd)
+	}
+	rights.Err = err
+	*out = append(*out, rights)
+
+	if (s.maxDepth == 0) || (level < s.maxDepth) {
+		s.limiter.Wait()
+		subKeys, err := s.api.ReadSubKeyNames(k)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %w", rights.Key, err)
+		}
+		for _, subKey := range subKeys {
+			if err := s.keyRights(root, joinPath(path, subKey), level+1, check, out); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
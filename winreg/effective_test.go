@@ -0,0 +1,55 @@
+//go:build windows
+
+package winreg
+
+import (
+	"testing"
+
+	"golang.org/x/sys/windows"
+)
+
+func TestEffectiveRights(t *testing.T) {
+	t.Log("Testing effective rights computation.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		var token windows.Token
+		err := windows.OpenProcessToken(windows.CurrentProcess(), windows.TOKEN_QUERY|windows.TOKEN_DUPLICATE, &token)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to open process token: %v.", failed, err)
+		}
+		defer token.Close()
+		user, err := token.GetTokenUser()
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to get token user: %v.", failed, err)
+		}
+
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey})
+		checks := map[string]func() ([]KeyRights, error){
+			"user SID": func() ([]KeyRights, error) { return p.EffectiveRights(user.User.Sid) },
+			"token":    func() ([]KeyRights, error) { return p.EffectiveRightsOfToken(token) },
+		}
+
+		testID := 0
+		for name, check := range checks {
+			t.Logf("\tTest %d:\t%s.", testID, name)
+			{
+				rights, err := check()
+				if err != nil {
+					t.Fatalf("\t%s\tUnable to compute rights: %v.", failed, err)
+				}
+				if len(rights) != 4 {
+					t.Fatalf("\t%s\tExpected rights of 4 keys, got %d.", failed, len(rights))
+				}
+				for _, r := range rights {
+					if r.Err != nil || !r.CanModify() {
+						t.Fatalf("\t%s\tOwner should be able to modify %s: %08X, %v.", failed, r.Key, r.Rights, r.Err)
+					}
+				}
+				t.Logf("\t%s\tRights are computed.", success)
+			}
+			testID++
+		}
+	}
+}
@@ -0,0 +1,214 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// Registry keys of the environment variables Windows creates new
+// processes with.
+const (
+	SystemEnvironmentPath = `SYSTEM\CurrentControlSet\Control\Session Manager\Environment`
+	UserEnvironmentPath   = `Environment`
+)
+
+// Environment is a provider of the effective environment of newly started
+// processes, as Windows builds it from the system variables of
+// HKLM\SYSTEM\CurrentControlSet\Control\Session Manager\Environment and the
+// user variables of HKCU\Environment:
+//
+//   - user variables override system ones, except Path, LibPath and
+//     Os2LibPath, where the user value is appended to the system one;
+//   - REG_EXPAND_SZ variables are expanded after REG_SZ ones of the same
+//     key, referring to variables defined so far. Variables which are not
+//     in the registry, like SystemRoot or USERPROFILE, are taken from the
+//     process environment.
+//
+// The tree is flat, variable names are keys.
+type Environment struct {
+	Mode int // 32/64 bit registry branch, one of RegAuto/Reg32Bit/Reg64Bit constant
+}
+
+// envVar is an environment variable as stored in the registry.
+type envVar struct {
+	name   string
+	value  string
+	expand bool
+}
+
+// appendedVars are concatenated instead of being overridden by user
+// variables.
+var appendedVars = map[string]bool{"PATH": true, "LIBPATH": true, "OS2LIBPATH": true}
+
+func (e *Environment) providers() []*WinReg {
+	return []*WinReg{
+		Provider(Config{Key: LOCAL_MACHINE, Path: SystemEnvironmentPath, Mode: e.Mode, MaxDepth: 1}),
+		Provider(Config{Key: CURRENT_USER, Path: UserEnvironmentPath, Mode: e.Mode, MaxDepth: 1, WatchMissing: true}),
+	}
+}
+
+// ReadBytes is not supported by the environment provider.
+func (e *Environment) ReadBytes() ([]byte, error) {
+	return nil, errors.New("winreg environment does not support this method")
+}
+
+// Read returns the effective environment.
+func (e *Environment) Read() (map[string]interface{}, error) {
+	env := make(map[string]string)
+	names := make(map[string]string) // Upper-cased name to the first seen one
+
+	for i, p := range e.providers() {
+		vars, err := p.readEnvironment()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read environment, %w", err)
+		}
+
+		// Expandable variables may refer to plain ones of the same key
+		sort.SliceStable(vars, func(i, j int) bool { return !vars[i].expand && vars[j].expand })
+		for _, v := range vars {
+			value := v.value
+			if v.expand {
+				value = expandEnv(value, env)
+			}
+			upper := strings.ToUpper(v.name)
+			if prev, ok := env[upper]; ok && i > 0 && appendedVars[upper] && prev != "" {
+				value = strings.TrimSuffix(prev, ";") + ";" + value
+			}
+			if _, ok := names[upper]; !ok {
+				names[upper] = v.name
+			}
+			env[upper] = value
+		}
+	}
+
+	retval := make(map[string]interface{}, len(env))
+	for upper, value := range env {
+		retval[names[upper]] = value
+	}
+
+	return retval, nil
+}
+
+// Watch calls cb whenever system or user variables change. The user key
+// is watched for creation if it doesn't exist.
+func (e *Environment) Watch(cb func(event interface{}, err error)) error {
+	for _, p := range e.providers() {
+		if err := p.Watch(cb); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readEnvironment reads string values of the provider's key, a missing
+// key has no variables.
+func (s *WinReg) readEnvironment() ([]envVar, error) {
+	s.limiter.Wait()
+	k, err := s.api.OpenKey(s.key, s.path, s.getAccess(registry.QUERY_VALUE))
+	if errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, s.keyError("open", s.path, "", err)
+	}
+	defer s.api.CloseKey(k)
+
+	s.limiter.Wait()
+	names, err := s.api.ReadValueNames(k)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, s.keyError("enumerate", s.path, "", err)
+	}
+
+	retval := make([]envVar, 0, len(names))
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		s.limiter.Wait()
+		// Not expanded by GetStringValue
+		value, typ, err := s.api.GetStringValue(k, name)
+		if errors.Is(err, registry.ErrUnexpectedType) || errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+			continue
+		}
+		if err != nil {
+			return nil, s.keyError("read", s.path, name, err)
+		}
+		retval = append(retval, envVar{name: name, value: value, expand: typ == registry.EXPAND_SZ})
+	}
+
+	return retval, nil
+}
+
+// Expander expands %NAME% references of a REG_EXPAND_SZ value, e.g. in
+// the environment of another user.
+type Expander func(str string) (string, error)
+
+// ExpandFrom returns an Expander of the variables of env only, not of the
+// process environment. Names are case-insensitive, unknown references are
+// left as they are.
+func ExpandFrom(env map[string]string) Expander {
+	vars := make(map[string]string, len(env))
+	for name, value := range env {
+		vars[strings.ToUpper(name)] = value
+	}
+
+	return func(str string) (string, error) {
+		return expandVars(str, func(name string) (string, bool) {
+			value, ok := vars[strings.ToUpper(name)]
+			return value, ok
+		}), nil
+	}
+}
+
+// expandEnv replaces %NAME% references with variables of env, keyed by
+// upper-cased names, or the process environment. Unknown references are
+// left as they are, as ExpandEnvironmentStrings does.
+func expandEnv(str string, env map[string]string) string {
+	return expandVars(str, func(name string) (string, bool) {
+		value, ok := env[strings.ToUpper(name)]
+		if !ok && name != "" {
+			value, ok = os.LookupEnv(name)
+		}
+		return value, ok
+	})
+}
+
+// expandVars replaces %NAME% references with variables found by lookup.
+func expandVars(str string, lookup func(name string) (string, bool)) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(str, '%')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(str[start+1:], '%')
+		if end < 0 {
+			break
+		}
+		end += start + 1
+
+		value, ok := lookup(str[start+1 : end])
+		if !ok {
+			// The closing % may open the next reference
+			b.WriteString(str[:end])
+			str = str[end:]
+			continue
+		}
+		b.WriteString(str[:start])
+		b.WriteString(value)
+		str = str[end+1:]
+	}
+	b.WriteString(str)
+
+	return b.String()
+}
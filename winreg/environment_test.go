@@ -0,0 +1,87 @@
+//go:build windows
+
+package winreg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Log("Testing expansion of environment variables.")
+	{
+		env := map[string]string{"APP": "C:\\App", "EMPTY": ""}
+		for testID, test := range []struct {
+			str, expect string
+		}{
+			{"%app%\\bin", "C:\\App\\bin"},
+			{"%EMPTY%x", "x"},
+			{"%MISSING_KOANF_WINREG%;%APP%", "%MISSING_KOANF_WINREG%;C:\\App"},
+			{"100% %APP%", "100% C:\\App"},
+			{"50%", "50%"},
+		} {
+			t.Logf("\tTest %d:\t%s.", testID, test.str)
+			{
+				if val := expandEnv(test.str, env); val != test.expect {
+					t.Fatalf("\t%s\tInvalid expansion, got %s, expect %s.", failed, val, test.expect)
+				}
+				t.Logf("\t%s\tString is expanded.", success)
+			}
+		}
+	}
+}
+
+func TestExpander(t *testing.T) {
+	t.Log("Testing custom expansion of strings.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		testID := 0
+		t.Logf("\tTest %d:\tSubKeyA.Expand.", testID)
+		{
+			p := Provider(Config{
+				Key:      CURRENT_USER,
+				Path:     "SOFTWARE\\" + testKey + "\\SubKeyA",
+				Expander: ExpandFrom(map[string]string{"path": "C:\\Other"}),
+			})
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if tree["Expand"] != "Some C:\\Other" {
+				t.Fatalf("\t%s\tSubKeyA.Expand is invalid, got \"%v\".", failed, tree["Expand"])
+			}
+			t.Logf("\t%s\tSubKeyA.Expand is expanded by the expander.", success)
+		}
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	t.Log("Testing effective environment.")
+	{
+		env, err := (&Environment{}).Read()
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to read environment: %v.", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tsystem variable.", testID)
+		{
+			if val := env["OS"]; val != "Windows_NT" {
+				t.Fatalf("\t%s\tOS is invalid, got %v.", failed, val)
+			}
+			t.Logf("\t%s\tSystem variable is read.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tPath variable.", testID)
+		{
+			path, _ := env["Path"].(string)
+			if path == "" || strings.Contains(strings.ToUpper(path), "%SYSTEMROOT%") {
+				t.Fatalf("\t%s\tPath is not expanded: %s.", failed, path)
+			}
+			t.Logf("\t%s\tPath is expanded.", success)
+		}
+	}
+}
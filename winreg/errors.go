@@ -0,0 +1,122 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// Error is a failure of an operation on a registry key or on one of its
+// values, like the os.PathError of a file. Its message doesn't depend on
+// the language of the OS: Win32 errors are reported by their symbolic
+// names, like ERROR_ACCESS_DENIED, the localized text is available from
+// Detail().
+type Error struct {
+	Op    string        // Failed operation: "open", "create", "enumerate", "read", "decode", "write", "delete" or "flush"
+	Hive  string        // Short name of the predefined key, e.g. "HKCU", prefixed by "\\host" for a remote registry
+	Path  string        // Key path in the hive
+	Value string        // Value name, empty if the operation concerns the key
+	Code  syscall.Errno // Win32 error code, zero if the failure isn't a Win32 error
+	Err   error         // Underlying error
+}
+
+// win32ErrorNames are the symbolic names of the Win32 errors of registry
+// operations.
+var win32ErrorNames = map[syscall.Errno]string{
+	windows.ERROR_FILE_NOT_FOUND:         "ERROR_FILE_NOT_FOUND",
+	windows.ERROR_PATH_NOT_FOUND:         "ERROR_PATH_NOT_FOUND",
+	windows.ERROR_ACCESS_DENIED:          "ERROR_ACCESS_DENIED",
+	windows.ERROR_INVALID_HANDLE:         "ERROR_INVALID_HANDLE",
+	windows.ERROR_NOT_ENOUGH_MEMORY:      "ERROR_NOT_ENOUGH_MEMORY",
+	windows.ERROR_NOT_SUPPORTED:          "ERROR_NOT_SUPPORTED",
+	windows.ERROR_BAD_NETPATH:            "ERROR_BAD_NETPATH",
+	windows.ERROR_INVALID_PARAMETER:      "ERROR_INVALID_PARAMETER",
+	windows.ERROR_MORE_DATA:              "ERROR_MORE_DATA",
+	windows.ERROR_NO_MORE_ITEMS:          "ERROR_NO_MORE_ITEMS",
+	windows.ERROR_BADDB:                  "ERROR_BADDB",
+	windows.ERROR_BADKEY:                 "ERROR_BADKEY",
+	windows.ERROR_CANTOPEN:               "ERROR_CANTOPEN",
+	windows.ERROR_CANTREAD:               "ERROR_CANTREAD",
+	windows.ERROR_CANTWRITE:              "ERROR_CANTWRITE",
+	windows.ERROR_REGISTRY_CORRUPT:       "ERROR_REGISTRY_CORRUPT",
+	windows.ERROR_KEY_DELETED:            "ERROR_KEY_DELETED",
+	windows.ERROR_CHILD_MUST_BE_VOLATILE: "ERROR_CHILD_MUST_BE_VOLATILE",
+	windows.ERROR_NO_SYSTEM_RESOURCES:    "ERROR_NO_SYSTEM_RESOURCES",
+	windows.ERROR_NOT_FOUND:              "ERROR_NOT_FOUND",
+	windows.ERROR_PRIVILEGE_NOT_HELD:     "ERROR_PRIVILEGE_NOT_HELD",
+}
+
+// win32ErrorName returns the symbolic name of code, or its number for
+// errors without a known name.
+func win32ErrorName(code syscall.Errno) string {
+	if name, ok := win32ErrorNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("Win32 error %d", uint32(code))
+}
+
+func (e *Error) Error() string {
+	name := e.Path
+	if e.Hive != "" {
+		name = e.Hive + "\\" + e.Path
+	}
+
+	var reason string
+	code, isCode := e.Err.(syscall.Errno)
+	switch {
+	case isCode:
+		reason = win32ErrorName(code)
+	case e.Err != nil:
+		reason = e.Err.Error()
+	case e.Code != 0:
+		reason = win32ErrorName(e.Code)
+	default:
+		reason = "unknown error"
+	}
+	if e.Value != "" {
+		name = fmt.Sprintf("%s: %s", name, e.Value)
+		reason = ", " + reason
+	} else {
+		reason = ": " + reason
+	}
+	if e.Op != "" {
+		name = e.Op + " " + name
+	}
+
+	return name + reason
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Detail returns the message of the Win32 error in the language of the
+// OS, empty if the failure isn't a Win32 error.
+func (e *Error) Detail() string {
+	if e.Code == 0 {
+		return ""
+	}
+	return e.Code.Error()
+}
+
+// keyError returns the *Error of the operation op on the value of the key
+// path, value is empty for operations on the key itself. Errors which
+// already are an *Error, e.g. of a subkey, are returned as they are.
+func (s *WinReg) keyError(op, path, value string, err error) error {
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+
+	e = &Error{Op: op, Hive: shortHiveName(s.key), Path: path, Value: value, Err: err}
+	if s.host != "" {
+		e.Hive = fmt.Sprintf("\\\\%s\\%s", s.host, e.Hive)
+	}
+	errors.As(err, &e.Code)
+
+	return e
+}
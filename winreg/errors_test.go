@@ -0,0 +1,79 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+	t.Log("Testing locale-independent errors.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tmissing key.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\Missing", Memory: NewMemory()})
+			_, err := p.Read()
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("\t%s\tRead() should fail with *Error, got: %v.", failed, err)
+			}
+			if e.Op != "open" || e.Hive != "HKCU" || e.Path != "SOFTWARE\\Missing" || e.Value != "" || e.Code != 2 || e.Detail() == "" {
+				t.Fatalf("\t%s\tError is invalid, got %+v.", failed, e)
+			}
+			if e.Error() != "open HKCU\\SOFTWARE\\Missing: ERROR_FILE_NOT_FOUND" {
+				t.Fatalf("\t%s\tInvalid error message \"%s\".", failed, e)
+			}
+			t.Logf("\t%s\tError is structured.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tfailed delete.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\Denied"})
+			p.api = deniedAPI{NewMemory()}
+			err := p.Delete("Name")
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("\t%s\tDelete() should fail with *Error, got: %v.", failed, err)
+			}
+			if e.Op != "open" || e.Path != "SOFTWARE\\Denied" || e.Code != 5 {
+				t.Fatalf("\t%s\tError is invalid, got %+v.", failed, e)
+			}
+			if e.Error() != "open HKCU\\SOFTWARE\\Denied: ERROR_ACCESS_DENIED" {
+				t.Fatalf("\t%s\tInvalid error message \"%s\".", failed, e)
+			}
+			t.Logf("\t%s\tError names the operation.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tmessages.", testID)
+		{
+			e := &Error{Op: "write", Hive: "HKLM", Path: "SOFTWARE", Value: "Name", Code: 12345, Err: syscall.Errno(12345)}
+			if e.Error() != "write HKLM\\SOFTWARE: Name, Win32 error 12345" {
+				t.Fatalf("\t%s\tInvalid error message \"%s\".", failed, e)
+			}
+			e = &Error{Path: "SOFTWARE", Err: ErrIncludeLoop}
+			if e.Error() != "SOFTWARE: include loop" || e.Detail() != "" {
+				t.Fatalf("\t%s\tInvalid error message \"%s\".", failed, e)
+			}
+			t.Logf("\t%s\tMessages are locale-independent.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tmissing underlying error.", testID)
+		{
+			e := &Error{Op: "read", Hive: "HKCU", Path: "SOFTWARE", Code: 5}
+			if e.Error() != "read HKCU\\SOFTWARE: ERROR_ACCESS_DENIED" {
+				t.Fatalf("\t%s\tInvalid error message \"%s\".", failed, e)
+			}
+			e = &Error{Op: "read", Path: "SOFTWARE"}
+			if e.Error() != "read SOFTWARE: unknown error" {
+				t.Fatalf("\t%s\tInvalid error message \"%s\".", failed, e)
+			}
+			t.Logf("\t%s\tNil Err doesn't panic.", success)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+//go:build windows
+
+package winreg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"golang.org/x/sys/windows"
+)
+
+// ChangeKind tells how a key or value has changed.
+type ChangeKind int
+
+const (
+	Created ChangeKind = iota
+	Modified
+	Deleted
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Created:
+		return "created"
+	case Modified:
+		return "modified"
+	case Deleted:
+		return "deleted"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+}
+
+// Event is passed to the watch callback for each changed key or value
+// when EventDetails is set.
+type Event struct {
+	Key   string     // Key path relative to the provider's path, empty for the provider's key
+	Value string     // Value name, the name of DefaultValue for the default value, empty for a change of the key
+	Kind  ChangeKind // Values of created and deleted keys are reported too
+}
+
+// notifyChanges passes events of the changes found by t to cb, or a plain
+// notification if there is no tracker or the subtree can't be read.
+func notifyChanges(t *changeTracker, cb func(event interface{}, err error)) {
+	if t != nil {
+		if events, err := t.changes(); err == nil {
+			for _, ev := range events {
+				cb(ev, nil)
+			}
+			return
+		}
+	}
+	cb(nil, nil)
+}
+
+// changeTracker keeps the stored values of the watched subtree to find
+// out what has changed since the previous notification.
+type changeTracker struct {
+	s    *WinReg
+	keys map[string]*syncKey
+}
+
+// newChangeTracker reads the current state of the provider's subtree.
+func (s *WinReg) newChangeTracker() (*changeTracker, error) {
+	t := &changeTracker{s: s}
+	keys, err := t.read()
+	if err != nil {
+		return nil, err
+	}
+	t.keys = keys
+
+	return t, nil
+}
+
+func (t *changeTracker) read() (map[string]*syncKey, error) {
+	root, err := t.s.connect()
+	if err != nil {
+		return nil, err
+	}
+	if root != t.s.key {
+		defer t.s.api.CloseKey(root)
+	}
+
+	keys := make(map[string]*syncKey)
+	if err = t.s.currentKeys(root, "", 1, keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// changes re-reads the subtree and returns events of the differences
+// from the previous state, ordered by key path. If the subtree can't be
+// read, the previous state is kept.
+func (t *changeTracker) changes() ([]*Event, error) {
+	keys, err := t.read()
+	if err != nil {
+		return nil, err
+	}
+
+	var retval []*Event
+	names := sortedKeys(t.keys)
+	for name := range keys {
+		if _, ok := t.keys[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prev, cur := t.keys[name], keys[name]
+		switch {
+		case prev == nil:
+			retval = append(retval, &Event{Key: cur.path, Kind: Created})
+			retval = append(retval, t.valueEvents(cur, Created)...)
+		case cur == nil:
+			retval = append(retval, &Event{Key: prev.path, Kind: Deleted})
+			retval = append(retval, t.valueEvents(prev, Deleted)...)
+		default:
+			for _, value := range sortedValues(cur.values) {
+				v := cur.values[value]
+				if p, ok := prev.values[value]; !ok {
+					retval = append(retval, t.valueEvent(v, Created))
+				} else if p.Type != v.Type || !bytes.Equal(p.Data, v.Data) {
+					retval = append(retval, t.valueEvent(v, Modified))
+				}
+			}
+			for _, value := range sortedValues(prev.values) {
+				if _, ok := cur.values[value]; !ok {
+					retval = append(retval, t.valueEvent(prev.values[value], Deleted))
+				}
+			}
+		}
+	}
+	t.keys = keys
+
+	return retval, nil
+}
+
+func (t *changeTracker) valueEvents(k *syncKey, kind ChangeKind) []*Event {
+	retval := make([]*Event, 0, len(k.values))
+	for _, value := range sortedValues(k.values) {
+		retval = append(retval, t.valueEvent(k.values[value], kind))
+	}
+
+	return retval
+}
+
+func (t *changeTracker) valueEvent(v rawValue, kind ChangeKind) *Event {
+	name := v.Name
+	if name == "" {
+		name = t.s.defaultValue
+	}
+
+	return &Event{Key: v.Path, Value: name, Kind: kind}
+}
+
+// Diff lists values changed between two reads of the tree, keyed by
+// value paths like "SubKey\Value". Values are converted as by Read().
+type Diff struct {
+	Added   map[string]interface{} // New values
+	Removed map[string]interface{} // Previous data of deleted values
+	Changed map[string]ValueChange
+}
+
+// ValueChange is the previous and the current data of a changed value.
+type ValueChange struct {
+	Old interface{}
+	New interface{}
+}
+
+// WatchDiff watches the provider's key as Watch() does, re-reads the tree
+// on each notification and passes the differences from the previous read
+// to cb. Notifications which didn't change the read tree are dropped. With
+// WatchMissing, a missing key counts as an empty tree.
+func (s *WinReg) WatchDiff(cb func(diff *Diff, err error)) error {
+	// A copy, so the provider's own Read() state isn't shared
+	p := s.readCopy()
+	prev, err := p.Read()
+	if err != nil {
+		if !s.watchMissing {
+			return fmt.Errorf("watch failed: %w", err)
+		}
+		prev = map[string]interface{}{}
+	}
+
+	return s.Watch(s.diffUpdates(p, prev, cb))
+}
+
+// diffUpdates returns the Watch() callback of WatchDiff, which reads the
+// tree by p and delivers its differences from prev to cb. A failed read
+// is delivered as an error and prev is kept, so the next diff covers all
+// changes since the last one delivered. With WatchMissing, a deleted key
+// is diffed as an empty tree. With Paths the callback is called by the
+// watchers of all paths, so updates are serialized.
+func (s *WinReg) diffUpdates(p *WinReg, prev map[string]interface{}, cb func(diff *Diff, err error)) func(event interface{}, err error) {
+	var mu sync.Mutex
+	return func(event interface{}, err error) {
+		if err != nil {
+			cb(nil, err)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		tree, err := p.Read()
+		if err != nil {
+			if !s.watchMissing || !errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+				cb(nil, err)
+				return
+			}
+			tree = map[string]interface{}{}
+		}
+		diff := diffTrees(prev, tree)
+		prev = tree
+		if len(diff.Added) > 0 || len(diff.Removed) > 0 || len(diff.Changed) > 0 {
+			cb(diff, nil)
+		}
+	}
+}
+
+// diffTrees compares two trees returned by Read().
+func diffTrees(prev, cur map[string]interface{}) *Diff {
+	before := make(map[string]interface{})
+	flattenTree("", prev, before)
+	after := make(map[string]interface{})
+	flattenTree("", cur, after)
+
+	retval := &Diff{
+		Added:   make(map[string]interface{}),
+		Removed: make(map[string]interface{}),
+		Changed: make(map[string]ValueChange),
+	}
+	for path, value := range after {
+		old, ok := before[path]
+		switch {
+		case !ok:
+			retval.Added[path] = value
+		case !reflect.DeepEqual(old, value):
+			retval.Changed[path] = ValueChange{Old: old, New: value}
+		}
+	}
+	for path, value := range before {
+		if _, ok := after[path]; !ok {
+			retval.Removed[path] = value
+		}
+	}
+
+	return retval
+}
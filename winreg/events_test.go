@@ -0,0 +1,206 @@
+//go:build windows
+
+package winreg
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestEventDetails(t *testing.T) {
+	t.Log("Testing events describing changes.")
+	{
+		const eventTimeout = 5
+		createTestData(t)
+		defer deleteTestData(t)
+
+		events := make(chan interface{}, 10)
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, EventDetails: true})
+		err := p.Watch(func(event interface{}, err error) {
+			if err != nil {
+				events <- err
+				return
+			}
+			events <- event
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tWatch() method failed: %v", failed, err)
+		}
+		defer p.Unwatch()
+
+		r, err := registry.OpenKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey, registry.ALL_ACCESS)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to open registry key: %v", failed, err)
+		}
+		defer r.Close()
+
+		receive := func() Event {
+			select {
+			case event := <-events:
+				ev, ok := event.(*Event)
+				if !ok {
+					t.Fatalf("\t%s\tExpected *Event, got %v.", failed, event)
+				}
+				return *ev
+			case <-time.After(eventTimeout * time.Second):
+				t.Fatalf("\t%s\tTimeout exceeded while waiting for event.", failed)
+			}
+			return Event{}
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tmodified value.", testID)
+		{
+			if err := r.SetDWordValue("on", 2); err != nil {
+				t.Fatalf("\t%s\tUnable to change value \"on\": %v", failed, err)
+			}
+			if ev := receive(); ev != (Event{Value: "on", Kind: Modified}) {
+				t.Fatalf("\t%s\tInvalid event %+v.", failed, ev)
+			}
+			t.Logf("\t%s\tModification is described.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tdeleted key.", testID)
+		{
+			if err := registry.DeleteKey(r, "SubKeyB"); err != nil {
+				t.Fatalf("\t%s\tUnable to delete key: %v", failed, err)
+			}
+			if ev := receive(); ev != (Event{Key: "SubKeyB", Kind: Deleted}) {
+				t.Fatalf("\t%s\tInvalid event %+v.", failed, ev)
+			}
+			t.Logf("\t%s\tDeletion is described.", success)
+		}
+	}
+}
+
+func TestWatchDiff(t *testing.T) {
+	t.Log("Testing watch of differences.")
+	{
+		const eventTimeout = 5
+		createTestData(t)
+		defer deleteTestData(t)
+
+		diffs := make(chan *Diff, 10)
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey})
+		err := p.WatchDiff(func(diff *Diff, err error) {
+			if err != nil {
+				t.Errorf("\t%s\tWatch failed: %v", failed, err)
+				return
+			}
+			diffs <- diff
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tWatchDiff() method failed: %v", failed, err)
+		}
+		defer p.Unwatch()
+
+		testID := 0
+		t.Logf("\tTest %d:\tchanged value.", testID)
+		{
+			r, err := registry.OpenKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey+"\\SubKeyA", registry.ALL_ACCESS)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to open registry key: %v", failed, err)
+			}
+			defer r.Close()
+			if err := r.SetDWordValue("IntVal", 5); err != nil {
+				t.Fatalf("\t%s\tUnable to change value: %v", failed, err)
+			}
+
+			var diff *Diff
+			select {
+			case diff = <-diffs:
+			case <-time.After(eventTimeout * time.Second):
+				t.Fatalf("\t%s\tTimeout exceeded while waiting for diff.", failed)
+			}
+			expected := map[string]ValueChange{"SubKeyA\\IntVal": {Old: uint64(4000000000), New: uint64(5)}}
+			if !reflect.DeepEqual(diff.Changed, expected) || len(diff.Added) != 0 || len(diff.Removed) != 0 {
+				t.Fatalf("\t%s\tInvalid diff %+v.", failed, diff)
+			}
+			t.Logf("\t%s\tDiff is delivered.", success)
+		}
+	}
+}
+
+func TestWatchDiffUpdates(t *testing.T) {
+	t.Log("Testing diffs of failed reads.")
+	{
+		m := NewMemory()
+		if err := m.Load(CURRENT_USER, "SOFTWARE\\Vendor", map[string]interface{}{"Name": "vendor"}); err != nil {
+			t.Fatalf("\t%s\tUnable to load memory registry: %v", failed, err)
+		}
+
+		var (
+			diffs []*Diff
+			errs  []error
+		)
+		deliver := func(diff *Diff, err error) {
+			diffs = append(diffs, diff)
+			errs = append(errs, err)
+		}
+		prev := map[string]interface{}{"Name": "vendor"}
+
+		testID := 0
+		t.Logf("\tTest %d:\tread error.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\Denied"})
+			p.api = deniedAPI{m}
+			p.diffUpdates(p, prev, deliver)(nil, nil)
+			if len(errs) != 1 || errs[0] == nil || diffs[0] != nil {
+				t.Fatalf("\t%s\tRead error is not delivered, got %v.", failed, errs)
+			}
+			t.Logf("\t%s\tRead error is delivered.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tdeleted key.", testID)
+		{
+			diffs, errs = nil, nil
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\Vendor", Memory: m, WatchMissing: true})
+			update := p.diffUpdates(p, prev, deliver)
+			if err := m.DeleteKey(CURRENT_USER, "SOFTWARE\\Vendor", 0); err != nil {
+				t.Fatalf("\t%s\tUnable to delete memory key: %v", failed, err)
+			}
+			update(nil, nil)
+			if err := m.Load(CURRENT_USER, "SOFTWARE\\Vendor", map[string]interface{}{"Name": "vendor"}); err != nil {
+				t.Fatalf("\t%s\tUnable to load memory registry: %v", failed, err)
+			}
+			update(nil, nil)
+			if len(diffs) != 2 || errs[0] != nil || errs[1] != nil {
+				t.Fatalf("\t%s\tDiffs are not delivered, got %v.", failed, errs)
+			}
+			if !reflect.DeepEqual(diffs[0].Removed, prev) || !reflect.DeepEqual(diffs[1].Added, prev) {
+				t.Fatalf("\t%s\tInvalid diffs %+v, %+v.", failed, diffs[0], diffs[1])
+			}
+			t.Logf("\t%s\tDeletion and recreation are delivered.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tconcurrent updates of paths.", testID)
+		{
+			diffs, errs = nil, nil
+			p := Provider(Config{Key: CURRENT_USER, Paths: []string{"SOFTWARE\\Vendor", "SOFTWARE\\Other"}, Memory: m})
+			update := p.diffUpdates(p.readCopy(), prev, deliver)
+			if err := m.Load(CURRENT_USER, "SOFTWARE\\Other", map[string]interface{}{"Size": "large"}); err != nil {
+				t.Fatalf("\t%s\tUnable to load memory registry: %v", failed, err)
+			}
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					update(nil, nil)
+				}()
+			}
+			wg.Wait()
+			if len(diffs) != 1 || errs[0] != nil || diffs[0].Added["Size"] != "large" {
+				t.Fatalf("\t%s\tExpect one diff, got %v, %v.", failed, diffs, errs)
+			}
+			t.Logf("\t%s\tThe change is delivered once.", success)
+		}
+	}
+}
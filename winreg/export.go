@@ -0,0 +1,151 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pda0/koanf-winreg/v2/regfile"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// Export writes the provider's key and its subkeys to w in Registry
+// Editor 5.00 format, selected by the key and value filters and MaxDepth
+// as by Read(). Data is exported as stored, environment variables are not
+// expanded and values are not decoded.
+func (s *WinReg) Export(w io.Writer) error {
+	if err := s.singleKey(); err != nil {
+		return err
+	}
+	hive := hiveName(s.key)
+	if hive == "" {
+		return fmt.Errorf("key %s can't be exported", s.getKeyName(s.path))
+	}
+
+	root, err := s.connect()
+	if err != nil {
+		return err
+	}
+	if root != s.key {
+		defer s.api.CloseKey(root)
+	}
+
+	rw := regfile.NewWriter(w)
+	if err = s.exportKey(rw, root, hive, s.path, 1); err != nil {
+		return fmt.Errorf("unable to export registry, %w", err)
+	}
+	if err = rw.Flush(); err != nil {
+		return fmt.Errorf("unable to write export: %w", err)
+	}
+
+	return nil
+}
+
+// ExportCompressed writes the export as Export() does, compressed by
+// method, one of the Compress constants. Import() detects the compression.
+func (s *WinReg) ExportCompressed(w io.Writer, method int) error {
+	if method == CompressNone {
+		return s.Export(w)
+	}
+	zw, err := compressWriter(method, w)
+	if err != nil {
+		return fmt.Errorf("unable to export registry, %w", err)
+	}
+	if err = s.Export(zw); err != nil {
+		zw.Close()
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return fmt.Errorf("unable to write export: %w", err)
+	}
+
+	return nil
+}
+
+func (s *WinReg) exportKey(rw *regfile.Writer, root registry.Key, hive, path string, level uint) error {
+	s.limiter.Wait()
+	k, err := s.api.OpenKey(root, path, s.getAccess(registry.READ))
+	if err != nil {
+		return s.keyError("open", path, "", err)
+	}
+	defer s.api.CloseKey(k)
+
+	rw.Key(joinPath(hive, s.viewPath(path)))
+	s.limiter.Wait()
+	values, err := s.api.ReadValueNames(k)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return s.keyError("enumerate", path, "", err)
+	}
+	for _, value := range values {
+		data, typ, err := s.getValueBytes(k, value)
+		if errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+			// The value was deleted after enumeration
+			continue
+		}
+		if err != nil {
+			return s.keyError("read", path, value, err)
+		}
+		if _, ok, err := s.valueSelected(value, typ); err != nil {
+			return s.keyError("read", path, value, err)
+		} else if !ok {
+			continue
+		}
+		rw.Value(regfile.Value{Name: value, Type: typ, Data: data})
+	}
+
+	if !s.readsSubKeys(path, level) {
+		return nil
+	}
+	s.limiter.Wait()
+	subKeys, err := s.api.ReadSubKeyNames(k)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return s.keyError("enumerate", path, "", err)
+	}
+	for _, subKey := range subKeys {
+		if !s.keySelected(joinPath(path, subKey)) {
+			continue
+		}
+		if err = s.exportKey(rw, root, hive, joinPath(path, subKey), level+1); err != nil && !errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// viewPath returns the real location of path read in the 32-bit view,
+// so the export is imported back to the same place by 64-bit tools.
+func (s *WinReg) viewPath(path string) string {
+	if s.key != LOCAL_MACHINE || s.access&registry.WOW64_32KEY == 0 || !is64BitSystem() {
+		return path
+	}
+	top, rest := splitPath(path)
+	next, _ := splitPath(rest)
+	if !strings.EqualFold(top, "SOFTWARE") || strings.EqualFold(next, "WOW6432Node") {
+		return path
+	}
+
+	return joinPath(joinPath(top, "WOW6432Node"), rest)
+}
+
+// hiveName returns the full name of a predefined key as used in .reg files.
+func hiveName(key registry.Key) string {
+	switch key {
+	case CLASSES_ROOT:
+		return "HKEY_CLASSES_ROOT"
+	case CURRENT_USER:
+		return "HKEY_CURRENT_USER"
+	case LOCAL_MACHINE:
+		return "HKEY_LOCAL_MACHINE"
+	case USERS:
+		return "HKEY_USERS"
+	case CURRENT_CONFIG:
+		return "HKEY_CURRENT_CONFIG"
+	default:
+		return ""
+	}
+}
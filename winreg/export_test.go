@@ -0,0 +1,123 @@
+//go:build windows
+
+package winreg
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/pda0/koanf-winreg/v2/regfile"
+	"unicode/utf16"
+)
+
+func TestExport(t *testing.T) {
+	t.Log("Testing export to .reg files.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		testID := 0
+		t.Logf("\tTest %d:\twhole tree.", testID)
+		{
+			var buf bytes.Buffer
+			if err := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey}).Export(&buf); err != nil {
+				t.Fatalf("\t%s\tUnable to export registry: %v.", failed, err)
+			}
+			data := buf.Bytes()
+			if len(data) < 2 || data[0] != 0xFF || data[1] != 0xFE {
+				t.Fatalf("\t%s\tExport doesn't start with a byte order mark.", failed)
+			}
+			text := string(utf16.Decode(bytesToUTF16(data[2:])))
+			for _, line := range []string{
+				regfile.Header + "\r\n\r\n",
+				"[HKEY_CURRENT_USER\\SOFTWARE\\" + testKey + "]\r\n",
+				"\"on\"=dword:00000001\r\n",
+				"[HKEY_CURRENT_USER\\SOFTWARE\\" + testKey + "\\SubKeyA]\r\n",
+				"\"Binary\"=hex:01,02,03\r\n",
+				"\"Int64\"=hex(b):00,f2,05,2a,01,00,00,00\r\n",
+				"\"StrValue\"=\"The quick brown fox jumps over the lazy dog\"\r\n",
+				"[HKEY_CURRENT_USER\\SOFTWARE\\" + testKey + "\\SubKeyA\\Sub Key]\r\n",
+			} {
+				if !strings.Contains(text, line) {
+					t.Fatalf("\t%s\tExport doesn't contain %q:\n%s", failed, line, text)
+				}
+			}
+			if !strings.Contains(text, "\"StrList\"=hex(7):42,00,6c,00,61,00,63,00,6b,00,20,00,63,00,61,00,74,00,00,00,\\\r\n  73,00,") {
+				t.Fatalf("\t%s\tLong hex data is not wrapped:\n%s", failed, text)
+			}
+			t.Logf("\t%s\tTree is exported.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tlimited depth.", testID)
+		{
+			var buf bytes.Buffer
+			if err := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, MaxDepth: 1}).Export(&buf); err != nil {
+				t.Fatalf("\t%s\tUnable to export registry: %v.", failed, err)
+			}
+			text := string(utf16.Decode(bytesToUTF16(buf.Bytes()[2:])))
+			if strings.Contains(text, "SubKeyA") {
+				t.Fatalf("\t%s\tSubkeys are exported:\n%s", failed, text)
+			}
+			t.Logf("\t%s\tOnly the key is exported.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tcompressed export.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey})
+			var plain bytes.Buffer
+			if err := p.Export(&plain); err != nil {
+				t.Fatalf("\t%s\tUnable to export registry: %v.", failed, err)
+			}
+			for _, method := range []int{CompressGzip, CompressZstd} {
+				var buf bytes.Buffer
+				if err := p.ExportCompressed(&buf, method); err != nil {
+					t.Fatalf("\t%s\tUnable to export registry: %v.", failed, err)
+				}
+				data, err := decompressBytes(buf.Bytes())
+				if err != nil {
+					t.Fatalf("\t%s\tUnable to decompress export: %v.", failed, err)
+				}
+				if !bytes.Equal(data, plain.Bytes()) {
+					t.Fatalf("\t%s\tDecompressed export differs from the plain one.", failed)
+				}
+			}
+			t.Logf("\t%s\tExport is compressed.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tfilters.", testID)
+		{
+			m := NewMemory()
+			err := m.Load(CURRENT_USER, "SOFTWARE\\Vendor", map[string]interface{}{
+				"Name":     "vendor",
+				"MRU":      "file",
+				"Excluded": map[string]interface{}{"Name": "excluded"},
+				"Sub":      map[string]interface{}{"Deep": map[string]interface{}{"Name": "deep"}},
+			})
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to load memory registry: %v", failed, err)
+			}
+			p := Provider(Config{
+				Key:           CURRENT_USER,
+				Path:          "SOFTWARE\\Vendor",
+				Memory:        m,
+				ExcludeValues: []string{"MRU"},
+				ExcludeKeys:   []*regexp.Regexp{regexp.MustCompile(`^Excluded$`)},
+				MaxDepths:     map[string]uint{"Sub": 1},
+			})
+			var buf bytes.Buffer
+			if err := p.Export(&buf); err != nil {
+				t.Fatalf("\t%s\tUnable to export registry: %v.", failed, err)
+			}
+			text := string(utf16.Decode(bytesToUTF16(buf.Bytes()[2:])))
+			if !strings.Contains(text, "\"Name\"=\"vendor\"") || strings.Contains(text, "MRU") || strings.Contains(text, "Excluded") || strings.Contains(text, "Deep") {
+				t.Fatalf("\t%s\tFilters are not applied:\n%s", failed, text)
+			}
+			t.Logf("\t%s\tOnly what Read() returns is exported.", success)
+		}
+	}
+}
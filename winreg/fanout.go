@@ -0,0 +1,88 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"sync"
+)
+
+// WatchEvent is a change notification delivered to a channel subscriber,
+// with the same meaning as the arguments of the Watch() callback.
+type WatchEvent struct {
+	Event interface{}
+	Err   error
+}
+
+// subscribers dispatches notifications of a single Watch() registration
+// to any number of subscribers.
+type subscribers struct {
+	mu      sync.Mutex
+	started bool
+	next    int
+	subs    map[int]func(event interface{}, err error)
+}
+
+// Subscribe attaches cb to the provider's change notifications. All
+// subscribers share a single underlying watch, which is started by the
+// first subscription with the same rules as Watch(). The returned function
+// detaches cb, the underlying watch keeps running. Unwatch() stops the
+// watch and detaches all subscribers.
+func (s *WinReg) Subscribe(cb func(event interface{}, err error)) (func(), error) {
+	subs := s.subscribers
+	subs.mu.Lock()
+	defer subs.mu.Unlock()
+
+	if !subs.started {
+		if err := s.Watch(subs.dispatch); err != nil {
+			return nil, err
+		}
+		subs.started = true
+		subs.subs = make(map[int]func(event interface{}, err error))
+	}
+
+	id := subs.next
+	subs.next++
+	subs.subs[id] = cb
+
+	return func() {
+		subs.mu.Lock()
+		defer subs.mu.Unlock()
+		delete(subs.subs, id)
+	}, nil
+}
+
+// SubscribeChan is like Subscribe() but delivers notifications to a
+// channel with the given buffer size, which must be at least 1. A
+// notification is dropped if the channel is full, since the subscriber has
+// one pending already. The channel is not closed by the returned function.
+func (s *WinReg) SubscribeChan(size int) (<-chan WatchEvent, func(), error) {
+	if size < 1 {
+		return nil, nil, errors.New("subscription channel needs a buffer")
+	}
+	ch := make(chan WatchEvent, size)
+	cancel, err := s.Subscribe(func(event interface{}, err error) {
+		select {
+		case ch <- WatchEvent{Event: event, Err: err}:
+		default:
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ch, cancel, nil
+}
+
+func (subs *subscribers) dispatch(event interface{}, err error) {
+	subs.mu.Lock()
+	cbs := make([]func(event interface{}, err error), 0, len(subs.subs))
+	for _, cb := range subs.subs {
+		cbs = append(cbs, cb)
+	}
+	subs.mu.Unlock()
+
+	for _, cb := range cbs {
+		cb(event, err)
+	}
+}
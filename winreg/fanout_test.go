@@ -0,0 +1,102 @@
+//go:build windows
+
+package winreg
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestSubscribe(t *testing.T) {
+	t.Log("Testing fan-out of watch events.")
+	{
+		const eventTimeout = 5
+		createTestData(t)
+		defer deleteTestData(t)
+
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey})
+		events := make(chan interface{}, 10)
+		cancel, err := p.Subscribe(func(event interface{}, err error) {
+			events <- err
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tSubscribe() method failed: %v", failed, err)
+		}
+		ch, cancelChan, err := p.SubscribeChan(1)
+		if err != nil {
+			t.Fatalf("\t%s\tSubscribeChan() method failed: %v", failed, err)
+		}
+		defer cancelChan()
+
+		r, err := registry.OpenKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey, registry.ALL_ACCESS)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to open registry key: %v", failed, err)
+		}
+		defer r.Close()
+
+		testID := 0
+		t.Logf("\tTest %d:\tall subscribers are notified.", testID)
+		{
+			if err := r.SetDWordValue("on", 2); err != nil {
+				t.Fatalf("\t%s\tUnable to change value \"on\": %v", failed, err)
+			}
+			for i := 0; i < 2; i++ {
+				select {
+				case err := <-events:
+					if err != nil {
+						t.Fatalf("\t%s\tWatch failed: %v.", failed, err)
+					}
+				case ev := <-ch:
+					if ev.Err != nil {
+						t.Fatalf("\t%s\tWatch failed: %v.", failed, ev.Err)
+					}
+				case <-time.After(eventTimeout * time.Second):
+					t.Fatalf("\t%s\tTimeout exceeded while waiting for change event.", failed)
+				}
+			}
+			t.Logf("\t%s\tSubscribers are notified.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tunsubscribed callback is not called.", testID)
+		{
+			cancel()
+			if err := r.SetDWordValue("on", 3); err != nil {
+				t.Fatalf("\t%s\tUnable to change value \"on\": %v", failed, err)
+			}
+			select {
+			case <-ch:
+			case <-time.After(eventTimeout * time.Second):
+				t.Fatalf("\t%s\tTimeout exceeded while waiting for change event.", failed)
+			}
+			time.Sleep(100 * time.Millisecond)
+			if len(events) != 0 {
+				t.Fatalf("\t%s\tUnsubscribed callback was called.", failed)
+			}
+			t.Logf("\t%s\tCallback is detached.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tunwatch detaches subscribers.", testID)
+		{
+			if err := p.Unwatch(); err != nil {
+				t.Fatalf("\t%s\tUnwatch() method failed: %v", failed, err)
+			}
+			if len(p.subscribers.subs) != 0 {
+				t.Fatalf("\t%s\tSubscribers are kept: %d.", failed, len(p.subscribers.subs))
+			}
+			t.Logf("\t%s\tSubscribers are detached.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tunbuffered channel.", testID)
+		{
+			if _, _, err := p.SubscribeChan(0); err == nil {
+				t.Fatalf("\t%s\tSubscribeChan(0) should fail.", failed)
+			}
+			t.Logf("\t%s\tUnbuffered channel is refused.", success)
+		}
+	}
+}
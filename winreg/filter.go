@@ -0,0 +1,166 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// nameFilter selects names by glob patterns, as understood by path.Match,
+// ignoring case as the registry does.
+type nameFilter struct {
+	include []string // Upper-cased, nil selects all names
+	exclude []string // Upper-cased
+}
+
+func newNameFilter(include, exclude []string) nameFilter {
+	return nameFilter{include: upperAll(include), exclude: upperAll(exclude)}
+}
+
+// match reports whether name is selected: it matches one of the include
+// patterns, if any, and none of the exclude patterns.
+func (f nameFilter) match(name string) (bool, error) {
+	name = strings.ToUpper(name)
+	if f.include != nil {
+		ok, err := matchAny(f.include, name)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	ok, err := matchAny(f.exclude, name)
+
+	return !ok, err
+}
+
+func matchAny(patterns []string, name string) (bool, error) {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, name); err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func upperAll(strs []string) []string {
+	if strs == nil {
+		return nil
+	}
+	retval := make([]string, len(strs))
+	for i, str := range strs {
+		retval[i] = strings.ToUpper(str)
+	}
+
+	return retval
+}
+
+// valueSelected returns the name of the registry value of type typ in
+// the tree and reports whether it is read: an unnamed default value only
+// with DefaultValue, and its name must pass IncludeValues and
+// ExcludeValues.
+func (s *WinReg) valueSelected(value string, typ uint32) (string, bool, error) {
+	name := escapeName(value)
+	if value == "" && typ == registry.SZ {
+		if s.defaultValue == "" {
+			return "", false, nil
+		}
+		name = s.defaultValue
+	}
+	ok, err := s.valueFilter.match(name)
+
+	return name, ok, err
+}
+
+// keySelected reports whether the subkey path is read: its path relative
+// to the provider's path matches none of ExcludeKeys and, if IncludeKeys
+// are set, it or one of its parents matches one of them. Keys outside of
+// the provider's path, like included ones, are always read.
+func (s *WinReg) keySelected(path string) bool {
+	rel, ok := s.relativePath(path)
+	if !ok {
+		return true
+	}
+	if matchRegexps(s.excludeKeys, rel) {
+		return false
+	}
+	if s.includeKeys == nil {
+		return true
+	}
+
+	for i := range rel {
+		if rel[i] == '\\' && matchRegexps(s.includeKeys, rel[:i]) {
+			return true
+		}
+	}
+	return matchRegexps(s.includeKeys, rel)
+}
+
+func matchRegexps(patterns []*regexp.Regexp, str string) bool {
+	for _, re := range patterns {
+		if re.MatchString(str) {
+			return true
+		}
+	}
+	return false
+}
+
+// readsSubKeys reports whether subkeys of the key path at level are read,
+// as limited by MaxDepth or by MaxDepths of the nearest key on the path.
+func (s *WinReg) readsSubKeys(path string, level uint) bool {
+	maxDepth, base := s.maxDepth, uint(1)
+	if rel, ok := s.relativePath(path); ok && s.maxDepths != nil {
+		for parent := strings.ToUpper(rel); ; parent, _ = splitParent(parent) {
+			if depth, ok := s.maxDepths[parent]; ok {
+				// Levels are counted from that key
+				maxDepth, base = depth, 1
+				if parent != "" {
+					base += uint(strings.Count(parent, "\\")) + 1
+				}
+				break
+			}
+			if parent == "" {
+				break
+			}
+		}
+	}
+
+	return maxDepth == 0 || level-base+1 < maxDepth
+}
+
+// NameMapper returns the name under which the key or value name is put into
+// the tree, e.g. lower-cased. path is the path of the parent key relative to
+// the provider's path, empty for keys outside of it, like included ones.
+type NameMapper func(path, name string) string
+
+// mapName returns the name of the key or value name of the key path in
+// the tree.
+func (s *WinReg) mapName(path, name string) string {
+	if s.delimEscape != "" {
+		name = strings.ReplaceAll(name, s.delimiter, s.delimEscape)
+	}
+	if s.nameMapper == nil {
+		return name
+	}
+	rel, _ := s.relativePath(path)
+
+	return s.nameMapper(rel, name)
+}
+
+// ErrMappedNames is returned by writes of koanf paths if names of the tree
+// are mapped by NameMapper or DelimEscape, which can't be reversed, or if
+// the tree is flattened.
+var ErrMappedNames = errors.New("names mapped by NameMapper, DelimEscape or Flatten can't be written")
+
+// namesWritable returns ErrMappedNames if names of the tree aren't the
+// registry names.
+func (s *WinReg) namesWritable() error {
+	if s.nameMapper != nil || s.delimEscape != "" || s.flatten {
+		return ErrMappedNames
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestValueFilter(t *testing.T) {
+	t.Log("Testing value name filters.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		testID := 0
+		t.Logf("\tTest %d:\tinclude and exclude patterns.", testID)
+		{
+			p := Provider(Config{
+				Key:           CURRENT_USER,
+				Path:          "SOFTWARE\\" + testKey,
+				IncludeValues: []string{"o*", "str*", "int*"},
+				ExcludeValues: []string{"off", "StrList"},
+			})
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if _, ok := tree["on"]; !ok || tree["off"] != nil {
+				t.Fatalf("\t%s\tInvalid values of the key, got %v.", failed, tree)
+			}
+			sub := tree["SubKeyA"].(map[string]interface{})
+			if len(sub) != 4 || sub["StrValue"] == nil || sub["Int64"] == nil || sub["IntVal"] == nil {
+				t.Fatalf("\t%s\tInvalid values of the subkey, got %v.", failed, sub)
+			}
+			t.Logf("\t%s\tValues are filtered.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tinvalid pattern.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, ExcludeValues: []string{"["}})
+			if _, err := p.Read(); err == nil {
+				t.Fatalf("\t%s\tRead() with an invalid pattern should fail.", failed)
+			}
+			t.Logf("\t%s\tInvalid pattern is reported.", success)
+		}
+	}
+}
+
+func TestKeyFilter(t *testing.T) {
+	t.Log("Testing subkey filters.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		testID := 0
+		t.Logf("\tTest %d:\texcluded branch.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, ExcludeKeys: []*regexp.Regexp{regexp.MustCompile(`(?i)\\sub key$`)}})
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if _, ok := tree["SubKeyA"].(map[string]interface{})["Sub Key"]; ok {
+				t.Fatalf("\t%s\tExcluded subkey is read.", failed)
+			}
+			if _, ok := tree["SubKeyB"]; !ok {
+				t.Fatalf("\t%s\tOther subkeys should be read, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tBranch is skipped.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tincluded branch.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, IncludeKeys: []*regexp.Regexp{regexp.MustCompile(`^SubKeyA$`)}})
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if _, ok := tree["SubKeyB"]; ok {
+				t.Fatalf("\t%s\tSubKeyB should not be read.", failed)
+			}
+			if _, ok := tree["SubKeyA"].(map[string]interface{})["Sub Key"]; !ok {
+				t.Fatalf("\t%s\tSubkeys of included key should be read, got %v.", failed, tree)
+			}
+			if _, ok := tree["on"]; !ok {
+				t.Fatalf("\t%s\tValues of the provider's key should be read.", failed)
+			}
+			t.Logf("\t%s\tOnly the branch is read.", success)
+		}
+	}
+}
+
+func TestNameMapper(t *testing.T) {
+	t.Log("Testing name mapping.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		testID := 0
+		t.Logf("\tTest %d:\tlower-cased names without spaces.", testID)
+		{
+			var paths []string
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, NameMapper: func(path, name string) string {
+				if name == "Sub Key" {
+					paths = append(paths, path)
+				}
+				return strings.ReplaceAll(strings.ToLower(name), " ", "_")
+			}})
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			sub, ok := tree["subkeya"].(map[string]interface{})
+			if !ok || sub["strvalue"] == nil || sub["sub_key"] == nil {
+				t.Fatalf("\t%s\tNames are not mapped, got %v.", failed, tree)
+			}
+			if len(paths) != 1 || paths[0] != "SubKeyA" {
+				t.Fatalf("\t%s\tInvalid parent paths %v.", failed, paths)
+			}
+			t.Logf("\t%s\tNames are mapped.", success)
+		}
+	}
+}
+
+func TestDelimEscape(t *testing.T) {
+	t.Log("Testing names containing the delimiter.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, DelimEscape: "_"})
+
+		testID := 0
+		t.Logf("\tTest %d:\tread of a dotted name.", testID)
+		{
+			k, _, err := registry.CreateKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey+"\\Server.Config", registry.SET_VALUE)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to create registry key: %v.", failed, err)
+			}
+			err = k.SetStringValue("Host.Name", "example.com")
+			k.Close()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to set value: %v.", failed, err)
+			}
+
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			sub, _ := tree["Server_Config"].(map[string]interface{})
+			if sub["Host_Name"] != "example.com" {
+				t.Fatalf("\t%s\tNames are not escaped, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tNames are escaped.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\twrite of an escaped name.", testID)
+		{
+			if err := p.Set("Server_Config.Host_Name", "example.org"); !errors.Is(err, ErrMappedNames) {
+				t.Fatalf("\t%s\tSet() should fail with ErrMappedNames, got: %v.", failed, err)
+			}
+			if _, err := p.Sync(map[string]interface{}{}); !errors.Is(err, ErrMappedNames) {
+				t.Fatalf("\t%s\tSync() should fail with ErrMappedNames, got: %v.", failed, err)
+			}
+			t.Logf("\t%s\tWrites are refused.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tescape containing the delimiter.", testID)
+		{
+			if _, err := NewProvider(Config{Key: CURRENT_USER, DelimEscape: `\.`}); !errors.Is(err, ErrInvalidConfig) {
+				t.Fatalf("\t%s\tNewProvider() should fail with ErrInvalidConfig, got: %v.", failed, err)
+			}
+			t.Logf("\t%s\tThe escape is refused.", success)
+		}
+	}
+}
+
+func TestMaxDepths(t *testing.T) {
+	t.Log("Testing depth limits of subtrees.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		testID := 0
+		t.Logf("\tTest %d:\tcapped subtree.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, MaxDepths: map[string]uint{"subkeya": 1}})
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			sub := tree["SubKeyA"].(map[string]interface{})
+			if _, ok := sub["Sub Key"]; ok || sub["StrValue"] == nil {
+				t.Fatalf("\t%s\tSubtree is not capped, got %v.", failed, sub)
+			}
+			t.Logf("\t%s\tSubtree is capped.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tunlimited subtree.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, MaxDepth: 2, MaxDepths: map[string]uint{"SubKeyA": 0}})
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if _, ok := tree["SubKeyA"].(map[string]interface{})["Sub Key"]; !ok {
+				t.Fatalf("\t%s\tSubtree is not read fully, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tSubtree is read fully.", success)
+		}
+	}
+}
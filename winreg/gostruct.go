@@ -0,0 +1,243 @@
+//go:build windows
+
+package winreg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// StructOptions controls Go struct generation.
+type StructOptions struct {
+	Package string // Package name of the generated file, "config" if empty
+	Name    string // Name of the top-level struct, "Config" if empty
+}
+
+// GenerateStruct reads the provider's tree with its registry value types
+// and writes Go source with struct definitions matching the layout. Fields
+// carry `koanf` tags with the original names and `winreg` tags with the
+// registry value types, so the result can be used with koanf.Unmarshal().
+func GenerateStruct(w io.Writer, p *WinReg, opts StructOptions) error {
+	if opts.Package == "" {
+		opts.Package = "config"
+	}
+	if opts.Name == "" {
+		opts.Name = "Config"
+	}
+
+	tree, err := p.Read()
+	if err != nil {
+		return err
+	}
+	types, err := p.readValueTypes()
+	if err != nil {
+		return err
+	}
+
+	g := &structGenerator{types: types}
+	fmt.Fprintf(&g.buf, "// Code generated by winreg from %s. DO NOT EDIT.\n\n", p.getKeyName(p.path))
+	fmt.Fprintf(&g.buf, "package %s\n", opts.Package)
+	g.generate(opts.Name, "", tree)
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("unable to format generated code: %w", err)
+	}
+	if _, err := w.Write(src); err != nil {
+		return fmt.Errorf("unable to write generated code: %w", err)
+	}
+
+	return nil
+}
+
+// readValueTypes returns registry types of the provider's values keyed
+// by value path relative to the provider's path.
+func (s *WinReg) readValueTypes() (map[string]uint32, error) {
+	if err := s.singleKey(); err != nil {
+		return nil, err
+	}
+	root, err := s.connect()
+	if err != nil {
+		return nil, err
+	}
+	if root != s.key {
+		defer s.api.CloseKey(root)
+	}
+
+	retval := make(map[string]uint32)
+	if err := s.readKeyTypes(root, s.path, "", 1, retval); err != nil {
+		return nil, err
+	}
+
+	return retval, nil
+}
+
+// readKeyTypes reads the types of the values of the key path into types,
+// keyed by the names in the tree under prefix. Keys and values are
+// selected and named as by Read().
+func (s *WinReg) readKeyTypes(root registry.Key, path, prefix string, level uint, types map[string]uint32) error {
+	s.limiter.Wait()
+	k, err := s.api.OpenKey(root, path, s.getAccess(registry.READ))
+	if err != nil {
+		return s.keyError("open", path, "", err)
+	}
+	defer s.api.CloseKey(k)
+
+	s.limiter.Wait()
+	values, err := s.api.ReadValueNames(k)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return s.keyError("enumerate", path, "", err)
+	}
+	for _, value := range values {
+		s.limiter.Wait()
+		_, typ, err := s.api.GetValue(k, value, nil)
+		if err != nil {
+			continue
+		}
+		name, ok, err := s.valueSelected(value, typ)
+		if err != nil {
+			return s.keyError("read", path, value, err)
+		}
+		if !ok {
+			continue
+		}
+		types[joinPath(prefix, s.mapName(path, name))] = typ
+	}
+
+	if !s.readsSubKeys(path, level) {
+		return nil
+	}
+	s.limiter.Wait()
+	subKeys, err := s.api.ReadSubKeyNames(k)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return s.keyError("enumerate", path, "", err)
+	}
+	for _, subKey := range subKeys {
+		if !s.keySelected(joinPath(path, subKey)) {
+			continue
+		}
+		name := s.mapName(path, escapeName(subKey))
+		if err := s.readKeyTypes(root, joinPath(path, subKey), joinPath(prefix, name), level+1, types); err != nil && !errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type structGenerator struct {
+	buf   bytes.Buffer
+	types map[string]uint32
+}
+
+// generate writes the struct typeName for tree and, recursively, the
+// structs of its subkeys.
+func (g *structGenerator) generate(typeName, path string, tree map[string]interface{}) {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var (
+		nested []string
+		used   = make(map[string]bool)
+	)
+	fmt.Fprintf(&g.buf, "\ntype %s struct {\n", typeName)
+	for _, name := range names {
+		field := uniqueIdentifier(goIdentifier(name), used)
+		valuePath := joinPath(path, name)
+
+		if _, ok := tree[name].(map[string]interface{}); ok {
+			sub := typeName + field
+			nested = append(nested, name, sub)
+			fmt.Fprintf(&g.buf, "\t%s %s `koanf:%q`\n", field, sub, name)
+			continue
+		}
+
+		goType, regType := g.fieldType(valuePath, tree[name])
+		if regType != "" {
+			fmt.Fprintf(&g.buf, "\t%s %s `koanf:%q winreg:%q`\n", field, goType, name, regType)
+		} else {
+			fmt.Fprintf(&g.buf, "\t%s %s `koanf:%q`\n", field, goType, name)
+		}
+	}
+	g.buf.WriteString("}\n")
+
+	for i := 0; i < len(nested); i += 2 {
+		g.generate(nested[i+1], joinPath(path, nested[i]), tree[nested[i]].(map[string]interface{}))
+	}
+}
+
+// fieldType returns the Go type of a value and the name of its registry
+// type, if known.
+func (g *structGenerator) fieldType(path string, value interface{}) (string, string) {
+	if typ, ok := g.types[path]; ok {
+		name := valueTypeName(typ)
+		switch typ {
+		case registry.SZ, registry.EXPAND_SZ:
+			return "string", name
+		case registry.MULTI_SZ:
+			return "[]string", name
+		case registry.DWORD, registry.DWORD_BIG_ENDIAN:
+			return "uint32", name
+		case registry.QWORD:
+			return "uint64", name
+		case registry.BINARY:
+			return "[]byte", name
+		}
+	}
+
+	if value == nil {
+		return "interface{}", ""
+	}
+	return reflect.TypeOf(value).String(), ""
+}
+
+// goIdentifier converts a registry name into an exported Go identifier,
+// e.g. "Sub Key" to "SubKey".
+func goIdentifier(name string) string {
+	var (
+		b     strings.Builder
+		upper = true
+	)
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if b.Len() == 0 && unicode.IsDigit(r) {
+			b.WriteRune('X')
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+
+	if b.Len() == 0 {
+		return "Value"
+	}
+	return b.String()
+}
+
+func uniqueIdentifier(name string, used map[string]bool) string {
+	retval := name
+	for i := 2; used[retval]; i++ {
+		retval = fmt.Sprintf("%s%d", name, i)
+	}
+	used[retval] = true
+
+	return retval
+}
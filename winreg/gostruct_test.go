@@ -0,0 +1,115 @@
+//go:build windows
+
+package winreg
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGenerateStruct(t *testing.T) {
+	t.Log("Testing Go struct generation.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		var buf bytes.Buffer
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, DefaultValue: "Default"})
+		if err := GenerateStruct(&buf, p, StructOptions{Package: "app", Name: "Settings"}); err != nil {
+			t.Fatalf("\t%s\tUnable to generate struct: %v.", failed, err)
+		}
+		src := buf.String()
+
+		for testID, expect := range []string{
+			"package app",
+			"type Settings struct {",
+			"SubKeyA SettingsSubKeyA `koanf:\"SubKeyA\"`",
+			"Expand   string   `koanf:\"Expand\" winreg:\"REG_EXPAND_SZ\"`",
+			"Int64    uint64   `koanf:\"Int64\" winreg:\"REG_QWORD\"`",
+			"StrList  []string `koanf:\"StrList\" winreg:\"REG_MULTI_SZ\"`",
+			"SubKey   SettingsSubKeyASubKey `koanf:\"Sub Key\"`",
+			"On      uint32          `koanf:\"on\" winreg:\"REG_DWORD\"`",
+		} {
+			t.Logf("\tTest %d:\t%s.", testID, expect)
+			if !strings.Contains(strings.Join(strings.Fields(src), " "), strings.Join(strings.Fields(expect), " ")) {
+				t.Fatalf("\t%s\tGenerated code doesn't contain it:\n%s", failed, src)
+			}
+			t.Logf("\t%s\tFound.", success)
+		}
+	}
+}
+
+func TestGenerateStructFilters(t *testing.T) {
+	t.Log("Testing Go struct generation of filtered and mapped names.")
+	{
+		m := NewMemory()
+		err := m.Load(CURRENT_USER, "SOFTWARE\\Vendor", map[string]interface{}{
+			"Width": uint32(800),
+			"MRU":   "file",
+			"Sub":   map[string]interface{}{"Size": uint64(1), "Deep": map[string]interface{}{"Name": "deep"}},
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to load memory registry: %v", failed, err)
+		}
+
+		var buf bytes.Buffer
+		p := Provider(Config{
+			Key:           CURRENT_USER,
+			Path:          "SOFTWARE\\Vendor",
+			Memory:        m,
+			MaxDepth:      2,
+			ExcludeValues: []string{"MRU"},
+			NameMapper:    func(path, name string) string { return strings.ToLower(name) },
+		})
+		if err := GenerateStruct(&buf, p, StructOptions{}); err != nil {
+			t.Fatalf("\t%s\tUnable to generate struct: %v.", failed, err)
+		}
+		src := strings.Join(strings.Fields(buf.String()), " ")
+
+		testID := 0
+		t.Logf("\tTest %d:\ttypes of mapped names.", testID)
+		{
+			for _, expect := range []string{
+				"Width uint32 `koanf:\"width\" winreg:\"REG_DWORD\"`",
+				"Size uint64 `koanf:\"size\" winreg:\"REG_QWORD\"`",
+			} {
+				if !strings.Contains(src, expect) {
+					t.Fatalf("\t%s\tGenerated code doesn't contain %s:\n%s", failed, expect, src)
+				}
+			}
+			t.Logf("\t%s\tTypes are found.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tkeys and values left out of the read.", testID)
+		{
+			types, err := p.readValueTypes()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read types: %v.", failed, err)
+			}
+			if len(types) != 2 {
+				t.Fatalf("\t%s\tExpect types of width and sub\\size, got %v.", failed, types)
+			}
+			t.Logf("\t%s\tThey are left out.", success)
+		}
+	}
+}
+
+func TestGoIdentifier(t *testing.T) {
+	t.Log("Testing Go identifier conversion.")
+	{
+		for testID, test := range [][2]string{
+			{"Sub Key", "SubKey"},
+			{"on", "On"},
+			{"3rd-party", "X3rdParty"},
+			{"", "Value"},
+		} {
+			t.Logf("\tTest %d:\t%q.", testID, test[0])
+			if got := goIdentifier(test[0]); got != test[1] {
+				t.Fatalf("\t%s\tGot %q, expect %q.", failed, got, test[1])
+			}
+			t.Logf("\t%s\tConverted.", success)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pda0/koanf-winreg/v2/regfile"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// Import modes, see Import().
+const (
+	ImportMerge   = iota // Keep keys and values missing in the file
+	ImportReplace        // Delete the provider's key before importing
+)
+
+// regOp is a single operation of a .reg file.
+type regOp struct {
+	deleteKey bool     // Delete the key value.Path with its subkeys
+	createKey bool     // Create the key value.Path
+	value     rawValue // Write or delete a value if neither of the above is set
+}
+
+// Import applies a file in Registry Editor 5.00 format, as written by
+// Export(), to the provider's key. The first key of the file is mapped to
+// the provider's key and all other keys must be its subkeys, so a file
+// exported from one location can be imported to another. A file
+// compressed with gzip or zstd is decompressed. The file is applied in
+// order and an error stops it, leaving the previous operations applied.
+func (s *WinReg) Import(r io.Reader, mode int) error {
+	if mode != ImportMerge && mode != ImportReplace {
+		return fmt.Errorf("unsupported import mode %d", mode)
+	}
+	if err := s.singleKey(); err != nil {
+		return err
+	}
+	if mode == ImportReplace && strings.Trim(s.path, "\\") == "" {
+		return errors.New("predefined keys can't be replaced")
+	}
+	ops, err := parseRegFile(r)
+	if err != nil {
+		return fmt.Errorf("unable to parse .reg file: %w", err)
+	}
+
+	root, err := s.connect()
+	if err != nil {
+		return err
+	}
+	if root != s.key {
+		defer s.api.CloseKey(root)
+	}
+
+	if mode == ImportReplace {
+		if err = s.deleteTree(root, s.path); err != nil {
+			return err
+		}
+	}
+	for _, op := range ops {
+		switch {
+		case op.deleteKey:
+			err = s.deleteTree(root, joinPath(s.path, op.value.Path))
+		case op.createKey:
+			err = s.createKey(root, joinPath(s.path, op.value.Path))
+		default:
+			err = s.writeRaw(root, op.value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *WinReg) createKey(root registry.Key, path string) error {
+	s.limiter.Wait()
+	k, err := s.api.CreateKey(root, path, s.keyOptions, s.getAccess(registry.QUERY_VALUE))
+	if err != nil {
+		return s.keyError("create", path, "", err)
+	}
+
+	return s.api.CloseKey(k)
+}
+
+// deleteTree deletes the key path with all its subkeys. A missing key is
+// not an error.
+func (s *WinReg) deleteTree(root registry.Key, path string) error {
+	s.limiter.Wait()
+	k, err := s.api.OpenKey(root, path, s.getAccess(registry.ENUMERATE_SUB_KEYS))
+	if errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+		return nil
+	}
+	if err != nil {
+		return s.keyError("open", path, "", err)
+	}
+	s.limiter.Wait()
+	subKeys, err := s.api.ReadSubKeyNames(k)
+	s.api.CloseKey(k)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return s.keyError("enumerate", path, "", err)
+	}
+
+	for _, subKey := range subKeys {
+		if err := s.deleteTree(root, joinPath(path, subKey)); err != nil {
+			return err
+		}
+	}
+	s.limiter.Wait()
+	if err = s.api.DeleteKey(root, path, s.access); err != nil && !errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+		return s.keyError("delete", path, "", err)
+	}
+
+	return nil
+}
+
+// parseRegFile parses a .reg file into operations on paths relative to its
+// first key.
+func parseRegFile(r io.Reader) ([]regOp, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = decompressBytes(data); err != nil {
+		return nil, err
+	}
+	keys, err := regfile.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var retval []regOp
+	for i, k := range keys {
+		if i == 0 && k.Delete {
+			return nil, fmt.Errorf("line %d: the first key can't be deleted", k.Line)
+		}
+		path, err := relativeRegPath(keys[0].Name, k.Name)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", k.Line, err)
+		}
+		retval = append(retval, regOp{deleteKey: k.Delete, createKey: !k.Delete, value: rawValue{Path: path}})
+		for _, v := range k.Values {
+			retval = append(retval, regOp{value: rawValue{Path: path, Name: v.Name, Exists: !v.Delete, Type: v.Type, Data: v.Data}})
+		}
+	}
+
+	return retval, nil
+}
+
+// relativeRegPath returns name relative to base, which must be name or
+// one of its parents.
+func relativeRegPath(base, name string) (string, error) {
+	if strings.EqualFold(name, base) {
+		return "", nil
+	}
+	if len(name) > len(base) && strings.EqualFold(name[:len(base)], base) && name[len(base)] == '\\' {
+		return name[len(base)+1:], nil
+	}
+
+	return "", fmt.Errorf("%s is not a subkey of %s", name, base)
+}
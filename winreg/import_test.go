@@ -0,0 +1,134 @@
+//go:build windows
+
+package winreg
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pda0/koanf-winreg/v2/regfile"
+)
+
+func TestImport(t *testing.T) {
+	t.Log("Testing import of .reg files.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		testID := 0
+		t.Logf("\tTest %d:\texported tree to another key.", testID)
+		{
+			src := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey + "\\SubKeyA"})
+			var buf bytes.Buffer
+			if err := src.Export(&buf); err != nil {
+				t.Fatalf("\t%s\tUnable to export registry: %v.", failed, err)
+			}
+			dst := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey + "\\Imported"})
+			if err := dst.Import(&buf, ImportMerge); err != nil {
+				t.Fatalf("\t%s\tUnable to import registry: %v.", failed, err)
+			}
+
+			expected, err := src.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			tree, err := dst.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if !reflect.DeepEqual(tree, expected) {
+				t.Fatalf("\t%s\tTrees differ, got %v, expect %v.", failed, tree, expected)
+			}
+			t.Logf("\t%s\tTree is copied.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tcompressed export.", testID)
+		{
+			src := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey + "\\SubKeyA"})
+			expected, err := src.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			for _, method := range []int{CompressGzip, CompressZstd} {
+				var buf bytes.Buffer
+				if err := src.ExportCompressed(&buf, method); err != nil {
+					t.Fatalf("\t%s\tUnable to export registry: %v.", failed, err)
+				}
+				dst := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey + "\\Compressed"})
+				if err := dst.Import(&buf, ImportReplace); err != nil {
+					t.Fatalf("\t%s\tUnable to import registry: %v.", failed, err)
+				}
+				tree, err := dst.Read()
+				if err != nil {
+					t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+				}
+				if !reflect.DeepEqual(tree, expected) {
+					t.Fatalf("\t%s\tTrees differ, got %v, expect %v.", failed, tree, expected)
+				}
+			}
+			t.Logf("\t%s\tCompression is detected.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tmerge and replace.", testID)
+		{
+			file := strings.Join([]string{
+				regfile.Header,
+				"",
+				"[HKEY_LOCAL_MACHINE\\SOFTWARE\\Vendor\\App]",
+				"@=\"default \\\"quoted\\\"\"",
+				"\"Binary\"=-",
+				"\"Path\"=hex(2):25,00,54,00,\\",
+				"  45,00,4d,00,50,00,25,00,00,00",
+				"",
+				"[-HKEY_LOCAL_MACHINE\\SOFTWARE\\Vendor\\App\\Sub Key]",
+				"",
+				"[HKEY_LOCAL_MACHINE\\SOFTWARE\\Vendor\\App\\Empty]",
+				"",
+			}, "\r\n")
+
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey + "\\Imported", DefaultValue: "Default"})
+			if err := p.Import(strings.NewReader(file), ImportMerge); err != nil {
+				t.Fatalf("\t%s\tUnable to import registry: %v.", failed, err)
+			}
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if tree["Default"] != "default \"quoted\"" || tree["StrValue"] == nil || tree["Binary"] != nil || tree["Sub Key"] != nil || tree["Empty"] == nil {
+				t.Fatalf("\t%s\tFile is not merged, got %v.", failed, tree)
+			}
+
+			if err := p.Import(strings.NewReader(file), ImportReplace); err != nil {
+				t.Fatalf("\t%s\tUnable to import registry: %v.", failed, err)
+			}
+			if tree, err = p.Read(); err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if len(tree) != 3 || tree["StrValue"] != nil {
+				t.Fatalf("\t%s\tKey is not replaced, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tFile is applied.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tinvalid files.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey + "\\Imported"})
+			for _, file := range []string{
+				"REGEDIT4\r\n\r\n[HKEY_CURRENT_USER\\A]\r\n",
+				regfile.Header + "\r\n\r\n\"Value\"=\"outside\"\r\n",
+				regfile.Header + "\r\n\r\n[HKEY_CURRENT_USER\\A]\r\n[HKEY_CURRENT_USER\\B]\r\n",
+				regfile.Header + "\r\n\r\n[HKEY_CURRENT_USER\\A]\r\n\"Value\"=dword:1000000000\r\n",
+			} {
+				if err := p.Import(strings.NewReader(file), ImportMerge); err == nil {
+					t.Fatalf("\t%s\tImport() of an invalid file should fail:\n%s", failed, file)
+				}
+			}
+			t.Logf("\t%s\tInvalid files are rejected.", success)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+//go:build windows
+
+package winreg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrIncludeLoop is returned when included keys include each other.
+var ErrIncludeLoop = errors.New("include loop")
+
+// inlineIncludes merges into tree the contents of the keys listed by the
+// include value as read. The value may be REG_SZ or REG_MULTI_SZ of full
+// key names ("HKLM\SOFTWARE\Vendor\Common") or paths in the provider's
+// key. Data of the key itself takes precedence over included data, and
+// keys listed later take precedence over keys listed earlier.
+func (s *WinReg) inlineIncludes(ctx context.Context, tree map[string]interface{}, include interface{}, level uint, includes []string) error {
+	var names []string
+	switch value := include.(type) {
+	case string:
+		names = []string{value}
+	case []string:
+		names = value
+	case []uint16:
+		names = []string{string(utf16.Decode(value))}
+	case [][]uint16:
+		for _, name := range value {
+			names = append(names, string(utf16.Decode(name)))
+		}
+	default:
+		return fmt.Errorf("%s: include value must be a string or a list of strings", s.includeValue)
+	}
+
+	for i := len(names) - 1; i >= 0; i-- {
+		name := strings.TrimSpace(names[i])
+		if name == "" {
+			continue
+		}
+
+		included, err := s.readInclude(ctx, name, level, includes)
+		if err != nil {
+			return fmt.Errorf("include %s: %w", name, err)
+		}
+		mergeBeneath(tree, included)
+	}
+
+	return nil
+}
+
+func (s *WinReg) readInclude(ctx context.Context, name string, level uint, includes []string) (map[string]interface{}, error) {
+	key, path, ok := parseKeyName(name)
+	if !ok {
+		key, path = s.key, strings.Trim(name, "\\")
+	}
+
+	id := strings.ToUpper(keyName(key, path))
+	for _, include := range includes {
+		if include == id {
+			return nil, ErrIncludeLoop
+		}
+	}
+	if isPerformanceKey(key) {
+		return nil, ErrPerformanceData
+	}
+
+	root, err := s.connectKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if root != key {
+		defer s.api.CloseKey(root)
+	}
+
+	// Each branch of the chain needs its own copy.
+	chain := make([]string, len(includes), len(includes)+1)
+	copy(chain, includes)
+
+	return s.readKey(ctx, root, path, level, append(chain, id))
+}
+
+// mergeBeneath copies data from src into dst, keeping values already
+// present in dst. Subkeys present in both are merged recursively.
+func mergeBeneath(dst, src map[string]interface{}) {
+	for name, value := range src {
+		current, ok := dst[name]
+		if !ok {
+			dst[name] = value
+			continue
+		}
+
+		dstSub, dstOk := current.(map[string]interface{})
+		srcSub, srcOk := value.(map[string]interface{})
+		if dstOk && srcOk {
+			mergeBeneath(dstSub, srcSub)
+		}
+	}
+}
+
+// copyTree returns a deep copy of the nested map tree.
+func copyTree(tree map[string]interface{}) map[string]interface{} {
+	retval := make(map[string]interface{}, len(tree))
+	for name, value := range tree {
+		if sub, ok := value.(map[string]interface{}); ok {
+			value = copyTree(sub)
+		}
+		retval[name] = value
+	}
+
+	return retval
+}
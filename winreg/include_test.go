@@ -0,0 +1,96 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/knadh/koanf/v2"
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestIncludeKey(t *testing.T) {
+	t.Log("Testing include key inlining.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		r, _, err := registry.CreateKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey+"\\App", registry.ALL_ACCESS)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to create test key: %v", failed, err)
+		}
+		defer r.Close()
+		if err := r.SetStringsValue("IncludeKey", []string{"SOFTWARE\\" + testKey + "\\SubKeyA", "HKCU\\SOFTWARE\\" + testKey + "\\SubKeyB"}); err != nil {
+			t.Fatalf("\t%s\tUnable to create test value: %v", failed, err)
+		}
+		if err := r.SetStringValue("StrValue", "local"); err != nil {
+			t.Fatalf("\t%s\tUnable to create test value: %v", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tinlined keys.", testID)
+		{
+			k := koanf.New(".")
+			if err := k.Load(Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey + "\\App", DefaultValue: "Default", IncludeValue: "IncludeKey"}), nil); err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if val := k.String("StrValue"); val != "local" {
+				t.Fatalf("\t%s\tStrValue is invalid, got \"%s\", expect \"local\".", failed, val)
+			}
+			if val := k.Int64("Int64"); val != 5000000000 {
+				t.Fatalf("\t%s\tInt64 is invalid, got %d, expect 5000000000.", failed, val)
+			}
+			if val := k.String("Default"); val != "default value" {
+				t.Fatalf("\t%s\tDefault is invalid, got \"%s\", expect \"default value\".", failed, val)
+			}
+			if k.Exists("IncludeKey") {
+				t.Fatalf("\t%s\tIncludeKey value was not removed.", failed)
+			}
+			t.Logf("\t%s\tKeys are inlined.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tinclude loop.", testID)
+		{
+			if err := r.SetStringValue("IncludeKey", "SOFTWARE\\"+testKey); err != nil {
+				t.Fatalf("\t%s\tUnable to create test value: %v", failed, err)
+			}
+			_, err := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, IncludeValue: "IncludeKey"}).Read()
+			if !errors.Is(err, ErrIncludeLoop) {
+				t.Fatalf("\t%s\tExpected include loop error, got %v.", failed, err)
+			}
+			t.Logf("\t%s\tLoop is detected.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tmapped names.", testID)
+		{
+			m := NewMemory()
+			err := m.Load(CURRENT_USER, "SOFTWARE\\Vendor", map[string]interface{}{
+				"App":    map[string]interface{}{"IncludeKey": "SOFTWARE\\Vendor\\Common", "Name": "app"},
+				"Common": map[string]interface{}{"Timeout": "30"},
+			})
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to load memory registry: %v", failed, err)
+			}
+			tree, err := Provider(Config{
+				Key:           CURRENT_USER,
+				Path:          "SOFTWARE\\Vendor\\App",
+				Memory:        m,
+				IncludeValue:  "IncludeKey",
+				ExcludeValues: []string{"Include*"},
+				NameMapper:    func(path, name string) string { return strings.ToLower(name) },
+			}).Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if !reflect.DeepEqual(tree, map[string]interface{}{"name": "app", "timeout": "30"}) {
+				t.Fatalf("\t%s\tTree is invalid, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tInclude value is found by its registry name.", success)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+//go:build windows
+
+package winreg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// Recovery actions of RecoverJournal().
+const (
+	RollBack = iota
+	RollForward
+)
+
+// ErrJournalPending is returned by ApplyJournaled() when the journal of an
+// interrupted batch still exists and must be recovered first.
+var ErrJournalPending = errors.New("unfinished write journal exists")
+
+// journal is the content of a journal file: the intended writes and the
+// data of the same values before they were written.
+type journal struct {
+	Key  string // Full name of the provider's key
+	Redo []rawValue
+	Undo []rawValue
+}
+
+// ApplyJournaled is like Apply() but protects the batch against crashes.
+// The intended writes and the previous data of the affected values are
+// saved to the journal file before anything is written. The file is
+// removed once all writes are flushed to disk, so if it exists on next
+// start the batch was interrupted and RecoverJournal() must be called.
+func (s *WinReg) ApplyJournaled(file string, writes []Write) error {
+	if _, err := os.Stat(file); err == nil {
+		return fmt.Errorf("%s: %w", file, ErrJournalPending)
+	}
+
+	redo, err := s.encodeWrites(writes)
+	if err != nil {
+		return err
+	}
+
+	root, err := s.connect()
+	if err != nil {
+		return err
+	}
+	if root != s.key {
+		defer s.api.CloseKey(root)
+	}
+
+	j := journal{Key: s.getKeyName(s.path), Redo: redo}
+	for _, v := range redo {
+		prev, err := s.readRaw(root, v.Path, v.Name)
+		if err != nil {
+			return err
+		}
+		j.Undo = append(j.Undo, prev)
+	}
+	if err := writeJournal(file, &j); err != nil {
+		return err
+	}
+
+	if err := s.replay(root, j.Redo); err != nil {
+		return err
+	}
+
+	return os.Remove(file)
+}
+
+// RecoverJournal completes (RollForward) or reverts (RollBack) the batch
+// of an interrupted ApplyJournaled() and removes the journal. Keys created
+// by the batch are not removed by RollBack. It reports false if there is
+// no journal, i.e. the last batch completed.
+func (s *WinReg) RecoverJournal(file string, action int) (bool, error) {
+	if err := s.singleKey(); err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return true, fmt.Errorf("unable to read journal: %w", err)
+	}
+
+	var j journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return true, fmt.Errorf("unable to decode journal %s: %w", file, err)
+	}
+	if j.Key != s.getKeyName(s.path) {
+		return true, fmt.Errorf("journal %s belongs to %s", file, j.Key)
+	}
+
+	var values []rawValue
+	switch action {
+	case RollForward:
+		values = j.Redo
+	case RollBack:
+		// The first undo record of a value written several times is the
+		// original data, so it must be applied last.
+		for i := len(j.Undo) - 1; i >= 0; i-- {
+			values = append(values, j.Undo[i])
+		}
+	default:
+		return true, fmt.Errorf("invalid journal recovery action %d", action)
+	}
+
+	root, err := s.connect()
+	if err != nil {
+		return true, err
+	}
+	if root != s.key {
+		defer s.api.CloseKey(root)
+	}
+	if err := s.replay(root, values); err != nil {
+		return true, err
+	}
+
+	return true, os.Remove(file)
+}
+
+// replay writes values and flushes them to disk.
+func (s *WinReg) replay(root registry.Key, values []rawValue) error {
+	for _, v := range values {
+		if err := s.writeRaw(root, v); err != nil {
+			return err
+		}
+	}
+
+	s.limiter.Wait()
+	k, err := s.api.OpenKey(root, s.path, s.getAccess(registry.QUERY_VALUE))
+	if err != nil {
+		return s.keyError("open", s.path, "", err)
+	}
+	defer s.api.CloseKey(k)
+	if err := s.api.FlushKey(k); err != nil {
+		return fmt.Errorf("unable to flush %w", s.keyError("flush", s.path, "", err))
+	}
+
+	return nil
+}
+
+// writeJournal saves the journal durably: a partially written journal
+// must never replace a complete one.
+func writeJournal(file string, j *journal) error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return fmt.Errorf("unable to encode journal: %w", err)
+	}
+
+	tmp := file + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("unable to create journal: %w", err)
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, file)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("unable to write journal: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+//go:build windows
+
+package winreg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestJournal(t *testing.T) {
+	t.Log("Testing journaled writes.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		file := filepath.Join(t.TempDir(), "winreg.journal")
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey})
+		writes := []Write{
+			{Name: "on", Value: uint32(5)},
+			{Path: "SubKeyC", Name: "New", Value: "text"},
+			{Name: "off"},
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tcompleted batch.", testID)
+		{
+			if err := p.ApplyJournaled(file, writes); err != nil {
+				t.Fatalf("\t%s\tUnable to apply writes: %v.", failed, err)
+			}
+			if _, err := os.Stat(file); !os.IsNotExist(err) {
+				t.Fatalf("\t%s\tJournal of a completed batch was not removed.", failed)
+			}
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if tree["on"] != uint64(5) || tree["SubKeyC"].(map[string]interface{})["New"] != "text" {
+				t.Fatalf("\t%s\tWrites were not applied: %v.", failed, tree)
+			}
+			if _, ok := tree["off"]; ok {
+				t.Fatalf("\t%s\tValue \"off\" was not deleted.", failed)
+			}
+			t.Logf("\t%s\tBatch is applied.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tinterrupted batch roll back.", testID)
+		{
+			// Simulate a crash after the first write of the batch
+			root := registry.CURRENT_USER
+			j := journal{Key: p.getKeyName(p.path)}
+			j.Redo, _ = p.encodeWrites([]Write{{Name: "on", Value: uint32(7)}, {Name: "off", Value: uint32(8)}})
+			for _, v := range j.Redo {
+				prev, err := p.readRaw(root, v.Path, v.Name)
+				if err != nil {
+					t.Fatalf("\t%s\tUnable to read value: %v.", failed, err)
+				}
+				j.Undo = append(j.Undo, prev)
+			}
+			if err := writeJournal(file, &j); err != nil {
+				t.Fatalf("\t%s\tUnable to write journal: %v.", failed, err)
+			}
+			if err := p.writeRaw(root, j.Redo[0]); err != nil {
+				t.Fatalf("\t%s\tUnable to write value: %v.", failed, err)
+			}
+
+			if err := p.ApplyJournaled(file, writes); err == nil {
+				t.Fatalf("\t%s\tApplyJournaled() should fail while a journal exists.", failed)
+			}
+			if found, err := p.RecoverJournal(file, RollBack); !found || err != nil {
+				t.Fatalf("\t%s\tUnable to recover journal: %v, %v.", failed, found, err)
+			}
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if tree["on"] != uint64(5) {
+				t.Fatalf("\t%s\tValue \"on\" was not rolled back, got %v.", failed, tree["on"])
+			}
+			if _, ok := tree["off"]; ok {
+				t.Fatalf("\t%s\tValue \"off\" should not exist.", failed)
+			}
+			if found, _ := p.RecoverJournal(file, RollBack); found {
+				t.Fatalf("\t%s\tJournal was not removed.", failed)
+			}
+			t.Logf("\t%s\tBatch is rolled back.", success)
+		}
+	}
+}
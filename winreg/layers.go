@@ -0,0 +1,238 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/knadh/koanf/v2"
+	"golang.org/x/sys/windows/registry"
+)
+
+// Layers assembles the canonical layering of a Windows application
+// configuration. From the lowest to the highest precedence:
+//
+//   - built-in defaults;
+//   - machine preferences, HKLM\Software\<Vendor>\<Product>;
+//   - machine policies, HKLM\Software\Policies\<Vendor>\<Product>;
+//   - user preferences, HKCU\Software\<Vendor>\<Product>;
+//   - user policies, HKCU\Software\Policies\<Vendor>\<Product>;
+//   - environment variables starting with EnvPrefix.
+//
+// Missing keys are treated as empty layers.
+type Layers struct {
+	Vendor    string
+	Product   string
+	Defaults  map[string]interface{} // Nested tree of built-in defaults
+	EnvPrefix string                 // Prefix of overriding environment variables, empty disables them
+	Delim     string                 // Key delimiter of the koanf instance, "." if empty
+	Mode      int                    // 32/64 bit registry branch, one of RegAuto/Reg32Bit/Reg64Bit constant
+
+	mu      sync.Mutex
+	watched []*WinReg // Providers watched by Watch()
+}
+
+// providers returns the registry layers from the lowest precedence.
+func (l *Layers) providers() []*WinReg {
+	prefs := joinPath(joinPath("Software", l.Vendor), l.Product)
+	policies := joinPath(joinPath("Software\\Policies", l.Vendor), l.Product)
+	layers := []struct {
+		key  registry.Key
+		path string
+	}{
+		{LOCAL_MACHINE, prefs},
+		{LOCAL_MACHINE, policies},
+		{CURRENT_USER, prefs},
+		{CURRENT_USER, policies},
+	}
+
+	retval := make([]*WinReg, 0, len(layers))
+	for _, layer := range layers {
+		retval = append(retval, Provider(Config{
+			Key:          layer.key,
+			Path:         layer.path,
+			Mode:         l.Mode,
+			WatchMissing: true,
+			// An empty tree makes a missing key an empty layer
+			Defaults: map[string]interface{}{},
+		}))
+	}
+
+	return retval
+}
+
+// Load reads all layers into a new koanf instance.
+func (l *Layers) Load() (*koanf.Koanf, error) {
+	return l.load(l.providers())
+}
+
+func (l *Layers) load(providers []*WinReg) (*koanf.Koanf, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delim := l.Delim
+	if delim == "" {
+		delim = "."
+	}
+	k := koanf.New(delim)
+
+	if l.Defaults != nil {
+		if err := k.Load(&Snapshot{Data: copyTree(l.Defaults)}, nil); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range providers {
+		if err := k.Load(p, nil); err != nil {
+			return nil, err
+		}
+	}
+	if l.EnvPrefix != "" {
+		l.loadEnv(k)
+	}
+
+	return k, nil
+}
+
+// loadEnv sets values from the environment. A variable name without the
+// prefix, with "_" standing for the delimiter, is matched against the
+// already loaded keys case-insensitively, e.g. APP_SERVER_PORT overrides
+// "Server.Port". Unmatched variables are added as lower-case keys.
+func (l *Layers) loadEnv(k *koanf.Koanf) {
+	keys := k.Keys()
+	for _, env := range os.Environ() {
+		name, value, ok := strings.Cut(env, "=")
+		if !ok || len(name) <= len(l.EnvPrefix) || !strings.EqualFold(name[:len(l.EnvPrefix)], l.EnvPrefix) {
+			continue
+		}
+
+		path := strings.ReplaceAll(name[len(l.EnvPrefix):], "_", k.Delim())
+		match := strings.ToLower(path)
+		for _, key := range keys {
+			if strings.EqualFold(key, path) {
+				match = key
+				break
+			}
+		}
+		_ = k.Set(match, value)
+	}
+}
+
+// Watch watches all registry layers and calls cb with a freshly loaded
+// koanf instance whenever any of them changes. Layer keys which don't
+// exist yet are watched for creation. If a layer can't be watched, those
+// already watched are stopped.
+func (l *Layers) Watch(cb func(k *koanf.Koanf, err error)) error {
+	providers := l.providers()
+	for i, p := range providers {
+		err := p.Watch(func(event interface{}, err error) {
+			if err != nil {
+				cb(nil, err)
+				return
+			}
+			cb(l.load(providers))
+		})
+		if err != nil {
+			for _, watched := range providers[:i] {
+				watched.Unwatch()
+			}
+			return err
+		}
+	}
+
+	l.mu.Lock()
+	l.watched = append(l.watched, providers...)
+	l.mu.Unlock()
+
+	return nil
+}
+
+// Unwatch stops all watches started by Watch(). No callback is called
+// after it returns, so it must not be called from a callback.
+func (l *Layers) Unwatch() error {
+	// Callbacks load under the lock, so the watches are stopped without it
+	l.mu.Lock()
+	watched := l.watched
+	l.watched = nil
+	l.mu.Unlock()
+
+	var retval error
+	for _, p := range watched {
+		if err := p.Unwatch(); retval == nil {
+			retval = err
+		}
+	}
+
+	return retval
+}
+
+// Layered is a provider of the same path in HKLM, holding machine
+// defaults, and in HKCU, holding user overrides which take precedence.
+type Layered struct {
+	Machine  *WinReg
+	User     *WinReg
+	defaults map[string]interface{}
+}
+
+// LayeredProvider returns a provider of cfg.Path in both HKLM and HKCU,
+// cfg.Key is ignored. Missing keys are empty layers and are watched for
+// creation. cfg.Defaults are merged beneath both layers.
+func LayeredProvider(cfg Config) *Layered {
+	defaults := cfg.Defaults
+	// An empty tree makes a missing key an empty layer
+	cfg.Defaults = map[string]interface{}{}
+	cfg.WatchMissing = true
+
+	machine, user := cfg, cfg
+	machine.Key, user.Key = LOCAL_MACHINE, CURRENT_USER
+
+	return &Layered{Machine: Provider(machine), User: Provider(user), defaults: defaults}
+}
+
+func (l *Layered) ReadBytes() ([]byte, error) {
+	return nil, errors.New("winreg provider does not support this method")
+}
+
+// Read reads both layers and merges the user layer over the machine one.
+func (l *Layered) Read() (map[string]interface{}, error) {
+	machine, err := l.Machine.Read()
+	if err != nil {
+		return nil, err
+	}
+	retval, err := l.User.Read()
+	if err != nil {
+		return nil, err
+	}
+	mergeBeneath(retval, machine)
+	if l.defaults != nil {
+		mergeBeneath(retval, copyTree(l.defaults))
+	}
+
+	return retval, nil
+}
+
+// Watch watches both layers, cb is called as by WinReg.Watch() when any
+// of them changes.
+func (l *Layered) Watch(cb func(event interface{}, err error)) error {
+	if err := l.Machine.Watch(cb); err != nil {
+		return err
+	}
+	if err := l.User.Watch(cb); err != nil {
+		l.Machine.Unwatch()
+		return err
+	}
+
+	return nil
+}
+
+// Unwatch stops watching both layers.
+func (l *Layered) Unwatch() error {
+	err := l.Machine.Unwatch()
+	if userErr := l.User.Unwatch(); err == nil {
+		err = userErr
+	}
+
+	return err
+}
@@ -0,0 +1,105 @@
+//go:build windows
+
+package winreg
+
+import (
+	"os"
+	"testing"
+
+	"github.com/knadh/koanf/v2"
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestLayers(t *testing.T) {
+	t.Log("Testing layered configuration.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		// The test key plays the vendor key, the user policy key is missing
+		k, _, err := registry.CreateKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey+"\\App", registry.ALL_ACCESS)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to create test key: %v", failed, err)
+		}
+		defer k.Close()
+		if err := k.SetStringValue("Level", "user"); err != nil {
+			t.Fatalf("\t%s\tUnable to create test value: %v", failed, err)
+		}
+		if err := k.SetStringValue("Name", "user"); err != nil {
+			t.Fatalf("\t%s\tUnable to create test value: %v", failed, err)
+		}
+		os.Setenv("KWTEST_NAME", "env")
+		defer os.Unsetenv("KWTEST_NAME")
+
+		testID := 0
+		t.Logf("\tTest %d:\tlayer precedence.", testID)
+		{
+			l := &Layers{
+				Vendor:    testKey,
+				Product:   "App",
+				Defaults:  map[string]interface{}{"Level": "default", "Other": "default"},
+				EnvPrefix: "KWTEST_",
+			}
+			ko, err := l.Load()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to load layers: %v.", failed, err)
+			}
+			if val := ko.String("Level"); val != "user" {
+				t.Fatalf("\t%s\tLevel is invalid, got \"%s\", expect \"user\".", failed, val)
+			}
+			if val := ko.String("Other"); val != "default" {
+				t.Fatalf("\t%s\tOther is invalid, got \"%s\", expect \"default\".", failed, val)
+			}
+			if val := ko.String("Name"); val != "env" {
+				t.Fatalf("\t%s\tName is invalid, got \"%s\", expect \"env\".", failed, val)
+			}
+			t.Logf("\t%s\tLayers are merged.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\twatch and unwatch.", testID)
+		{
+			l := &Layers{Vendor: testKey, Product: "App"}
+			if err := l.Watch(func(k *koanf.Koanf, err error) {}); err != nil {
+				t.Fatalf("\t%s\tWatch() method failed: %v", failed, err)
+			}
+			if len(l.watched) != 4 {
+				t.Fatalf("\t%s\tExpect 4 watched layers, got %d.", failed, len(l.watched))
+			}
+			if err := l.Unwatch(); err != nil {
+				t.Fatalf("\t%s\tUnwatch() method failed: %v", failed, err)
+			}
+			if l.watched != nil {
+				t.Fatalf("\t%s\tLayers are still watched.", failed)
+			}
+			t.Logf("\t%s\tLayers are unwatched.", success)
+		}
+	}
+}
+
+func TestLayeredProvider(t *testing.T) {
+	t.Log("Testing provider of machine and user layers.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		testID := 0
+		t.Logf("\tTest %d:\tuser layer over missing machine layer.", testID)
+		{
+			// The test key exists only in HKCU
+			p := LayeredProvider(Config{
+				Path:     "SOFTWARE\\" + testKey,
+				MaxDepth: 1,
+				Defaults: map[string]interface{}{"on": uint64(5), "Other": "default"},
+			})
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read layers: %v.", failed, err)
+			}
+			if tree["on"] != uint64(1) || tree["Other"] != "default" {
+				t.Fatalf("\t%s\tLayers are not merged, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tLayers are merged.", success)
+		}
+	}
+}
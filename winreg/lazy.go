@@ -0,0 +1,174 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// Lazy is a provider which reads only the values of its key and the names
+// of its subkeys, the subkeys are read on demand by LoadSubtree(). koanf
+// keeps a copy of the tree, so a loaded subtree becomes visible to it when
+// the provider is loaded again.
+type Lazy struct {
+	top  *WinReg
+	all  *WinReg // The whole subtree, for Watch()
+	cfg  Config
+	mu   sync.Mutex
+	subs []*lazySubtree
+}
+
+type lazySubtree struct {
+	path string // Path relative to the provider's path
+	p    *WinReg
+}
+
+// LazyProvider returns a lazy provider of cfg.Path. cfg.MaxDepth limits
+// the depth of each loaded subtree, counted from its key.
+func LazyProvider(cfg Config) *Lazy {
+	top := cfg
+	top.MaxDepth = 1
+	top.MaxDepths = nil
+
+	return &Lazy{top: Provider(top), all: Provider(cfg), cfg: cfg}
+}
+
+func (l *Lazy) ReadBytes() ([]byte, error) {
+	return nil, errors.New("winreg provider does not support this method")
+}
+
+// Read reads the values of the provider's key and the subtrees loaded so
+// far. Subkeys which haven't been loaded are empty maps.
+func (l *Lazy) Read() (map[string]interface{}, error) {
+	retval, err := l.top.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := l.top.subKeyNames()
+	if err != nil {
+		if l.cfg.Defaults == nil || !errors.Is(err, registry.ErrNotExist) {
+			return nil, fmt.Errorf("unable to read registry, %w", err)
+		}
+		// Nothing has been written to the registry yet
+		names = nil
+	}
+	for _, name := range names {
+		name = l.top.mapName(l.top.path, escapeName(name))
+		if _, ok := retval[name]; !ok {
+			retval[name] = map[string]interface{}{}
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, sub := range l.subs {
+		tree, err := sub.p.Read()
+		if err != nil {
+			if errors.Is(err, registry.ErrNotExist) {
+				// The subkey has been deleted
+				continue
+			}
+			return nil, err
+		}
+		l.place(retval, sub.path, tree)
+	}
+
+	return retval, nil
+}
+
+// LoadSubtree reads the subkey path, relative to the provider's path, and
+// returns its tree. The subtree is included in all following reads.
+func (l *Lazy) LoadSubtree(path string) (map[string]interface{}, error) {
+	path = strings.Trim(path, "\\")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, sub := range l.subs {
+		if strings.EqualFold(sub.path, path) {
+			return sub.p.Read()
+		}
+	}
+
+	cfg := l.cfg
+	cfg.Path = joinPath(l.cfg.Path, path)
+	cfg.Defaults = nil
+	sub := &lazySubtree{path: path, p: Provider(cfg)}
+	tree, err := sub.p.Read()
+	if err != nil {
+		return nil, err
+	}
+	l.subs = append(l.subs, sub)
+
+	return tree, nil
+}
+
+// place puts tree into retval at the subkey path, creating the maps of
+// its parents.
+func (l *Lazy) place(retval map[string]interface{}, path string, tree map[string]interface{}) {
+	parent, names := l.top.path, strings.Split(path, "\\")
+	for _, name := range names[:len(names)-1] {
+		key := l.top.mapName(parent, escapeName(name))
+		sub, ok := retval[key].(map[string]interface{})
+		if !ok {
+			sub = map[string]interface{}{}
+			retval[key] = sub
+		}
+		retval, parent = sub, joinPath(parent, name)
+	}
+	retval[l.top.mapName(parent, escapeName(names[len(names)-1]))] = tree
+}
+
+// Watch watches the whole subtree of the provider's key, as
+// WinReg.Watch() does.
+func (l *Lazy) Watch(cb func(event interface{}, err error)) error {
+	return l.all.Watch(cb)
+}
+
+// Unwatch stops watching the provider's key.
+func (l *Lazy) Unwatch() error {
+	return l.all.Unwatch()
+}
+
+// subKeyNames returns the names of the selected subkeys of the provider's
+// key.
+func (s *WinReg) subKeyNames() ([]string, error) {
+	if err := s.singleKey(); err != nil {
+		return nil, err
+	}
+	root, err := s.connect()
+	if err != nil {
+		return nil, err
+	}
+	if root != s.key {
+		defer s.api.CloseKey(root)
+	}
+
+	s.limiter.Wait()
+	k, err := s.api.OpenKey(root, s.path, s.getAccess(registry.ENUMERATE_SUB_KEYS))
+	if err != nil {
+		return nil, s.keyError("open", s.path, "", err)
+	}
+	defer s.api.CloseKey(k)
+
+	s.limiter.Wait()
+	names, err := s.api.ReadSubKeyNames(k)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, s.keyError("enumerate", s.path, "", err)
+	}
+
+	retval := names[:0]
+	for _, name := range names {
+		if s.keySelected(joinPath(s.path, name)) {
+			retval = append(retval, name)
+		}
+	}
+
+	return retval, nil
+}
@@ -0,0 +1,51 @@
+//go:build windows
+
+package winreg
+
+import (
+	"testing"
+)
+
+func TestLazyProvider(t *testing.T) {
+	t.Log("Testing lazy loading of subtrees.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		p := LazyProvider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey})
+
+		testID := 0
+		t.Logf("\tTest %d:\ttop level.", testID)
+		{
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read: %v.", failed, err)
+			}
+			sub, ok := tree["SubKeyA"].(map[string]interface{})
+			if tree["on"] != uint64(1) || !ok || len(sub) != 0 {
+				t.Fatalf("\t%s\tTop level is invalid, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tOnly the top level is read.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tloaded subtree.", testID)
+		{
+			sub, err := p.LoadSubtree("SubKeyA")
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to load subtree: %v.", failed, err)
+			}
+			if sub["IntVal"] != uint64(4000000000) {
+				t.Fatalf("\t%s\tSubtree is invalid, got %v.", failed, sub)
+			}
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read: %v.", failed, err)
+			}
+			if sub, _ := tree["SubKeyA"].(map[string]interface{}); sub["IntVal"] != uint64(4000000000) {
+				t.Fatalf("\t%s\tLoaded subtree is not read, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tLoaded subtree is read.", success)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+//go:build windows
+
+package winreg
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter limits the rate of registry API calls made by providers,
+// so large scans don't saturate filter drivers or the Remote Registry
+// service. One limiter can be shared by several providers to give them
+// a common budget.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // Calls per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a limiter allowing perSecond calls per second
+// on average and up to burst calls at once.
+func NewRateLimiter(perSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until the next call is allowed. A nil limiter never blocks.
+func (l *RateLimiter) Wait() {
+	if l == nil || l.rate <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	// Tokens may go negative, which reserves a slot for this caller
+	l.tokens--
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
@@ -0,0 +1,49 @@
+//go:build windows
+
+package winreg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Log("Testing registry call rate limiter.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tburst is not delayed.", testID)
+		{
+			l := NewRateLimiter(1, 5)
+			start := time.Now()
+			for i := 0; i < 5; i++ {
+				l.Wait()
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Fatalf("\t%s\tBurst took %v.", failed, elapsed)
+			}
+			t.Logf("\t%s\tBurst was not delayed.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\trate is limited.", testID)
+		{
+			l := NewRateLimiter(100, 1)
+			start := time.Now()
+			for i := 0; i < 11; i++ {
+				l.Wait()
+			}
+			if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+				t.Fatalf("\t%s\t11 calls at 100/s took only %v.", failed, elapsed)
+			}
+			t.Logf("\t%s\tCalls were delayed.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tnil limiter.", testID)
+		{
+			var l *RateLimiter
+			l.Wait()
+			t.Logf("\t%s\tNil limiter doesn't block.", success)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+//go:build windows
+
+package winreg
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// symbolicLinkValue is the value holding the target of a link key.
+const symbolicLinkValue = "SymbolicLinkValue"
+
+// Handling of link keys by Read(), see Config.Links.
+const (
+	LinksDefault = iota // Links are read as their targets, without detection
+	LinksFollow         // Links are read as their targets, links to keys already being read are left out
+	LinksSkip           // Links are left out
+	LinksValue          // Links are read as string values of their targets, e.g. "HKLM\SYSTEM\ControlSet001"
+)
+
+// CreateLink creates the link key path, relative to the provider's path,
+// pointing to target, a full key name like "HKLM\SOFTWARE\Vendor\New".
+// Keys opened through the link are the target's, which makes it an alias
+// of a key moved to a new path. The link must not exist yet.
+//
+// Links are resolved by the kernel, so the target is stored as a native
+// path; HKCU is the key of the user creating the link.
+func (s *WinReg) CreateLink(path, target string) error {
+	if err := s.singleKey(); err != nil {
+		return err
+	}
+	nativeTarget, err := nativeKeyPath(target)
+	if err != nil {
+		return err
+	}
+
+	root, err := s.connect()
+	if err != nil {
+		return err
+	}
+	if root != s.key {
+		defer s.api.CloseKey(root)
+	}
+
+	full := joinPath(s.path, path)
+	s.limiter.Wait()
+	k, err := s.api.CreateLinkKey(root, full, s.getAccess(registry.SET_VALUE|KEY_CREATE_LINK))
+	if err != nil {
+		return s.keyError("create", full, "", err)
+	}
+	defer s.api.CloseKey(k)
+
+	// Stored without the terminating NUL
+	s.limiter.Wait()
+	if err = s.api.SetValue(k, symbolicLinkValue, registry.LINK, utf16Bytes(utf16.Encode([]rune(nativeTarget)))); err != nil {
+		s.api.DeleteLinkKey(root, full)
+		return s.keyError("write", full, symbolicLinkValue, err)
+	}
+
+	return nil
+}
+
+// DeleteLink deletes the link key path, relative to the provider's path,
+// leaving its target intact.
+func (s *WinReg) DeleteLink(path string) error {
+	if err := s.singleKey(); err != nil {
+		return err
+	}
+	root, err := s.connect()
+	if err != nil {
+		return err
+	}
+	if root != s.key {
+		defer s.api.CloseKey(root)
+	}
+
+	full := joinPath(s.path, path)
+	s.limiter.Wait()
+	if err = s.api.DeleteLinkKey(root, full); err != nil {
+		return s.keyError("delete", full, "", err)
+	}
+
+	return nil
+}
+
+// linkedSubKey handles the subkey path if it's a link. It returns whether
+// the subkey is handled, and the include chain to read the target with.
+func (s *WinReg) linkedSubKey(root registry.Key, path, name string, tree map[string]interface{}, includes []string) (bool, []string, error) {
+	s.limiter.Wait()
+	target, isLink, err := s.api.LinkTarget(root, path, s.getAccess(registry.QUERY_VALUE))
+	if err != nil || !isLink {
+		return false, includes, err
+	}
+
+	switch s.links {
+	case LinksSkip:
+		return true, includes, nil
+	case LinksValue:
+		tree[name] = keyNameOfNative(target)
+		return true, includes, nil
+	default:
+		id := "LINK:" + strings.ToUpper(target)
+		for _, include := range includes {
+			if include == id {
+				// The target is already being read
+				return true, includes, nil
+			}
+		}
+		chain := make([]string, len(includes), len(includes)+1)
+		copy(chain, includes)
+		return false, append(chain, id), nil
+	}
+}
+
+// keyNameOfNative converts the native path of a key to a full key name,
+// e.g. "\Registry\Machine\SOFTWARE" to "HKLM\SOFTWARE". Other paths are
+// returned as they are.
+func keyNameOfNative(path string) string {
+	for _, root := range []struct{ native, name string }{
+		{`\Registry\Machine`, "HKLM"},
+		{`\Registry\User`, "HKU"},
+	} {
+		if strings.EqualFold(path, root.native) {
+			return root.name
+		}
+		if len(path) > len(root.native) && path[len(root.native)] == '\\' && strings.EqualFold(path[:len(root.native)], root.native) {
+			return root.name + path[len(root.native):]
+		}
+	}
+
+	return path
+}
+
+// nativeKeyPath converts a full key name to the native path of the key,
+// e.g. "HKLM\SOFTWARE" to "\Registry\Machine\SOFTWARE".
+func nativeKeyPath(name string) (string, error) {
+	key, path, ok := parseKeyName(name)
+	if !ok {
+		return "", fmt.Errorf("invalid key name %s", name)
+	}
+	path = strings.Trim(path, `\`)
+
+	if root, ok := ntRoots[key]; ok {
+		return joinPath(root, path), nil
+	}
+	if key == CURRENT_USER {
+		user, err := windows.GetCurrentProcessToken().GetTokenUser()
+		if err != nil {
+			return "", fmt.Errorf("unable to get current user: %w", err)
+		}
+		return joinPath(`\Registry\User\`+user.User.Sid.String(), path), nil
+	}
+
+	return "", fmt.Errorf("links to %s are not supported", name)
+}
@@ -0,0 +1,115 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/windows"
+)
+
+func TestCreateLink(t *testing.T) {
+	t.Log("Testing link keys.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey})
+		if err := p.CreateLink("Alias", "HKCU\\SOFTWARE\\"+testKey+"\\SubKeyA"); err != nil {
+			t.Fatalf("\t%s\tUnable to create link: %v.", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\treading through the link.", testID)
+		{
+			tree, err := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey + "\\Alias"}).Read()
+			if err != nil {
+				p.DeleteLink("Alias")
+				t.Fatalf("\t%s\tUnable to read link: %v.", failed, err)
+			}
+			if tree["StrValue"] != "The quick brown fox jumps over the lazy dog" {
+				p.DeleteLink("Alias")
+				t.Fatalf("\t%s\tLink target is invalid: %v.", failed, tree)
+			}
+			t.Logf("\t%s\tTarget is read.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\texisting link.", testID)
+		{
+			if err := p.CreateLink("Alias", "HKCU\\SOFTWARE\\"+testKey+"\\SubKeyB"); !errors.Is(err, windows.ERROR_ALREADY_EXISTS) {
+				p.DeleteLink("Alias")
+				t.Fatalf("\t%s\tCreateLink() should fail with ERROR_ALREADY_EXISTS, got: %v.", failed, err)
+			}
+			t.Logf("\t%s\tExisting key is not replaced.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tdeleting the link.", testID)
+		{
+			if err := p.DeleteLink("Alias"); err != nil {
+				t.Fatalf("\t%s\tUnable to delete link: %v.", failed, err)
+			}
+			if !p.keyExists(CURRENT_USER, "SOFTWARE\\"+testKey+"\\SubKeyA") || p.keyExists(CURRENT_USER, "SOFTWARE\\"+testKey+"\\Alias") {
+				t.Fatalf("\t%s\tLink is not deleted or target is deleted.", failed)
+			}
+			t.Logf("\t%s\tOnly the link is deleted.", success)
+		}
+	}
+}
+
+func TestLinks(t *testing.T) {
+	t.Log("Testing handling of link keys.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		// The link points to its own parent
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey})
+		if err := p.CreateLink("SubKeyA\\Loop", "HKCU\\SOFTWARE\\"+testKey+"\\SubKeyA"); err != nil {
+			t.Fatalf("\t%s\tUnable to create link: %v.", failed, err)
+		}
+		defer p.DeleteLink("SubKeyA\\Loop")
+
+		read := func(links int) map[string]interface{} {
+			tree, err := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, Links: links}).Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			sub, _ := tree["SubKeyA"].(map[string]interface{})
+			return sub
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tfollowed links.", testID)
+		{
+			sub := read(LinksFollow)
+			loop, ok := sub["Loop"].(map[string]interface{})
+			if !ok || loop["StrValue"] != "The quick brown fox jumps over the lazy dog" || loop["Loop"] != nil {
+				t.Fatalf("\t%s\tLink is not followed once, got %v.", failed, sub["Loop"])
+			}
+			t.Logf("\t%s\tLoop is stopped.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tskipped links.", testID)
+		{
+			if sub := read(LinksSkip); sub["Loop"] != nil || sub["StrValue"] == nil {
+				t.Fatalf("\t%s\tLink is not skipped, got %v.", failed, sub)
+			}
+			t.Logf("\t%s\tLink is skipped.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tlinks as values.", testID)
+		{
+			target, _ := read(LinksValue)["Loop"].(string)
+			if !strings.HasPrefix(target, "HKU\\") || !strings.HasSuffix(strings.ToUpper(target), strings.ToUpper("\\SOFTWARE\\"+testKey+"\\SubKeyA")) {
+				t.Fatalf("\t%s\tLink target is invalid, got %q.", failed, target)
+			}
+			t.Logf("\t%s\tTarget is returned.", success)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ErrUnexpectedValues is returned by Read() of a strict provider when the
+// tree contains values which are not listed in Config.Expected.
+var ErrUnexpectedValues = errors.New("unexpected registry values")
+
+// Unexpected returns paths of values found by the last Read() which are
+// not listed in Config.Expected, sorted. It is always empty if no list of
+// expected values was configured.
+func (s *WinReg) Unexpected() []string {
+	return s.unexpected
+}
+
+// findUnexpected returns sorted paths of the tree values which are not
+// expected. Registry names are case-insensitive, so is the comparison.
+func (s *WinReg) findUnexpected(tree map[string]interface{}) []string {
+	flat := make(map[string]interface{})
+	flattenTree("", tree, flat)
+
+	var retval []string
+	for path := range flat {
+		if !s.expected[strings.ToUpper(path)] {
+			retval = append(retval, path)
+		}
+	}
+	sort.Strings(retval)
+
+	return retval
+}
@@ -0,0 +1,47 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestExpectedValues(t *testing.T) {
+	t.Log("Testing expected values manifest.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		testID := 0
+		t.Logf("\tTest %d:\treport unexpected values.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, MaxDepth: 1, Expected: []string{"ON"}})
+			if _, err := p.Read(); err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if val := p.Unexpected(); !reflect.DeepEqual(val, []string{"off"}) {
+				t.Fatalf("\t%s\tUnexpected values are invalid, got %v, expect [off].", failed, val)
+			}
+			t.Logf("\t%s\tUnexpected values are reported.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tstrict mode.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, MaxDepth: 1, Expected: []string{"on"}, Strict: true})
+			if _, err := p.Read(); !errors.Is(err, ErrUnexpectedValues) {
+				t.Fatalf("\t%s\tRead() should fail with ErrUnexpectedValues, got: %v.", failed, err)
+			}
+			p = Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, MaxDepth: 1, Expected: []string{"on", "off"}, Strict: true})
+			if _, err := p.Read(); err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if val := p.Unexpected(); len(val) != 0 {
+				t.Fatalf("\t%s\tNo values should be unexpected, got %v.", failed, val)
+			}
+			t.Logf("\t%s\tStrict mode works.", success)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// ErrMemoryRemote is returned when a provider of an in-memory registry is
+// configured with a remote host.
+var ErrMemoryRemote = errors.New("in-memory registry has no remote hosts")
+
+// Memory is a registry held in memory, used by providers configured with
+// Config.Memory instead of the Windows registry. Applications can test
+// loading, writing and watching their configuration without touching the
+// real registry. Each predefined key is an empty hive until filled by
+// Load() or by writes of providers. Notifications are signaled for any
+// change of a watched key, whatever the filter. Link keys, class names
+// and MUI strings are not supported.
+type Memory struct {
+	sysAPI
+
+	mu      sync.Mutex
+	hives   map[registry.Key]*memKey
+	handles map[registry.Key]*memKey
+	next    registry.Key
+	notify  []memNotify
+}
+
+// memNotify is a pending NotifyChangeKeyValue() of a key.
+type memNotify struct {
+	key     *memKey
+	subtree bool
+	event   windows.Handle
+}
+
+// NewMemory returns an empty in-memory registry.
+func NewMemory() *Memory {
+	return &Memory{hives: make(map[registry.Key]*memKey), handles: make(map[registry.Key]*memKey), next: 1}
+}
+
+// Load creates the key path of the predefined key and stores the values
+// of the nested tree in it, Go types are stored as by WinReg.Apply().
+// Nested maps are subkeys.
+func (m *Memory) Load(key registry.Key, path string, tree map[string]interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := m.hive(key)
+	for _, name := range strings.Split(strings.Trim(path, "\\"), "\\") {
+		if name != "" {
+			k = k.addSubKey(name, newMemKey())
+		}
+	}
+	if err := m.load(k, tree); err != nil {
+		return fmt.Errorf("%s: %w", keyName(key, path), err)
+	}
+
+	return nil
+}
+
+func (m *Memory) load(k *memKey, tree map[string]interface{}) error {
+	for name, value := range tree {
+		if sub, ok := value.(map[string]interface{}); ok {
+			if err := m.load(k.addSubKey(name, newMemKey()), sub); err != nil {
+				return fmt.Errorf("%s\\%w", name, err)
+			}
+			continue
+		}
+		typ, data, err := encodeValue(value)
+		if err != nil {
+			return fmt.Errorf("%s, %w", name, err)
+		}
+		k.setValue(name, memValue{typ: typ, data: data})
+	}
+	m.changed(k)
+
+	return nil
+}
+
+// hive returns the root of the predefined key, creating it on first use.
+func (m *Memory) hive(key registry.Key) *memKey {
+	root, ok := m.hives[key]
+	if !ok {
+		root = newMemKey()
+		m.hives[key] = root
+	}
+
+	return root
+}
+
+// lookup returns the key of a handle or a predefined key, m.mu must be
+// held.
+func (m *Memory) lookup(k registry.Key) (*memKey, error) {
+	if key, ok := m.handles[k]; ok {
+		if key.deleted {
+			return nil, windows.ERROR_KEY_DELETED
+		}
+		return key, nil
+	}
+	if k >= 0x80000000 {
+		return m.hive(k), nil
+	}
+
+	return nil, windows.ERROR_INVALID_HANDLE
+}
+
+// open returns a new handle of key, m.mu must be held.
+func (m *Memory) open(key *memKey) registry.Key {
+	// Below predefined keys, which start at 0x80000000
+	retval := m.next
+	m.next++
+	m.handles[retval] = key
+
+	return retval
+}
+
+// changed signals and removes the notifications of key and of the keys
+// watching their subtree, m.mu must be held.
+func (m *Memory) changed(key *memKey) {
+	pending := m.notify[:0]
+	for _, n := range m.notify {
+		signal := n.key == key
+		for k := key.parent; !signal && n.subtree && k != nil; k = k.parent {
+			signal = n.key == k
+		}
+		if signal {
+			windows.SetEvent(n.event)
+			continue
+		}
+		pending = append(pending, n)
+	}
+	m.notify = pending
+}
+
+func (m *Memory) OpenKey(k registry.Key, path string, access uint32) (registry.Key, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, err := m.lookup(k)
+	if err != nil {
+		return 0, err
+	}
+	for _, name := range strings.Split(strings.Trim(path, "\\"), "\\") {
+		if name == "" {
+			continue
+		}
+		if key = key.subKeys[strings.ToUpper(name)]; key == nil {
+			return 0, windows.ERROR_FILE_NOT_FOUND
+		}
+	}
+
+	return m.open(key), nil
+}
+
+func (m *Memory) OpenRemoteKey(host string, k registry.Key) (registry.Key, error) {
+	return 0, ErrMemoryRemote
+}
+
+func (m *Memory) CloseKey(k registry.Key) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.handles, k)
+
+	return nil
+}
+
+func (m *Memory) ReadValueNames(k registry.Key) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, err := m.lookup(k)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), key.names...), nil
+}
+
+func (m *Memory) ReadSubKeyNames(k registry.Key) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, err := m.lookup(k)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), key.subs...), nil
+}
+
+func (m *Memory) GetValue(k registry.Key, name string, buf []byte) (int, uint32, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, err := m.lookup(k)
+	if err != nil {
+		return 0, 0, err
+	}
+	v, ok := key.values[strings.ToUpper(name)]
+	if !ok {
+		return 0, 0, windows.ERROR_FILE_NOT_FOUND
+	}
+	if len(buf) < len(v.data) {
+		return len(v.data), v.typ, registry.ErrShortBuffer
+	}
+
+	return copy(buf, v.data), v.typ, nil
+}
+
+func (m *Memory) GetStringValue(k registry.Key, name string) (string, uint32, error) {
+	return storedString(m.GetValue, k, name)
+}
+
+func (m *Memory) GetStringsValue(k registry.Key, name string) ([]string, uint32, error) {
+	return storedStrings(m.GetValue, k, name)
+}
+
+func (m *Memory) GetIntegerValue(k registry.Key, name string) (uint64, uint32, error) {
+	return storedInteger(m.GetValue, k, name)
+}
+
+func (m *Memory) GetBinaryValue(k registry.Key, name string) ([]byte, uint32, error) {
+	return storedData(m.GetValue, k, name, registry.BINARY)
+}
+
+func (m *Memory) GetMUIStringValue(k registry.Key, name string) (string, error) {
+	return "", windows.ERROR_NOT_SUPPORTED
+}
+
+func (m *Memory) GetKeyClass(k registry.Key) (string, error) {
+	return "", nil
+}
+
+func (m *Memory) NotifyChangeKeyValue(k registry.Key, watchSubtree bool, filter uint32, event windows.Handle) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, err := m.lookup(k)
+	if err != nil {
+		return err
+	}
+	m.notify = append(m.notify, memNotify{key: key, subtree: watchSubtree, event: event})
+
+	return nil
+}
+
+func (m *Memory) CreateKey(k registry.Key, path string, options, access uint32) (registry.Key, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, err := m.lookup(k)
+	if err != nil {
+		return 0, err
+	}
+	for _, name := range strings.Split(strings.Trim(path, "\\"), "\\") {
+		if name == "" {
+			continue
+		}
+		sub, ok := key.subKeys[strings.ToUpper(name)]
+		if !ok {
+			sub = key.addSubKey(name, newMemKey())
+			m.changed(key)
+		}
+		key = sub
+	}
+
+	return m.open(key), nil
+}
+
+func (m *Memory) SetValue(k registry.Key, name string, valtype uint32, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, err := m.lookup(k)
+	if err != nil {
+		return err
+	}
+	key.setValue(name, memValue{typ: valtype, data: append([]byte(nil), data...)})
+	m.changed(key)
+
+	return nil
+}
+
+func (m *Memory) DeleteValue(k registry.Key, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, err := m.lookup(k)
+	if err != nil {
+		return err
+	}
+	if !key.deleteValue(name) {
+		return windows.ERROR_FILE_NOT_FOUND
+	}
+	m.changed(key)
+
+	return nil
+}
+
+// DeleteKey deletes the subkey path of k, which must not have subkeys.
+func (m *Memory) DeleteKey(k registry.Key, path string, access uint32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	parent, err := m.lookup(k)
+	if err != nil {
+		return err
+	}
+	parentPath, name := splitParent(strings.Trim(path, "\\"))
+	for _, sub := range strings.Split(parentPath, "\\") {
+		if sub == "" {
+			continue
+		}
+		if parent = parent.subKeys[strings.ToUpper(sub)]; parent == nil {
+			return windows.ERROR_FILE_NOT_FOUND
+		}
+	}
+	key := parent.subKeys[strings.ToUpper(name)]
+	switch {
+	case key == nil:
+		return windows.ERROR_FILE_NOT_FOUND
+	case len(key.subs) > 0:
+		return windows.ERROR_ACCESS_DENIED
+	}
+
+	m.changed(key)
+	parent.removeSubKey(name)
+	key.deleted = true
+	m.changed(parent)
+
+	return nil
+}
+
+func (m *Memory) FlushKey(k registry.Key) error {
+	return nil
+}
+
+func (m *Memory) CreateLinkKey(k registry.Key, path string, access uint32) (registry.Key, error) {
+	return 0, windows.ERROR_NOT_SUPPORTED
+}
+
+func (m *Memory) DeleteLinkKey(k registry.Key, path string) error {
+	return windows.ERROR_NOT_SUPPORTED
+}
+
+func (m *Memory) LinkTarget(k registry.Key, path string, access uint32) (string, bool, error) {
+	return "", false, nil
+}
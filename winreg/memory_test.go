@@ -0,0 +1,91 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestMemory(t *testing.T) {
+	t.Log("Testing in-memory registry.")
+	{
+		const eventTimeout = 5
+		m := NewMemory()
+		err := m.Load(CURRENT_USER, "SOFTWARE\\Vendor\\App", map[string]interface{}{
+			"Name":   "app",
+			"Size":   uint32(800),
+			"Window": map[string]interface{}{"Title": "main"},
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to load memory registry: %v", failed, err)
+		}
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\Vendor\\App", Memory: m})
+
+		testID := 0
+		t.Logf("\tTest %d:\treading.", testID)
+		{
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			expected := map[string]interface{}{
+				"Name":   "app",
+				"Size":   uint64(800),
+				"Window": map[string]interface{}{"Title": "main"},
+			}
+			if !reflect.DeepEqual(tree, expected) {
+				t.Fatalf("\t%s\tTree is invalid, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tTree is read.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\twatching writes.", testID)
+		{
+			events := make(chan error, 10)
+			if err := p.Watch(func(event interface{}, err error) { events <- err }); err != nil {
+				t.Fatalf("\t%s\tWatch() method failed: %v", failed, err)
+			}
+			defer p.Unwatch()
+
+			if err := p.Set("Window\\Title", "changed"); err != nil {
+				t.Fatalf("\t%s\tUnable to write value: %v.", failed, err)
+			}
+			select {
+			case err := <-events:
+				if err != nil {
+					t.Fatalf("\t%s\tWatch reported an error: %v.", failed, err)
+				}
+			case <-time.After(eventTimeout * time.Second):
+				t.Fatalf("\t%s\tTimeout exceeded while waiting for change event.", failed)
+			}
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if window, _ := tree["Window"].(map[string]interface{}); window["Title"] != "changed" {
+				t.Fatalf("\t%s\tValue is not written, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tWrite is watched.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tmissing keys.", testID)
+		{
+			missing := Provider(Config{Key: LOCAL_MACHINE, Path: "SOFTWARE\\Vendor\\App", Memory: m})
+			if _, err := missing.Read(); !errors.Is(err, registry.ErrNotExist) {
+				t.Fatalf("\t%s\tRead() should fail with ErrNotExist, got: %v.", failed, err)
+			}
+			remote := Provider(Config{Key: CURRENT_USER, Host: "server", Memory: m})
+			if _, err := remote.Read(); !errors.Is(err, ErrMemoryRemote) {
+				t.Fatalf("\t%s\tRead() should fail with ErrMemoryRemote, got: %v.", failed, err)
+			}
+			t.Logf("\t%s\tErrors are reported.", success)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+//go:build windows
+
+package winreg
+
+import (
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// resolveMUIString returns the localized string of the indirect string
+// str, the data of the value name, e.g. "@%SystemRoot%\system32\shell32.dll,-21787".
+// Strings which are not indirect, value references and strings whose
+// resource can't be loaded are returned unchanged.
+func (s *WinReg) resolveMUIString(k registry.Key, name, str string) string {
+	if !strings.HasPrefix(str, "@") || s.host != "" {
+		// RegLoadMUIString only works with the local registry
+		return str
+	}
+	if _, _, _, ok := parseReference(str); ok {
+		return str
+	}
+
+	s.limiter.Wait()
+	retval, err := s.api.GetMUIStringValue(k, name)
+	if err != nil {
+		return str
+	}
+
+	return retval
+}
@@ -0,0 +1,45 @@
+//go:build windows
+
+package winreg
+
+import (
+	"sync"
+	"time"
+)
+
+// negativeCache remembers that a key doesn't exist, so optional keys read
+// over and over don't hammer the registry and fill the audit log.
+type negativeCache struct {
+	mu    sync.Mutex
+	err   error
+	until time.Time
+}
+
+// get returns the cached not-found error, nil if there is none or it has
+// expired by now.
+func (c *negativeCache) get(now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err == nil || !now.Before(c.until) {
+		c.err = nil
+		return nil
+	}
+
+	return c.err
+}
+
+// put remembers err until the given time.
+func (c *negativeCache) put(err error, until time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+	c.until = until
+}
+
+// reset forgets the cached error, e.g. when the key has been created.
+func (c *negativeCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = nil
+}
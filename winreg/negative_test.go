@@ -0,0 +1,86 @@
+//go:build windows
+
+package winreg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMissingTTL(t *testing.T) {
+	t.Log("Testing negative lookup caching.")
+	{
+		const eventTimeout = 5
+		deleteTestData(t)
+		defer deleteTestData(t)
+
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, MissingTTL: time.Hour, WatchMissing: true})
+		if _, err := p.Read(); err == nil {
+			t.Fatalf("\t%s\tRead() of a missing key should fail.", failed)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tcached missing key.", testID)
+		{
+			createTestData(t)
+			if _, err := p.Read(); err == nil {
+				t.Fatalf("\t%s\tMissing key was not cached.", failed)
+			}
+			t.Logf("\t%s\tMissing key is cached.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tinvalidation by watch.", testID)
+		{
+			deleteTestData(t)
+			created := make(chan bool, 10)
+			err := p.Watch(func(event interface{}, err error) {
+				if _, ok := event.(*CreatedEvent); ok {
+					created <- true
+				}
+			})
+			if err != nil {
+				t.Fatalf("\t%s\tWatch() method failed: %v", failed, err)
+			}
+			createTestData(t)
+
+			select {
+			case <-created:
+			case <-time.After(eventTimeout * time.Second):
+				t.Fatalf("\t%s\tTimeout exceeded while waiting for created event.", failed)
+			}
+			if _, err := p.Read(); err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			t.Logf("\t%s\tCache is invalidated.", success)
+		}
+	}
+}
+
+func TestMissingTTLCopies(t *testing.T) {
+	t.Log("Testing negative lookup caching of provider copies.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tcopies have their own cache.", testID)
+		{
+			for _, cfg := range []Config{
+				{Key: CURRENT_USER, Path: "SOFTWARE\\Vendor", MissingTTL: time.Hour},
+				{Key: CURRENT_USER, Paths: []string{"SOFTWARE\\Vendor"}, MissingTTL: time.Hour},
+			} {
+				m := NewMemory()
+				cfg.Memory = m
+				p := Provider(cfg)
+				if _, err := p.readCopy().Read(); err == nil {
+					t.Fatalf("\t%s\tRead() of a missing key should fail.", failed)
+				}
+				if err := m.Load(CURRENT_USER, "SOFTWARE\\Vendor", map[string]interface{}{"Name": "vendor"}); err != nil {
+					t.Fatalf("\t%s\tUnable to load memory registry: %v", failed, err)
+				}
+				if _, err := p.Read(); err != nil {
+					t.Fatalf("\t%s\tMissing key of a copy hides the key: %v.", failed, err)
+				}
+			}
+			t.Logf("\t%s\tCaches are not shared.", success)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+//go:build windows
+
+package winreg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// Notification is a change notification of the provider's key armed
+// without a goroutine waiting for it. The caller waits for Event itself,
+// e.g. with WaitForMultipleObjects along with other handles, and calls
+// Rearm() after each signal.
+//
+// Without SystemCapabilities().ThreadAgnosticNotify, the notification is
+// cancelled when the OS thread which armed it exits, so Notify() and
+// Rearm() should be called from a thread locked by runtime.LockOSThread().
+type Notification struct {
+	Event windows.Handle // Manual-reset event signaled when the key changes
+
+	api     regAPI
+	key     registry.Key
+	subtree bool
+	filter  uint32
+}
+
+// Notify opens the provider's key and arms a change notification of it.
+// The notification must be closed by Close().
+func (s *WinReg) Notify() (*Notification, error) {
+	if err := s.singleKey(); err != nil {
+		return nil, err
+	}
+	if isPerformanceKey(s.key) {
+		return nil, fmt.Errorf("failed to watch %s: %w", s.getKeyName(s.path), ErrPerformanceData)
+	}
+	if s.host != "" {
+		return nil, errors.New("notifications are not supported by remote registry")
+	}
+
+	k, err := s.api.OpenKey(s.key, s.path, s.getAccess(registry.NOTIFY))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open registry key %w", s.keyError("open", s.path, "", err))
+	}
+	event, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		s.api.CloseKey(k)
+		return nil, fmt.Errorf("watch failed: %w", err)
+	}
+
+	n := &Notification{Event: event, api: s.api, key: k, subtree: s.maxDepth != 1, filter: s.notifyFilter}
+	if err = n.arm(); err != nil {
+		n.Close()
+		return nil, err
+	}
+
+	return n, nil
+}
+
+// WaitForChange blocks until the provider's key changes or ctx is done,
+// in which case ctx.Err() is returned.
+func (s *WinReg) WaitForChange(ctx context.Context) error {
+	// The notification must outlive the thread which armed it
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	n, err := s.Notify()
+	if err != nil {
+		return err
+	}
+	defer n.Close()
+
+	cancel, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return fmt.Errorf("watch failed: %w", err)
+	}
+	defer windows.Close(cancel)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			windows.SetEvent(cancel)
+		case <-done:
+		}
+	}()
+
+	waitResult, err := windows.WaitForMultipleObjects([]windows.Handle{n.Event, cancel}, false, windows.INFINITE)
+	switch {
+	case err != nil:
+		return fmt.Errorf("watch failed: %w", err)
+	case waitResult == windows.WAIT_OBJECT_0:
+		return nil
+	default:
+		return ctx.Err()
+	}
+}
+
+// Rearm resets Event and requests the next notification. Changes made
+// between the signal and Rearm() are not reported, so the key should be
+// read after Rearm().
+func (n *Notification) Rearm() error {
+	if err := windows.ResetEvent(n.Event); err != nil {
+		return fmt.Errorf("watch failed: %w", err)
+	}
+
+	return n.arm()
+}
+
+// Close stops the notification and closes Event.
+func (n *Notification) Close() error {
+	err := n.api.CloseKey(n.key)
+	windows.Close(n.Event)
+
+	return err
+}
+
+func (n *Notification) arm() error {
+	if err := n.api.NotifyChangeKeyValue(n.key, n.subtree, n.filter, n.Event); err != nil {
+		return fmt.Errorf("watch failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+//go:build windows
+
+package winreg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestNotify(t *testing.T) {
+	t.Log("Testing externally waited notifications.")
+	{
+		const eventTimeout = 5000
+		createTestData(t)
+		defer deleteTestData(t)
+
+		n, err := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey}).Notify()
+		if err != nil {
+			t.Fatalf("\t%s\tNotify() method failed: %v", failed, err)
+		}
+		defer n.Close()
+
+		r, err := registry.OpenKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey, registry.ALL_ACCESS)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to open registry key: %v", failed, err)
+		}
+		defer r.Close()
+
+		for testID, value := range []uint32{2, 3} {
+			t.Logf("\tTest %d:\twaiting for change %d.", testID, value)
+			{
+				if err := r.SetDWordValue("on", value); err != nil {
+					t.Fatalf("\t%s\tUnable to change value \"on\": %v", failed, err)
+				}
+				if result, err := windows.WaitForSingleObject(n.Event, eventTimeout); err != nil || result != windows.WAIT_OBJECT_0 {
+					t.Fatalf("\t%s\tEvent was not set: %d, %v.", failed, result, err)
+				}
+				if err := n.Rearm(); err != nil {
+					t.Fatalf("\t%s\tRearm() method failed: %v", failed, err)
+				}
+				t.Logf("\t%s\tEvent is set.", success)
+			}
+		}
+	}
+}
+
+func TestWaitForChange(t *testing.T) {
+	t.Log("Testing blocking wait for a change.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey})
+
+		testID := 0
+		t.Logf("\tTest %d:\tkey is changed.", testID)
+		{
+			go func() {
+				time.Sleep(100 * time.Millisecond)
+				if r, err := registry.OpenKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey, registry.SET_VALUE); err == nil {
+					r.SetDWordValue("on", 2)
+					r.Close()
+				}
+			}()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := p.WaitForChange(ctx); err != nil {
+				t.Fatalf("\t%s\tWaitForChange() method failed: %v", failed, err)
+			}
+			t.Logf("\t%s\tChange is reported.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tcontext is done.", testID)
+		{
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			if err := p.WaitForChange(ctx); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("\t%s\tWaitForChange() should fail with DeadlineExceeded, got: %v", failed, err)
+			}
+			t.Logf("\t%s\tDeadline is reported.", success)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+//go:build windows
+
+package winreg
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// Registry APIs used by providers, see Config.Backend.
+const (
+	BackendWin32 = iota
+	BackendNT
+)
+
+// ntAPI is the native NT registry API. Keys are opened by NtOpenKey and
+// enumerated by NtEnumerateKey and NtEnumerateValueKey, the rest is shared
+// with sysAPI: handles of the native API are valid for Win32 calls too.
+//
+// Names are passed and returned as counted strings, so keys with names the
+// Win32 API can't express are accessible. Registry redirection of the
+// 32-bit view is done by the Win32 layer and doesn't apply; classes root is
+// the machine-wide HKLM\SOFTWARE\Classes only, without per-user classes.
+type ntAPI struct {
+	sysAPI
+}
+
+// ntRoots maps predefined keys to native paths. CURRENT_USER is opened by
+// RtlOpenCurrentUser.
+var ntRoots = map[registry.Key]string{
+	registry.LOCAL_MACHINE:  `\Registry\Machine`,
+	registry.USERS:          `\Registry\User`,
+	registry.CLASSES_ROOT:   `\Registry\Machine\SOFTWARE\Classes`,
+	registry.CURRENT_CONFIG: `\Registry\Machine\SYSTEM\CurrentControlSet\Hardware Profiles\Current`,
+}
+
+// ErrRemoteNT is returned when the NT backend is used with a remote registry.
+var ErrRemoteNT = errors.New("remote registry is not supported by the NT backend")
+
+func (ntAPI) OpenKey(k registry.Key, path string, access uint32) (registry.Key, error) {
+	// Redirection flags are not supported by the native API
+	access &^= registry.WOW64_32KEY | registry.WOW64_64KEY
+	path = strings.Trim(path, `\`)
+
+	if root, ok := ntRoots[k]; ok {
+		return ntOpenKey(0, joinPath(root, path), access)
+	}
+	if k == registry.CURRENT_USER {
+		user, err := rtlOpenCurrentUser(access)
+		if err != nil || path == "" {
+			return user, err
+		}
+		defer user.Close()
+		k = user
+	}
+
+	return ntOpenKey(windows.Handle(k), path, access)
+}
+
+func (ntAPI) OpenRemoteKey(host string, k registry.Key) (registry.Key, error) {
+	return 0, ErrRemoteNT
+}
+
+func (ntAPI) ReadValueNames(k registry.Key) ([]string, error) {
+	// KEY_VALUE_BASIC_INFORMATION: TitleIndex, Type, NameLength, Name
+	return ntEnumerateNames(k, ntEnumerateValueKey, 8, 12)
+}
+
+func (ntAPI) ReadSubKeyNames(k registry.Key) ([]string, error) {
+	// KEY_BASIC_INFORMATION: LastWriteTime, TitleIndex, NameLength, Name
+	return ntEnumerateNames(k, ntEnumerateKey, 12, 16)
+}
+
+// Values with NULs in their names are read by NtQueryValueKey, the Win32
+// API can't name them.
+
+func (a ntAPI) GetValue(k registry.Key, name string, buf []byte) (int, uint32, error) {
+	if !strings.ContainsRune(name, 0) {
+		return a.sysAPI.GetValue(k, name, buf)
+	}
+	return ntQueryValue(k, name, buf)
+}
+
+func (a ntAPI) GetStringValue(k registry.Key, name string) (string, uint32, error) {
+	if !strings.ContainsRune(name, 0) {
+		return a.sysAPI.GetStringValue(k, name)
+	}
+	return storedString(ntQueryValue, k, name)
+}
+
+func (a ntAPI) GetStringsValue(k registry.Key, name string) ([]string, uint32, error) {
+	if !strings.ContainsRune(name, 0) {
+		return a.sysAPI.GetStringsValue(k, name)
+	}
+	return storedStrings(ntQueryValue, k, name)
+}
+
+func (a ntAPI) GetIntegerValue(k registry.Key, name string) (uint64, uint32, error) {
+	if !strings.ContainsRune(name, 0) {
+		return a.sysAPI.GetIntegerValue(k, name)
+	}
+	return storedInteger(ntQueryValue, k, name)
+}
+
+func (a ntAPI) GetBinaryValue(k registry.Key, name string) ([]byte, uint32, error) {
+	if !strings.ContainsRune(name, 0) {
+		return a.sysAPI.GetBinaryValue(k, name)
+	}
+	return storedData(ntQueryValue, k, name, registry.BINARY)
+}
+
+// ntQueryValue behaves as registry.Key.GetValue, reading the value with the
+// native API.
+func ntQueryValue(k registry.Key, name string, buf []byte) (int, uint32, error) {
+	valueName, err := ntString(name)
+	if err != nil {
+		return 0, 0, err
+	}
+	// KEY_VALUE_PARTIAL_INFORMATION: TitleIndex, Type, DataLength, Data
+	info := make([]byte, 12+len(buf))
+	n, status := ntQueryValueKey(k, valueName, info)
+	switch status {
+	case windows.STATUS_SUCCESS:
+	case windows.STATUS_BUFFER_OVERFLOW:
+		return int(n) - 12, binary.LittleEndian.Uint32(info[4:]), registry.ErrShortBuffer
+	default:
+		return 0, 0, status.Errno()
+	}
+
+	typ := binary.LittleEndian.Uint32(info[4:])
+	length := int(binary.LittleEndian.Uint32(info[8:]))
+	copy(buf, info[12:12+length])
+	return length, typ, nil
+}
+
+// ntEnumerateNames lists names returned by enumerate, a native enumeration
+// function called with the basic information class. lengthOffset and
+// nameOffset locate the name in the returned structure.
+func ntEnumerateNames(k registry.Key, enumerate func(registry.Key, uint32, []byte) (uint32, windows.NTStatus), lengthOffset, nameOffset int) ([]string, error) {
+	var names []string
+	buf := make([]byte, 512)
+	for index := uint32(0); ; {
+		n, status := enumerate(k, index, buf)
+		switch status {
+		case windows.STATUS_SUCCESS:
+		case windows.STATUS_NO_MORE_ENTRIES:
+			return names, nil
+		case windows.STATUS_BUFFER_OVERFLOW, windows.STATUS_BUFFER_TOO_SMALL:
+			buf = make([]byte, n)
+			continue
+		default:
+			return names, status.Errno()
+		}
+
+		length := int(*(*uint32)(unsafe.Pointer(&buf[lengthOffset]))) / 2
+		name := unsafe.Slice((*uint16)(unsafe.Pointer(&buf[nameOffset])), length)
+		names = append(names, string(utf16.Decode(name)))
+		index++
+	}
+}
+
+// ntString returns s as a counted UTF-16 string, which may contain NULs.
+func ntString(s string) (*windows.NTUnicodeString, error) {
+	buf := utf16.Encode([]rune(s))
+	if len(buf) > 0x7fff {
+		return nil, windows.ERROR_FILENAME_EXCED_RANGE
+	}
+	retval := &windows.NTUnicodeString{}
+	if len(buf) > 0 {
+		retval.Length = uint16(2 * len(buf))
+		retval.MaximumLength = retval.Length
+		retval.Buffer = &buf[0]
+	}
+
+	return retval, nil
+}
+
+// escapeName replaces NULs in key and value names, which only the native
+// API returns, with a visible `\0` so hidden entries stand out.
+func escapeName(name string) string {
+	return strings.ReplaceAll(name, "\x00", `\0`)
+}
@@ -0,0 +1,127 @@
+//go:build windows
+
+package winreg
+
+import (
+	"reflect"
+	"syscall"
+	"testing"
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+var (
+	procNtCreateKey   = ntdll.NewProc("NtCreateKey")
+	procNtSetValueKey = ntdll.NewProc("NtSetValueKey")
+)
+
+// createHiddenEntries creates a subkey and a value with NULs in their names
+// under SubKeyA, returning a function deleting the subkey.
+func createHiddenEntries(t *testing.T) func() {
+	k, err := registry.OpenKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey+"\\SubKeyA", registry.ALL_ACCESS)
+	if err != nil {
+		t.Fatalf("\t%s\tUnable to open test key: %v", failed, err)
+	}
+	defer k.Close()
+
+	name, _ := ntString("Hidden\x00Key")
+	attrs := windows.OBJECT_ATTRIBUTES{RootDirectory: windows.Handle(k), ObjectName: name, Attributes: windows.OBJ_CASE_INSENSITIVE}
+	attrs.Length = uint32(unsafe.Sizeof(attrs))
+	var hidden windows.Handle
+	r0, _, _ := syscall.Syscall9(procNtCreateKey.Addr(), 7, uintptr(unsafe.Pointer(&hidden)), uintptr(registry.ALL_ACCESS), uintptr(unsafe.Pointer(&attrs)), 0, 0, 0, 0, 0, 0)
+	if r0 != 0 {
+		t.Fatalf("\t%s\tUnable to create hidden key: %v", failed, windows.NTStatus(r0))
+	}
+
+	value, _ := ntString("Hidden\x00Value")
+	data := utf16.Encode([]rune("payload\x00"))
+	r0, _, _ = syscall.Syscall6(procNtSetValueKey.Addr(), 6, uintptr(k), uintptr(unsafe.Pointer(value)), 0, uintptr(registry.SZ), uintptr(unsafe.Pointer(&data[0])), uintptr(2*len(data)))
+	if r0 != 0 {
+		syscall.Syscall(procNtDeleteKey.Addr(), 1, uintptr(hidden), 0, 0)
+		windows.Close(hidden)
+		t.Fatalf("\t%s\tUnable to create hidden value: %v", failed, windows.NTStatus(r0))
+	}
+
+	return func() {
+		syscall.Syscall(procNtDeleteKey.Addr(), 1, uintptr(hidden), 0, 0)
+		windows.Close(hidden)
+	}
+}
+
+func TestNTBackend(t *testing.T) {
+	t.Log("Testing native NT registry API.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		testID := 0
+		for _, cfg := range []Config{
+			{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey},
+			{Key: LOCAL_MACHINE, Path: "SOFTWARE\\Microsoft\\Windows NT\\CurrentVersion", MaxDepth: 1},
+		} {
+			t.Logf("\tTest %d:\t%s.", testID, keyName(cfg.Key, cfg.Path))
+			{
+				expected, err := Provider(cfg).Read()
+				if err != nil {
+					t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+				}
+				cfg.Backend = BackendNT
+				tree, err := Provider(cfg).Read()
+				if err != nil {
+					t.Fatalf("\t%s\tUnable to read registry with NT backend: %v.", failed, err)
+				}
+				if !reflect.DeepEqual(tree, expected) {
+					t.Fatalf("\t%s\tTrees differ, got %v, expect %v.", failed, tree, expected)
+				}
+				t.Logf("\t%s\tTrees are equal.", success)
+			}
+			testID++
+		}
+
+		t.Logf("\tTest %d:\tmissing key.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey + "\\Missing", Backend: BackendNT})
+			if _, err := p.Read(); err == nil || p.keyExists(CURRENT_USER, p.path) {
+				t.Fatalf("\t%s\tMissing key is read.", failed)
+			}
+			t.Logf("\t%s\tMissing key is reported.", success)
+		}
+	}
+}
+
+func TestNULNames(t *testing.T) {
+	t.Log("Testing names with embedded NULs.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+		defer createHiddenEntries(t)()
+
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, Backend: BackendNT})
+		tree, err := p.Read()
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+		}
+		sub, _ := tree["SubKeyA"].(map[string]interface{})
+
+		testID := 0
+		t.Logf("\tTest %d:\thidden key.", testID)
+		{
+			if _, ok := sub[`Hidden\0Key`].(map[string]interface{}); !ok {
+				t.Fatalf("\t%s\tHidden key is not found in %v.", failed, sub)
+			}
+			t.Logf("\t%s\tHidden key is found.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\thidden value.", testID)
+		{
+			if val := sub[`Hidden\0Value`]; val != "payload" {
+				t.Fatalf("\t%s\tHidden value is invalid, got %v.", failed, val)
+			}
+			t.Logf("\t%s\tHidden value is read.", success)
+		}
+	}
+}
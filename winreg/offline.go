@@ -0,0 +1,285 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// Hive files of an offline Windows image, relative to the image root.
+const (
+	offlineConfigDir  = "Windows\\System32\\config"
+	offlineUsersDir   = "Users"
+	offlineUserHive   = "NTUSER.DAT"
+	offlineDefaultHKU = ".DEFAULT"
+)
+
+// Machine hives which can be mapped from HKLM\<name>.
+var offlineMachineHives = map[string]string{
+	"SOFTWARE": "SOFTWARE",
+	"SYSTEM":   "SYSTEM",
+	"SAM":      "SAM",
+	"SECURITY": "SECURITY",
+}
+
+// OfflineImage provides access to the registry of a mounted offline
+// Windows image (e.g. mounted by DISM or the system drive seen from WinPE).
+// Hive files are loaded privately on demand and unloaded by Close().
+type OfflineImage struct {
+	root    string
+	staging string // If set, hive files are copied here before loading
+	owned   bool   // The staging directory was created by the image
+	mu      sync.Mutex
+	hives   map[string]registry.Key
+	staged  []string
+}
+
+// OpenOfflineImage prepares an offline image rooted at root, usually
+// a mount directory or a drive like "D:\".
+func OpenOfflineImage(root string) (*OfflineImage, error) {
+	fi, err := os.Stat(filepath.Join(root, offlineConfigDir))
+	if err != nil {
+		return nil, fmt.Errorf("not a Windows image %s: %w", root, err)
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("not a Windows image %s: %s is not a directory", root, offlineConfigDir)
+	}
+
+	return &OfflineImage{root: root, hives: make(map[string]registry.Key)}, nil
+}
+
+// Users returns the names of user profiles in the image which have
+// a registry hive. They can be used as the first HKU path component.
+func (o *OfflineImage) Users() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(o.root, offlineUsersDir))
+	if err != nil {
+		return nil, fmt.Errorf("unable to list user profiles: %w", err)
+	}
+
+	retval := []string{offlineDefaultHKU}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(o.root, offlineUsersDir, entry.Name(), offlineUserHive)); err == nil {
+			retval = append(retval, entry.Name())
+		}
+	}
+
+	return retval, nil
+}
+
+// Provider returns a provider for the offline image. Config.Key must be
+// LOCAL_MACHINE or USERS, Config.Path is interpreted as on a live system,
+// e.g. "SOFTWARE\Vendor\App" or "<profile name>\Software\Vendor\App".
+// The provider is valid until the image is closed.
+func (o *OfflineImage) Provider(cfg Config) (*WinReg, error) {
+	hive, path, err := o.mapPath(cfg.Key, cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	k, err := o.loadHive(hive)
+	if err != nil {
+		return nil, err
+	}
+
+	// Offline hives have no WOW64 redirection, so the 32-bit view is
+	// mapped to its real location.
+	if cfg.Mode == Reg32Bit && strings.EqualFold(filepath.Base(hive), "SOFTWARE") && !strings.HasPrefix(strings.ToUpper(path), "WOW6432NODE") {
+		path = joinPath("WOW6432Node", path)
+	}
+	cfg.Mode = RegAuto
+	cfg.Key = k
+	cfg.Path = path
+
+	return Provider(cfg), nil
+}
+
+// Close unloads all hives loaded from the image.
+func (o *OfflineImage) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var retval error
+	for name, k := range o.hives {
+		if err := k.Close(); err != nil && retval == nil {
+			retval = fmt.Errorf("unable to unload hive %s: %w", name, err)
+		}
+		delete(o.hives, name)
+	}
+	if o.owned {
+		if err := os.RemoveAll(o.staging); err != nil && retval == nil {
+			retval = fmt.Errorf("unable to remove staged hives: %w", err)
+		}
+	} else {
+		for _, file := range o.staged {
+			if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) && retval == nil {
+				retval = fmt.Errorf("unable to remove staged hives: %w", err)
+			}
+		}
+	}
+	o.staged = nil
+
+	return retval
+}
+
+// mapPath converts a live registry path into a hive file name and a path
+// relative to the root of that hive.
+func (o *OfflineImage) mapPath(key registry.Key, path string) (string, string, error) {
+	top, rest := splitPath(path)
+
+	switch key {
+	case LOCAL_MACHINE:
+		name, ok := offlineMachineHives[strings.ToUpper(top)]
+		if !ok {
+			return "", "", fmt.Errorf("HKLM\\%s is not available in an offline image", path)
+		}
+		hive := filepath.Join(o.root, offlineConfigDir, name)
+		if name == "SYSTEM" {
+			var err error
+			if rest, err = o.mapControlSet(hive, rest); err != nil {
+				return "", "", err
+			}
+		}
+		return hive, rest, nil
+	case USERS:
+		if top == "" {
+			return "", "", errors.New("HKU path must start with a profile name")
+		}
+		if strings.EqualFold(top, offlineDefaultHKU) {
+			return filepath.Join(o.root, offlineConfigDir, "DEFAULT"), rest, nil
+		}
+		return filepath.Join(o.root, offlineUsersDir, top, offlineUserHive), rest, nil
+	default:
+		return "", "", errors.New("offline image supports only LOCAL_MACHINE and USERS keys")
+	}
+}
+
+// mapControlSet replaces the CurrentControlSet link, which exists only on
+// a running system, with the control set selected in the SYSTEM hive.
+func (o *OfflineImage) mapControlSet(hive, path string) (string, error) {
+	top, rest := splitPath(path)
+	if !strings.EqualFold(top, "CurrentControlSet") {
+		return path, nil
+	}
+
+	root, err := o.loadHive(hive)
+	if err != nil {
+		return "", err
+	}
+	k, err := registry.OpenKey(root, "Select", registry.QUERY_VALUE)
+	if err != nil {
+		return "", fmt.Errorf("unable to find current control set: %w", err)
+	}
+	defer k.Close()
+
+	current, _, err := k.GetIntegerValue("Current")
+	if err != nil {
+		return "", fmt.Errorf("unable to find current control set: %w", err)
+	}
+
+	return joinPath(fmt.Sprintf("ControlSet%03d", current), rest), nil
+}
+
+func (o *OfflineImage) loadHive(file string) (registry.Key, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	name := strings.ToUpper(file)
+	if k, ok := o.hives[name]; ok {
+		return k, nil
+	}
+
+	if _, err := os.Stat(file); err != nil {
+		return 0, fmt.Errorf("unable to load hive %s: %w", file, err)
+	}
+	load := file
+	if o.staging != "" {
+		var err error
+		if load, err = o.stageHive(file); err != nil {
+			return 0, fmt.Errorf("unable to load hive %s: %w", file, err)
+		}
+	}
+	k, err := regLoadAppKey(load, registry.READ, 0)
+	if err != nil {
+		return 0, fmt.Errorf("unable to load hive %s: %w", file, err)
+	}
+	o.hives[name] = k
+
+	return k, nil
+}
+
+// stageHive copies a hive file together with its transaction logs into
+// a new file of the staging directory, so the hive can be loaded from
+// read-only media. The staged files are removed by Close().
+func (o *OfflineImage) stageHive(file string) (string, error) {
+	out, err := os.CreateTemp(o.staging, "hive*")
+	if err != nil {
+		return "", err
+	}
+	staged := out.Name()
+	o.staged = append(o.staged, staged)
+	if err = copyTo(file, out); err != nil {
+		return "", err
+	}
+	for _, ext := range []string{".LOG1", ".LOG2"} {
+		if _, err := os.Stat(file + ext); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		out, err := os.OpenFile(staged+ext, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			return "", err
+		}
+		o.staged = append(o.staged, staged+ext)
+		if err = copyTo(file+ext, out); err != nil {
+			return "", err
+		}
+	}
+
+	return staged, nil
+}
+
+// copyTo copies the file src to out and closes out.
+func copyTo(src string, out *os.File) error {
+	in, err := os.Open(src)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	defer in.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// splitPath splits a registry path into its first component and the rest.
+func splitPath(path string) (string, string) {
+	path = strings.Trim(path, "\\")
+	if i := strings.IndexByte(path, '\\'); i >= 0 {
+		return path[:i], path[i+1:]
+	}
+	return path, ""
+}
+
+func joinPath(parent, child string) string {
+	if child == "" {
+		return parent
+	}
+	if parent == "" {
+		return child
+	}
+	return parent + "\\" + child
+}
@@ -0,0 +1,116 @@
+//go:build windows
+
+package winreg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/knadh/koanf/v2"
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestOfflineImage(t *testing.T) {
+	t.Log("Testing offline image hives.")
+	{
+		root := t.TempDir()
+		createOfflineImage(t, root)
+
+		img, err := OpenOfflineImage(root)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to open offline image: %v", failed, err)
+		}
+		defer img.Close()
+
+		testID := 0
+		t.Logf("\tTest %d:\tHKLM\\SOFTWARE mapping.", testID)
+		{
+			p, err := img.Provider(Config{Key: LOCAL_MACHINE, Path: "SOFTWARE\\" + testKey})
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to create provider: %v", failed, err)
+			}
+			k := koanf.New(".")
+			if err := k.Load(p, nil); err != nil {
+				t.Fatalf("\t%s\tUnable to read offline hive: %v.", failed, err)
+			}
+			if val := k.String("SubKey.StrValue"); val != "offline" {
+				t.Fatalf("\t%s\tSubKey.StrValue is invalid, got \"%s\", expect \"offline\".", failed, val)
+			}
+			t.Logf("\t%s\tOffline SOFTWARE hive was read.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tHKLM\\SYSTEM\\CurrentControlSet mapping.", testID)
+		{
+			p, err := img.Provider(Config{Key: LOCAL_MACHINE, Path: "SYSTEM\\CurrentControlSet\\" + testKey})
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to create provider: %v", failed, err)
+			}
+			k := koanf.New(".")
+			if err := k.Load(p, nil); err != nil {
+				t.Fatalf("\t%s\tUnable to read offline hive: %v.", failed, err)
+			}
+			if val := k.Int("on"); val != 1 {
+				t.Fatalf("\t%s\ton is invalid, got %d, expect 1.", failed, val)
+			}
+			t.Logf("\t%s\tCurrent control set was resolved.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tunsupported key.", testID)
+		{
+			if _, err := img.Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE"}); err == nil {
+				t.Fatalf("\t%s\tHKCU was mapped in an offline image.", failed)
+			}
+			t.Logf("\t%s\tHKCU is rejected.", success)
+		}
+	}
+}
+
+func createOfflineImage(t *testing.T, root string) {
+	config := filepath.Join(root, offlineConfigDir)
+	if err := os.MkdirAll(config, 0755); err != nil {
+		t.Fatalf("\t%s\tUnable to create image directory: %v", failed, err)
+	}
+
+	// RegLoadAppKey creates an empty hive if the file does not exist.
+	software, err := regLoadAppKey(filepath.Join(config, "SOFTWARE"), registry.ALL_ACCESS, 0)
+	if err != nil {
+		t.Fatalf("\t%s\tUnable to create test hive: %v", failed, err)
+	}
+	defer software.Close()
+
+	ks, _, err := registry.CreateKey(software, testKey+"\\SubKey", registry.ALL_ACCESS)
+	if err != nil {
+		t.Fatalf("\t%s\tUnable to create test key: %v", failed, err)
+	}
+	defer ks.Close()
+	if err := ks.SetStringValue("StrValue", "offline"); err != nil {
+		t.Fatalf("\t%s\tUnable to create test value: %v", failed, err)
+	}
+
+	system, err := regLoadAppKey(filepath.Join(config, "SYSTEM"), registry.ALL_ACCESS, 0)
+	if err != nil {
+		t.Fatalf("\t%s\tUnable to create test hive: %v", failed, err)
+	}
+	defer system.Close()
+
+	sel, _, err := registry.CreateKey(system, "Select", registry.ALL_ACCESS)
+	if err != nil {
+		t.Fatalf("\t%s\tUnable to create test key: %v", failed, err)
+	}
+	defer sel.Close()
+	if err := sel.SetDWordValue("Current", 2); err != nil {
+		t.Fatalf("\t%s\tUnable to create test value: %v", failed, err)
+	}
+
+	cs, _, err := registry.CreateKey(system, "ControlSet002\\"+testKey, registry.ALL_ACCESS)
+	if err != nil {
+		t.Fatalf("\t%s\tUnable to create test key: %v", failed, err)
+	}
+	defer cs.Close()
+	if err := cs.SetDWordValue("on", 1); err != nil {
+		t.Fatalf("\t%s\tUnable to create test value: %v", failed, err)
+	}
+}
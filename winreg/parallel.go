@@ -0,0 +1,62 @@
+//go:build windows
+
+package winreg
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// subKeyRead is a subkey to be read by readSubKeys() and its result.
+type subKeyRead struct {
+	name     string
+	path     string
+	includes []string
+	tree     map[string]interface{}
+	err      error
+}
+
+// readSubKeys reads the subkeys of the key path at level. With Workers, a
+// subkey is read by a goroutine of its own while one of the slots is
+// free, otherwise by the caller, so nested reads never wait for slots
+// held by their parents. Sequential reads stop at the first error which
+// fails the read, the context is checked before each subkey.
+func (s *WinReg) readSubKeys(ctx context.Context, root registry.Key, path string, level uint, reads []subKeyRead) {
+	var wg sync.WaitGroup
+reading:
+	for i := range reads {
+		r := &reads[i]
+		if err := ctx.Err(); err != nil {
+			r.err = s.keyError("enumerate", path, "", err)
+			break
+		}
+
+		select {
+		case s.workers <- struct{}{}:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				r.tree, r.err = s.readSubKey(ctx, root, r.path, level, r.includes)
+				<-s.workers
+			}()
+		default:
+			// All slots are busy or reads are sequential
+			r.tree, r.err = s.readSubKey(ctx, root, r.path, level, r.includes)
+			if r.err != nil && !s.skippable(r.err) {
+				break reading
+			}
+		}
+	}
+	wg.Wait()
+}
+
+// skippable reports whether the subkey failing with err is left out of
+// the read instead of failing it, as with SkipUnreadable or Audit.
+func (s *WinReg) skippable(err error) bool {
+	var code syscall.Errno
+	return (s.skipUnread || s.audit) && errors.As(err, &code)
+}
@@ -0,0 +1,75 @@
+//go:build windows
+
+package winreg
+
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// slowAPI serves an in-memory registry with slow opening of keys,
+// tracking the largest number of keys being opened at once.
+type slowAPI struct {
+	*Memory
+	open, max *int32
+}
+
+func (a slowAPI) OpenKey(k registry.Key, path string, access uint32) (registry.Key, error) {
+	n := atomic.AddInt32(a.open, 1)
+	defer atomic.AddInt32(a.open, -1)
+	for {
+		max := atomic.LoadInt32(a.max)
+		if n <= max || atomic.CompareAndSwapInt32(a.max, max, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+
+	return a.Memory.OpenKey(k, path, access)
+}
+
+func TestWorkers(t *testing.T) {
+	t.Log("Testing concurrent reads of subkeys.")
+	{
+		m := NewMemory()
+		tree := map[string]interface{}{"Name": "vendor"}
+		for i := 0; i < 20; i++ {
+			sub := map[string]interface{}{"Index": uint32(i)}
+			for j := 0; j < 3; j++ {
+				sub[fmt.Sprintf("Nested%d", j)] = map[string]interface{}{"Name": fmt.Sprintf("%d.%d", i, j)}
+			}
+			tree[fmt.Sprintf("App%02d", i)] = sub
+		}
+		if err := m.Load(CURRENT_USER, "SOFTWARE\\Vendor", tree); err != nil {
+			t.Fatalf("\t%s\tUnable to load memory registry: %v", failed, err)
+		}
+		expected, err := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\Vendor", Memory: m}).Read()
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tbounded worker pool.", testID)
+		{
+			var open, max int32
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\Vendor", Workers: 4})
+			p.api = slowAPI{Memory: m, open: &open, max: &max}
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if !reflect.DeepEqual(tree, expected) {
+				t.Fatalf("\t%s\tTree differs from a sequential read, got %v.", failed, tree)
+			}
+			if max < 2 || max > 4 {
+				t.Fatalf("\t%s\tUp to 4 keys should be opened at once, got %d.", failed, max)
+			}
+			t.Logf("\t%s\tSubkeys are read concurrently.", success)
+		}
+	}
+}
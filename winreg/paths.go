@@ -0,0 +1,64 @@
+//go:build windows
+
+package winreg
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrPaths is returned by operations on the provider's key, e.g. writes,
+// if the provider reads Paths instead of a single key.
+var ErrPaths = errors.New("operation is not supported by a provider of Paths")
+
+// singleKey returns ErrPaths if the provider reads Paths.
+func (s *WinReg) singleKey() error {
+	if s.paths != nil {
+		return ErrPaths
+	}
+
+	return nil
+}
+
+// readPaths reads the keys of Paths and merges their trees, or puts them
+// under the last names of their paths with NestPaths. Missing keys are
+// left out, missing is set only if all of them are missing.
+func (s *WinReg) readPaths(ctx context.Context) (map[string]interface{}, bool, error) {
+	retval := make(map[string]interface{})
+	var lastErr error
+	found := false
+	// Later paths take precedence
+	for i := len(s.paths) - 1; i >= 0; i-- {
+		p := s.paths[i]
+		atomic.StoreInt32(&p.torn, 0)
+		tree, missing, err := p.reader()(ctx)
+		if p.Torn() {
+			atomic.StoreInt32(&s.torn, 1)
+		}
+		if err != nil {
+			if !missing {
+				return nil, false, err
+			}
+			lastErr = err
+			continue
+		}
+		found = true
+
+		if s.nestPaths {
+			_, name := splitParent(p.path)
+			if sub, ok := retval[name].(map[string]interface{}); ok {
+				mergeBeneath(sub, tree)
+				continue
+			}
+			retval[name] = tree
+			continue
+		}
+		mergeBeneath(retval, tree)
+	}
+	if !found && lastErr != nil {
+		return nil, true, lastErr
+	}
+
+	return retval, false, nil
+}
@@ -0,0 +1,131 @@
+//go:build windows
+
+package winreg
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPaths(t *testing.T) {
+	t.Log("Testing several paths read by one provider.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		paths := []string{
+			"SOFTWARE\\" + testKey + "\\SubKeyA",
+			"SOFTWARE\\" + testKey + "\\Missing",
+			"SOFTWARE\\" + testKey + "\\SubKeyB",
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tmerged paths.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Paths: paths, DefaultValue: "Default", MaxDepth: 1})
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if len(tree) != 7 || tree["IntVal"] != uint64(4000000000) || tree["Default"] != "default value" {
+				t.Fatalf("\t%s\tTrees are not merged, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tTrees are merged.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tnested paths.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Paths: paths, DefaultValue: "Default", NestPaths: true})
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			a, _ := tree["SubKeyA"].(map[string]interface{})
+			b, _ := tree["SubKeyB"].(map[string]interface{})
+			if len(tree) != 2 || a["IntVal"] != uint64(4000000000) || b["Default"] != "default value" {
+				t.Fatalf("\t%s\tTrees are not nested, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tTrees are nested.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tall paths missing.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Paths: []string{"SOFTWARE\\" + testKey + "\\Missing"}})
+			if _, err := p.Read(); err == nil {
+				t.Fatalf("\t%s\tRead() of missing keys should fail.", failed)
+			}
+			t.Logf("\t%s\tMissing keys are reported.", success)
+		}
+	}
+}
+
+func TestPathsSingleKey(t *testing.T) {
+	t.Log("Testing operations on a single key refused by a provider of Paths.")
+	{
+		m := NewMemory()
+		err := m.Load(CURRENT_USER, "SOFTWARE\\Vendor", map[string]interface{}{
+			"Shared": map[string]interface{}{"Name": "shared"},
+			"App":    map[string]interface{}{"Name": "app"},
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to load memory registry: %v", failed, err)
+		}
+		p := Provider(Config{Key: CURRENT_USER, Paths: []string{"SOFTWARE\\Vendor\\Shared", "SOFTWARE\\Vendor\\App"}, Memory: m})
+
+		testID := 0
+		t.Logf("\tTest %d:\twrites.", testID)
+		{
+			if err := p.Set("Name", "other"); !errors.Is(err, ErrPaths) {
+				t.Fatalf("\t%s\tSet() should return ErrPaths, got %v.", failed, err)
+			}
+			if err := p.Delete("Name"); !errors.Is(err, ErrPaths) {
+				t.Fatalf("\t%s\tDelete() should return ErrPaths, got %v.", failed, err)
+			}
+			if _, err := p.Sync(map[string]interface{}{}); !errors.Is(err, ErrPaths) {
+				t.Fatalf("\t%s\tSync() should return ErrPaths, got %v.", failed, err)
+			}
+			if err := p.Import(bytes.NewReader(nil), ImportReplace); !errors.Is(err, ErrPaths) {
+				t.Fatalf("\t%s\tImport() should return ErrPaths, got %v.", failed, err)
+			}
+			hive := Provider(Config{Key: CURRENT_USER, Memory: m})
+			tree, err := hive.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if tree["SOFTWARE"] == nil || tree["Name"] != nil {
+				t.Fatalf("\t%s\tThe hive root is modified, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tWrites are refused.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\treads of the key.", testID)
+		{
+			if err := p.Export(&bytes.Buffer{}); !errors.Is(err, ErrPaths) {
+				t.Fatalf("\t%s\tExport() should return ErrPaths, got %v.", failed, err)
+			}
+			if _, err := p.Notify(); !errors.Is(err, ErrPaths) {
+				t.Fatalf("\t%s\tNotify() should return ErrPaths, got %v.", failed, err)
+			}
+			if err := GenerateStruct(&bytes.Buffer{}, p, StructOptions{}); !errors.Is(err, ErrPaths) {
+				t.Fatalf("\t%s\tGenerateStruct() should return ErrPaths, got %v.", failed, err)
+			}
+			if _, err := p.OpenValue("", "Name"); !errors.Is(err, ErrPaths) {
+				t.Fatalf("\t%s\tOpenValue() should return ErrPaths, got %v.", failed, err)
+			}
+			t.Logf("\t%s\tReads of the key are refused.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\taccess checks.", testID)
+		{
+			if err := p.CanRead(); err != nil {
+				t.Fatalf("\t%s\tUnable to check paths: %v.", failed, err)
+			}
+			t.Logf("\t%s\tPaths are checked.", success)
+		}
+	}
+}
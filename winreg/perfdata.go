@@ -0,0 +1,250 @@
+//go:build windows
+
+package winreg
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// perfNoInstances is NumInstances of objects with a single counter block.
+const perfNoInstances = -1
+
+// PerfData is a provider of performance counters, read from the
+// HKEY_PERFORMANCE_DATA pseudo-key which regular providers reject with
+// ErrPerformanceData. The PERF_DATA_BLOCK returned by the system is parsed
+// into a tree of objects, instances and counters, named by their English
+// titles from HKEY_PERFORMANCE_TEXT, e.g. "Processor" > "_Total" >
+// "% Processor Time". Objects without instances map counter names
+// directly. Counters of 4 and 8 bytes are uint64, others are []byte.
+// Counter values are raw, as they are sampled; computing rates is left to
+// the caller.
+type PerfData struct {
+	Query string // "Global", "Costly" or space separated object title indices like "238 230", "Global" if empty
+
+	api regAPI
+}
+
+// ReadBytes is not supported by the performance data provider.
+func (p *PerfData) ReadBytes() ([]byte, error) {
+	return nil, errors.New("winreg performance data does not support this method")
+}
+
+// Read samples the counters of the queried objects.
+func (p *PerfData) Read() (map[string]interface{}, error) {
+	api := p.api
+	if api == nil {
+		api = sysAPI{}
+	}
+	query := p.Query
+	if query == "" {
+		query = "Global"
+	}
+
+	data, err := readPerfData(api, query)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read performance data, %w", err)
+	}
+	names, err := perfTitles(api)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read performance counter titles, %w", err)
+	}
+
+	return parsePerfData(data, names)
+}
+
+// readPerfData queries HKEY_PERFORMANCE_DATA, which doesn't report the
+// size of its data, so the buffer grows until it fits.
+func readPerfData(api regAPI, query string) ([]byte, error) {
+	defer api.CloseKey(PERFORMANCE_DATA)
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := api.GetValue(PERFORMANCE_DATA, query, buf)
+		if errors.Is(err, registry.ErrShortBuffer) {
+			buf = make([]byte, len(buf)*2)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+}
+
+// perfTitles returns the English titles of objects and counters by their
+// indices. The Counter value lists index and title pairs.
+func perfTitles(api regAPI) (map[uint32]string, error) {
+	list, _, err := api.GetStringsValue(PERFORMANCE_TEXT, "Counter")
+	if err != nil {
+		return nil, err
+	}
+
+	retval := make(map[uint32]string, len(list)/2)
+	for i := 0; i+1 < len(list); i += 2 {
+		index, err := strconv.ParseUint(list[i], 10, 32)
+		if err != nil {
+			continue
+		}
+		retval[uint32(index)] = list[i+1]
+	}
+
+	return retval, nil
+}
+
+// perfTitle returns the title of index, or the index itself if it has
+// no title.
+func perfTitle(names map[uint32]string, index uint32) string {
+	if name, ok := names[index]; ok {
+		return name
+	}
+	return strconv.FormatUint(uint64(index), 10)
+}
+
+// parsePerfData parses a PERF_DATA_BLOCK.
+func parsePerfData(b []byte, names map[uint32]string) (map[string]interface{}, error) {
+	if len(b) < 32 || string(utf16.Decode(bytesToUTF16(b[:8]))) != "PERF" {
+		return nil, errors.New("invalid performance data block")
+	}
+	headerLength := binary.LittleEndian.Uint32(b[24:])
+	count := binary.LittleEndian.Uint32(b[28:])
+
+	retval := make(map[string]interface{})
+	offset := uint64(headerLength)
+	for i := uint32(0); i < count; i++ {
+		if offset+64 > uint64(len(b)) {
+			return nil, fmt.Errorf("performance object %d out of data block", i)
+		}
+		object := b[offset:]
+		length := binary.LittleEndian.Uint32(object)
+		if uint64(length) < 64 || offset+uint64(length) > uint64(len(b)) {
+			return nil, fmt.Errorf("invalid length of performance object %d", i)
+		}
+		object = object[:length]
+		name := perfTitle(names, binary.LittleEndian.Uint32(object[12:]))
+		tree, err := parsePerfObject(object, names)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		retval[name] = tree
+		offset += uint64(length)
+	}
+
+	return retval, nil
+}
+
+type perfCounter struct {
+	name   string
+	size   uint32
+	offset uint32
+}
+
+// parsePerfObject parses a PERF_OBJECT_TYPE with its counter definitions
+// and instances.
+func parsePerfObject(b []byte, names map[uint32]string) (map[string]interface{}, error) {
+	definitionLength := binary.LittleEndian.Uint32(b[4:])
+	headerLength := binary.LittleEndian.Uint32(b[8:])
+	numCounters := binary.LittleEndian.Uint32(b[32:])
+	numInstances := int32(binary.LittleEndian.Uint32(b[40:]))
+	if definitionLength > uint32(len(b)) || headerLength > definitionLength {
+		return nil, errors.New("invalid object definition")
+	}
+
+	// PERF_COUNTER_DEFINITION structures follow the object header
+	counters := make([]perfCounter, 0, numCounters)
+	offset := headerLength
+	for i := uint32(0); i < numCounters; i++ {
+		if offset+40 > definitionLength {
+			return nil, fmt.Errorf("counter definition %d out of object", i)
+		}
+		def := b[offset:]
+		counters = append(counters, perfCounter{
+			name:   perfTitle(names, binary.LittleEndian.Uint32(def[4:])),
+			size:   binary.LittleEndian.Uint32(def[32:]),
+			offset: binary.LittleEndian.Uint32(def[36:]),
+		})
+		length := binary.LittleEndian.Uint32(def)
+		if length < 40 {
+			return nil, fmt.Errorf("invalid length of counter definition %d", i)
+		}
+		offset += length
+	}
+
+	if numInstances == perfNoInstances {
+		retval, _, err := parsePerfCounters(b[definitionLength:], counters)
+		return retval, err
+	}
+
+	retval := make(map[string]interface{})
+	seen := make(map[string]int)
+	rest := b[definitionLength:]
+	for i := int32(0); i < numInstances; i++ {
+		// PERF_INSTANCE_DEFINITION followed by its name and counter block
+		if len(rest) < 24 {
+			return nil, fmt.Errorf("instance %d out of object", i)
+		}
+		length := binary.LittleEndian.Uint32(rest)
+		nameOffset := binary.LittleEndian.Uint32(rest[16:])
+		nameLength := binary.LittleEndian.Uint32(rest[20:])
+		if length < 24 || uint64(length) > uint64(len(rest)) || uint64(nameOffset)+uint64(nameLength) > uint64(length) {
+			return nil, fmt.Errorf("invalid instance %d", i)
+		}
+		name := string(utf16.Decode(bytesToUTF16(rest[nameOffset : nameOffset+nameLength])))
+		if end := strings.IndexByte(name, 0); end >= 0 {
+			name = name[:end]
+		}
+		// Instances of the same name are numbered as by Performance Monitor
+		if n := seen[name]; n > 0 {
+			seen[name]++
+			name = fmt.Sprintf("%s#%d", name, n)
+		} else {
+			seen[name] = 1
+		}
+
+		tree, next, err := parsePerfCounters(rest[length:], counters)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		retval[name] = tree
+		rest = next
+	}
+
+	return retval, nil
+}
+
+// parsePerfCounters parses the PERF_COUNTER_BLOCK at the start of b and
+// returns the bytes following it.
+func parsePerfCounters(b []byte, counters []perfCounter) (map[string]interface{}, []byte, error) {
+	if len(b) < 4 {
+		return nil, nil, errors.New("counter block out of object")
+	}
+	length := binary.LittleEndian.Uint32(b)
+	if length < 4 || uint64(length) > uint64(len(b)) {
+		return nil, nil, errors.New("invalid counter block")
+	}
+	block := b[:length]
+
+	retval := make(map[string]interface{}, len(counters))
+	for _, c := range counters {
+		if uint64(c.offset)+uint64(c.size) > uint64(len(block)) {
+			return nil, nil, fmt.Errorf("%s: counter out of counter block", c.name)
+		}
+		data := block[c.offset : c.offset+c.size]
+		switch c.size {
+		case 4:
+			retval[c.name] = uint64(binary.LittleEndian.Uint32(data))
+		case 8:
+			retval[c.name] = binary.LittleEndian.Uint64(data)
+		default:
+			retval[c.name] = append([]byte(nil), data...)
+		}
+	}
+
+	return retval, b[length:], nil
+}
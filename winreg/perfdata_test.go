@@ -0,0 +1,149 @@
+//go:build windows
+
+package winreg
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// perfAPI returns data as the performance data of every query.
+type perfAPI struct {
+	sysAPI
+	data []byte
+}
+
+func (a perfAPI) GetValue(k registry.Key, name string, buf []byte) (int, uint32, error) {
+	if len(buf) < len(a.data) {
+		return 0, registry.BINARY, registry.ErrShortBuffer
+	}
+	return copy(buf, a.data), registry.BINARY, nil
+}
+
+func (a perfAPI) GetStringsValue(k registry.Key, name string) ([]string, uint32, error) {
+	return []string{"1", "1847", "2", "System", "4", "Uptime", "6", "% Processor Time", "238", "Processor"}, registry.MULTI_SZ, nil
+}
+
+func (perfAPI) CloseKey(k registry.Key) error {
+	return nil
+}
+
+// perfCounterDefinition returns a PERF_COUNTER_DEFINITION.
+func perfCounterDefinition(title, size, offset uint32) []byte {
+	b := make([]byte, 40)
+	binary.LittleEndian.PutUint32(b, 40)
+	binary.LittleEndian.PutUint32(b[4:], title)
+	binary.LittleEndian.PutUint32(b[32:], size)
+	binary.LittleEndian.PutUint32(b[36:], offset)
+	return b
+}
+
+// perfObject returns a PERF_OBJECT_TYPE with its counter definitions
+// followed by body.
+func perfObject(title uint32, instances int32, counters [][]byte, body []byte) []byte {
+	b := make([]byte, 64)
+	for _, c := range counters {
+		b = append(b, c...)
+	}
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(b)))
+	binary.LittleEndian.PutUint32(b[8:], 64)
+	binary.LittleEndian.PutUint32(b[12:], title)
+	binary.LittleEndian.PutUint32(b[32:], uint32(len(counters)))
+	binary.LittleEndian.PutUint32(b[40:], uint32(instances))
+	b = append(b, body...)
+	binary.LittleEndian.PutUint32(b, uint32(len(b)))
+	return b
+}
+
+// perfInstance returns a PERF_INSTANCE_DEFINITION with its counter block.
+func perfInstance(name string, block []byte) []byte {
+	b := make([]byte, 24)
+	for _, unit := range utf16.Encode(append([]rune(name), 0)) {
+		b = append(b, byte(unit), byte(unit>>8))
+	}
+	binary.LittleEndian.PutUint32(b, uint32(len(b)))
+	binary.LittleEndian.PutUint32(b[16:], 24)
+	binary.LittleEndian.PutUint32(b[20:], uint32(len(b)-24))
+	return append(b, block...)
+}
+
+func testPerfData() []byte {
+	system := make([]byte, 16)
+	binary.LittleEndian.PutUint32(system, 16)
+	binary.LittleEndian.PutUint64(system[8:], 5000000000)
+
+	cpu := func(time uint64, other uint32) []byte {
+		b := make([]byte, 24)
+		binary.LittleEndian.PutUint32(b, 24)
+		binary.LittleEndian.PutUint64(b[8:], time)
+		binary.LittleEndian.PutUint32(b[16:], other)
+		return b
+	}
+	counters := [][]byte{perfCounterDefinition(6, 8, 8), perfCounterDefinition(10, 4, 16)}
+	instances := append(perfInstance("cpu", cpu(1, 2)), perfInstance("cpu", cpu(3, 4))...)
+
+	b := make([]byte, 88)
+	for i, c := range "PERF" {
+		b[i*2] = byte(c)
+	}
+	binary.LittleEndian.PutUint32(b[24:], 88)
+	binary.LittleEndian.PutUint32(b[28:], 2)
+	b = append(b, perfObject(2, perfNoInstances, [][]byte{perfCounterDefinition(4, 8, 8)}, system)...)
+	return append(b, perfObject(238, 2, counters, instances)...)
+}
+
+func TestPerfData(t *testing.T) {
+	t.Log("Testing performance data provider.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tparsing of counters.", testID)
+		{
+			p := &PerfData{api: perfAPI{data: testPerfData()}}
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read performance data: %v.", failed, err)
+			}
+			expected := map[string]interface{}{
+				"System": map[string]interface{}{"Uptime": uint64(5000000000)},
+				"Processor": map[string]interface{}{
+					"cpu":   map[string]interface{}{"% Processor Time": uint64(1), "10": uint64(2)},
+					"cpu#1": map[string]interface{}{"% Processor Time": uint64(3), "10": uint64(4)},
+				},
+			}
+			if !reflect.DeepEqual(tree, expected) {
+				t.Fatalf("\t%s\tTree is invalid, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tCounters are parsed.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tcorrupted data.", testID)
+		{
+			data := testPerfData()
+			p := &PerfData{api: perfAPI{data: data[:len(data)-8]}}
+			if _, err := p.Read(); err == nil {
+				t.Fatalf("\t%s\tReading truncated data should fail.", failed)
+			}
+			t.Logf("\t%s\tCorruption is reported.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tsystem counters.", testID)
+		{
+			// 238 is the index of the Processor object
+			tree, err := (&PerfData{Query: "238"}).Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read performance data: %v.", failed, err)
+			}
+			processor, ok := tree["Processor"].(map[string]interface{})
+			if !ok || processor["_Total"] == nil {
+				t.Fatalf("\t%s\tProcessor object is invalid, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tCounters are read.", success)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// PolicyLayer is a source of an application setting, ordered by Group
+// Policy precedence from the lowest.
+type PolicyLayer int
+
+const (
+	MachinePreference PolicyLayer = iota // HKLM\Software\<Vendor>\<Product>
+	UserPreference                       // HKCU\Software\<Vendor>\<Product>
+	UserPolicy                           // HKCU\Software\Policies\<Vendor>\<Product>
+	MachinePolicy                        // HKLM\Software\Policies\<Vendor>\<Product>
+)
+
+func (l PolicyLayer) String() string {
+	switch l {
+	case MachinePreference:
+		return "machine preference"
+	case UserPreference:
+		return "user preference"
+	case UserPolicy:
+		return "user policy"
+	case MachinePolicy:
+		return "machine policy"
+	default:
+		return fmt.Sprintf("PolicyLayer(%d)", int(l))
+	}
+}
+
+// EffectiveSetting is the value of a setting in effect and where it comes
+// from.
+type EffectiveSetting struct {
+	Path       string        // Value path relative to the product key, e.g. "Window\Width"
+	Value      interface{}   // Effective data
+	Layer      PolicyLayer   // Layer which supplied the value
+	Key        string        // Full name of the key which supplied the value
+	Overridden []PolicyLayer // Lower layers which set the value as well
+}
+
+// EffectivePolicy evaluates the preference and policy keys of a product
+// the way Group Policy does: machine policy takes precedence over user
+// policy, which takes precedence over user preferences, machine
+// preferences come last. It returns every setting sorted by path with the
+// layer which supplied it, to answer "why is this setting X?".
+//
+// Note that Layers.Load() ranks user keys over machine ones, as usual for
+// application settings which users may change.
+func EffectivePolicy(vendor, product string, mode int) ([]EffectiveSetting, error) {
+	prefs := joinPath(joinPath("Software", vendor), product)
+	policies := joinPath(joinPath("Software\\Policies", vendor), product)
+	layers := []struct {
+		layer PolicyLayer
+		cfg   Config
+	}{
+		{MachinePreference, Config{Key: LOCAL_MACHINE, Path: prefs}},
+		{UserPreference, Config{Key: CURRENT_USER, Path: prefs}},
+		{UserPolicy, Config{Key: CURRENT_USER, Path: policies}},
+		{MachinePolicy, Config{Key: LOCAL_MACHINE, Path: policies}},
+	}
+
+	settings := make(map[string]*EffectiveSetting)
+	for _, layer := range layers {
+		layer.cfg.Mode = mode
+		// A missing key is an empty layer
+		layer.cfg.Defaults = map[string]interface{}{}
+		p := Provider(layer.cfg)
+		tree, err := p.Read()
+		if err != nil {
+			return nil, fmt.Errorf("unable to evaluate %s, %w", layer.layer, err)
+		}
+
+		values := make(map[string]interface{})
+		flattenTree("", tree, values)
+		for path, value := range values {
+			setting := &EffectiveSetting{Path: path, Value: value, Layer: layer.layer}
+			parent, _ := splitParent(path)
+			setting.Key = p.getKeyName(joinPath(layer.cfg.Path, parent))
+			// Names are case-insensitive
+			if prev, ok := settings[strings.ToUpper(path)]; ok {
+				setting.Overridden = append(prev.Overridden, prev.Layer)
+			}
+			settings[strings.ToUpper(path)] = setting
+		}
+	}
+
+	retval := make([]EffectiveSetting, 0, len(settings))
+	for _, setting := range settings {
+		retval = append(retval, *setting)
+	}
+	sort.Slice(retval, func(i, j int) bool {
+		return strings.ToUpper(retval[i].Path) < strings.ToUpper(retval[j].Path)
+	})
+
+	return retval, nil
+}
+
+// Policy is a provider of the preference and policy keys of a product,
+// merged by Group Policy precedence as EffectivePolicy() evaluates them.
+// Layers are indexed by PolicyLayer.
+type Policy struct {
+	Layers   [MachinePolicy + 1]*WinReg
+	defaults map[string]interface{}
+
+	mu      sync.Mutex
+	sources map[string]PolicyLayer
+}
+
+// PolicyProvider returns a provider of the keys of vendor's product in
+// all policy layers, cfg.Key and cfg.Path are ignored. Missing keys are
+// empty layers and are watched for creation. cfg.Defaults are merged
+// beneath all layers.
+func PolicyProvider(vendor, product string, cfg Config) *Policy {
+	prefs := joinPath(joinPath("Software", vendor), product)
+	policies := joinPath(joinPath("Software\\Policies", vendor), product)
+	defaults := cfg.Defaults
+	// An empty tree makes a missing key an empty layer
+	cfg.Defaults = map[string]interface{}{}
+	cfg.WatchMissing = true
+
+	p := &Policy{defaults: defaults}
+	for layer, key := range map[PolicyLayer]struct {
+		key  registry.Key
+		path string
+	}{
+		MachinePreference: {LOCAL_MACHINE, prefs},
+		UserPreference:    {CURRENT_USER, prefs},
+		UserPolicy:        {CURRENT_USER, policies},
+		MachinePolicy:     {LOCAL_MACHINE, policies},
+	} {
+		layerCfg := cfg
+		layerCfg.Key, layerCfg.Path = key.key, key.path
+		p.Layers[layer] = Provider(layerCfg)
+	}
+
+	return p
+}
+
+func (p *Policy) ReadBytes() ([]byte, error) {
+	return nil, errors.New("winreg provider does not support this method")
+}
+
+// Read reads all layers and merges each of them beneath the layers of
+// higher precedence.
+func (p *Policy) Read() (map[string]interface{}, error) {
+	retval := make(map[string]interface{})
+	sources := make(map[string]PolicyLayer)
+	for layer := MachinePolicy; layer >= MachinePreference; layer-- {
+		tree, err := p.Layers[layer].Read()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s, %w", layer, err)
+		}
+
+		values := make(map[string]interface{})
+		flattenTree("", tree, values)
+		for path := range values {
+			// Names are case-insensitive
+			if _, ok := sources[strings.ToUpper(path)]; !ok {
+				sources[strings.ToUpper(path)] = layer
+			}
+		}
+		mergeBeneath(retval, tree)
+	}
+	if p.defaults != nil {
+		mergeBeneath(retval, copyTree(p.defaults))
+	}
+
+	p.mu.Lock()
+	p.sources = sources
+	p.mu.Unlock()
+
+	return retval, nil
+}
+
+// Layer returns the layer which supplied the value path, e.g.
+// "Window\Width", in the last Read(). ok is false for values which come
+// from the defaults or don't exist.
+func (p *Policy) Layer(path string) (layer PolicyLayer, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	layer, ok = p.sources[strings.ToUpper(path)]
+
+	return layer, ok
+}
+
+// Watch watches all layers, cb is called as by WinReg.Watch() when any of
+// them changes.
+func (p *Policy) Watch(cb func(event interface{}, err error)) error {
+	for i, layer := range p.Layers {
+		if err := layer.Watch(cb); err != nil {
+			for _, watched := range p.Layers[:i] {
+				watched.Unwatch()
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Unwatch stops watching all layers.
+func (p *Policy) Unwatch() error {
+	var retval error
+	for _, layer := range p.Layers {
+		if err := layer.Unwatch(); retval == nil {
+			retval = err
+		}
+	}
+
+	return retval
+}
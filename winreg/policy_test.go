@@ -0,0 +1,94 @@
+//go:build windows
+
+package winreg
+
+import (
+	"testing"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestEffectivePolicy(t *testing.T) {
+	t.Log("Testing effective policy evaluation.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		// The test key plays the vendor key, policy keys are missing
+		k, _, err := registry.CreateKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey+"\\App\\Window", registry.ALL_ACCESS)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to create test key: %v", failed, err)
+		}
+		defer k.Close()
+		if err := k.SetDWordValue("Width", 800); err != nil {
+			t.Fatalf("\t%s\tUnable to create test value: %v", failed, err)
+		}
+
+		settings, err := EffectivePolicy(testKey, "App", RegAuto)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to evaluate policy: %v.", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tuser preference.", testID)
+		{
+			if len(settings) != 1 {
+				t.Fatalf("\t%s\tInvalid settings: %+v.", failed, settings)
+			}
+			s := settings[0]
+			if s.Path != "Window\\Width" || s.Value != uint64(800) || s.Layer != UserPreference ||
+				s.Key != "HKCU\\Software\\"+testKey+"\\App\\Window" || len(s.Overridden) != 0 {
+				t.Fatalf("\t%s\tInvalid setting: %+v.", failed, s)
+			}
+			t.Logf("\t%s\tSetting is attributed to %s.", success, s.Layer)
+		}
+	}
+}
+
+func TestPolicyProvider(t *testing.T) {
+	t.Log("Testing policy provider.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		// The test key plays the vendor key, policy keys are missing
+		k, _, err := registry.CreateKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey+"\\App\\Window", registry.ALL_ACCESS)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to create test key: %v", failed, err)
+		}
+		defer k.Close()
+		if err := k.SetDWordValue("Width", 800); err != nil {
+			t.Fatalf("\t%s\tUnable to create test value: %v", failed, err)
+		}
+
+		p := PolicyProvider(testKey, "App", Config{
+			Defaults: map[string]interface{}{"Window": map[string]interface{}{"Width": uint64(640), "Height": uint64(480)}},
+		})
+		tree, err := p.Read()
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to read policy: %v.", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tlayers over defaults.", testID)
+		{
+			window, _ := tree["Window"].(map[string]interface{})
+			if window["Width"] != uint64(800) || window["Height"] != uint64(480) {
+				t.Fatalf("\t%s\tLayers are not merged, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tLayers are merged.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tvalue sources.", testID)
+		{
+			if layer, ok := p.Layer("window\\width"); !ok || layer != UserPreference {
+				t.Fatalf("\t%s\tWidth is attributed to %s, %t.", failed, layer, ok)
+			}
+			if _, ok := p.Layer("Window\\Height"); ok {
+				t.Fatalf("\t%s\tDefault Height is attributed to a layer.", failed)
+			}
+			t.Logf("\t%s\tValues are attributed.", success)
+		}
+	}
+}
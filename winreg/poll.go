@@ -0,0 +1,95 @@
+//go:build windows
+
+package winreg
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// DefaultPollInterval is the interval of polling a registry which can't
+// notify about changes, used if Config.PollInterval is zero.
+const DefaultPollInterval = 30 * time.Second
+
+// watchPoll watches a registry which can't notify about changes, like
+// a remote one, by reading the tree periodically and comparing it with the
+// previous one. Read errors are reported and polling continues, so a host
+// which is temporarily unreachable is picked up again.
+func (s *WinReg) watchPoll(cb func(event interface{}, err error)) error {
+	interval := s.pollInterval
+	if interval == 0 {
+		interval = DefaultPollInterval
+	}
+
+	// A copy, so the provider's own Read() state isn't shared
+	p := s.readCopy()
+	prev, err := p.Read()
+	missing := err != nil
+	if missing && (!s.watchMissing || s.keyReachable()) {
+		return fmt.Errorf("watch failed: %w", err)
+	}
+
+	var tracker *changeTracker
+	if s.eventDetails && !missing {
+		tracker, _ = p.newChangeTracker()
+	}
+	stop, err := s.watches.start()
+	if err != nil {
+		return fmt.Errorf("watch failed: %w", err)
+	}
+
+	go func() {
+		defer s.watches.running.Done()
+		for {
+			waitResult, err := windows.WaitForSingleObject(stop, uint32(interval/time.Millisecond))
+			if err != nil {
+				cb(nil, fmt.Errorf("watch failed: %w", err))
+				return
+			}
+			if waitResult != uint32(windows.WAIT_TIMEOUT) {
+				// Stopped by Unwatch()
+				return
+			}
+
+			tree, err := p.Read()
+			if err != nil {
+				if !missing {
+					cb(nil, err)
+				}
+				continue
+			}
+			if missing {
+				missing = false
+				prev = tree
+				if s.eventDetails {
+					tracker, _ = p.newChangeTracker()
+				}
+				cb(&CreatedEvent{Key: s.getKeyName(s.path)}, nil)
+				continue
+			}
+			if !reflect.DeepEqual(prev, tree) {
+				prev = tree
+				notifyChanges(tracker, cb)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// keyReachable reports whether the provider's key exists, a failure to
+// connect counts as existing.
+func (s *WinReg) keyReachable() bool {
+	root, err := s.connect()
+	if err != nil {
+		return true
+	}
+	if root != s.key {
+		defer s.api.CloseKey(root)
+	}
+
+	return s.keyExists(root, s.path)
+}
@@ -0,0 +1,59 @@
+//go:build windows
+
+package winreg
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestSystemCapabilities(t *testing.T) {
+	t.Log("Testing system capabilities.")
+	{
+		c := SystemCapabilities()
+		if c.Major < 6 {
+			t.Fatalf("\t%s\tUnexpected capabilities of %s: %+v.", failed, c, c)
+		}
+		t.Logf("\t%s\tRunning on %s.", success, c)
+	}
+}
+
+func TestWatchPoll(t *testing.T) {
+	t.Log("Testing watch by polling.")
+	{
+		const eventTimeout = 5
+		createTestData(t)
+		defer deleteTestData(t)
+
+		events := make(chan error, 10)
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, PollInterval: 100 * time.Millisecond})
+		if err := p.watchPoll(func(event interface{}, err error) { events <- err }); err != nil {
+			t.Fatalf("\t%s\tWatch failed: %v", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tchanged value is detected.", testID)
+		{
+			k, err := registry.OpenKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey, registry.ALL_ACCESS)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to open registry key: %v", failed, err)
+			}
+			defer k.Close()
+			if err := k.SetDWordValue("on", 2); err != nil {
+				t.Fatalf("\t%s\tUnable to change value \"on\": %v", failed, err)
+			}
+
+			select {
+			case err := <-events:
+				if err != nil {
+					t.Fatalf("\t%s\tWatch failed: %v.", failed, err)
+				}
+			case <-time.After(eventTimeout * time.Second):
+				t.Fatalf("\t%s\tTimeout exceeded while waiting for change event.", failed)
+			}
+			t.Logf("\t%s\tChange is detected.", success)
+		}
+	}
+}
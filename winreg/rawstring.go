@@ -0,0 +1,41 @@
+//go:build windows
+
+package winreg
+
+import "golang.org/x/sys/windows/registry"
+
+// rawString decodes the data of a string value as UTF-16 code units exactly
+// as stored, including unpaired surrogates which can't be converted to Go
+// strings losslessly. REG_SZ and REG_EXPAND_SZ are returned as []uint16
+// without the terminating NUL, REG_MULTI_SZ as [][]uint16.
+func rawString(typ uint32, buf []byte) interface{} {
+	units := trimNUL(bytesToUTF16(buf))
+	if typ != registry.MULTI_SZ {
+		return units
+	}
+
+	// The list is terminated by an empty string
+	units = trimNUL(units)
+	retval := [][]uint16{}
+	if len(units) == 0 {
+		return retval
+	}
+	start := 0
+	for i, c := range units {
+		if c == 0 {
+			retval = append(retval, units[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(retval, units[start:])
+}
+
+// trimNUL removes a single terminating NUL.
+func trimNUL(units []uint16) []uint16 {
+	if len(units) > 0 && units[len(units)-1] == 0 {
+		return units[:len(units)-1]
+	}
+
+	return units
+}
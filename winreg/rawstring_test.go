@@ -0,0 +1,55 @@
+//go:build windows
+
+package winreg
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestRawStrings(t *testing.T) {
+	t.Log("Testing raw UTF-16 strings.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		k, err := registry.OpenKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey, registry.ALL_ACCESS)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to open test key: %v", failed, err)
+		}
+		defer k.Close()
+		// An unpaired high surrogate
+		if err := regSetValueEx(k, "Surrogate", registry.SZ, utf16Bytes([]uint16{0xD800, 'a', 0})); err != nil {
+			t.Fatalf("\t%s\tUnable to create test value: %v", failed, err)
+		}
+		if err := k.SetStringsValue("Multi", []string{"a", "", "bc"}); err != nil {
+			t.Fatalf("\t%s\tUnable to create test value: %v", failed, err)
+		}
+
+		tree, err := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, MaxDepth: 1, RawStrings: true}).Read()
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tREG_SZ.", testID)
+		{
+			if val := tree["Surrogate"]; !reflect.DeepEqual(val, []uint16{0xD800, 'a'}) {
+				t.Fatalf("\t%s\tSurrogate is invalid, got %v.", failed, val)
+			}
+			t.Logf("\t%s\tCode units are preserved.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tREG_MULTI_SZ.", testID)
+		{
+			expect := [][]uint16{{'a'}, {}, {'b', 'c'}}
+			if val := tree["Multi"]; !reflect.DeepEqual(val, expect) {
+				t.Fatalf("\t%s\tMulti is invalid, got %v, expect %v.", failed, val, expect)
+			}
+			t.Logf("\t%s\tList is split.", success)
+		}
+	}
+}
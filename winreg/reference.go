@@ -0,0 +1,98 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// maxReferenceDepth limits chains of references to references.
+const maxReferenceDepth = 16
+
+// ErrReferenceCycle is returned when value references form a loop.
+var ErrReferenceCycle = errors.New("reference cycle")
+
+// parseReference parses a value reference "@<hive>\<path>:<value name>",
+// e.g. "@HKLM\SOFTWARE\Shared\Common:LogDir". An empty value name refers
+// to the default value of the key. Strings which don't start with a known
+// hive name (like MUI resource strings "@file.dll,-123") are not references.
+func parseReference(str string) (key registry.Key, path string, value string, ok bool) {
+	if !strings.HasPrefix(str, "@") {
+		return 0, "", "", false
+	}
+
+	ref := str[1:]
+	sep := strings.LastIndexByte(ref, '\\')
+	colon := strings.IndexByte(ref[sep+1:], ':')
+	if sep < 0 || colon < 0 {
+		return 0, "", "", false
+	}
+	colon += sep + 1
+
+	if key, path, ok = parseKeyName(ref[:colon]); !ok {
+		return 0, "", "", false
+	}
+
+	return key, path, ref[colon+1:], true
+}
+
+// resolveReference returns the data of the value referenced by str, or
+// str itself if it is not a reference. References to references are
+// followed, seen holds references already visited on this chain.
+func (s *WinReg) resolveReference(str string, seen map[string]bool) (interface{}, error) {
+	key, path, value, ok := parseReference(str)
+	if !ok {
+		return str, nil
+	}
+
+	if seen == nil {
+		seen = make(map[string]bool)
+	}
+	id := strings.ToUpper(str)
+	if seen[id] {
+		return nil, fmt.Errorf("%s: %w", str, ErrReferenceCycle)
+	}
+	if len(seen) >= maxReferenceDepth {
+		return nil, fmt.Errorf("%s: reference chain is too long", str)
+	}
+	seen[id] = true
+	if isPerformanceKey(key) {
+		return nil, fmt.Errorf("%s: %w", str, ErrPerformanceData)
+	}
+
+	root, err := s.connectKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", str, err)
+	}
+	if root != key {
+		defer s.api.CloseKey(root)
+	}
+
+	s.limiter.Wait()
+	k, err := s.api.OpenKey(root, path, s.getAccess(registry.QUERY_VALUE))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", str, err)
+	}
+	defer s.api.CloseKey(k)
+
+	buf, typ, err := s.getValueBytes(k, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", str, err)
+	}
+	data, ok, err := s.readValue(k, value, typ, buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", str, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported value type %d", str, typ)
+	}
+
+	if str, ok := data.(string); ok && typ == registry.SZ {
+		return s.resolveReference(str, seen)
+	}
+	return data, nil
+}
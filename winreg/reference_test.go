@@ -0,0 +1,89 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/knadh/koanf/v2"
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestParseReference(t *testing.T) {
+	t.Log("Testing value reference syntax.")
+	{
+		tests := []struct {
+			str   string
+			ok    bool
+			key   registry.Key
+			path  string
+			value string
+		}{
+			{"@HKLM\\SOFTWARE\\Shared\\Common:LogDir", true, LOCAL_MACHINE, "SOFTWARE\\Shared\\Common", "LogDir"},
+			{"@HKEY_CURRENT_USER\\Software\\App:", true, CURRENT_USER, "Software\\App", ""},
+			{"@HKCU\\Software\\App:Log:Dir", true, CURRENT_USER, "Software\\App", "Log:Dir"},
+			{"@%SystemRoot%\\system32\\shell32.dll,-123", false, 0, "", ""},
+			{"@C:\\Windows\\foo.dll,-1", false, 0, "", ""},
+			{"HKLM\\SOFTWARE:Value", false, 0, "", ""},
+		}
+
+		for testID, test := range tests {
+			t.Logf("\tTest %d:\t%s.", testID, test.str)
+			key, path, value, ok := parseReference(test.str)
+			if ok != test.ok || key != test.key || path != test.path || value != test.value {
+				t.Fatalf("\t%s\tGot %v %v %q %q.", failed, ok, key, path, value)
+			}
+			t.Logf("\t%s\tReference is parsed.", success)
+		}
+	}
+}
+
+func TestResolveReferences(t *testing.T) {
+	t.Log("Testing resolution of value references.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		r, _, err := registry.CreateKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey+"\\Refs", registry.ALL_ACCESS)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to create test key: %v", failed, err)
+		}
+		defer r.Close()
+		base := "@HKCU\\SOFTWARE\\" + testKey
+		if err := r.SetStringValue("Str", base+"\\SubKeyA:StrValue"); err != nil {
+			t.Fatalf("\t%s\tUnable to create test value: %v", failed, err)
+		}
+		if err := r.SetStringValue("Chain", base+"\\Refs:Str"); err != nil {
+			t.Fatalf("\t%s\tUnable to create test value: %v", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tresolved references.", testID)
+		{
+			k := koanf.New(".")
+			if err := k.Load(Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey + "\\Refs", References: true}), nil); err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			for _, name := range []string{"Str", "Chain"} {
+				if val := k.String(name); val != "The quick brown fox jumps over the lazy dog" {
+					t.Fatalf("\t%s\t%s is invalid, got \"%s\".", failed, name, val)
+				}
+			}
+			t.Logf("\t%s\tReferences are resolved.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\treference cycle.", testID)
+		{
+			if err := r.SetStringValue("Loop", base+"\\Refs:Loop"); err != nil {
+				t.Fatalf("\t%s\tUnable to create test value: %v", failed, err)
+			}
+			_, err := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey + "\\Refs", References: true}).Read()
+			if !errors.Is(err, ErrReferenceCycle) {
+				t.Fatalf("\t%s\tExpected reference cycle error, got %v.", failed, err)
+			}
+			t.Logf("\t%s\tCycle is detected.", success)
+		}
+	}
+}
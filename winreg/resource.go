@@ -0,0 +1,176 @@
+//go:build windows
+
+package winreg
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Types of CM_PARTIAL_RESOURCE_DESCRIPTOR.
+const (
+	cmResourceTypeNull           = 0
+	cmResourceTypePort           = 1
+	cmResourceTypeInterrupt      = 2
+	cmResourceTypeMemory         = 3
+	cmResourceTypeDma            = 4
+	cmResourceTypeDeviceSpecific = 5
+	cmResourceTypeBusNumber      = 6
+	cmResourceTypeMemoryLarge    = 7
+)
+
+var resourceTypeNames = map[uint8]string{
+	cmResourceTypeNull:           "Null",
+	cmResourceTypePort:           "Port",
+	cmResourceTypeInterrupt:      "Interrupt",
+	cmResourceTypeMemory:         "Memory",
+	cmResourceTypeDma:            "Dma",
+	cmResourceTypeDeviceSpecific: "DeviceSpecific",
+	cmResourceTypeBusNumber:      "BusNumber",
+	cmResourceTypeMemoryLarge:    "MemoryLarge",
+}
+
+// DecodeResourceList is a Decoder of a REG_RESOURCE_LIST value, the
+// CM_RESOURCE_LIST of hardware resources assigned to a device, e.g. in
+// HKLM\HARDWARE\RESOURCEMAP. It returns a list of full resource
+// descriptors as decoded by DecodeFullResourceDescriptor.
+func DecodeResourceList(data interface{}) (interface{}, error) {
+	b, ok := data.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unable to decode resource list from %T", data)
+	}
+
+	return decodeResourceList(b, is64BitSystem())
+}
+
+// DecodeFullResourceDescriptor is a Decoder of a
+// REG_FULL_RESOURCE_DESCRIPTOR value, e.g. in HKLM\HARDWARE\DESCRIPTION.
+// It returns a map of InterfaceType, BusNumber, Version, Revision and
+// Descriptors, the list of partial descriptors. Each of them has Type,
+// ShareDisposition, Flags and the fields of its type, e.g. Start and
+// Length of a port or memory range; unknown types have the raw Data.
+func DecodeFullResourceDescriptor(data interface{}) (interface{}, error) {
+	b, ok := data.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unable to decode resource descriptor from %T", data)
+	}
+
+	retval, rest, err := decodeFullDescriptor(b, is64BitSystem())
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%d bytes after resource descriptor", len(rest))
+	}
+
+	return retval, nil
+}
+
+// decodeResourceList decodes CM_RESOURCE_LIST, wide is set for the
+// layout of 64-bit Windows.
+func decodeResourceList(b []byte, wide bool) ([]interface{}, error) {
+	if len(b) < 4 {
+		return nil, fmt.Errorf("resource list of %d bytes", len(b))
+	}
+	count := binary.LittleEndian.Uint32(b)
+	b = b[4:]
+
+	var retval []interface{}
+	for i := uint32(0); i < count; i++ {
+		desc, rest, err := decodeFullDescriptor(b, wide)
+		if err != nil {
+			return nil, fmt.Errorf("descriptor %d: %w", i, err)
+		}
+		retval = append(retval, desc)
+		b = rest
+	}
+
+	return retval, nil
+}
+
+// decodeFullDescriptor decodes CM_FULL_RESOURCE_DESCRIPTOR at the start
+// of b and returns the bytes following it.
+func decodeFullDescriptor(b []byte, wide bool) (map[string]interface{}, []byte, error) {
+	if len(b) < 16 {
+		return nil, nil, fmt.Errorf("resource descriptor of %d bytes", len(b))
+	}
+	retval := map[string]interface{}{
+		"InterfaceType": int32(binary.LittleEndian.Uint32(b)),
+		"BusNumber":     binary.LittleEndian.Uint32(b[4:]),
+		"Version":       binary.LittleEndian.Uint16(b[8:]),
+		"Revision":      binary.LittleEndian.Uint16(b[10:]),
+	}
+	count := binary.LittleEndian.Uint32(b[12:])
+	b = b[16:]
+
+	size := 16
+	if wide {
+		size = 20
+	}
+	descriptors := []interface{}{}
+	for i := uint32(0); i < count; i++ {
+		if len(b) < size {
+			return nil, nil, fmt.Errorf("partial descriptor %d of %d bytes", i, len(b))
+		}
+		desc, extra := decodePartialDescriptor(b[:size], wide)
+		b = b[size:]
+		if extra > 0 {
+			// Device specific data follows its descriptor
+			if uint32(len(b)) < extra {
+				return nil, nil, fmt.Errorf("partial descriptor %d: %d bytes of %d of device specific data", i, len(b), extra)
+			}
+			desc["Data"] = append([]byte(nil), b[:extra]...)
+			b = b[extra:]
+		}
+		descriptors = append(descriptors, desc)
+	}
+	retval["Descriptors"] = descriptors
+
+	return retval, b, nil
+}
+
+// decodePartialDescriptor decodes CM_PARTIAL_RESOURCE_DESCRIPTOR. extra
+// is the size of device specific data following it.
+func decodePartialDescriptor(b []byte, wide bool) (retval map[string]interface{}, extra uint32) {
+	typ := b[0]
+	name, ok := resourceTypeNames[typ]
+	if !ok {
+		name = fmt.Sprintf("0x%02X", typ)
+	}
+	retval = map[string]interface{}{
+		"Type":             name,
+		"ShareDisposition": b[1],
+		"Flags":            binary.LittleEndian.Uint16(b[2:]),
+	}
+
+	u := b[4:]
+	switch typ {
+	case cmResourceTypePort, cmResourceTypeMemory, cmResourceTypeMemoryLarge:
+		retval["Start"] = binary.LittleEndian.Uint64(u)
+		retval["Length"] = binary.LittleEndian.Uint32(u[8:])
+	case cmResourceTypeInterrupt:
+		if wide {
+			retval["Level"] = uint32(binary.LittleEndian.Uint16(u))
+			retval["Group"] = binary.LittleEndian.Uint16(u[2:])
+			retval["Vector"] = binary.LittleEndian.Uint32(u[4:])
+			retval["Affinity"] = binary.LittleEndian.Uint64(u[8:])
+		} else {
+			retval["Level"] = binary.LittleEndian.Uint32(u)
+			retval["Vector"] = binary.LittleEndian.Uint32(u[4:])
+			retval["Affinity"] = uint64(binary.LittleEndian.Uint32(u[8:]))
+		}
+	case cmResourceTypeDma:
+		retval["Channel"] = binary.LittleEndian.Uint32(u)
+		retval["Port"] = binary.LittleEndian.Uint32(u[4:])
+	case cmResourceTypeBusNumber:
+		retval["Start"] = binary.LittleEndian.Uint32(u)
+		retval["Length"] = binary.LittleEndian.Uint32(u[4:])
+	case cmResourceTypeDeviceSpecific:
+		extra = binary.LittleEndian.Uint32(u)
+	case cmResourceTypeNull:
+	default:
+		retval["Data"] = append([]byte(nil), u...)
+	}
+
+	return retval, extra
+}
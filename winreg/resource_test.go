@@ -0,0 +1,101 @@
+//go:build windows
+
+package winreg
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestDecodeResourceList(t *testing.T) {
+	t.Log("Testing resource lists.")
+	{
+		// A port range and device specific data of the 64-bit layout
+		full := []byte{5, 0, 0, 0, 0, 0, 0, 0, 1, 0, 1, 0, 2, 0, 0, 0}
+		port := make([]byte, 20)
+		port[0], port[1], port[2] = cmResourceTypePort, 1, 0x11
+		binary.LittleEndian.PutUint64(port[4:], 0x3F8)
+		binary.LittleEndian.PutUint32(port[12:], 8)
+		specific := make([]byte, 20)
+		specific[0] = cmResourceTypeDeviceSpecific
+		binary.LittleEndian.PutUint32(specific[4:], 2)
+		full = append(append(append(full, port...), specific...), 0xAA, 0xBB)
+		list := append([]byte{1, 0, 0, 0}, full...)
+
+		testID := 0
+		t.Logf("\tTest %d:\tfull resource descriptor.", testID)
+		{
+			val, err := decodeResourceList(list, true)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to decode: %v.", failed, err)
+			}
+			expected := []interface{}{map[string]interface{}{
+				"InterfaceType": int32(5),
+				"BusNumber":     uint32(0),
+				"Version":       uint16(1),
+				"Revision":      uint16(1),
+				"Descriptors": []interface{}{
+					map[string]interface{}{"Type": "Port", "ShareDisposition": uint8(1), "Flags": uint16(0x11), "Start": uint64(0x3F8), "Length": uint32(8)},
+					map[string]interface{}{"Type": "DeviceSpecific", "ShareDisposition": uint8(0), "Flags": uint16(0), "Data": []byte{0xAA, 0xBB}},
+				},
+			}}
+			if !reflect.DeepEqual(val, expected) {
+				t.Fatalf("\t%s\tInvalid list, got %v, expect %v.", failed, val, expected)
+			}
+			t.Logf("\t%s\tList is decoded.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\ttruncated data.", testID)
+		{
+			if _, err := decodeResourceList(list[:len(list)-1], true); err == nil {
+				t.Fatalf("\t%s\tDecoding truncated list should fail.", failed)
+			}
+			t.Logf("\t%s\tList is rejected.", success)
+		}
+	}
+}
+
+func TestRawTypes(t *testing.T) {
+	t.Log("Testing values of REG_NONE type.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, TypeRules: TypeRules{
+			Paths: map[string]uint32{"None": registry.NONE},
+		}})
+		if err := p.Set("None", []byte{1, 2}); err != nil {
+			t.Fatalf("\t%s\tUnable to write REG_NONE value: %v.", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tleft out by default.", testID)
+		{
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if _, ok := tree["None"]; ok {
+				t.Fatalf("\t%s\tREG_NONE value is read.", failed)
+			}
+			t.Logf("\t%s\tValue is left out.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\traw bytes.", testID)
+		{
+			tree, err := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, RawTypes: true}).Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if !reflect.DeepEqual(tree["None"], []byte{1, 2}) {
+				t.Fatalf("\t%s\tREG_NONE value is invalid, got %v.", failed, tree["None"])
+			}
+			t.Logf("\t%s\tValue is passed through.", success)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+//go:build windows
+
+package winreg
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// TreeReader is anything returning a configuration tree, e.g. a provider
+// or a snapshot.
+type TreeReader interface {
+	Read() (map[string]interface{}, error)
+}
+
+// SchemaOptions controls JSON Schema generation.
+type SchemaOptions struct {
+	ID          string                   // Schema $id
+	Title       string                   // Schema title
+	Enums       map[string][]interface{} // Allowed values by value path, e.g. "SubKey\Mode"
+	AllOptional bool                     // Don't mark values found in the tree as required
+}
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// GenerateSchema infers a JSON Schema describing the tree read from src.
+// Types are derived from the registry data, and values present in the tree
+// are marked as required unless AllOptional is set. The schema can be used
+// to validate future reads and to document the configuration contract.
+func GenerateSchema(src TreeReader, opts SchemaOptions) ([]byte, error) {
+	tree, err := src.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	schema := opts.objectSchema("", tree)
+	schema["$schema"] = jsonSchemaDialect
+	if opts.ID != "" {
+		schema["$id"] = opts.ID
+	}
+	if opts.Title != "" {
+		schema["title"] = opts.Title
+	}
+
+	retval, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode schema: %w", err)
+	}
+
+	return retval, nil
+}
+
+func (o *SchemaOptions) objectSchema(path string, tree map[string]interface{}) map[string]interface{} {
+	properties := make(map[string]interface{}, len(tree))
+	required := make([]string, 0, len(tree))
+	for name, value := range tree {
+		properties[name] = o.valueSchema(joinPath(path, name), value)
+		required = append(required, name)
+	}
+
+	retval := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if !o.AllOptional && len(required) > 0 {
+		sort.Strings(required)
+		retval["required"] = required
+	}
+
+	return retval
+}
+
+func (o *SchemaOptions) valueSchema(path string, value interface{}) map[string]interface{} {
+	var retval map[string]interface{}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return o.objectSchema(path, value)
+	case string:
+		retval = map[string]interface{}{"type": "string"}
+	case []string:
+		retval = map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+	case []byte:
+		retval = map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+	case uint32:
+		retval = map[string]interface{}{"type": "integer", "minimum": 0, "maximum": uint32(0xFFFFFFFF)}
+	case uint64:
+		retval = map[string]interface{}{"type": "integer", "minimum": 0}
+	default:
+		switch reflect.ValueOf(value).Kind() {
+		case reflect.Bool:
+			retval = map[string]interface{}{"type": "boolean"}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			retval = map[string]interface{}{"type": "integer"}
+		case reflect.Float32, reflect.Float64:
+			retval = map[string]interface{}{"type": "number"}
+		default:
+			retval = map[string]interface{}{}
+		}
+	}
+
+	if enum, ok := o.Enums[path]; ok {
+		retval["enum"] = enum
+	}
+
+	return retval
+}
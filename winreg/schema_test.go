@@ -0,0 +1,62 @@
+//go:build windows
+
+package winreg
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateSchema(t *testing.T) {
+	t.Log("Testing JSON Schema generation.")
+	{
+		sn := &Snapshot{Data: map[string]interface{}{
+			"on":   uint64(1),
+			"Name": "app",
+			"Sub": map[string]interface{}{
+				"List": []string{"a"},
+				"Blob": []byte{1},
+			},
+		}}
+
+		raw, err := GenerateSchema(sn, SchemaOptions{Title: "App", Enums: map[string][]interface{}{"on": {0, 1}}})
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to generate schema: %v.", failed, err)
+		}
+		var schema map[string]interface{}
+		if err := json.Unmarshal(raw, &schema); err != nil {
+			t.Fatalf("\t%s\tInvalid schema JSON: %v.", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\troot object.", testID)
+		{
+			if schema["$schema"] != jsonSchemaDialect || schema["title"] != "App" || schema["type"] != "object" {
+				t.Fatalf("\t%s\tInvalid root schema %v.", failed, schema)
+			}
+			if !reflect.DeepEqual(schema["required"], []interface{}{"Name", "Sub", "on"}) {
+				t.Fatalf("\t%s\tInvalid required list %v.", failed, schema["required"])
+			}
+			t.Logf("\t%s\tRoot schema is valid.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tvalue types.", testID)
+		{
+			props := schema["properties"].(map[string]interface{})
+			on := props["on"].(map[string]interface{})
+			if on["type"] != "integer" || !reflect.DeepEqual(on["enum"], []interface{}{float64(0), float64(1)}) {
+				t.Fatalf("\t%s\tInvalid schema of on %v.", failed, on)
+			}
+			sub := props["Sub"].(map[string]interface{})["properties"].(map[string]interface{})
+			if sub["List"].(map[string]interface{})["type"] != "array" {
+				t.Fatalf("\t%s\tInvalid schema of Sub.List %v.", failed, sub["List"])
+			}
+			if sub["Blob"].(map[string]interface{})["contentEncoding"] != "base64" {
+				t.Fatalf("\t%s\tInvalid schema of Sub.Blob %v.", failed, sub["Blob"])
+			}
+			t.Logf("\t%s\tValue schemas are valid.", success)
+		}
+	}
+}
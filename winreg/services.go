@@ -0,0 +1,192 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ServicesPath is the key of the service control manager database.
+const ServicesPath = `SYSTEM\CurrentControlSet\Services`
+
+// Service is the configuration of a service or a driver.
+type Service struct {
+	Name             string   // Name of the service key
+	DisplayName      string   // Localized display name
+	Description      string   // Localized description
+	ImagePath        string   // Expanded path of the executable or driver, with arguments
+	ObjectName       string   // Account the service runs as, or driver object name
+	Start            string   // One of "boot", "system", "auto", "demand", "disabled"
+	DelayedAutoStart bool     // Auto start is delayed after boot
+	Type             string   // Like "own process" or "kernel driver", see serviceTypes
+	Interactive      bool     // The service can interact with the desktop
+	ErrorControl     string   // One of "ignore", "normal", "severe", "critical"
+	Group            string   // Load order group
+	DependOnService  []string // Services which must start first
+	DependOnGroup    []string // Load order groups which must start first
+}
+
+var serviceStarts = []string{"boot", "system", "auto", "demand", "disabled"}
+
+var serviceErrorControls = []string{"ignore", "normal", "severe", "critical"}
+
+// serviceTypes maps the Type values without SERVICE_INTERACTIVE_PROCESS.
+var serviceTypes = map[uint64]string{
+	0x01: "kernel driver",
+	0x02: "file system driver",
+	0x04: "adapter",
+	0x08: "recognizer driver",
+	0x10: "own process",
+	0x20: "share process",
+	0x50: "user own process",
+	0x60: "user share process",
+}
+
+const serviceInteractiveProcess = 0x100
+
+// Services is a provider of services and drivers configuration, read from
+// HKLM\SYSTEM\CurrentControlSet\Services. Keys without a Type value, which
+// are not services, are left out. The tree maps service names to trees
+// with the fields of Service, enumerations are represented by names.
+type Services struct{}
+
+// ReadBytes is not supported by the services provider.
+func (s *Services) ReadBytes() ([]byte, error) {
+	return nil, errors.New("winreg services does not support this method")
+}
+
+// Read returns services by their names.
+func (s *Services) Read() (map[string]interface{}, error) {
+	services, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	retval := make(map[string]interface{}, len(services))
+	for _, svc := range services {
+		retval[svc.Name] = map[string]interface{}{
+			"DisplayName":      svc.DisplayName,
+			"Description":      svc.Description,
+			"ImagePath":        svc.ImagePath,
+			"ObjectName":       svc.ObjectName,
+			"Start":            svc.Start,
+			"DelayedAutoStart": svc.DelayedAutoStart,
+			"Type":             svc.Type,
+			"Interactive":      svc.Interactive,
+			"ErrorControl":     svc.ErrorControl,
+			"Group":            svc.Group,
+			"DependOnService":  svc.DependOnService,
+			"DependOnGroup":    svc.DependOnGroup,
+		}
+	}
+
+	return retval, nil
+}
+
+// List returns services sorted by name.
+func (s *Services) List() ([]Service, error) {
+	tree, err := Provider(Config{Key: LOCAL_MACHINE, Path: ServicesPath, MaxDepth: 2, ResolveMUI: true}).Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read services, %w", err)
+	}
+
+	var retval []Service
+	for name, sub := range tree {
+		values, ok := sub.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		typ, ok := values["Type"].(uint64)
+		if !ok {
+			continue
+		}
+		delayed, _ := values["DelayedAutoStart"].(uint64)
+		retval = append(retval, Service{
+			Name:             name,
+			DisplayName:      stringField(values, "DisplayName"),
+			Description:      stringField(values, "Description"),
+			ImagePath:        normalizeImagePath(stringField(values, "ImagePath")),
+			ObjectName:       stringField(values, "ObjectName"),
+			Start:            enumField(values, "Start", serviceStarts),
+			DelayedAutoStart: delayed != 0,
+			Type:             serviceType(typ &^ serviceInteractiveProcess),
+			Interactive:      typ&serviceInteractiveProcess != 0,
+			ErrorControl:     enumField(values, "ErrorControl", serviceErrorControls),
+			Group:            stringField(values, "Group"),
+			DependOnService:  stringsField(values, "DependOnService"),
+			DependOnGroup:    stringsField(values, "DependOnGroup"),
+		})
+	}
+
+	sort.Slice(retval, func(i, j int) bool {
+		return strings.ToLower(retval[i].Name) < strings.ToLower(retval[j].Name)
+	})
+
+	return retval, nil
+}
+
+// enumField returns the name of the integer value name, empty if it's
+// missing, or its number if it has no name.
+func enumField(values map[string]interface{}, name string, names []string) string {
+	value, ok := values[name].(uint64)
+	if !ok {
+		return ""
+	}
+	if value < uint64(len(names)) {
+		return names[value]
+	}
+
+	return fmt.Sprint(value)
+}
+
+func serviceType(typ uint64) string {
+	if name, ok := serviceTypes[typ]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("0x%X", typ)
+}
+
+// stringsField returns the REG_MULTI_SZ value name without empty strings,
+// a single REG_SZ is accepted as well.
+func stringsField(values map[string]interface{}, name string) []string {
+	retval := []string{}
+	switch value := values[name].(type) {
+	case []string:
+		for _, str := range value {
+			if str = strings.TrimSpace(str); str != "" {
+				retval = append(retval, str)
+			}
+		}
+	case string:
+		if value = strings.TrimSpace(value); value != "" {
+			retval = append(retval, value)
+		}
+	}
+
+	return retval
+}
+
+// normalizeImagePath converts the NT path forms the loader accepts for
+// drivers, "\SystemRoot\...", "\??\C:\..." and paths relative to the
+// system root, to Win32 paths.
+func normalizeImagePath(path string) string {
+	root := os.Getenv("SystemRoot")
+	switch {
+	case path == "":
+		return path
+	case strings.HasPrefix(path, `\??\`):
+		return path[len(`\??\`):]
+	case len(path) > len(`\SystemRoot\`) && strings.EqualFold(path[:len(`\SystemRoot\`)], `\SystemRoot\`):
+		return filepath.Join(root, path[len(`\SystemRoot\`):])
+	case strings.HasPrefix(path, `"`) || filepath.IsAbs(path) || root == "":
+		return path
+	default:
+		return filepath.Join(root, path)
+	}
+}
@@ -0,0 +1,62 @@
+//go:build windows
+
+package winreg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeImagePath(t *testing.T) {
+	t.Log("Testing service image paths.")
+	{
+		root := os.Getenv("SystemRoot")
+		for testID, test := range []struct {
+			path, expect string
+		}{
+			{`\SystemRoot\System32\drivers\disk.sys`, filepath.Join(root, `System32\drivers\disk.sys`)},
+			{`System32\drivers\disk.sys`, filepath.Join(root, `System32\drivers\disk.sys`)},
+			{`\??\C:\Drivers\x.sys`, `C:\Drivers\x.sys`},
+			{`"C:\Program Files\App\app.exe" -service`, `"C:\Program Files\App\app.exe" -service`},
+		} {
+			t.Logf("\tTest %d:\t%s.", testID, test.path)
+			{
+				if path := normalizeImagePath(test.path); path != test.expect {
+					t.Fatalf("\t%s\tInvalid path, got %s, expect %s.", failed, path, test.expect)
+				}
+				t.Logf("\t%s\tPath is normalized.", success)
+			}
+		}
+	}
+}
+
+func TestServices(t *testing.T) {
+	t.Log("Testing services configuration.")
+	{
+		services, err := (&Services{}).List()
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to list services: %v.", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tEventLog service.", testID)
+		{
+			var svc *Service
+			for i := range services {
+				if strings.EqualFold(services[i].Name, "EventLog") {
+					svc = &services[i]
+				}
+			}
+			if svc == nil {
+				t.Fatalf("\t%s\tEventLog service is not found.", failed)
+			}
+			if svc.Start != "auto" || !strings.HasSuffix(svc.Type, "process") || strings.Contains(svc.ImagePath, "%") ||
+				strings.HasPrefix(svc.DisplayName, "@") {
+				t.Fatalf("\t%s\tEventLog service is invalid: %+v.", failed, *svc)
+			}
+			t.Logf("\t%s\tService is decoded.", success)
+		}
+	}
+}
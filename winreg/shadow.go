@@ -0,0 +1,137 @@
+//go:build windows
+
+package winreg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ShadowCopy is a Volume Shadow Copy snapshot of a volume. Hives read
+// from it give a consistent point-in-time picture of the registry as of
+// the moment the snapshot was taken.
+type ShadowCopy struct {
+	ID           string // Shadow copy ID, e.g. "{A1B2...}"
+	DeviceObject string // Device path, e.g. "\\?\GLOBALROOT\Device\HarddiskVolumeShadowCopy1"
+	owned        bool   // Created by CreateShadowCopy
+}
+
+// CreateShadowCopy creates a new shadow copy of volume (e.g. "C:\").
+// The caller must be an administrator and should call Delete() when done.
+func CreateShadowCopy(volume string) (*ShadowCopy, error) {
+	if !strings.HasSuffix(volume, "\\") {
+		volume += "\\"
+	}
+
+	out, err := runPowerShell(fmt.Sprintf(`$r = Invoke-CimMethod -ClassName Win32_ShadowCopy -MethodName Create -Arguments @{Volume=%s; Context='ClientAccessible'}
+if ($r.ReturnValue -ne 0) { throw "Win32_ShadowCopy.Create returned $($r.ReturnValue)" }
+$s = Get-CimInstance -ClassName Win32_ShadowCopy -Filter "ID='$($r.ShadowID)'"
+$s.ID
+$s.DeviceObject`, psQuote(volume)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create shadow copy of %s: %w", volume, err)
+	}
+
+	lines := strings.Fields(out)
+	if len(lines) != 2 {
+		return nil, fmt.Errorf("unable to create shadow copy of %s: unexpected output %q", volume, out)
+	}
+
+	return &ShadowCopy{ID: lines[0], DeviceObject: lines[1], owned: true}, nil
+}
+
+// OpenShadowCopy returns an existing shadow copy by its device path.
+func OpenShadowCopy(device string) *ShadowCopy {
+	return &ShadowCopy{DeviceObject: strings.TrimSuffix(device, "\\")}
+}
+
+// Image returns an offline image which reads hive files from the
+// snapshot. Hives are copied to a temporary directory before loading,
+// since the snapshot is read-only.
+func (sc *ShadowCopy) Image() (*OfflineImage, error) {
+	return OpenSnapshotImage(sc.DeviceObject + "\\")
+}
+
+// Delete removes a shadow copy created by CreateShadowCopy. Shadow copies
+// opened with OpenShadowCopy are left intact.
+func (sc *ShadowCopy) Delete() error {
+	if !sc.owned {
+		return nil
+	}
+
+	if _, err := runPowerShell(fmt.Sprintf(`Get-CimInstance -ClassName Win32_ShadowCopy -Filter %s | Remove-CimInstance`, psQuote("ID='"+sc.ID+"'"))); err != nil {
+		return fmt.Errorf("unable to delete shadow copy %s: %w", sc.ID, err)
+	}
+	sc.owned = false
+
+	return nil
+}
+
+// OpenSnapshotImage returns an offline image which stages hive files from
+// root before loading. It is useful for snapshots exposed as a directory
+// (e.g. a mounted shadow copy or backup).
+func OpenSnapshotImage(root string) (*OfflineImage, error) {
+	img, err := OpenOfflineImage(root)
+	if err != nil {
+		return nil, err
+	}
+	if img.staging, err = os.MkdirTemp("", "winreg-vss-"); err != nil {
+		return nil, fmt.Errorf("unable to create staging directory: %w", err)
+	}
+	img.owned = true
+
+	return img, nil
+}
+
+// OpenSnapshotImageIn returns an offline image as OpenSnapshotImage()
+// does, staging hive files in the existing directory dir, e.g. on a volume
+// with enough space for large hives. Close() removes the staged files but
+// leaves dir in place.
+func OpenSnapshotImageIn(root, dir string) (*OfflineImage, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to use staging directory: %w", err)
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("unable to use staging directory: %s is not a directory", dir)
+	}
+	img, err := OpenOfflineImage(root)
+	if err != nil {
+		return nil, err
+	}
+	img.staging = dir
+
+	return img, nil
+}
+
+func runPowerShell(script string) (string, error) {
+	return runPowerShellContext(context.Background(), script)
+}
+
+// runPowerShellContext runs script as runPowerShell does, killing
+// PowerShell when ctx is done.
+func runPowerShellContext(ctx context.Context, script string) (string, error) {
+	cmd := exec.CommandContext(ctx, "powershell.exe", "-NoProfile", "-NonInteractive", "-Command", "$ErrorActionPreference = 'Stop'\n"+script)
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		var ee *exec.ExitError
+		if errors.As(err, &ee) && len(ee.Stderr) > 0 {
+			return "", errors.New(strings.TrimSpace(string(ee.Stderr)))
+		}
+		return "", err
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// psQuote returns s as a single-quoted PowerShell string literal.
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
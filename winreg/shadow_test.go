@@ -0,0 +1,82 @@
+//go:build windows
+
+package winreg
+
+import (
+	"os"
+	"testing"
+
+	"github.com/knadh/koanf/v2"
+)
+
+func TestSnapshotImage(t *testing.T) {
+	t.Log("Testing snapshot image hive staging.")
+	{
+		root := t.TempDir()
+		createOfflineImage(t, root)
+
+		img, err := OpenSnapshotImage(root)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to open snapshot image: %v", failed, err)
+		}
+		staging := img.staging
+
+		testID := 0
+		t.Logf("\tTest %d:\tread staged hive.", testID)
+		{
+			p, err := img.Provider(Config{Key: LOCAL_MACHINE, Path: "SOFTWARE\\" + testKey})
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to create provider: %v", failed, err)
+			}
+			k := koanf.New(".")
+			if err := k.Load(p, nil); err != nil {
+				t.Fatalf("\t%s\tUnable to read staged hive: %v.", failed, err)
+			}
+			if val := k.String("SubKey.StrValue"); val != "offline" {
+				t.Fatalf("\t%s\tSubKey.StrValue is invalid, got \"%s\", expect \"offline\".", failed, val)
+			}
+			t.Logf("\t%s\tStaged hive was read.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tstaging cleanup.", testID)
+		{
+			if err := img.Close(); err != nil {
+				t.Fatalf("\t%s\tUnable to close image: %v", failed, err)
+			}
+			if _, err := os.Stat(staging); !os.IsNotExist(err) {
+				t.Fatalf("\t%s\tStaging directory %s was not removed.", failed, staging)
+			}
+			t.Logf("\t%s\tStaging directory was removed.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tcaller staging directory.", testID)
+		{
+			dir := t.TempDir()
+			img, err := OpenSnapshotImageIn(root, dir)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to open snapshot image: %v", failed, err)
+			}
+			for _, path := range []string{"SOFTWARE\\" + testKey, "SYSTEM"} {
+				if _, err := img.Provider(Config{Key: LOCAL_MACHINE, Path: path}); err != nil {
+					t.Fatalf("\t%s\tUnable to create provider: %v", failed, err)
+				}
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) < 2 {
+				t.Fatalf("\t%s\tHives are not staged in %s, got %d files.", failed, dir, len(entries))
+			}
+			if err := img.Close(); err != nil {
+				t.Fatalf("\t%s\tUnable to close image: %v", failed, err)
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatalf("\t%s\tStaging directory %s was removed: %v.", failed, dir, err)
+			}
+			if len(entries) != 0 {
+				t.Fatalf("\t%s\tStaged files were not removed, got %d files.", failed, len(entries))
+			}
+			t.Logf("\t%s\tOnly staged files were removed.", success)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ReadErrors are the errors of the subkeys left out of a read with
+// SkipUnreadable. Like the result of errors.Join(), it unwraps to the
+// errors, which are *Error. Is and As match any of them also with Go
+// versions not following Unwrap() []error.
+type ReadErrors []error
+
+func (e ReadErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e ReadErrors) Unwrap() []error {
+	return e
+}
+
+func (e ReadErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e ReadErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// skippedKeys are the subkeys left out of a Read() with SkipUnreadable,
+// shared by the providers of Paths.
+type skippedKeys struct {
+	mu   sync.Mutex
+	keys []string
+	errs []error
+}
+
+func (k *skippedKeys) reset() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys, k.errs = nil, nil
+}
+
+func (k *skippedKeys) add(name string, err error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = append(k.keys, name)
+	k.errs = append(k.errs, err)
+}
+
+// Skipped returns full names of the subkeys left out of the last Read()
+// because they couldn't be read, sorted. It is always empty unless
+// SkipUnreadable or Audit is set.
+func (s *WinReg) Skipped() []string {
+	s.skipped.mu.Lock()
+	defer s.skipped.mu.Unlock()
+	retval := append([]string(nil), s.skipped.keys...)
+	sort.Strings(retval)
+
+	return retval
+}
+
+// ReadPartial reads the tree as Read() does. With SkipUnreadable set, the
+// errors of the subkeys left out are returned alongside the tree as
+// ReadErrors, so each failed branch can be reported; the tree is nil only
+// if the read failed as a whole.
+func (s *WinReg) ReadPartial() (map[string]interface{}, error) {
+	retval, err := s.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	s.skipped.mu.Lock()
+	defer s.skipped.mu.Unlock()
+	if len(s.skipped.errs) > 0 {
+		return retval, append(ReadErrors(nil), s.skipped.errs...)
+	}
+
+	return retval, nil
+}
@@ -0,0 +1,117 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// deniedAPI serves an in-memory registry, denying access to keys whose
+// path ends with "Denied".
+type deniedAPI struct {
+	*Memory
+}
+
+func (a deniedAPI) OpenKey(k registry.Key, path string, access uint32) (registry.Key, error) {
+	if strings.HasSuffix(path, "Denied") {
+		return 0, windows.ERROR_ACCESS_DENIED
+	}
+	return a.Memory.OpenKey(k, path, access)
+}
+
+func TestSkipUnreadable(t *testing.T) {
+	t.Log("Testing partial read of keys without access.")
+	{
+		m := NewMemory()
+		err := m.Load(LOCAL_MACHINE, "SOFTWARE\\Vendor", map[string]interface{}{
+			"Name":   "vendor",
+			"App":    map[string]interface{}{"Name": "app", "Denied": map[string]interface{}{"Secret": "secret"}},
+			"Denied": map[string]interface{}{"Secret": "secret"},
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to load memory registry: %v", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tread fails by default.", testID)
+		{
+			p := Provider(Config{Key: LOCAL_MACHINE, Path: "SOFTWARE\\Vendor"})
+			p.api = deniedAPI{m}
+			if _, err := p.Read(); !errors.Is(err, ErrAccessDenied) {
+				t.Fatalf("\t%s\tRead() should fail with ErrAccessDenied, got: %v.", failed, err)
+			}
+			t.Logf("\t%s\tAccess denied is reported.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tunreadable keys are skipped.", testID)
+		{
+			p := Provider(Config{Key: LOCAL_MACHINE, Path: "SOFTWARE\\Vendor", SkipUnreadable: true})
+			p.api = deniedAPI{m}
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			expected := map[string]interface{}{"Name": "vendor", "App": map[string]interface{}{"Name": "app"}}
+			if !reflect.DeepEqual(tree, expected) {
+				t.Fatalf("\t%s\tTree is invalid, got %v.", failed, tree)
+			}
+			skipped := []string{"HKLM\\SOFTWARE\\Vendor\\App\\Denied", "HKLM\\SOFTWARE\\Vendor\\Denied"}
+			if !reflect.DeepEqual(p.Skipped(), skipped) {
+				t.Fatalf("\t%s\tSkipped keys are invalid, got %v.", failed, p.Skipped())
+			}
+			t.Logf("\t%s\tReadable keys are read.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\taggregated errors.", testID)
+		{
+			p := Provider(Config{Key: LOCAL_MACHINE, Path: "SOFTWARE\\Vendor", SkipUnreadable: true})
+			p.api = deniedAPI{m}
+			tree, err := p.ReadPartial()
+			if tree["Name"] != "vendor" {
+				t.Fatalf("\t%s\tPartial tree is not returned, got %v.", failed, tree)
+			}
+			var errs ReadErrors
+			if !errors.As(err, &errs) || len(errs) != 2 {
+				t.Fatalf("\t%s\tReadPartial() should return two errors, got: %v.", failed, err)
+			}
+			for _, err := range errs {
+				var e *Error
+				if !errors.As(err, &e) || e.Code != windows.ERROR_ACCESS_DENIED || !strings.HasSuffix(e.Path, "Denied") {
+					t.Fatalf("\t%s\tInvalid error: %v.", failed, err)
+				}
+			}
+			var e *Error
+			if !errs.Is(windows.ERROR_ACCESS_DENIED) || !errs.As(&e) || e.Code != windows.ERROR_ACCESS_DENIED {
+				t.Fatalf("\t%s\tErrors are not matched by Is and As.", failed)
+			}
+			t.Logf("\t%s\tErrors of all branches are returned.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\treads of copies.", testID)
+		{
+			p := Provider(Config{Key: LOCAL_MACHINE, Paths: []string{"SOFTWARE\\Vendor"}, SkipUnreadable: true})
+			p.paths[0].api = deniedAPI{m}
+			if _, err := p.Read(); err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			c := p.readCopy()
+			c.paths[0].api = m
+			if _, err := c.Read(); err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if len(p.Skipped()) != 2 || len(c.Skipped()) != 0 {
+				t.Fatalf("\t%s\tSkipped keys are shared, got %v and %v.", failed, p.Skipped(), c.Skipped())
+			}
+			t.Logf("\t%s\tCopies have their own skipped keys.", success)
+		}
+	}
+}
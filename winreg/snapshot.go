@@ -0,0 +1,448 @@
+//go:build windows
+
+package winreg
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func init() {
+	// Types of the tree beyond those gob registers itself: subtrees, raw
+	// strings, JSON arrays and FILETIME values
+	gob.Register(map[string]interface{}{})
+	gob.Register([][]uint16{})
+	gob.Register([]interface{}{})
+	gob.Register(time.Time{})
+}
+
+// Snapshot is a point-in-time copy of a provider's tree. It can be saved
+// to a file, loaded back later and used as a koanf.Provider.
+type Snapshot struct {
+	Key   string                 // Full name of the key the snapshot was taken from
+	Taken time.Time              // Time the snapshot was taken
+	Data  map[string]interface{} // Tree as returned by Read()
+}
+
+// SnapshotOptions controls how snapshots are saved and loaded.
+type SnapshotOptions struct {
+	EncryptionKey []byte // AES-128/192/256 key to encrypt with AES-GCM
+	Passphrase    string // Passphrase to derive the encryption key from, used if EncryptionKey is empty
+	Compression   int    // Compression of saved data, one of CompressNone/CompressGzip/CompressZstd constant
+
+	SigningKey crypto.Signer    // Ed25519 or ECDSA private key to sign saved snapshots with
+	VerifyKey  crypto.PublicKey // Ed25519 or ECDSA public key, if set only snapshots signed by it are loaded
+}
+
+// Compression methods of snapshots and exports. Compression is detected
+// automatically on load.
+const (
+	CompressNone = iota
+	CompressGzip
+	CompressZstd
+)
+
+// ErrSnapshotSignature is returned when a snapshot is not signed or its
+// signature doesn't match SnapshotOptions.VerifyKey.
+var ErrSnapshotSignature = errors.New("invalid snapshot signature")
+
+// ErrSnapshotDecrypt is returned when an encrypted snapshot can't be
+// decrypted: the key or passphrase is wrong or the file was modified.
+var ErrSnapshotDecrypt = errors.New("unable to decrypt snapshot")
+
+const (
+	snapshotMagic = "WRSNAP"
+
+	snapshotVersion = 1
+
+	snapshotEncrypted  = 1 << 0
+	snapshotCompressed = 1 << 1
+	snapshotSigned     = 1 << 2
+
+	snapshotKeyRaw    = 0
+	snapshotKeyPBKDF2 = 1
+
+	snapshotSaltSize   = 16
+	snapshotIterations = 600000
+	// Files asking for more are rejected before deriving the key
+	snapshotMaxIterations = 10 * snapshotIterations
+)
+
+// Snapshot reads the provider's tree into a snapshot.
+func (s *WinReg) Snapshot() (*Snapshot, error) {
+	data, err := s.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{Key: s.getKeyName(s.path), Taken: s.api.Now(), Data: data}, nil
+}
+
+// Read returns the snapshot tree, which makes Snapshot a koanf.Provider.
+func (sn *Snapshot) Read() (map[string]interface{}, error) {
+	return sn.Data, nil
+}
+
+// ReadBytes is not supported by snapshots.
+func (sn *Snapshot) ReadBytes() ([]byte, error) {
+	return nil, errors.New("winreg snapshot does not support this method")
+}
+
+// Save writes the snapshot to w.
+func (sn *Snapshot) Save(w io.Writer, opts SnapshotOptions) error {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(sn); err != nil {
+		return fmt.Errorf("unable to encode snapshot: %w", err)
+	}
+
+	header := []byte{snapshotVersion, 0}
+	if opts.SigningKey != nil {
+		header[1] |= snapshotSigned
+	}
+	body := payload.Bytes()
+	if opts.Compression != CompressNone {
+		header[1] |= snapshotCompressed
+		var err error
+		if body, err = compressBytes(opts.Compression, body); err != nil {
+			return fmt.Errorf("unable to compress snapshot: %w", err)
+		}
+	}
+	if opts.encrypted() {
+		header[1] |= snapshotEncrypted
+		var err error
+		if body, err = opts.encrypt(append([]byte(snapshotMagic), header...), body); err != nil {
+			return err
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(snapshotMagic)
+	bw.Write(header)
+	bw.Write(body)
+	if opts.SigningKey != nil {
+		// The signature covers everything before it and is followed by
+		// its length, so it can be found from the end of the file.
+		signed := append(append([]byte(snapshotMagic), header...), body...)
+		sig, err := signSnapshot(opts.SigningKey, signed)
+		if err != nil {
+			return err
+		}
+		bw.Write(sig)
+		bw.Write([]byte{byte(len(sig)), byte(len(sig) >> 8)})
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("unable to write snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// LoadSnapshot reads a snapshot saved by Save() from r.
+func LoadSnapshot(r io.Reader, opts SnapshotOptions) (*Snapshot, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read snapshot: %w", err)
+	}
+	// A whole snapshot file compressed by an external tool
+	if raw, err = decompressBytes(raw); err != nil {
+		return nil, fmt.Errorf("unable to decompress snapshot: %w", err)
+	}
+
+	headerSize := len(snapshotMagic) + 2
+	if len(raw) < headerSize || string(raw[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, errors.New("not a winreg snapshot")
+	}
+	version, flags := raw[len(snapshotMagic)], raw[len(snapshotMagic)+1]
+	if version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", version)
+	}
+
+	body := raw[headerSize:]
+	if flags&snapshotSigned != 0 {
+		if len(raw) < headerSize+2 {
+			return nil, ErrSnapshotSignature
+		}
+		sigLen := int(raw[len(raw)-2]) | int(raw[len(raw)-1])<<8
+		end := len(raw) - 2 - sigLen
+		if end < headerSize {
+			return nil, ErrSnapshotSignature
+		}
+		if opts.VerifyKey != nil {
+			if err := verifySnapshot(opts.VerifyKey, raw[:end], raw[end:len(raw)-2]); err != nil {
+				return nil, err
+			}
+		}
+		body = raw[headerSize:end]
+	} else if opts.VerifyKey != nil {
+		return nil, fmt.Errorf("snapshot is not signed: %w", ErrSnapshotSignature)
+	}
+	if flags&snapshotEncrypted != 0 {
+		if !opts.encrypted() {
+			return nil, errors.New("snapshot is encrypted, key or passphrase is required")
+		}
+		if body, err = opts.decrypt(raw[:headerSize], body); err != nil {
+			return nil, err
+		}
+	}
+	if flags&snapshotCompressed != 0 {
+		if body, err = decompressBytes(body); err != nil {
+			return nil, fmt.Errorf("unable to decompress snapshot: %w", err)
+		}
+	}
+
+	sn := &Snapshot{}
+	if err := gob.NewDecoder(bytes.NewReader(body)).Decode(sn); err != nil {
+		return nil, fmt.Errorf("unable to decode snapshot: %w", err)
+	}
+
+	return sn, nil
+}
+
+func signSnapshot(key crypto.Signer, data []byte) ([]byte, error) {
+	var (
+		sig []byte
+		err error
+	)
+
+	switch key.Public().(type) {
+	case ed25519.PublicKey:
+		sig, err = key.Sign(rand.Reader, data, crypto.Hash(0))
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		sig, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	default:
+		return nil, fmt.Errorf("unsupported snapshot signing key %T", key.Public())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign snapshot: %w", err)
+	}
+
+	return sig, nil
+}
+
+func verifySnapshot(key crypto.PublicKey, data, sig []byte) error {
+	var ok bool
+
+	switch key := key.(type) {
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(key, data, sig)
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		ok = ecdsa.VerifyASN1(key, digest[:], sig)
+	default:
+		return fmt.Errorf("unsupported snapshot verification key %T", key)
+	}
+	if !ok {
+		return ErrSnapshotSignature
+	}
+
+	return nil
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// compressWriter returns a writer compressing to w by method, which must
+// be closed to flush it.
+func compressWriter(method int, w io.Writer) (io.WriteCloser, error) {
+	switch method {
+	case CompressGzip:
+		return gzip.NewWriter(w), nil
+	case CompressZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported compression %d", method)
+	}
+}
+
+func compressBytes(method int, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := compressWriter(method, &buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err = zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decompressBytes decompresses gzip or zstd data detected by its magic
+// number, other data is returned as is.
+func decompressBytes(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+
+		return io.ReadAll(zr)
+	case bytes.HasPrefix(data, zstdMagic):
+		zr, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+
+		return zr.DecodeAll(data, nil)
+	default:
+		return data, nil
+	}
+}
+
+func (o *SnapshotOptions) encrypted() bool {
+	return len(o.EncryptionKey) > 0 || o.Passphrase != ""
+}
+
+// encrypt seals data with AES-GCM, authenticating the file header too.
+// The output is: key derivation type, [iterations, salt], nonce, ciphertext.
+func (o *SnapshotOptions) encrypt(header, data []byte) ([]byte, error) {
+	var (
+		params []byte
+		key    = o.EncryptionKey
+	)
+
+	if len(key) == 0 {
+		salt := make([]byte, snapshotSaltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("unable to encrypt snapshot: %w", err)
+		}
+		params = make([]byte, 5, 5+snapshotSaltSize)
+		params[0] = snapshotKeyPBKDF2
+		binary.LittleEndian.PutUint32(params[1:], snapshotIterations)
+		params = append(params, salt...)
+		key = pbkdf2SHA256([]byte(o.Passphrase), salt, snapshotIterations, 32)
+	} else {
+		params = []byte{snapshotKeyRaw}
+	}
+
+	aead, err := newSnapshotAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("unable to encrypt snapshot: %w", err)
+	}
+
+	out := append(params, nonce...)
+	return aead.Seal(out, nonce, data, append(header, params...)), nil
+}
+
+func (o *SnapshotOptions) decrypt(header, data []byte) ([]byte, error) {
+	if len(data) < 1 {
+		return nil, ErrSnapshotDecrypt
+	}
+
+	var (
+		params []byte
+		key    = o.EncryptionKey
+	)
+	switch data[0] {
+	case snapshotKeyRaw:
+		if len(key) == 0 {
+			return nil, errors.New("snapshot is encrypted with a key, passphrase can't be used")
+		}
+		params = data[:1]
+	case snapshotKeyPBKDF2:
+		if len(data) < 5+snapshotSaltSize {
+			return nil, ErrSnapshotDecrypt
+		}
+		if o.Passphrase == "" {
+			return nil, errors.New("snapshot is encrypted with a passphrase, key can't be used")
+		}
+		params = data[:5+snapshotSaltSize]
+		iterations := binary.LittleEndian.Uint32(data[1:])
+		if iterations < snapshotIterations || iterations > snapshotMaxIterations {
+			return nil, fmt.Errorf("%w: %d PBKDF2 iterations out of range", ErrSnapshotDecrypt, iterations)
+		}
+		key = pbkdf2SHA256([]byte(o.Passphrase), data[5:5+snapshotSaltSize], int(iterations), 32)
+	default:
+		return nil, fmt.Errorf("unsupported snapshot key derivation %d", data[0])
+	}
+
+	aead, err := newSnapshotAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	rest := data[len(params):]
+	if len(rest) < aead.NonceSize() {
+		return nil, ErrSnapshotDecrypt
+	}
+	nonce, ciphertext := rest[:aead.NonceSize()], rest[aead.NonceSize():]
+
+	plain, err := aead.Open(nil, nonce, ciphertext, append(append([]byte{}, header...), params...))
+	if err != nil {
+		return nil, ErrSnapshotDecrypt
+	}
+
+	return plain, nil
+}
+
+func newSnapshotAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot encryption key: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2SHA256 derives a key from a password as described in RFC 8018.
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+
+	var (
+		buf = make([]byte, 4)
+		dk  = make([]byte, 0, blocks*hashLen)
+		u   = make([]byte, hashLen)
+	)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf, uint32(block))
+		prf.Write(buf)
+		dk = prf.Sum(dk)
+		t := dk[len(dk)-hashLen:]
+		copy(u, t)
+
+		for i := 2; i <= iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = u[:0]
+			u = prf.Sum(u)
+			for j := range u {
+				t[j] ^= u[j]
+			}
+		}
+	}
+
+	return dk[:keyLen]
+}
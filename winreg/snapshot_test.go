@@ -0,0 +1,257 @@
+//go:build windows
+
+package winreg
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Log("Testing snapshot save and load.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		sn, err := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, DefaultValue: "Default"}).Snapshot()
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to take snapshot: %v.", failed, err)
+		}
+
+		tests := []struct {
+			name string
+			save SnapshotOptions
+			load SnapshotOptions
+		}{
+			{"plain", SnapshotOptions{}, SnapshotOptions{}},
+			{"key", SnapshotOptions{EncryptionKey: make([]byte, 32)}, SnapshotOptions{EncryptionKey: make([]byte, 32)}},
+			{"passphrase", SnapshotOptions{Passphrase: "secret"}, SnapshotOptions{Passphrase: "secret"}},
+			{"gzip", SnapshotOptions{Compression: CompressGzip}, SnapshotOptions{}},
+			{"gzip and key", SnapshotOptions{Compression: CompressGzip, EncryptionKey: make([]byte, 16)}, SnapshotOptions{EncryptionKey: make([]byte, 16)}},
+			{"zstd", SnapshotOptions{Compression: CompressZstd}, SnapshotOptions{}},
+			{"zstd and passphrase", SnapshotOptions{Compression: CompressZstd, Passphrase: "secret"}, SnapshotOptions{Passphrase: "secret"}},
+		}
+		for testID, test := range tests {
+			t.Logf("\tTest %d:\t%s round trip.", testID, test.name)
+			var buf bytes.Buffer
+			if err := sn.Save(&buf, test.save); err != nil {
+				t.Fatalf("\t%s\tUnable to save snapshot: %v.", failed, err)
+			}
+			loaded, err := LoadSnapshot(&buf, test.load)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to load snapshot: %v.", failed, err)
+			}
+			if loaded.Key != sn.Key || !reflect.DeepEqual(loaded.Data, sn.Data) {
+				t.Fatalf("\t%s\tLoaded snapshot differs from the saved one.", failed)
+			}
+			t.Logf("\t%s\tSnapshot is restored.", success)
+		}
+
+		testID := len(tests)
+		t.Logf("\tTest %d:\texternally compressed file.", testID)
+		{
+			var buf bytes.Buffer
+			if err := sn.Save(&buf, SnapshotOptions{}); err != nil {
+				t.Fatalf("\t%s\tUnable to save snapshot: %v.", failed, err)
+			}
+			for _, method := range []int{CompressGzip, CompressZstd} {
+				compressed, err := compressBytes(method, buf.Bytes())
+				if err != nil {
+					t.Fatalf("\t%s\tUnable to compress snapshot: %v.", failed, err)
+				}
+				if _, err := LoadSnapshot(bytes.NewReader(compressed), SnapshotOptions{}); err != nil {
+					t.Fatalf("\t%s\tUnable to load snapshot: %v.", failed, err)
+				}
+			}
+			t.Logf("\t%s\tCompression is detected.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\twrong passphrase.", testID)
+		{
+			var buf bytes.Buffer
+			if err := sn.Save(&buf, SnapshotOptions{Passphrase: "secret"}); err != nil {
+				t.Fatalf("\t%s\tUnable to save snapshot: %v.", failed, err)
+			}
+			if _, err := LoadSnapshot(&buf, SnapshotOptions{Passphrase: "wrong"}); !errors.Is(err, ErrSnapshotDecrypt) {
+				t.Fatalf("\t%s\tExpected decryption error, got %v.", failed, err)
+			}
+			t.Logf("\t%s\tWrong passphrase is rejected.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tmodified file.", testID)
+		{
+			var buf bytes.Buffer
+			if err := sn.Save(&buf, SnapshotOptions{EncryptionKey: make([]byte, 16)}); err != nil {
+				t.Fatalf("\t%s\tUnable to save snapshot: %v.", failed, err)
+			}
+			raw := buf.Bytes()
+			raw[len(raw)-1] ^= 1
+			if _, err := LoadSnapshot(bytes.NewReader(raw), SnapshotOptions{EncryptionKey: make([]byte, 16)}); !errors.Is(err, ErrSnapshotDecrypt) {
+				t.Fatalf("\t%s\tExpected decryption error, got %v.", failed, err)
+			}
+			t.Logf("\t%s\tModified file is rejected.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\titeration count out of range.", testID)
+		{
+			var buf bytes.Buffer
+			if err := sn.Save(&buf, SnapshotOptions{Passphrase: "secret"}); err != nil {
+				t.Fatalf("\t%s\tUnable to save snapshot: %v.", failed, err)
+			}
+			for _, iterations := range []uint32{0, snapshotIterations - 1, snapshotMaxIterations + 1, math.MaxUint32} {
+				raw := append([]byte{}, buf.Bytes()...)
+				binary.LittleEndian.PutUint32(raw[len(snapshotMagic)+3:], iterations)
+				if _, err := LoadSnapshot(bytes.NewReader(raw), SnapshotOptions{Passphrase: "secret"}); !errors.Is(err, ErrSnapshotDecrypt) {
+					t.Fatalf("\t%s\tExpected decryption error for %d iterations, got %v.", failed, iterations, err)
+				}
+			}
+			t.Logf("\t%s\tKey derivation is not attempted.", success)
+		}
+	}
+}
+
+func TestSnapshotDecoded(t *testing.T) {
+	t.Log("Testing snapshots of decoded trees.")
+	{
+		m := NewMemory()
+		filetime := make([]byte, 8)
+		binary.LittleEndian.PutUint64(filetime, 132223104000000000)
+		err := m.Load(CURRENT_USER, "SOFTWARE\\Vendor", map[string]interface{}{
+			"Installed": filetime,
+			"Ports":     []byte{80, 0, 0, 0, 187, 1, 0, 0},
+			"Offset":    uint32(0xFFFFFFFF),
+			"Options":   `{"list": [1, "a", null], "on": true}`,
+			"Raw":       "raw",
+			"Names":     []string{"a", "b"},
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to load memory registry: %v", failed, err)
+		}
+		p := Provider(Config{
+			Key:    CURRENT_USER,
+			Path:   "SOFTWARE\\Vendor",
+			Memory: m,
+			Decoders: map[string]Decoder{
+				"Installed": DecodeFILETIME,
+				"Ports":     DecodeDWORDs,
+				"Offset":    DecodeInt32,
+				"Options":   DecodeJSON,
+			},
+		})
+		sn, err := p.Snapshot()
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to take snapshot: %v.", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tround trip.", testID)
+		{
+			var buf bytes.Buffer
+			if err := sn.Save(&buf, SnapshotOptions{}); err != nil {
+				t.Fatalf("\t%s\tUnable to save snapshot: %v.", failed, err)
+			}
+			loaded, err := LoadSnapshot(&buf, SnapshotOptions{})
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to load snapshot: %v.", failed, err)
+			}
+			installed, ok := loaded.Data["Installed"].(time.Time)
+			if !ok || !installed.Equal(sn.Data["Installed"].(time.Time)) {
+				t.Fatalf("\t%s\tTime is not restored, got %#v.", failed, loaded.Data["Installed"])
+			}
+			delete(loaded.Data, "Installed")
+			delete(sn.Data, "Installed")
+			if !reflect.DeepEqual(loaded.Data, sn.Data) {
+				t.Fatalf("\t%s\tTree is not restored, got %#v, expect %#v.", failed, loaded.Data, sn.Data)
+			}
+			t.Logf("\t%s\tDecoded values are restored.", success)
+		}
+	}
+}
+
+func TestSnapshotSignature(t *testing.T) {
+	t.Log("Testing signed snapshots.")
+	{
+		sn := &Snapshot{Key: "HKCU\\SOFTWARE\\" + testKey, Data: map[string]interface{}{"on": uint64(1)}}
+
+		edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to generate key: %v.", failed, err)
+		}
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to generate key: %v.", failed, err)
+		}
+
+		tests := []struct {
+			name string
+			sign crypto.Signer
+			pub  crypto.PublicKey
+			opts SnapshotOptions
+		}{
+			{"Ed25519", edKey, edPub, SnapshotOptions{}},
+			{"ECDSA", ecKey, &ecKey.PublicKey, SnapshotOptions{}},
+			{"Ed25519 encrypted", edKey, edPub, SnapshotOptions{EncryptionKey: make([]byte, 16), Compression: CompressGzip}},
+		}
+		for testID, test := range tests {
+			t.Logf("\tTest %d:\t%s signature.", testID, test.name)
+			var buf bytes.Buffer
+			save := test.opts
+			save.SigningKey = test.sign
+			if err := sn.Save(&buf, save); err != nil {
+				t.Fatalf("\t%s\tUnable to save snapshot: %v.", failed, err)
+			}
+			raw := buf.Bytes()
+
+			load := test.opts
+			load.VerifyKey = test.pub
+			if _, err := LoadSnapshot(bytes.NewReader(raw), load); err != nil {
+				t.Fatalf("\t%s\tUnable to load snapshot: %v.", failed, err)
+			}
+
+			raw[len(snapshotMagic)+3] ^= 1
+			if _, err := LoadSnapshot(bytes.NewReader(raw), load); !errors.Is(err, ErrSnapshotSignature) {
+				t.Fatalf("\t%s\tExpected signature error, got %v.", failed, err)
+			}
+			t.Logf("\t%s\tSignature is verified.", success)
+		}
+
+		testID := len(tests)
+		t.Logf("\tTest %d:\tunsigned snapshot.", testID)
+		{
+			var buf bytes.Buffer
+			if err := sn.Save(&buf, SnapshotOptions{}); err != nil {
+				t.Fatalf("\t%s\tUnable to save snapshot: %v.", failed, err)
+			}
+			if _, err := LoadSnapshot(&buf, SnapshotOptions{VerifyKey: edPub}); !errors.Is(err, ErrSnapshotSignature) {
+				t.Fatalf("\t%s\tExpected signature error, got %v.", failed, err)
+			}
+			t.Logf("\t%s\tUnsigned snapshot is rejected.", success)
+		}
+	}
+}
+
+func TestPBKDF2(t *testing.T) {
+	t.Log("Testing PBKDF2-HMAC-SHA256 (RFC 7914 test vector).")
+	{
+		dk := hex.EncodeToString(pbkdf2SHA256([]byte("passwd"), []byte("salt"), 1, 64))
+		expect := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+		if dk != expect {
+			t.Fatalf("\t%s\tGot %s, expect %s.", failed, dk, expect)
+		}
+		t.Logf("\t%s\tDerived key is valid.", success)
+	}
+}
@@ -0,0 +1,44 @@
+//go:build windows
+
+package winreg
+
+import (
+	"bytes"
+
+	"golang.org/x/sys/windows"
+)
+
+// ValueReader reads the data of a single value in its stored form. It
+// implements io.Reader, io.ReaderAt, io.Seeker and io.WriterTo.
+type ValueReader struct {
+	*bytes.Reader
+	Type uint32 // Registry type of the value, e.g. registry.BINARY
+}
+
+// OpenValue returns a reader of the value name of the key path relative
+// to the provider's path. It is meant for large REG_BINARY blobs left out
+// of Read() by MaxValueSize: they can be hashed or copied without being
+// part of the configuration tree. The registry API returns a value in one
+// piece only, so the reader holds a single copy of the data.
+func (s *WinReg) OpenValue(path, name string) (*ValueReader, error) {
+	if err := s.singleKey(); err != nil {
+		return nil, err
+	}
+	root, err := s.connect()
+	if err != nil {
+		return nil, err
+	}
+	if root != s.key {
+		defer s.api.CloseKey(root)
+	}
+
+	v, err := s.readRaw(root, path, name)
+	if err != nil {
+		return nil, err
+	}
+	if !v.Exists {
+		return nil, s.keyError("read", joinPath(s.path, path), name, windows.ERROR_FILE_NOT_FOUND)
+	}
+
+	return &ValueReader{Reader: bytes.NewReader(v.Data), Type: v.Type}, nil
+}
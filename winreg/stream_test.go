@@ -0,0 +1,56 @@
+//go:build windows
+
+package winreg
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestOpenValue(t *testing.T) {
+	t.Log("Testing large value streaming.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, MaxValueSize: 2})
+
+		testID := 0
+		t.Logf("\tTest %d:\tlarge value is left out.", testID)
+		{
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if _, ok := tree["SubKeyA"].(map[string]interface{})["Binary"]; ok {
+				t.Fatalf("\t%s\tBinary value should be left out.", failed)
+			}
+			t.Logf("\t%s\tValue is left out.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tvalue reader.", testID)
+		{
+			r, err := p.OpenValue("SubKeyA", "Binary")
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to open value: %v.", failed, err)
+			}
+			data, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read value: %v.", failed, err)
+			}
+			if r.Type != registry.BINARY || !bytes.Equal(data, []byte{1, 2, 3}) {
+				t.Fatalf("\t%s\tValue is invalid, got %d %v.", failed, r.Type, data)
+			}
+			if _, err := p.OpenValue("SubKeyA", "Missing"); !errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+				t.Fatalf("\t%s\tOpenValue() of a missing value should fail, got: %v.", failed, err)
+			}
+			t.Logf("\t%s\tValue is read.", success)
+		}
+	}
+}
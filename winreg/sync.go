@@ -0,0 +1,275 @@
+//go:build windows
+
+package winreg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// SyncReport lists changes made by Sync(). Paths are relative to the
+// provider's key and sorted.
+type SyncReport struct {
+	Created     []string // Value paths which didn't exist, e.g. "SubKey\Value"
+	Updated     []string // Value paths whose type or data changed
+	Deleted     []string // Value paths missing in the tree
+	CreatedKeys []string // Subkeys which didn't exist
+	DeletedKeys []string // Subkeys missing in the tree, deleted with their values and subkeys
+}
+
+// syncKey is a key of a synchronized subtree.
+type syncKey struct {
+	path   string              // As in the registry or in the tree
+	values map[string]rawValue // By upper-cased name
+}
+
+// Sync makes the provider's subtree equal to tree: values are written as
+// by Apply(), values and subkeys missing in tree are deleted. Unchanged
+// values are not written. With TypesKey, values are stored as the types
+// listed in the types map of their key, other existing values keep their
+// types as by Apply(). Only the keys and values read by Read() are
+// synchronized: those deeper than MaxDepth or left out by the key and
+// value filters are kept. The default key value is synchronized only if
+// DefaultValue is set. With NameMapper, DelimEscape or Flatten,
+// ErrMappedNames is returned. An error stops the synchronization, the
+// report then lists the changes made so far.
+func (s *WinReg) Sync(tree map[string]interface{}) (*SyncReport, error) {
+	if err := s.singleKey(); err != nil {
+		return nil, err
+	}
+	if err := s.namesWritable(); err != nil {
+		return nil, err
+	}
+	if strings.Trim(s.path, "\\") == "" {
+		return nil, errors.New("predefined keys can't be synchronized")
+	}
+	root, err := s.connect()
+	if err != nil {
+		return nil, err
+	}
+	if root != s.key {
+		defer s.api.CloseKey(root)
+	}
+	current := make(map[string]*syncKey)
+	if err = s.currentKeys(root, "", 1, current); err != nil && !errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+		return nil, err
+	}
+
+	desired := make(map[string]*syncKey)
+	if err = s.desiredKeys("", tree, current, desired); err != nil {
+		return nil, err
+	}
+
+	report := &SyncReport{}
+	// Stale keys go first, so their values aren't reported one by one
+	var deleted []string
+	for _, name := range sortedKeys(current) {
+		if _, ok := desired[name]; ok || hasParent(deleted, name) {
+			continue
+		}
+		if err = s.deleteTree(root, joinPath(s.path, current[name].path)); err != nil {
+			return report, err
+		}
+		deleted = append(deleted, name)
+		report.DeletedKeys = append(report.DeletedKeys, current[name].path)
+	}
+
+	for _, name := range sortedKeys(desired) {
+		k, cur := desired[name], current[name]
+		if cur == nil {
+			if err = s.createKey(root, joinPath(s.path, k.path)); err != nil {
+				return report, err
+			}
+			if k.path != "" {
+				report.CreatedKeys = append(report.CreatedKeys, k.path)
+			}
+			cur = &syncKey{values: map[string]rawValue{}}
+		}
+
+		for _, value := range sortedValues(k.values) {
+			v := k.values[value]
+			prev, ok := cur.values[value]
+			if ok && prev.Type == v.Type && bytes.Equal(prev.Data, v.Data) {
+				continue
+			}
+			if err = s.writeRaw(root, v); err != nil {
+				return report, err
+			}
+			if ok {
+				report.Updated = append(report.Updated, s.valuePath(v))
+			} else {
+				report.Created = append(report.Created, s.valuePath(v))
+			}
+		}
+		for _, value := range sortedValues(cur.values) {
+			if _, ok := k.values[value]; ok {
+				continue
+			}
+			v := cur.values[value]
+			v.Exists = false
+			if err = s.writeRaw(root, v); err != nil {
+				return report, err
+			}
+			report.Deleted = append(report.Deleted, s.valuePath(v))
+		}
+	}
+	sort.Strings(report.Created)
+	sort.Strings(report.Updated)
+	sort.Strings(report.Deleted)
+
+	return report, nil
+}
+
+// desiredKeys converts tree into keys with values in their stored form,
+// values in current keep their types.
+func (s *WinReg) desiredKeys(path string, tree map[string]interface{}, current, out map[string]*syncKey) error {
+	k := &syncKey{path: path, values: make(map[string]rawValue)}
+	out[strings.ToUpper(path)] = k
+	types, _ := tree[s.typesKey].(map[string]interface{})
+	for name, value := range tree {
+		if s.typesKey != "" && name == s.typesKey {
+			continue
+		}
+		if sub, ok := value.(map[string]interface{}); ok {
+			if err := s.desiredKeys(joinPath(path, name), sub, current, out); err != nil {
+				return err
+			}
+			continue
+		}
+		if value == nil {
+			continue
+		}
+
+		v := rawValue{Path: path, Name: name, Exists: true}
+		if s.defaultValue != "" && name == s.defaultValue {
+			v.Name = ""
+		}
+		var err error
+		if typ, ok := types[name].(string); ok {
+			if t, ok := valueTypeByName(typ); ok {
+				switch t {
+				case registry.BINARY, registry.NONE, registry.RESOURCE_LIST, registry.FULL_RESOURCE_DESCRIPTOR, registry.RESOURCE_REQUIREMENTS_LIST:
+					value, err = s.binaryData(value)
+				}
+				if err == nil {
+					v.Type, v.Data, err = encodeValueAs(t, value)
+				}
+			} else {
+				err = fmt.Errorf("unknown value type %s", typ)
+			}
+		} else {
+			var prev rawValue
+			if cur := current[strings.ToUpper(path)]; cur != nil {
+				prev = cur.values[strings.ToUpper(v.Name)]
+			}
+			v.Type, v.Data, err = s.encodeWrite(Write{Path: path, Name: v.Name, Value: value}, prev.Type, prev.Exists)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", joinPath(path, name), err)
+		}
+		k.values[strings.ToUpper(v.Name)] = v
+	}
+
+	return nil
+}
+
+// currentKeys reads the provider's subtree with values in their stored
+// form, limited by MaxDepth and filtered as by readKey.
+func (s *WinReg) currentKeys(root registry.Key, path string, level uint, out map[string]*syncKey) error {
+	full := joinPath(s.path, path)
+	s.limiter.Wait()
+	k, err := s.api.OpenKey(root, full, s.getAccess(registry.READ))
+	if err != nil {
+		return s.keyError("open", full, "", err)
+	}
+	defer s.api.CloseKey(k)
+
+	key := &syncKey{path: path, values: make(map[string]rawValue)}
+	out[strings.ToUpper(path)] = key
+	s.limiter.Wait()
+	values, err := s.api.ReadValueNames(k)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return s.keyError("enumerate", full, "", err)
+	}
+	for _, value := range values {
+		data, typ, err := s.getValueBytes(k, value)
+		if errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+			// The value was deleted after enumeration
+			continue
+		}
+		if err != nil {
+			return s.keyError("read", full, value, err)
+		}
+		if _, ok, err := s.valueSelected(value, typ); err != nil {
+			return s.keyError("read", full, value, err)
+		} else if !ok {
+			continue
+		}
+		key.values[strings.ToUpper(value)] = rawValue{Path: path, Name: value, Exists: true, Type: typ, Data: data}
+	}
+
+	if !s.readsSubKeys(full, level) {
+		return nil
+	}
+	s.limiter.Wait()
+	subKeys, err := s.api.ReadSubKeyNames(k)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return s.keyError("enumerate", full, "", err)
+	}
+	for _, subKey := range subKeys {
+		if !s.keySelected(joinPath(full, subKey)) {
+			continue
+		}
+		if err = s.currentKeys(root, joinPath(path, subKey), level+1, out); err != nil && !errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// valuePath returns the path of v as in the tree.
+func (s *WinReg) valuePath(v rawValue) string {
+	if v.Name == "" {
+		return joinPath(v.Path, s.defaultValue)
+	}
+	return joinPath(v.Path, v.Name)
+}
+
+// hasParent reports whether one of keys is a parent of the upper-cased
+// path.
+func hasParent(keys []string, path string) bool {
+	for _, k := range keys {
+		if strings.HasPrefix(path, k+"\\") {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(keys map[string]*syncKey) []string {
+	retval := make([]string, 0, len(keys))
+	for name := range keys {
+		retval = append(retval, name)
+	}
+	sort.Strings(retval)
+
+	return retval
+}
+
+func sortedValues(values map[string]rawValue) []string {
+	retval := make([]string, 0, len(values))
+	for name := range values {
+		retval = append(retval, name)
+	}
+	sort.Strings(retval)
+
+	return retval
+}
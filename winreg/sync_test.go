@@ -0,0 +1,172 @@
+//go:build windows
+
+package winreg
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestSync(t *testing.T) {
+	t.Log("Testing synchronization of trees.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey})
+		tree := map[string]interface{}{
+			"on":  uint32(1),
+			"off": uint32(2),
+			"New": "value",
+			"SubKeyA": map[string]interface{}{
+				"StrValue": "The quick brown fox jumps over the lazy dog",
+			},
+			"Empty": map[string]interface{}{},
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tconverge.", testID)
+		{
+			report, err := p.Sync(tree)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to synchronize: %v.", failed, err)
+			}
+			expected := &SyncReport{
+				Created:     []string{"New"},
+				Updated:     []string{"off"},
+				Deleted:     []string{"SubKeyA\\Binary", "SubKeyA\\Expand", "SubKeyA\\Int64", "SubKeyA\\IntVal", "SubKeyA\\StrList"},
+				CreatedKeys: []string{"Empty"},
+				DeletedKeys: []string{"SubKeyA\\Sub Key", "SubKeyB"},
+			}
+			if !reflect.DeepEqual(report, expected) {
+				t.Fatalf("\t%s\tReport is invalid, got %+v, expect %+v.", failed, report, expected)
+			}
+
+			read, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if len(read) != 5 || read["off"] != uint64(2) || len(read["SubKeyA"].(map[string]interface{})) != 1 {
+				t.Fatalf("\t%s\tTree is not synchronized, got %v.", failed, read)
+			}
+			t.Logf("\t%s\tTree is synchronized.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tno changes.", testID)
+		{
+			report, err := p.Sync(tree)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to synchronize: %v.", failed, err)
+			}
+			if !reflect.DeepEqual(report, &SyncReport{}) {
+				t.Fatalf("\t%s\tNothing should change, got %+v.", failed, report)
+			}
+			t.Logf("\t%s\tNothing is written.", success)
+		}
+	}
+}
+
+func TestTypesKey(t *testing.T) {
+	t.Log("Testing registry types of values.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, TypesKey: "__types"})
+		tree, err := p.Read()
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\ttypes read.", testID)
+		{
+			sub, _ := tree["SubKeyA"].(map[string]interface{})
+			types, _ := sub["__types"].(map[string]interface{})
+			if types["IntVal"] != "REG_DWORD" || types["Int64"] != "REG_QWORD" || types["Expand"] != "REG_EXPAND_SZ" {
+				t.Fatalf("\t%s\tTypes are invalid, got %v.", failed, types)
+			}
+			t.Logf("\t%s\tTypes are read.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\ttypes kept by writes.", testID)
+		{
+			// Read() returns the expanded string
+			tree["SubKeyA"].(map[string]interface{})["Expand"] = "Some %PATH%"
+			report, err := p.Sync(tree)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to synchronize: %v.", failed, err)
+			}
+			if !reflect.DeepEqual(report, &SyncReport{}) {
+				t.Fatalf("\t%s\tValues are changed, got %+v.", failed, report)
+			}
+			t.Logf("\t%s\tTypes are kept.", success)
+		}
+	}
+}
+
+func TestSyncRead(t *testing.T) {
+	t.Log("Testing synchronization of the read tree only.")
+	{
+		m := NewMemory()
+		err := m.Load(CURRENT_USER, "SOFTWARE\\Vendor", map[string]interface{}{
+			"Count":    uint32(1),
+			"MRU":      "file",
+			"Deep":     map[string]interface{}{"Name": "deep", "Deeper": map[string]interface{}{"Name": "deeper"}},
+			"Excluded": map[string]interface{}{"Name": "excluded"},
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to load memory registry: %v", failed, err)
+		}
+		p := Provider(Config{
+			Key:           CURRENT_USER,
+			Path:          "SOFTWARE\\Vendor",
+			Memory:        m,
+			MaxDepth:      2,
+			ExcludeValues: []string{"MRU"},
+			ExcludeKeys:   []*regexp.Regexp{regexp.MustCompile(`^Excluded$`)},
+		})
+
+		testID := 0
+		t.Logf("\tTest %d:\tsync of the read tree.", testID)
+		{
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			report, err := p.Sync(tree)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to synchronize: %v.", failed, err)
+			}
+			if !reflect.DeepEqual(report, &SyncReport{}) {
+				t.Fatalf("\t%s\tNothing should change, got %+v.", failed, report)
+			}
+			all, err := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\Vendor", Memory: m}).Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			deep, _ := all["Deep"].(map[string]interface{})
+			if all["MRU"] != "file" || all["Excluded"] == nil || deep["Deeper"] == nil {
+				t.Fatalf("\t%s\tKeys and values not read are deleted, got %v.", failed, all)
+			}
+			t.Logf("\t%s\tKeys and values not read are kept.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tsync of a predefined key.", testID)
+		{
+			hive := Provider(Config{Key: CURRENT_USER, Memory: m})
+			if _, err := hive.Sync(map[string]interface{}{}); err == nil {
+				t.Fatalf("\t%s\tSync() of a predefined key should fail.", failed)
+			}
+			all, err := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\Vendor", Memory: m}).Read()
+			if err != nil || all["Name"] != "vendor" {
+				t.Fatalf("\t%s\tKeys are deleted, got %v, %v.", failed, all, err)
+			}
+			t.Logf("\t%s\tPredefined keys are refused.", success)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+//go:build windows
+
+package winreg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// txAPI opens keys in a registry transaction, so everything read through
+// the opened keys belongs to one view of the registry.
+type txAPI struct {
+	regAPI
+	tx windows.Handle
+}
+
+func (a txAPI) OpenKey(k registry.Key, path string, access uint32) (registry.Key, error) {
+	return regOpenKeyTransacted(k, path, access, a.tx)
+}
+
+func (a txAPI) CreateKey(k registry.Key, path string, options, access uint32) (registry.Key, error) {
+	return regCreateKeyTransacted(k, path, options, access, a.tx)
+}
+
+// ApplyTransacted writes values as Apply does, inside a registry
+// transaction. Either all writes and created keys are committed or, if any
+// of them fails, none of them.
+func (s *WinReg) ApplyTransacted(writes []Write) error {
+	raw, err := s.encodeWrites(writes)
+	if err != nil {
+		return err
+	}
+	if s.host != "" {
+		return errors.New("transactions are not supported by remote registry")
+	}
+
+	tx, err := createTransaction("koanf-winreg write")
+	if err != nil {
+		return fmt.Errorf("unable to create transaction: %w", err)
+	}
+	defer windows.CloseHandle(tx)
+
+	p := *s
+	p.api = txAPI{regAPI: s.api, tx: tx}
+	if err := p.applyRaw(raw); err != nil {
+		rollbackTransaction(tx)
+		return err
+	}
+	if err := commitTransaction(tx); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// readTransacted reads the provider's key as readTree does, inside a
+// transaction which is rolled back afterwards.
+func (s *WinReg) readTransacted(ctx context.Context) (map[string]interface{}, bool, error) {
+	if s.host != "" {
+		return nil, false, errors.New("transactions are not supported by remote registry")
+	}
+
+	tx, err := createTransaction("koanf-winreg read")
+	if err != nil {
+		return nil, false, fmt.Errorf("unable to create transaction: %w", err)
+	}
+	// Nothing is written, closing the only handle rolls it back
+	defer windows.CloseHandle(tx)
+
+	p := *s
+	p.api = txAPI{regAPI: s.api, tx: tx}
+	tree, missing, err := p.readTree(ctx)
+	if p.Torn() {
+		atomic.StoreInt32(&s.torn, 1)
+	}
+
+	return tree, missing, err
+}
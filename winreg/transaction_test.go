@@ -0,0 +1,122 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestTransactedRead(t *testing.T) {
+	t.Log("Testing reads inside a transaction.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		testID := 0
+		t.Logf("\tTest %d:\texisting key.", testID)
+		{
+			expected, err := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey}).Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, Transacted: true})
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry in transaction: %v.", failed, err)
+			}
+			if !reflect.DeepEqual(tree, expected) {
+				t.Fatalf("\t%s\tTrees differ, got %v, expect %v.", failed, tree, expected)
+			}
+			t.Logf("\t%s\tTrees are equal.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tmissing key with defaults.", testID)
+		{
+			p := Provider(Config{
+				Key:        CURRENT_USER,
+				Path:       "SOFTWARE\\" + testKey + "\\Missing",
+				Transacted: true,
+				Defaults:   map[string]interface{}{"on": uint64(1)},
+			})
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry in transaction: %v.", failed, err)
+			}
+			if tree["on"] != uint64(1) {
+				t.Fatalf("\t%s\tDefaults are not applied, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tMissing key is detected.", success)
+		}
+	}
+}
+
+// failSetAPI wraps the real API, failing writes of the value "fail".
+type failSetAPI struct {
+	sysAPI
+}
+
+func (a failSetAPI) SetValue(k registry.Key, name string, valtype uint32, data []byte) error {
+	if name == "fail" {
+		return windows.ERROR_ACCESS_DENIED
+	}
+	return a.sysAPI.SetValue(k, name, valtype, data)
+}
+
+func TestTransactedWrite(t *testing.T) {
+	t.Log("Testing writes inside a transaction.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey})
+		p.api = failSetAPI{}
+
+		testID := 0
+		t.Logf("\tTest %d:\tcommitted batch.", testID)
+		{
+			err := p.ApplyTransacted([]Write{
+				{Path: "TxKey\\Sub", Name: "Width", Value: uint32(800)},
+				{Name: "on", Value: uint32(5)},
+			})
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to apply writes: %v.", failed, err)
+			}
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			sub, _ := tree["TxKey"].(map[string]interface{})["Sub"].(map[string]interface{})
+			if tree["on"] != uint64(5) || sub["Width"] != uint64(800) {
+				t.Fatalf("\t%s\tWrites were not committed: %v.", failed, tree)
+			}
+			t.Logf("\t%s\tBatch is committed.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tfailed batch.", testID)
+		{
+			err := p.ApplyTransacted([]Write{
+				{Path: "RolledBack", Name: "Width", Value: uint32(800)},
+				{Name: "on", Value: uint32(7)},
+				{Name: "fail", Value: uint32(1)},
+			})
+			if !errors.Is(err, windows.ERROR_ACCESS_DENIED) {
+				t.Fatalf("\t%s\tApplyTransacted() should fail, got: %v.", failed, err)
+			}
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if _, ok := tree["RolledBack"]; ok || tree["on"] != uint64(5) {
+				t.Fatalf("\t%s\tWrites were not rolled back: %v.", failed, tree)
+			}
+			t.Logf("\t%s\tBatch is rolled back.", success)
+		}
+	}
+}
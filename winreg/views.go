@@ -0,0 +1,99 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+)
+
+// Names under which Views nests the trees of the registry views.
+const (
+	View32Name = "32bit"
+	View64Name = "64bit"
+)
+
+// Views is a provider of the same path in both the 32-bit and the 64-bit
+// registry views, which differ for redirected keys like
+// HKLM\SOFTWARE.
+type Views struct {
+	View32   *WinReg
+	View64   *WinReg
+	winner   int
+	defaults map[string]interface{}
+}
+
+// ViewsProvider returns a provider of cfg.Path in both registry views.
+// cfg.Mode selects how the views are combined: with RegAuto the trees are
+// nested under View32Name and View64Name, with Reg32Bit or Reg64Bit they
+// are merged and the selected view takes precedence. Missing keys are
+// empty views and are watched for creation. cfg.Defaults are merged
+// beneath each view.
+func ViewsProvider(cfg Config) *Views {
+	winner := cfg.Mode
+	defaults := cfg.Defaults
+	// An empty tree makes a missing key an empty view
+	cfg.Defaults = map[string]interface{}{}
+	cfg.WatchMissing = true
+
+	view32, view64 := cfg, cfg
+	view32.Mode, view64.Mode = Reg32Bit, Reg64Bit
+
+	return &Views{View32: Provider(view32), View64: Provider(view64), winner: winner, defaults: defaults}
+}
+
+func (v *Views) ReadBytes() ([]byte, error) {
+	return nil, errors.New("winreg provider does not support this method")
+}
+
+// Read reads both views and nests or merges them as selected by the
+// Mode of the provider's Config.
+func (v *Views) Read() (map[string]interface{}, error) {
+	tree32, err := v.View32.Read()
+	if err != nil {
+		return nil, err
+	}
+	tree64, err := v.View64.Read()
+	if err != nil {
+		return nil, err
+	}
+	if v.defaults != nil {
+		mergeBeneath(tree32, copyTree(v.defaults))
+		mergeBeneath(tree64, copyTree(v.defaults))
+	}
+
+	switch v.winner {
+	case Reg32Bit:
+		mergeBeneath(tree32, tree64)
+		return tree32, nil
+	case Reg64Bit:
+		mergeBeneath(tree64, tree32)
+		return tree64, nil
+	default:
+		return map[string]interface{}{View32Name: tree32, View64Name: tree64}, nil
+	}
+}
+
+// Watch watches both views, cb is called as by WinReg.Watch() when any of
+// them changes. Keys which aren't redirected are the same in both views,
+// so a change of them is reported twice.
+func (v *Views) Watch(cb func(event interface{}, err error)) error {
+	if err := v.View32.Watch(cb); err != nil {
+		return err
+	}
+	if err := v.View64.Watch(cb); err != nil {
+		v.View32.Unwatch()
+		return err
+	}
+
+	return nil
+}
+
+// Unwatch stops watching both views.
+func (v *Views) Unwatch() error {
+	err := v.View32.Unwatch()
+	if err64 := v.View64.Unwatch(); err == nil {
+		err = err64
+	}
+
+	return err
+}
@@ -0,0 +1,53 @@
+//go:build windows
+
+package winreg
+
+import (
+	"testing"
+)
+
+func TestViewsProvider(t *testing.T) {
+	t.Log("Testing provider of both registry views.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		testID := 0
+		t.Logf("\tTest %d:\tviews nested.", testID)
+		{
+			// HKCU\SOFTWARE is shared by both views
+			p := ViewsProvider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, MaxDepth: 1})
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read views: %v.", failed, err)
+			}
+			for _, name := range []string{View32Name, View64Name} {
+				view, ok := tree[name].(map[string]interface{})
+				if !ok || view["on"] != uint64(1) {
+					t.Fatalf("\t%s\tView %s is invalid, got %v.", failed, name, tree[name])
+				}
+			}
+			t.Logf("\t%s\tViews are nested.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tviews merged.", testID)
+		{
+			p := ViewsProvider(Config{
+				Key:      CURRENT_USER,
+				Path:     "SOFTWARE\\" + testKey,
+				MaxDepth: 1,
+				Mode:     Reg64Bit,
+				Defaults: map[string]interface{}{"Other": "default"},
+			})
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read views: %v.", failed, err)
+			}
+			if tree["on"] != uint64(1) || tree["Other"] != "default" || tree[View64Name] != nil {
+				t.Fatalf("\t%s\tViews are not merged, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tViews are merged.", success)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+//go:build windows
+
+package winreg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// CreatedEvent is passed to the watch callback when the watched key,
+// which didn't exist when Watch() was called, has been created.
+type CreatedEvent struct {
+	Key string // Full name of the created key
+}
+
+// watchStop stops the goroutines started by Watch() of a provider.
+type watchStop struct {
+	mu      sync.Mutex
+	event   windows.Handle   // Manual-reset event set by Unwatch(), zero if not created yet
+	running sync.WaitGroup   // Goroutines which may still call back
+	handles []windows.Handle // Closed by Unwatch() once the goroutines have exited
+}
+
+// start returns the stop event for a new watch goroutine, which must call
+// running.Done() when it exits.
+func (w *watchStop) start() (windows.Handle, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.event == 0 {
+		event, err := windows.CreateEvent(nil, 1, 0, nil)
+		if err != nil {
+			return 0, err
+		}
+		w.event = event
+	}
+	w.running.Add(1)
+
+	return w.event, nil
+}
+
+// closeOnStop makes Unwatch() close h after the watch goroutines, which
+// may still use it, have exited.
+func (w *watchStop) closeOnStop(h windows.Handle) {
+	w.mu.Lock()
+	w.handles = append(w.handles, h)
+	w.mu.Unlock()
+}
+
+// Unwatch stops all watches started by Watch() and Subscribe(), closing
+// their keys and events. No callback is called after Unwatch() returns,
+// so it must not be called from a callback. The provider can be watched
+// again afterwards.
+func (s *WinReg) Unwatch() error {
+	w := s.watches
+	w.mu.Lock()
+	event := w.event
+	w.mu.Unlock()
+
+	var err error
+	if event != 0 {
+		// Watches started meanwhile, e.g. of a key which has just been
+		// created, get the signaled event and stop immediately
+		err = windows.SetEvent(event)
+		w.running.Wait()
+	}
+	w.mu.Lock()
+	if event != 0 && w.event == event {
+		windows.Close(event)
+		w.event = 0
+	}
+	handles := w.handles
+	w.handles = nil
+	w.mu.Unlock()
+	for _, h := range handles {
+		windows.Close(h)
+	}
+
+	// Subscribers are detached, they don't survive a restarted watch
+	subs := s.subscribers
+	subs.mu.Lock()
+	subs.started = false
+	subs.subs = nil
+	subs.mu.Unlock()
+
+	return err
+}
+
+// WatchContext watches the provider's key as Watch() does until ctx is
+// done. The watch is independent of other watches of the provider, it
+// isn't stopped by Unwatch(). Callbacks may be called until the watch has
+// stopped, shortly after ctx is done.
+func (s *WinReg) WatchContext(ctx context.Context, cb func(event interface{}, err error)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	p := s.readCopy()
+	p.watches = &watchStop{}
+	p.subscribers = &subscribers{}
+	if err := p.Watch(cb); err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		p.Unwatch()
+	}()
+
+	return nil
+}
+
+// settle waits until the key k hasn't changed for the debounce period,
+// re-arming the notification on event after each change. stopped is set
+// if the watch has been stopped meanwhile.
+func (s *WinReg) settle(k registry.Key, filter uint32, event, stop windows.Handle) (stopped bool, err error) {
+	for {
+		waitResult, err := windows.WaitForMultipleObjects([]windows.Handle{event, stop}, false, uint32(s.debounce/time.Millisecond))
+		if err != nil {
+			return false, fmt.Errorf("watch failed: %w", err)
+		}
+		switch waitResult {
+		case uint32(windows.WAIT_TIMEOUT):
+			return false, nil
+		case windows.WAIT_OBJECT_0:
+			if err = windows.ResetEvent(event); err != nil {
+				return false, fmt.Errorf("watch failed: %w", err)
+			}
+			if err = s.api.NotifyChangeKeyValue(k, (s.maxDepth != 1), filter, event); err != nil {
+				return false, fmt.Errorf("watch failed: %w", err)
+			}
+		default:
+			return true, nil
+		}
+	}
+}
+
+// openChangeEvent opens the named event object, creating it as an
+// auto-reset event if it doesn't exist yet.
+func openChangeEvent(name string) (windows.Handle, error) {
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return 0, err
+	}
+	// Returns the existing event, along with ERROR_ALREADY_EXISTS
+	event, err := windows.CreateEvent(nil, 0, 0, namePtr)
+	if event == 0 {
+		return 0, fmt.Errorf("unable to create event %s: %w", name, err)
+	}
+
+	return event, nil
+}
+
+// signalChange wraps cb to set the named event after each processed change.
+func signalChange(event windows.Handle, cb func(event interface{}, err error)) func(event interface{}, err error) {
+	return func(ev interface{}, err error) {
+		cb(ev, err)
+		if err == nil {
+			windows.SetEvent(event)
+		}
+	}
+}
+
+// watchMissingKey waits for the provider's key to be created by watching
+// its nearest existing parent for new subkeys, then switches to watching
+// the key itself.
+func (s *WinReg) watchMissingKey(cb func(event interface{}, err error)) error {
+	event, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return fmt.Errorf("watch failed: %w", err)
+	}
+	stop, err := s.watches.start()
+	if err != nil {
+		windows.Close(event)
+		return fmt.Errorf("watch failed: %w", err)
+	}
+
+	// The notification is armed by the goroutine waiting for it
+	armed := make(chan error)
+	go func() {
+		defer s.watches.running.Done()
+		defer windows.Close(event)
+		defer lockNotifyThread()()
+		parent, err := s.armParentWatch(event)
+		armed <- err
+		if err != nil {
+			return
+		}
+		for {
+			waitResult, err := windows.WaitForMultipleObjects([]windows.Handle{event, stop}, false, windows.INFINITE)
+			if err != nil {
+				s.api.CloseKey(parent)
+				cb(nil, fmt.Errorf("watch failed: %w", err))
+				return
+			}
+			if waitResult != windows.WAIT_OBJECT_0 {
+				// Stopped by Unwatch() or the program was terminated.
+				s.api.CloseKey(parent)
+				return
+			}
+			s.api.CloseKey(parent)
+
+			if err = windows.ResetEvent(event); err != nil {
+				cb(nil, fmt.Errorf("watch failed: %w", err))
+				return
+			}
+
+			k, err := s.api.OpenKey(s.key, s.path, s.getAccess(registry.NOTIFY))
+			if err == nil {
+				if err = s.watchKey(k, cb); err != nil {
+					cb(nil, err)
+					return
+				}
+				s.missing.reset()
+				cb(&CreatedEvent{Key: s.getKeyName(s.path)}, nil)
+				return
+			}
+			if !errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+				cb(nil, fmt.Errorf("watch failed: %w", err))
+				return
+			}
+
+			// Some parent was created (or deleted), find the nearest one again
+			if parent, err = s.armParentWatch(event); err != nil {
+				cb(nil, fmt.Errorf("watch failed: %w", err))
+				return
+			}
+		}
+	}()
+	if err = <-armed; err != nil {
+		return fmt.Errorf("watch failed: %w", err)
+	}
+
+	return nil
+}
+
+// lockNotifyThread locks the calling goroutine to its OS thread unless
+// notifications are registered as thread agnostic, as otherwise they are
+// cancelled when the thread which armed them exits. The returned function
+// unlocks the thread.
+func lockNotifyThread() func() {
+	if SystemCapabilities().ThreadAgnosticNotify {
+		return func() {}
+	}
+	runtime.LockOSThread()
+
+	return runtime.UnlockOSThread
+}
+
+// rewatch reports the deletion of the watched key and waits for it to be
+// created again, as with WatchMissing.
+func (s *WinReg) rewatch(t *changeTracker, cb func(event interface{}, err error)) {
+	notifyChanges(t, cb)
+	if err := s.watchMissingKey(cb); err != nil {
+		cb(nil, err)
+	}
+}
+
+// armParentWatch opens the nearest existing parent of the provider's key
+// and requests a notification on event when its subkeys change. If the key
+// has appeared meanwhile, the event is signaled immediately.
+func (s *WinReg) armParentWatch(event windows.Handle) (registry.Key, error) {
+	parent, err := s.openNearestParent()
+	if err != nil {
+		return 0, err
+	}
+	if err = s.api.NotifyChangeKeyValue(parent, false, REG_NOTIFY_CHANGE_NAME, event); err != nil {
+		s.api.CloseKey(parent)
+		return 0, err
+	}
+
+	if s.keyExists(s.key, s.path) {
+		windows.SetEvent(event)
+	}
+
+	return parent, nil
+}
+
+func (s *WinReg) openNearestParent() (registry.Key, error) {
+	path := strings.Trim(s.path, "\\")
+	for path != "" {
+		path, _ = splitParent(path)
+
+		k, err := s.api.OpenKey(s.key, path, s.getAccess(registry.NOTIFY))
+		if err == nil {
+			return k, nil
+		}
+		if !errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+			return 0, err
+		}
+	}
+
+	return 0, windows.ERROR_FILE_NOT_FOUND
+}
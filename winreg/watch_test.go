@@ -0,0 +1,433 @@
+//go:build windows
+
+package winreg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestWatchMissing(t *testing.T) {
+	t.Log("Testing watch of a key which doesn't exist yet.")
+	{
+		const eventTimeout = 5
+		deleteTestData(t)
+		defer deleteTestData(t)
+
+		events := make(chan interface{}, 10)
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, WatchMissing: true})
+		err := p.Watch(func(event interface{}, err error) {
+			if err != nil {
+				events <- err
+				return
+			}
+			events <- event
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tWatch() method failed: %v", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\twaiting for key to be created.", testID)
+		{
+			createTestData(t)
+
+			select {
+			case event := <-events:
+				created, ok := event.(*CreatedEvent)
+				if !ok {
+					t.Fatalf("\t%s\tExpected created event, got %v.", failed, event)
+				}
+				if created.Key != "HKCU\\SOFTWARE\\"+testKey {
+					t.Fatalf("\t%s\tInvalid created key %s.", failed, created.Key)
+				}
+			case <-time.After(eventTimeout * time.Second):
+				t.Fatalf("\t%s\tTimeout exceeded while waiting for created event.", failed)
+			}
+			t.Logf("\t%s\tCreated event received.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\twaiting for value to be changed.", testID)
+		{
+			// Drain notifications caused by the rest of the test data
+			time.Sleep(100 * time.Millisecond)
+			for len(events) > 0 {
+				<-events
+			}
+
+			r, err := registry.OpenKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey, registry.ALL_ACCESS)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to open registry key: %v", failed, err)
+			}
+			defer r.Close()
+			if err := r.SetDWordValue("on", 2); err != nil {
+				t.Fatalf("\t%s\tUnable to change value \"on\": %v", failed, err)
+			}
+
+			select {
+			case event := <-events:
+				if event != nil {
+					t.Fatalf("\t%s\tExpected change event, got %v.", failed, event)
+				}
+			case <-time.After(eventTimeout * time.Second):
+				t.Fatalf("\t%s\tTimeout exceeded while waiting for change event.", failed)
+			}
+			t.Logf("\t%s\tThe key is watched after creation.", success)
+		}
+	}
+}
+
+func TestChangeEvent(t *testing.T) {
+	t.Log("Testing named change event.")
+	{
+		const eventTimeout = 5
+		const eventName = "Local\\koanf-winreg-test-changed"
+		createTestData(t)
+		defer deleteTestData(t)
+
+		event, err := openChangeEvent(eventName)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to create event: %v", failed, err)
+		}
+		defer windows.Close(event)
+
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, ChangeEvent: eventName})
+		if err := p.Watch(func(event interface{}, err error) {}); err != nil {
+			t.Fatalf("\t%s\tWatch() method failed: %v", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\twaiting for event to be set.", testID)
+		{
+			r, err := registry.OpenKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey, registry.ALL_ACCESS)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to open registry key: %v", failed, err)
+			}
+			defer r.Close()
+			if err := r.SetDWordValue("on", 2); err != nil {
+				t.Fatalf("\t%s\tUnable to change value \"on\": %v", failed, err)
+			}
+
+			if result, err := windows.WaitForSingleObject(event, eventTimeout*1000); err != nil || result != windows.WAIT_OBJECT_0 {
+				t.Fatalf("\t%s\tEvent was not set: %d, %v.", failed, result, err)
+			}
+			t.Logf("\t%s\tEvent is set.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tunwatch.", testID)
+		{
+			if err := p.Unwatch(); err != nil {
+				t.Fatalf("\t%s\tUnwatch() method failed: %v", failed, err)
+			}
+			if len(p.watches.handles) != 0 {
+				t.Fatalf("\t%s\tEvent handle is kept open.", failed)
+			}
+			t.Logf("\t%s\tEvent handle is closed.", success)
+		}
+	}
+}
+
+func TestVerifyData(t *testing.T) {
+	t.Log("Testing suppression of notifications without data changes.")
+	{
+		const eventTimeout = 5
+		createTestData(t)
+		defer deleteTestData(t)
+
+		events := make(chan error, 10)
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, VerifyData: true})
+		if err := p.Watch(func(event interface{}, err error) { events <- err }); err != nil {
+			t.Fatalf("\t%s\tWatch() method failed: %v", failed, err)
+		}
+
+		r, err := registry.OpenKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey, registry.ALL_ACCESS)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to open registry key: %v", failed, err)
+		}
+		defer r.Close()
+
+		testID := 0
+		t.Logf("\tTest %d:\tidentical data rewritten.", testID)
+		{
+			if err := r.SetDWordValue("on", 1); err != nil {
+				t.Fatalf("\t%s\tUnable to rewrite value \"on\": %v", failed, err)
+			}
+			select {
+			case err := <-events:
+				t.Fatalf("\t%s\tUnexpected event received: %v.", failed, err)
+			case <-time.After(time.Second):
+			}
+			t.Logf("\t%s\tNotification is suppressed.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tdata changed.", testID)
+		{
+			if err := r.SetDWordValue("on", 2); err != nil {
+				t.Fatalf("\t%s\tUnable to change value \"on\": %v", failed, err)
+			}
+			select {
+			case err := <-events:
+				if err != nil {
+					t.Fatalf("\t%s\tWatch failed: %v.", failed, err)
+				}
+			case <-time.After(eventTimeout * time.Second):
+				t.Fatalf("\t%s\tTimeout exceeded while waiting for change event.", failed)
+			}
+			t.Logf("\t%s\tChange is reported.", success)
+		}
+	}
+}
+
+func TestUnwatch(t *testing.T) {
+	t.Log("Testing stop of watching.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		events := make(chan error, 10)
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey})
+		if err := p.Watch(func(event interface{}, err error) { events <- err }); err != nil {
+			t.Fatalf("\t%s\tWatch() method failed: %v", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tno events after Unwatch().", testID)
+		{
+			if err := p.Unwatch(); err != nil {
+				t.Fatalf("\t%s\tUnwatch() method failed: %v", failed, err)
+			}
+
+			r, err := registry.OpenKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey, registry.ALL_ACCESS)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to open registry key: %v", failed, err)
+			}
+			defer r.Close()
+			if err := r.SetDWordValue("on", 2); err != nil {
+				t.Fatalf("\t%s\tUnable to change value \"on\": %v", failed, err)
+			}
+			select {
+			case err := <-events:
+				t.Fatalf("\t%s\tUnexpected event received: %v.", failed, err)
+			case <-time.After(time.Second):
+			}
+			if err := p.Unwatch(); err != nil {
+				t.Fatalf("\t%s\tRepeated Unwatch() should succeed, got: %v", failed, err)
+			}
+			t.Logf("\t%s\tWatching is stopped.", success)
+		}
+	}
+}
+
+func TestWatchContext(t *testing.T) {
+	t.Log("Testing watch cancelled by a context.")
+	{
+		const eventTimeout = 5
+		createTestData(t)
+		defer deleteTestData(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		events := make(chan error, 10)
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey})
+		if err := p.WatchContext(ctx, func(event interface{}, err error) { events <- err }); err != nil {
+			t.Fatalf("\t%s\tWatchContext() method failed: %v", failed, err)
+		}
+
+		r, err := registry.OpenKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey, registry.ALL_ACCESS)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to open registry key: %v", failed, err)
+		}
+		defer r.Close()
+
+		testID := 0
+		t.Logf("\tTest %d:\tevents until cancellation.", testID)
+		{
+			if err := r.SetDWordValue("on", 2); err != nil {
+				t.Fatalf("\t%s\tUnable to change value \"on\": %v", failed, err)
+			}
+			select {
+			case err := <-events:
+				if err != nil {
+					t.Fatalf("\t%s\tWatch failed: %v.", failed, err)
+				}
+			case <-time.After(eventTimeout * time.Second):
+				t.Fatalf("\t%s\tTimeout exceeded while waiting for change event.", failed)
+			}
+
+			cancel()
+			// The watch stops asynchronously
+			time.Sleep(100 * time.Millisecond)
+			for len(events) > 0 {
+				<-events
+			}
+			if err := r.SetDWordValue("on", 3); err != nil {
+				t.Fatalf("\t%s\tUnable to change value \"on\": %v", failed, err)
+			}
+			select {
+			case err := <-events:
+				t.Fatalf("\t%s\tUnexpected event received: %v.", failed, err)
+			case <-time.After(time.Second):
+			}
+			if err := p.WatchContext(ctx, func(event interface{}, err error) {}); err != context.Canceled {
+				t.Fatalf("\t%s\tWatch with a done context should fail, got: %v", failed, err)
+			}
+			t.Logf("\t%s\tWatching is stopped.", success)
+		}
+	}
+}
+
+func TestDebounce(t *testing.T) {
+	t.Log("Testing collapse of bursts of changes.")
+	{
+		const eventTimeout = 5
+		createTestData(t)
+		defer deleteTestData(t)
+
+		events := make(chan error, 10)
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, Debounce: 300 * time.Millisecond})
+		if err := p.Watch(func(event interface{}, err error) { events <- err }); err != nil {
+			t.Fatalf("\t%s\tWatch() method failed: %v", failed, err)
+		}
+		defer p.Unwatch()
+
+		r, err := registry.OpenKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey, registry.ALL_ACCESS)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to open registry key: %v", failed, err)
+		}
+		defer r.Close()
+
+		testID := 0
+		t.Logf("\tTest %d:\tburst of writes.", testID)
+		{
+			for i := uint32(2); i < 7; i++ {
+				if err := r.SetDWordValue("on", i); err != nil {
+					t.Fatalf("\t%s\tUnable to change value \"on\": %v", failed, err)
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+			select {
+			case err := <-events:
+				if err != nil {
+					t.Fatalf("\t%s\tWatch failed: %v.", failed, err)
+				}
+			case <-time.After(eventTimeout * time.Second):
+				t.Fatalf("\t%s\tTimeout exceeded while waiting for change event.", failed)
+			}
+			select {
+			case err := <-events:
+				t.Fatalf("\t%s\tUnexpected event received: %v.", failed, err)
+			case <-time.After(time.Second):
+			}
+			t.Logf("\t%s\tBurst is collapsed.", success)
+		}
+	}
+}
+
+func TestResubscribe(t *testing.T) {
+	t.Log("Testing watch of a deleted and recreated key.")
+	{
+		const eventTimeout = 5
+		createTestData(t)
+		defer deleteTestData(t)
+
+		events := make(chan interface{}, 10)
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, Resubscribe: true})
+		err := p.Watch(func(event interface{}, err error) {
+			if err != nil {
+				events <- err
+				return
+			}
+			events <- event
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tWatch() method failed: %v", failed, err)
+		}
+		defer p.Unwatch()
+
+		testID := 0
+		t.Logf("\tTest %d:\tkey is deleted and created again.", testID)
+		{
+			deleteTestData(t)
+			// Deletion of the test data signals several changes
+			time.Sleep(100 * time.Millisecond)
+			for len(events) > 0 {
+				if err, ok := (<-events).(error); ok {
+					t.Fatalf("\t%s\tWatch failed: %v.", failed, err)
+				}
+			}
+			createTestData(t)
+
+			select {
+			case event := <-events:
+				if _, ok := event.(*CreatedEvent); !ok {
+					t.Fatalf("\t%s\tExpected created event, got %v.", failed, event)
+				}
+			case <-time.After(eventTimeout * time.Second):
+				t.Fatalf("\t%s\tTimeout exceeded while waiting for created event.", failed)
+			}
+			t.Logf("\t%s\tWatch is re-established.", success)
+		}
+	}
+}
+
+func TestNotifyFilter(t *testing.T) {
+	t.Log("Testing notification filter.")
+	{
+		const eventTimeout = 5
+		createTestData(t)
+		defer deleteTestData(t)
+
+		events := make(chan error, 10)
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, NotifyFilter: REG_NOTIFY_CHANGE_NAME})
+		if err := p.Watch(func(event interface{}, err error) { events <- err }); err != nil {
+			t.Fatalf("\t%s\tWatch() method failed: %v", failed, err)
+		}
+		defer p.Unwatch()
+
+		r, err := registry.OpenKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey, registry.ALL_ACCESS)
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to open registry key: %v", failed, err)
+		}
+		defer r.Close()
+
+		testID := 0
+		t.Logf("\tTest %d:\tvalue changes are filtered out.", testID)
+		{
+			if err := r.SetDWordValue("on", 2); err != nil {
+				t.Fatalf("\t%s\tUnable to change value \"on\": %v", failed, err)
+			}
+			select {
+			case err := <-events:
+				t.Fatalf("\t%s\tUnexpected event received: %v.", failed, err)
+			case <-time.After(time.Second):
+			}
+			t.Logf("\t%s\tValue change is not reported.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tsubkey changes are reported.", testID)
+		{
+			k, _, err := registry.CreateKey(r, "SubKeyC", registry.ALL_ACCESS)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to create key: %v", failed, err)
+			}
+			k.Close()
+			select {
+			case err := <-events:
+				if err != nil {
+					t.Fatalf("\t%s\tWatch failed: %v.", failed, err)
+				}
+			case <-time.After(eventTimeout * time.Second):
+				t.Fatalf("\t%s\tTimeout exceeded while waiting for change event.", failed)
+			}
+			t.Logf("\t%s\tSubkey change is reported.", success)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"golang.org/x/sys/windows"
+)
+
+// maxWatcherKeys is the number of keys a Watcher can wait for, one of the
+// MAXIMUM_WAIT_OBJECTS handles is its own wake event.
+const maxWatcherKeys = 63
+
+// ErrWatcherClosed is returned by Add() of a closed Watcher.
+var ErrWatcherClosed = errors.New("watcher is closed")
+
+// Watcher waits for changes of keys of many providers in a single
+// goroutine, instead of a goroutine and an event per Watch(). Up to 63
+// keys can be watched. Callbacks are called one at a time from the
+// Watcher's goroutine, so a slow callback delays the others. The options
+// of providers which affect Watch(), like VerifyData or Debounce, are not
+// applied; remote providers can't be added.
+type Watcher struct {
+	mu       sync.Mutex
+	wake     windows.Handle // Auto-reset event signaled when requests are queued
+	requests []*watcherRequest
+	done     chan struct{}
+}
+
+// watcherRequest is a change of the watched keys done by the Watcher's
+// goroutine, which must own all notifications.
+type watcherRequest struct {
+	add    *watcherKey // Key to be added, nil to remove
+	remove *watcherKey // Key to be removed, both nil to close the Watcher
+	result chan error
+}
+
+type watcherKey struct {
+	p  *WinReg
+	n  *Notification
+	cb func(event interface{}, err error)
+}
+
+// NewWatcher starts a Watcher, which must be stopped by Close().
+func NewWatcher() (*Watcher, error) {
+	wake, err := windows.CreateEvent(nil, 0, 0, nil)
+	if err != nil {
+		return nil, fmt.Errorf("watch failed: %w", err)
+	}
+
+	w := &Watcher{wake: wake, done: make(chan struct{})}
+	go w.loop()
+
+	return w, nil
+}
+
+// Add starts watching the key of p, changes are passed to cb as by
+// Watch(). The returned function stops watching it; no callback of it is
+// called after the function returns, so it must not be called from a
+// callback.
+func (w *Watcher) Add(p *WinReg, cb func(event interface{}, err error)) (func(), error) {
+	key := &watcherKey{p: p, cb: cb}
+	if err := w.request(&watcherRequest{add: key}); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		w.request(&watcherRequest{remove: key})
+	}, nil
+}
+
+// Close stops watching all keys and the Watcher's goroutine.
+func (w *Watcher) Close() error {
+	err := w.request(&watcherRequest{})
+	if errors.Is(err, ErrWatcherClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// request passes r to the Watcher's goroutine and waits for the result.
+func (w *Watcher) request(r *watcherRequest) error {
+	r.result = make(chan error, 1)
+	w.mu.Lock()
+	select {
+	case <-w.done:
+		w.mu.Unlock()
+		return ErrWatcherClosed
+	default:
+	}
+	w.requests = append(w.requests, r)
+	w.mu.Unlock()
+
+	if err := windows.SetEvent(w.wake); err != nil {
+		return fmt.Errorf("watch failed: %w", err)
+	}
+	select {
+	case err := <-r.result:
+		return err
+	case <-w.done:
+		return ErrWatcherClosed
+	}
+}
+
+func (w *Watcher) loop() {
+	// Without thread agnostic notifications, they are cancelled when the
+	// thread which armed them exits
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	var keys []*watcherKey
+	defer func() {
+		for _, key := range keys {
+			key.n.Close()
+		}
+		w.mu.Lock()
+		close(w.done)
+		for _, r := range w.requests {
+			r.result <- ErrWatcherClosed
+		}
+		w.requests = nil
+		w.mu.Unlock()
+		windows.Close(w.wake)
+	}()
+
+	for {
+		handles := make([]windows.Handle, 0, len(keys)+1)
+		handles = append(handles, w.wake)
+		for _, key := range keys {
+			handles = append(handles, key.n.Event)
+		}
+
+		waitResult, err := windows.WaitForMultipleObjects(handles, false, windows.INFINITE)
+		if err != nil {
+			for _, key := range keys {
+				key.cb(nil, fmt.Errorf("watch failed: %w", err))
+			}
+			return
+		}
+		i := int(waitResult - windows.WAIT_OBJECT_0)
+		if i < 0 || i >= len(handles) {
+			// The program was terminated.
+			return
+		}
+
+		if i == 0 {
+			var closed bool
+			if keys, closed = w.handleRequests(keys); closed {
+				return
+			}
+			continue
+		}
+
+		key := keys[i-1]
+		if err = key.n.Rearm(); err != nil {
+			key.n.Close()
+			keys = append(keys[:i-1], keys[i:]...)
+			key.cb(nil, err)
+			continue
+		}
+		key.cb(nil, nil)
+	}
+}
+
+// handleRequests applies the queued requests to keys. closed is set if
+// the Watcher is to be closed.
+func (w *Watcher) handleRequests(keys []*watcherKey) (retval []*watcherKey, closed bool) {
+	w.mu.Lock()
+	requests := w.requests
+	w.requests = nil
+	w.mu.Unlock()
+
+	for _, r := range requests {
+		switch {
+		case r.add != nil:
+			if len(keys) >= maxWatcherKeys {
+				r.result <- fmt.Errorf("watch failed: more than %d keys", maxWatcherKeys)
+				continue
+			}
+			n, err := r.add.p.Notify()
+			if err != nil {
+				r.result <- err
+				continue
+			}
+			r.add.n = n
+			keys = append(keys, r.add)
+			r.result <- nil
+		case r.remove != nil:
+			for i, key := range keys {
+				if key == r.remove {
+					key.n.Close()
+					keys = append(keys[:i], keys[i+1:]...)
+					break
+				}
+			}
+			r.result <- nil
+		default:
+			r.result <- nil
+			closed = true
+		}
+	}
+
+	return keys, closed
+}
@@ -0,0 +1,77 @@
+//go:build windows
+
+package winreg
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestWatcher(t *testing.T) {
+	t.Log("Testing shared watch loop.")
+	{
+		const eventTimeout = 5
+		createTestData(t)
+		defer deleteTestData(t)
+
+		w, err := NewWatcher()
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to start watcher: %v", failed, err)
+		}
+		defer w.Close()
+
+		events := make(chan string, 10)
+		for _, path := range []string{"SubKeyA", "SubKeyB"} {
+			path := path
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey + "\\" + path})
+			if _, err := w.Add(p, func(event interface{}, err error) {
+				if err != nil {
+					t.Errorf("\t%s\tWatch failed: %v", failed, err)
+				}
+				events <- path
+			}); err != nil {
+				t.Fatalf("\t%s\tAdd() method failed: %v", failed, err)
+			}
+		}
+
+		for testID, path := range []string{"SubKeyB", "SubKeyA"} {
+			t.Logf("\tTest %d:\tchange of %s.", testID, path)
+			{
+				k, err := registry.OpenKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey+"\\"+path, registry.ALL_ACCESS)
+				if err != nil {
+					t.Fatalf("\t%s\tUnable to open registry key: %v", failed, err)
+				}
+				err = k.SetDWordValue("Changed", 1)
+				k.Close()
+				if err != nil {
+					t.Fatalf("\t%s\tUnable to change value: %v", failed, err)
+				}
+
+				select {
+				case got := <-events:
+					if got != path {
+						t.Fatalf("\t%s\tExpected change of %s, got %s.", failed, path, got)
+					}
+				case <-time.After(eventTimeout * time.Second):
+					t.Fatalf("\t%s\tTimeout exceeded while waiting for change event.", failed)
+				}
+				t.Logf("\t%s\tChange is dispatched.", success)
+			}
+		}
+
+		testID := 2
+		t.Logf("\tTest %d:\tclosed watcher.", testID)
+		{
+			if err := w.Close(); err != nil {
+				t.Fatalf("\t%s\tClose() method failed: %v", failed, err)
+			}
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey})
+			if _, err := w.Add(p, func(event interface{}, err error) {}); err != ErrWatcherClosed {
+				t.Fatalf("\t%s\tAdd() to a closed watcher should fail, got: %v", failed, err)
+			}
+			t.Logf("\t%s\tWatcher is closed.", success)
+		}
+	}
+}
@@ -5,12 +5,20 @@
 package winreg
 
 import (
+	"context"
+	"crypto/sha256"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"regexp"
+	"strings"
+	"sync/atomic"
 	"syscall"
+	"time"
+	"unsafe"
 
+	"github.com/pda0/koanf-winreg/v2/regfile"
 	"golang.org/x/sys/windows"
 	"golang.org/x/sys/windows/registry"
 )
@@ -34,14 +42,103 @@ const (
 	PERFORMANCE_DATA = registry.PERFORMANCE_DATA
 )
 
+// Performance data pseudo-keys which are not part of the registry package.
+const (
+	PERFORMANCE_TEXT    = registry.Key(0x80000050)
+	PERFORMANCE_NLSTEXT = registry.Key(0x80000060)
+)
+
+// ErrPerformanceData is returned when a provider targets one of the
+// performance data pseudo-keys, which can't be read as regular keys.
+var ErrPerformanceData = errors.New("performance data pseudo-keys can't be read as registry keys")
+
+// Errors of registry operations, which are wrapped by the errors returned
+// by providers, e.g. errors.Is(err, ErrKeyNotFound). They are Win32 error
+// codes, errors.As() of a syscall.Errno returns the code of any other
+// failure.
+var (
+	ErrKeyNotFound  error = registry.ErrNotExist        // The key or the value doesn't exist
+	ErrAccessDenied error = windows.ERROR_ACCESS_DENIED // The caller has no access to the key
+)
+
+// ErrUnsupportedType is returned by writes of Go types or registry types
+// which can't be stored.
+var ErrUnsupportedType = errors.New("unsupported value type")
+
+// ErrInvalidConfig is returned by NewProvider() for a Config with an out
+// of range Mode, Backend, Transport, BinaryFormat or Links.
+var ErrInvalidConfig = errors.New("invalid winreg config")
+
 type Config struct {
-	Key          registry.Key // Registry key
-	Path         string       // A top path in selected key
-	DefaultValue string       // The name of the value to which the default key value will be mapped
-	MaxDepth     uint         // Maximum subkey reading depth
-	Mode         int          // 32/64 bit registry branch, one of RegAuto/Reg32Bit/Reg64Bit constant
+	Key            registry.Key           // Registry key
+	Path           string                 // A top path in selected key
+	DefaultValue   string                 // The name of the value to which the default key value will be mapped
+	MaxDepth       uint                   // Maximum subkey reading depth
+	Mode           int                    // 32/64 bit registry branch, one of RegAuto/Reg32Bit/Reg64Bit constant
+	Retries        uint                   // Maximum retries of a subkey modified during reading, zero means DefaultRetries
+	Host           string                 // Remote computer name, empty for local registry
+	Limiter        *RateLimiter           // Optional limit of registry API call rate, may be shared by providers
+	References     bool                   // Resolve REG_SZ values of the form "@HKLM\Path\Key:Value" to the referenced value
+	IncludeValue   string                 // The name of the value listing keys to be inlined into its key
+	WatchMissing   bool                   // Watch() waits for the key to be created if it doesn't exist
+	Expected       []string               // Complete list of expected value paths, e.g. "SubKey\Value", nil disables the check
+	Strict         bool                   // Read() fails with ErrUnexpectedValues if values not listed in Expected are found
+	Defaults       map[string]interface{} // Nested tree of values used where the registry has none
+	ChangeEvent    string                 // Name of an event object set by Watch() after each processed change
+	MissingTTL     time.Duration          // How long a missing key is remembered without querying the registry again
+	MaxValueSize   int                    // REG_BINARY values larger than this are left out of Read(), zero means no limit
+	RawStrings     bool                   // Return string values as []uint16 and [][]uint16 code units, unexpanded
+	PollInterval   time.Duration          // Interval of polling a remote registry by Watch(), zero means DefaultPollInterval
+	Decoders       map[string]Decoder     // Decoders of values keyed by value path, e.g. "SubKey\Value"
+	DetectJSON     bool                   // Decode REG_SZ values holding a JSON object or array
+	DetectUTF16    bool                   // Decode REG_BINARY values holding UTF-16 text to strings
+	ResolveMUI     bool                   // Resolve indirect strings "@file.dll,-123" to localized strings
+	Backend        int                    // Registry API, one of BackendWin32/BackendNT constant
+	Transacted     bool                   // Read the tree inside a registry transaction
+	VerifyData     bool                   // Suppress notifications which didn't change data of the tree
+	ClassValue     string                 // The name of the value to which non-empty key class names will be mapped
+	Transport      int                    // Transport of remote reads, one of Transport* constants
+	TypeRules      TypeRules              // Registry types of written Go types
+	Volatile       bool                   // Keys created by writes are volatile and disappear at reboot
+	EventDetails   bool                   // Watch() passes an *Event for each changed key or value instead of nil
+	Debounce       time.Duration          // Quiet period after a change before Watch() calls back, collapsing bursts of changes
+	Resubscribe    bool                   // Watch() waits for the deleted key to be recreated and keeps watching it
+	NotifyFilter   uint32                 // REG_NOTIFY_CHANGE_* flags of changes reported by Watch(), zero means DefaultNotifyFilter
+	IncludeValues  []string               // Glob patterns of value names to be read, e.g. "Window*", nil reads all values
+	ExcludeValues  []string               // Glob patterns of value names to be left out, e.g. "MRU*"
+	IncludeKeys    []*regexp.Regexp       // Patterns of subkey paths to be read, e.g. "SubKey\Nested", nil reads all subkeys
+	ExcludeKeys    []*regexp.Regexp       // Patterns of subkey paths to be left out along with their subtrees
+	NameMapper     NameMapper             // Maps key and value names to names of the tree
+	Delimiter      string                 // koanf key path delimiter, "." if empty
+	DelimEscape    string                 // Replaces Delimiter in key and value names, e.g. "_", empty keeps names as they are
+	Flatten        bool                   // Read() returns a flat map keyed by value paths like "SubKey\Value"
+	FlatSeparator  string                 // Separator of names in flat value paths, "\" if empty
+	MaxDepths      map[string]uint        // MaxDepth of subtrees by key path, e.g. "SubKey", counted from that key
+	Paths          []string               // Paths in Key read and watched instead of Path and merged, later paths take precedence
+	NestPaths      bool                   // The tree of each of Paths is put under the last name of its path instead of being merged
+	TypesKey       string                 // Name of a map added to each key, holding REG_* type names of its values, e.g. "__types"
+	NoExpand       bool                   // Return REG_EXPAND_SZ values with %VAR% references unexpanded
+	Expander       Expander               // Expands REG_EXPAND_SZ values instead of the process environment, see ExpandFrom()
+	Signed         bool                   // Return REG_DWORD and REG_QWORD values as signed int64, per value see DecodeInt32()
+	BinaryFormat   int                    // Representation of REG_BINARY values, one of BinaryBytes/BinaryHex/BinaryBase64 constant
+	DecoderRules   []DecoderRule          // Decoders of values by name patterns, the first matching rule applies, Decoders take precedence
+	Links          int                    // Handling of link keys, one of Links* constant
+	RawTypes       bool                   // Return REG_NONE and resource values as []byte instead of leaving them out
+	Resources      bool                   // Decode REG_RESOURCE_LIST and REG_FULL_RESOURCE_DESCRIPTOR values into maps
+	Memory         *Memory                // In-memory registry used instead of Backend, e.g. by tests of applications
+	SkipUnreadable bool                   // Leave out subkeys failing with a Win32 error, like access denied, instead of failing Read(), see Skipped()
+	Audit          bool                   // Record keys and values left out of Read(), see ReadAudit(), unreadable subkeys are left out as with SkipUnreadable
+	Workers        int                    // Sibling subkeys are read concurrently by up to this many goroutines, zero or one reads sequentially
 }
 
+// DefaultNotifyFilter reports changes of subkeys and of values, which are
+// the changes of the configuration tree.
+const DefaultNotifyFilter = REG_NOTIFY_CHANGE_NAME | REG_NOTIFY_CHANGE_LAST_SET
+
+// DefaultRetries is the number of times a subkey deleted or modified by
+// a concurrent writer is re-read before the error is reported.
+const DefaultRetries = 3
+
 func (c *Config) getAccess() (retval uint32) {
 	retval = 0
 
@@ -59,22 +156,294 @@ func (c *Config) getAccess() (retval uint32) {
 	return
 }
 
+func (c *Config) getFlatSeparator() string {
+	if c.FlatSeparator == "" {
+		return "\\"
+	}
+	return c.FlatSeparator
+}
+
+func (c *Config) getDelimiter() string {
+	if c.Delimiter == "" {
+		return "."
+	}
+	return c.Delimiter
+}
+
+func (c *Config) getNotifyFilter() uint32 {
+	if c.NotifyFilter == 0 {
+		return DefaultNotifyFilter
+	}
+	return c.NotifyFilter
+}
+
+func (c *Config) getKeyOptions() uint32 {
+	if c.Volatile {
+		return REG_OPTION_VOLATILE
+	}
+	return 0
+}
+
+func (c *Config) getBinaryFormat() int {
+	switch c.BinaryFormat {
+	case BinaryBytes, BinaryHex, BinaryBase64:
+		return c.BinaryFormat
+	default:
+		panic("invalid winreg.Config.BinaryFormat value")
+	}
+}
+
+// validate checks the fields which select one of a set of constants.
+func (c *Config) validate() error {
+	switch {
+	case c.Mode != RegAuto && c.Mode != Reg32Bit && c.Mode != Reg64Bit:
+		return fmt.Errorf("%w, Mode %d", ErrInvalidConfig, c.Mode)
+	case c.Memory == nil && c.Backend != BackendWin32 && c.Backend != BackendNT:
+		return fmt.Errorf("%w, Backend %d", ErrInvalidConfig, c.Backend)
+	case c.Transport != TransportRemoteRegistry && c.Transport != TransportWinRM && c.Transport != TransportAuto:
+		return fmt.Errorf("%w, Transport %d", ErrInvalidConfig, c.Transport)
+	case c.BinaryFormat != BinaryBytes && c.BinaryFormat != BinaryHex && c.BinaryFormat != BinaryBase64:
+		return fmt.Errorf("%w, BinaryFormat %d", ErrInvalidConfig, c.BinaryFormat)
+	case c.Links != LinksDefault && c.Links != LinksFollow && c.Links != LinksSkip && c.Links != LinksValue:
+		return fmt.Errorf("%w, Links %d", ErrInvalidConfig, c.Links)
+	case c.DelimEscape != "" && strings.Contains(c.DelimEscape, c.getDelimiter()):
+		return fmt.Errorf("%w, DelimEscape %q contains the delimiter", ErrInvalidConfig, c.DelimEscape)
+	}
+
+	return nil
+}
+
+func (c *Config) getAPI() regAPI {
+	if c.Memory != nil {
+		return c.Memory
+	}
+
+	switch c.Backend {
+	case BackendWin32:
+		return sysAPI{}
+	case BackendNT:
+		return ntAPI{}
+	default:
+		panic("invalid winreg.Config.Backend value")
+	}
+}
+
 type WinReg struct {
 	key          registry.Key
 	path         string
 	defaultValue string
 	maxDepth     uint
 	access       uint32
+	retries      uint
+	host         string
+	limiter      *RateLimiter
+	resolveRefs  bool
+	includeValue string
+	watchMissing bool
+	expected     map[string]bool // Upper-cased expected value paths, nil if not checked
+	strict       bool
+	defaults     map[string]interface{}
+	changeEvent  string
+	subscribers  *subscribers // Callbacks sharing a single watch, see Subscribe()
+	watches      *watchStop   // Stops goroutines started by Watch()
+	missingTTL   time.Duration
+	missing      *negativeCache // The last not-found result of the provider's key
+	api          regAPI
+	maxValueSize int
+	rawStrings   bool
+	pollInterval time.Duration
+	decoders     map[string]Decoder // Keyed by upper-cased value path
+	detectJSON   bool
+	detectUTF16  bool
+	resolveMUI   bool
+	classValue   string
+	transacted   bool
+	verifyData   bool
+	transport    int
+	typeRules    TypeRules
+	keyOptions   uint32 // Options of keys created by writes
+	eventDetails bool
+	debounce     time.Duration
+	resubscribe  bool
+	notifyFilter uint32
+	valueFilter  nameFilter
+	includeKeys  []*regexp.Regexp
+	excludeKeys  []*regexp.Regexp
+	nameMapper   NameMapper
+	delimiter    string
+	delimEscape  string
+	flatten      bool
+	flatSep      string
+	maxDepths    map[string]uint // Keyed by upper-cased key path
+	paths        []*WinReg       // Providers of Paths, nil if not set
+	nestPaths    bool
+	typesKey     string
+	noExpand     bool
+	expander     Expander
+	signed       bool
+	binaryFormat int
+	decoderRules []DecoderRule
+	links        int
+	rawTypes     bool
+	resources    bool
+	skipUnread   bool
+	skipped      *skippedKeys
+	audit        bool
+	audited      *auditLog
+	workers      chan struct{}
+	unexpected   []string // Unexpected value paths found by the last Read
+	torn         int32    // Set if the last Read observed a concurrent modification
 }
 
+// NewProvider returns a provider of cfg, or ErrInvalidConfig if one of
+// the fields selecting a constant is out of range, e.g. a Mode built from
+// user input.
+func NewProvider(cfg Config) (*WinReg, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return Provider(cfg), nil
+}
+
+// Provider returns a provider of cfg. It panics if one of the fields
+// selecting a constant is out of range, NewProvider() reports it as an
+// error instead.
 func Provider(cfg Config) *WinReg {
-	return &WinReg{
+	var expected map[string]bool
+	if cfg.Expected != nil {
+		expected = make(map[string]bool, len(cfg.Expected))
+		for _, path := range cfg.Expected {
+			expected[strings.ToUpper(strings.Trim(path, "\\"))] = true
+		}
+	}
+
+	var decoders map[string]Decoder
+	if cfg.Decoders != nil {
+		decoders = make(map[string]Decoder, len(cfg.Decoders))
+		for path, decoder := range cfg.Decoders {
+			decoders[strings.ToUpper(strings.Trim(path, "\\"))] = decoder
+		}
+	}
+
+	typeRules := cfg.TypeRules
+	if cfg.TypeRules.Paths != nil {
+		typeRules.Paths = make(map[string]uint32, len(cfg.TypeRules.Paths))
+		for path, typ := range cfg.TypeRules.Paths {
+			typeRules.Paths[strings.ToUpper(strings.Trim(path, "\\"))] = typ
+		}
+	}
+
+	paths := cfg.Paths
+	cfg.Paths = nil
+
+	var maxDepths map[string]uint
+	if cfg.MaxDepths != nil {
+		maxDepths = make(map[string]uint, len(cfg.MaxDepths))
+		for path, depth := range cfg.MaxDepths {
+			maxDepths[strings.ToUpper(strings.Trim(path, "\\"))] = depth
+		}
+	}
+
+	retval := &WinReg{
 		key:          cfg.Key,
 		path:         cfg.Path,
 		defaultValue: cfg.DefaultValue,
 		maxDepth:     cfg.MaxDepth,
 		access:       cfg.getAccess(),
+		retries:      cfg.Retries,
+		host:         cfg.Host,
+		limiter:      cfg.Limiter,
+		resolveRefs:  cfg.References,
+		includeValue: cfg.IncludeValue,
+		watchMissing: cfg.WatchMissing,
+		expected:     expected,
+		strict:       cfg.Strict,
+		defaults:     cfg.Defaults,
+		changeEvent:  cfg.ChangeEvent,
+		subscribers:  &subscribers{},
+		watches:      &watchStop{},
+		missingTTL:   cfg.MissingTTL,
+		missing:      &negativeCache{},
+		api:          cfg.getAPI(),
+		maxValueSize: cfg.MaxValueSize,
+		rawStrings:   cfg.RawStrings,
+		pollInterval: cfg.PollInterval,
+		decoders:     decoders,
+		detectJSON:   cfg.DetectJSON,
+		detectUTF16:  cfg.DetectUTF16,
+		resolveMUI:   cfg.ResolveMUI,
+		classValue:   cfg.ClassValue,
+		transacted:   cfg.Transacted,
+		verifyData:   cfg.VerifyData,
+		transport:    cfg.Transport,
+		typeRules:    typeRules,
+		keyOptions:   cfg.getKeyOptions(),
+		eventDetails: cfg.EventDetails,
+		debounce:     cfg.Debounce,
+		resubscribe:  cfg.Resubscribe,
+		notifyFilter: cfg.getNotifyFilter(),
+		valueFilter:  newNameFilter(cfg.IncludeValues, cfg.ExcludeValues),
+		includeKeys:  cfg.IncludeKeys,
+		excludeKeys:  cfg.ExcludeKeys,
+		nameMapper:   cfg.NameMapper,
+		delimiter:    cfg.getDelimiter(),
+		delimEscape:  cfg.DelimEscape,
+		flatten:      cfg.Flatten,
+		flatSep:      cfg.getFlatSeparator(),
+		maxDepths:    maxDepths,
+		nestPaths:    cfg.NestPaths,
+		typesKey:     cfg.TypesKey,
+		noExpand:     cfg.NoExpand,
+		expander:     cfg.Expander,
+		signed:       cfg.Signed,
+		binaryFormat: cfg.getBinaryFormat(),
+		decoderRules: cfg.DecoderRules,
+		links:        cfg.Links,
+		rawTypes:     cfg.RawTypes,
+		resources:    cfg.Resources,
+		skipUnread:   cfg.SkipUnreadable,
+		skipped:      &skippedKeys{},
+		audit:        cfg.Audit,
+		audited:      &auditLog{},
+	}
+	if cfg.Workers > 1 {
+		retval.workers = make(chan struct{}, cfg.Workers-1)
 	}
+	for _, path := range paths {
+		// Each path has its own state, like the cache of a missing key
+		cfg.Path = path
+		p := Provider(cfg)
+		p.skipped = retval.skipped
+		p.audited = retval.audited
+		p.workers = retval.workers
+		retval.paths = append(retval.paths, p)
+	}
+
+	return retval
+}
+
+// readCopy returns a copy of the provider for reads of its own, e.g. by a
+// watch, with its own cache of a missing key, so a key missing for the
+// copy isn't hidden from the provider or from other copies, and its own
+// skipped keys and audit log, so reads of the copy don't reset those of
+// the provider's last Read(). The rate limiter is shared on purpose, as the common budget
+// of the providers using it.
+func (s *WinReg) readCopy() *WinReg {
+	p := *s
+	p.missing = &negativeCache{}
+	p.skipped = &skippedKeys{}
+	p.audited = &auditLog{}
+	if s.paths != nil {
+		p.paths = make([]*WinReg, len(s.paths))
+		for i, sub := range s.paths {
+			p.paths[i] = sub.readCopy()
+			p.paths[i].skipped = p.skipped
+			p.paths[i].audited = p.audited
+		}
+	}
+
+	return &p
 }
 
 func (s *WinReg) getAccess(base uint32) uint32 {
@@ -86,184 +455,702 @@ func (s *WinReg) ReadBytes() ([]byte, error) {
 }
 
 func (s *WinReg) Read() (map[string]interface{}, error) {
-	if retval, err := s.readKey(s.path, 1); err != nil {
-		return nil, fmt.Errorf("unable to read registry, %s", err.Error())
-	} else {
-		return retval, nil
+	return s.read(context.Background())
+}
+
+// read reads the tree, the traversal is aborted when ctx is done.
+func (s *WinReg) read(ctx context.Context) (map[string]interface{}, error) {
+	atomic.StoreInt32(&s.torn, 0)
+	s.unexpected = nil
+	s.skipped.reset()
+	s.audited.reset()
+	if isPerformanceKey(s.key) {
+		return nil, fmt.Errorf("unable to read registry, %s: %w", s.getKeyName(s.path), ErrPerformanceData)
+	}
+
+	read := s.reader()
+	if s.paths != nil {
+		read = s.readPaths
+	}
+	retval, missing, err := read(ctx)
+	if err != nil {
+		if s.defaults == nil || !missing {
+			return nil, fmt.Errorf("unable to read registry, %w", err)
+		}
+		// Nothing has been written to the registry yet
+		retval = make(map[string]interface{})
+	}
+	if s.defaults != nil {
+		mergeBeneath(retval, copyTree(s.defaults))
+	}
+
+	if s.expected != nil {
+		s.unexpected = s.findUnexpected(retval)
+		if s.strict && len(s.unexpected) > 0 {
+			return nil, fmt.Errorf("unable to read registry, %s: %w: %s",
+				s.getKeyName(s.path), ErrUnexpectedValues, strings.Join(s.unexpected, ", "))
+		}
+	}
+
+	if s.flatten {
+		flat := make(map[string]interface{})
+		flattenPaths("", s.flatSep, retval, flat)
+		retval = flat
+	}
+
+	return retval, nil
+}
+
+// ReadContext reads the tree as Read() does, aborting the traversal when
+// ctx is done. The context is checked between subkeys, so a read of a
+// huge or slow tree, e.g. of a remote registry, fails with ctx.Err()
+// wrapped shortly after. A read over WinRM is stopped as well.
+func (s *WinReg) ReadContext(ctx context.Context) (map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read registry, %w", err)
+	}
+
+	return s.read(ctx)
+}
+
+// reader returns the function reading the provider's key by the
+// configured means.
+func (s *WinReg) reader() func(ctx context.Context) (map[string]interface{}, bool, error) {
+	switch {
+	case s.host != "" && s.transport != TransportRemoteRegistry:
+		return s.readRemote
+	case s.transacted:
+		return s.readTransacted
+	default:
+		return s.readTree
+	}
+}
+
+// readTree reads the provider's key, missing is set if the key doesn't
+// exist. A missing key is remembered for MissingTTL.
+func (s *WinReg) readTree(ctx context.Context) (tree map[string]interface{}, missing bool, err error) {
+	if err = s.missing.get(s.api.Now()); err != nil {
+		return nil, true, err
+	}
+
+	root, err := s.connect()
+	if err != nil {
+		return nil, false, err
+	}
+	if root != s.key {
+		defer s.api.CloseKey(root)
+	}
+
+	tree, err = s.readKey(ctx, root, s.path, 1, nil)
+	if err != nil && !s.keyExists(root, s.path) {
+		if s.missingTTL > 0 {
+			s.missing.put(err, s.api.Now().Add(s.missingTTL))
+		}
+		return nil, true, err
 	}
+
+	return tree, false, err
+}
+
+// Torn reports whether the last Read() observed keys or values deleted
+// by a concurrent writer, so the result may combine data from before and
+// after that modification.
+func (s *WinReg) Torn() bool {
+	return atomic.LoadInt32(&s.torn) != 0
+}
+
+// isPerformanceKey reports whether key is one of the performance data
+// pseudo-keys. They only look like keys: values are generated on the fly,
+// enumeration and notifications don't work as for regular keys.
+func isPerformanceKey(key registry.Key) bool {
+	switch key {
+	case PERFORMANCE_DATA, PERFORMANCE_TEXT, PERFORMANCE_NLSTEXT:
+		return true
+	default:
+		return false
+	}
+}
+
+// connect returns the root key to read from, which is a connection to
+// the remote registry if a host is configured.
+func (s *WinReg) connect() (registry.Key, error) {
+	return s.connectKey(s.key)
+}
+
+// connectKey returns the predefined key on the configured host.
+func (s *WinReg) connectKey(key registry.Key) (registry.Key, error) {
+	if s.host == "" {
+		return key, nil
+	}
+
+	s.limiter.Wait()
+	k, err := s.api.OpenRemoteKey(s.host, key)
+	if err != nil {
+		return 0, fmt.Errorf("unable to connect to %s: %w", s.host, err)
+	}
+
+	return k, nil
 }
 
 func (s *WinReg) getKeyName(path string) string {
-	switch s.key {
+	if s.host != "" {
+		return fmt.Sprintf("\\\\%s\\%s", s.host, keyName(s.key, path))
+	}
+	return keyName(s.key, path)
+}
+
+// parseKeyName splits a full key name like "HKLM\SOFTWARE\Vendor" into
+// a predefined key and a path. Both short and long hive names are accepted.
+func parseKeyName(name string) (registry.Key, string, bool) {
+	top, path := splitPath(name)
+
+	switch strings.ToUpper(top) {
+	case "HKCR", "HKEY_CLASSES_ROOT":
+		return CLASSES_ROOT, path, true
+	case "HKCU", "HKEY_CURRENT_USER":
+		return CURRENT_USER, path, true
+	case "HKLM", "HKEY_LOCAL_MACHINE":
+		return LOCAL_MACHINE, path, true
+	case "HKU", "HKEY_USERS":
+		return USERS, path, true
+	case "HKCC", "HKEY_CURRENT_CONFIG":
+		return CURRENT_CONFIG, path, true
+	case "HKPD", "HKEY_PERFORMANCE_DATA":
+		return PERFORMANCE_DATA, path, true
+	case "HKPT", "HKEY_PERFORMANCE_TEXT":
+		return PERFORMANCE_TEXT, path, true
+	case "HKPN", "HKEY_PERFORMANCE_NLSTEXT":
+		return PERFORMANCE_NLSTEXT, path, true
+	default:
+		return 0, "", false
+	}
+}
+
+// keyName returns a full key name with a short hive name prefix.
+func keyName(key registry.Key, path string) string {
+	if hive := shortHiveName(key); hive != "" {
+		return hive + "\\" + path
+	}
+	return path
+}
+
+// shortHiveName returns the short name of a predefined key, empty for other
+// keys.
+func shortHiveName(key registry.Key) string {
+	switch key {
 	case CLASSES_ROOT:
-		return fmt.Sprintf("HKCR\\%s", path)
+		return "HKCR"
 	case CURRENT_USER:
-		return fmt.Sprintf("HKCU\\%s", path)
+		return "HKCU"
 	case LOCAL_MACHINE:
-		return fmt.Sprintf("HKLM\\%s", path)
+		return "HKLM"
 	case USERS:
-		return fmt.Sprintf("HKU\\%s", path)
+		return "HKU"
 	case CURRENT_CONFIG:
-		return fmt.Sprintf("HKCC\\%s", path)
+		return "HKCC"
 	case PERFORMANCE_DATA:
-		return fmt.Sprintf("HKPD\\%s", path)
+		return "HKPD"
+	case PERFORMANCE_TEXT:
+		return "HKPT"
+	case PERFORMANCE_NLSTEXT:
+		return "HKPN"
+	default:
+		return ""
+	}
+}
+
+// valueTypeName returns the name of a registry value type, e.g. "REG_SZ".
+func valueTypeName(typ uint32) string {
+	switch typ {
+	case registry.NONE:
+		return "REG_NONE"
+	case registry.SZ:
+		return "REG_SZ"
+	case registry.EXPAND_SZ:
+		return "REG_EXPAND_SZ"
+	case registry.BINARY:
+		return "REG_BINARY"
+	case registry.DWORD:
+		return "REG_DWORD"
+	case registry.DWORD_BIG_ENDIAN:
+		return "REG_DWORD_BIG_ENDIAN"
+	case registry.LINK:
+		return "REG_LINK"
+	case registry.MULTI_SZ:
+		return "REG_MULTI_SZ"
+	case registry.RESOURCE_LIST:
+		return "REG_RESOURCE_LIST"
+	case registry.FULL_RESOURCE_DESCRIPTOR:
+		return "REG_FULL_RESOURCE_DESCRIPTOR"
+	case registry.RESOURCE_REQUIREMENTS_LIST:
+		return "REG_RESOURCE_REQUIREMENTS_LIST"
+	case registry.QWORD:
+		return "REG_QWORD"
 	default:
-		return path
+		return fmt.Sprintf("REG_0x%X", typ)
 	}
 }
 
-func (s *WinReg) readKey(path string, level uint) (map[string]interface{}, error) {
-	k, err := registry.OpenKey(s.key, path, s.getAccess(registry.READ))
+// valueTypeByName returns the registry value type named as by
+// valueTypeName().
+func valueTypeByName(name string) (uint32, bool) {
+	for typ := uint32(registry.NONE); typ <= registry.QWORD; typ++ {
+		if strings.EqualFold(name, valueTypeName(typ)) {
+			return typ, true
+		}
+	}
+	return 0, false
+}
+
+// readKey reads the key path with its values and subkeys. The includes
+// are the keys being inlined on the way to this key, used to detect loops.
+func (s *WinReg) readKey(ctx context.Context, root registry.Key, path string, level uint, includes []string) (map[string]interface{}, error) {
+	s.limiter.Wait()
+	k, err := s.api.OpenKey(root, path, s.getAccess(registry.READ))
 	if err != nil {
-		return nil, fmt.Errorf("%s: %s", s.getKeyName(path), err.Error())
+		return nil, s.keyError("open", path, "", err)
 	}
-	defer k.Close()
+	defer s.api.CloseKey(k)
 
 	retval := make(map[string]interface{})
+	var (
+		types    map[string]interface{}
+		included interface{}
+	)
+	if s.typesKey != "" {
+		types = make(map[string]interface{})
+	}
 	// Reading key values
-	if values, err := k.ReadValueNames(0); err != nil && !errors.Is(err, io.EOF) {
-		return nil, fmt.Errorf("%s: %s", s.getKeyName(path), err.Error())
+	s.limiter.Wait()
+	if values, err := s.api.ReadValueNames(k); err != nil && !errors.Is(err, io.EOF) {
+		return nil, s.keyError("enumerate", path, "", err)
 	} else {
 		var (
-			koanfValue string
-			tmpBuffer  []byte
-			tmpStr     string
-			typ        uint32
+			size int
+			typ  uint32
+			// Values are read with their type in one call, only those
+			// larger than the buffer take another one
+			buf = make([]byte, valueBufferSize)
 		)
 
 		for _, value := range values {
-			if _, typ, err = k.GetValue(value, nil); err != nil {
-				return nil, fmt.Errorf("%s: %s, %s", s.getKeyName(path), value, err.Error())
-			}
-			switch typ {
-			case registry.SZ:
-				// Is it default key value
-				if value == "" {
-					if s.defaultValue == "" {
-						continue
-					}
-					koanfValue = s.defaultValue
-				} else {
-					koanfValue = value
-				}
-				if retval[koanfValue], _, err = k.GetStringValue(value); err != nil {
-					return nil, fmt.Errorf("%s: %s, %s", s.getKeyName(path), value, err.Error())
-				}
-			case registry.EXPAND_SZ:
-				if tmpStr, _, err = k.GetStringValue(value); err != nil {
-					return nil, fmt.Errorf("%s: %s, %s", s.getKeyName(path), value, err.Error())
-				}
-				if retval[value], err = registry.ExpandString(tmpStr); err != nil {
-					return nil, fmt.Errorf("%s: %s, %s", s.getKeyName(path), value, err.Error())
-				}
-			case registry.MULTI_SZ:
-				if retval[value], _, err = k.GetStringsValue(value); err != nil {
-					return nil, fmt.Errorf("%s: %s, %s", s.getKeyName(path), value, err.Error())
+			s.limiter.Wait()
+			if size, typ, err = s.api.GetValue(k, value, buf); err != nil && !errors.Is(err, registry.ErrShortBuffer) {
+				if errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+					// The value was deleted after enumeration
+					atomic.StoreInt32(&s.torn, 1)
+					continue
 				}
-			case registry.DWORD, registry.QWORD:
-				if retval[value], _, err = k.GetIntegerValue(value); err != nil {
-					return nil, fmt.Errorf("%s: %s, %s", s.getKeyName(path), value, err.Error())
-				}
-			case registry.DWORD_BIG_ENDIAN:
-				if len(tmpBuffer) == 0 {
-					tmpBuffer = make([]byte, 4)
-				}
-				if _, _, err = k.GetValue(value, tmpBuffer); err != nil {
-					return nil, fmt.Errorf("%s: %s, %v", s.getKeyName(path), value, err)
+				return nil, s.keyError("read", path, value, err)
+			}
+
+			// The include value is found by its registry name, it isn't
+			// filtered nor mapped as it doesn't go into the tree
+			include := s.includeValue != "" && strings.EqualFold(value, s.includeValue)
+			koanfValue, ok, err := s.valueSelected(value, typ)
+			if err != nil {
+				return nil, s.keyError("read", path, value, err)
+			}
+			if !ok && !include {
+				s.auditValue(path, value, typ, AuditFiltered)
+				continue
+			}
+			if !include && typ == registry.BINARY && s.maxValueSize > 0 && size > s.maxValueSize {
+				// Large blobs are read by OpenValue()
+				s.auditValue(path, value, typ, AuditTooLarge)
+				continue
+			}
+
+			var stored []byte
+			if size <= len(buf) {
+				stored = buf[:size]
+			} else {
+				if stored, typ, err = s.getValueSized(k, value, size); err != nil {
+					return nil, s.keyError("read", path, value, err)
 				}
-				retval[value] = binary.LittleEndian.Uint32(tmpBuffer)
-			case registry.BINARY:
-				if retval[value], _, err = k.GetBinaryValue(value); err != nil {
-					return nil, fmt.Errorf("%s: %s, %v", s.getKeyName(path), value, err)
+				// The grown buffer is kept for the following values
+				buf = stored[:cap(stored)]
+			}
+
+			data, ok, err := s.readValue(k, value, typ, stored)
+			if err != nil {
+				return nil, s.keyError("read", path, value, err)
+			}
+			if !ok {
+				s.auditValue(path, value, typ, AuditUnsupported)
+				continue
+			}
+			if include {
+				included = data
+				continue
+			}
+			if str, ok := data.(string); s.resolveRefs && ok && typ == registry.SZ {
+				if data, err = s.resolveReference(str, nil); err != nil {
+					return nil, s.keyError("read", path, value, err)
 				}
 			}
+			if data, err = s.decodeValue(path, koanfValue, typ, data); err != nil {
+				return nil, s.keyError("decode", path, value, err)
+			}
+			name := s.mapName(path, koanfValue)
+			retval[name] = data
+			if types != nil {
+				types[name] = valueTypeName(typ)
+			}
+		}
+	}
+	if len(types) > 0 {
+		retval[s.typesKey] = types
+	}
+
+	if s.classValue != "" {
+		s.limiter.Wait()
+		class, err := s.api.GetKeyClass(k)
+		if err != nil {
+			return nil, s.keyError("read", path, "", err)
+		}
+		if class != "" {
+			retval[s.classValue] = class
 		}
 	}
 
 	// Reading subkeys
-	if (s.maxDepth == 0) || (level < s.maxDepth) {
-		if subKeys, err := k.ReadSubKeyNames(0); err != nil && !errors.Is(err, io.EOF) {
-			return nil, fmt.Errorf("%s: %v", s.getKeyName(path), err)
+	if s.readsSubKeys(path, level) {
+		s.limiter.Wait()
+		if subKeys, err := s.api.ReadSubKeyNames(k); err != nil && !errors.Is(err, io.EOF) {
+			return nil, s.keyError("enumerate", path, "", err)
 		} else {
+			var reads []subKeyRead
 			for _, subKey := range subKeys {
-				if retval[subKey], err = s.readKey(path+"\\"+subKey, level+1); err != nil {
-					return nil, fmt.Errorf("%s: %v", s.getKeyName(path), err)
+				if !s.keySelected(joinPath(path, subKey)) {
+					s.auditKey(joinPath(path, subKey), AuditFiltered, nil)
+					continue
+				}
+				chain := includes
+				if s.links != LinksDefault {
+					var handled bool
+					handled, chain, err = s.linkedSubKey(root, joinPath(path, subKey), s.mapName(path, escapeName(subKey)), retval, includes)
+					if err != nil {
+						return nil, s.keyError("open", joinPath(path, subKey), "", err)
+					}
+					if handled {
+						continue
+					}
+				}
+				reads = append(reads, subKeyRead{name: subKey, path: joinPath(path, subKey), includes: chain})
+			}
+
+			// Results are merged in the order of enumeration, also when
+			// subkeys are read concurrently
+			s.readSubKeys(ctx, root, path, level+1, reads)
+			for _, r := range reads {
+				if r.err != nil {
+					if s.skippable(r.err) {
+						err = s.keyError("open", r.path, "", r.err)
+						s.skipped.add(s.getKeyName(r.path), err)
+						s.auditKey(r.path, AuditUnreadable, err)
+						continue
+					}
+					return nil, s.keyError("open", path, "", r.err)
+				}
+				if r.tree != nil {
+					retval[s.mapName(path, escapeName(r.name))] = r.tree
 				}
 			}
 		}
 	}
 
+	if included != nil {
+		if err := s.inlineIncludes(ctx, retval, included, level, includes); err != nil {
+			return nil, s.keyError("read", path, "", err)
+		}
+	}
+
 	return retval, nil
 }
 
+// valueBufferSize is the initial size of the buffer values are read in,
+// enough for most of them.
+const valueBufferSize = 512
+
+// readValue decodes the data stored in buf of the value name of type typ.
+// The buffer may be reused, so the result doesn't refer to it. Values of
+// unsupported types are reported with ok set to false.
+func (s *WinReg) readValue(k registry.Key, name string, typ uint32, buf []byte) (data interface{}, ok bool, err error) {
+	if s.rawStrings && (typ == registry.SZ || typ == registry.EXPAND_SZ || typ == registry.MULTI_SZ) {
+		return rawString(typ, buf), true, nil
+	}
+
+	switch typ {
+	case registry.SZ:
+		data = storedStringData(buf)
+	case registry.EXPAND_SZ:
+		str := storedStringData(buf)
+		switch {
+		case s.noExpand:
+			data = str
+		case s.expander != nil:
+			data, err = s.expander(str)
+		default:
+			data, err = registry.ExpandString(str)
+		}
+	case registry.MULTI_SZ:
+		data = regfile.DecodeMultiString(buf)
+	case registry.DWORD, registry.QWORD:
+		data, err = storedIntegerData(typ, buf)
+	case registry.DWORD_BIG_ENDIAN:
+		word := make([]byte, 4)
+		if copy(word, buf) < len(buf) {
+			err = registry.ErrShortBuffer
+		} else {
+			data = binary.LittleEndian.Uint32(word)
+		}
+	case registry.BINARY:
+		data = append(make([]byte, 0, len(buf)), buf...)
+	case registry.RESOURCE_LIST, registry.FULL_RESOURCE_DESCRIPTOR:
+		if !s.rawTypes && !s.resources {
+			return nil, false, nil
+		}
+		data = append(make([]byte, 0, len(buf)), buf...)
+	case registry.NONE, registry.RESOURCE_REQUIREMENTS_LIST:
+		if !s.rawTypes {
+			return nil, false, nil
+		}
+		data = append(make([]byte, 0, len(buf)), buf...)
+	default:
+		return nil, false, nil
+	}
+	if str, ok := data.(string); ok && err == nil && s.resolveMUI {
+		data = s.resolveMUIString(k, name, str)
+	}
+
+	return data, true, err
+}
+
+// readSubKey reads a subkey found by enumeration. A subkey deleted by
+// a concurrent writer is omitted (nil map is returned), a subkey deleted
+// while it was being read is retried a bounded number of times.
+func (s *WinReg) readSubKey(ctx context.Context, root registry.Key, path string, level uint, includes []string) (map[string]interface{}, error) {
+	retries := s.retries
+	if retries == 0 {
+		retries = DefaultRetries
+	}
+
+	for attempt := uint(0); ; attempt++ {
+		retval, err := s.readKey(ctx, root, path, level, includes)
+		if err == nil {
+			return retval, nil
+		}
+		if !errors.Is(err, windows.ERROR_FILE_NOT_FOUND) && !errors.Is(err, windows.ERROR_KEY_DELETED) {
+			return nil, err
+		}
+
+		atomic.StoreInt32(&s.torn, 1)
+		if !s.keyExists(root, path) {
+			// The subkey was deleted after enumeration
+			return nil, nil
+		}
+		if attempt >= retries {
+			return nil, err
+		}
+	}
+}
+
+func (s *WinReg) keyExists(root registry.Key, path string) bool {
+	s.limiter.Wait()
+	k, err := s.api.OpenKey(root, path, s.getAccess(registry.QUERY_VALUE))
+	if err != nil {
+		return !errors.Is(err, windows.ERROR_FILE_NOT_FOUND)
+	}
+	s.api.CloseKey(k)
+
+	return true
+}
+
 // Watch() watches the registry key and triggers a callback when it changes.
 // Due to the nature of the Windows API, you cannot flexibly choose the depth
 // of change tracking. If MaxDepth is not set to 1 in the provider, changes
 // will be monitored to the full depth.
 // If the monitored top-level key is deleted, the function will stop
 // notifications, even if a key with the same name will create again. You must
-// call the Watch() method again.
+// call the Watch() method again, unless Resubscribe is set.
+// If WatchMissing is set in the provider and the key doesn't exist yet,
+// its nearest existing parent is watched until the key is created. Then
+// the callback receives a *CreatedEvent and the key itself is watched.
+// A remote registry can't notify about changes, so it is polled every
+// PollInterval instead.
+// Watching continues until Unwatch() is called.
 func (s *WinReg) Watch(cb func(event interface{}, err error)) error {
-	const filter uint32 = REG_NOTIFY_CHANGE_NAME | REG_NOTIFY_CHANGE_LAST_SET
+	if isPerformanceKey(s.key) {
+		return fmt.Errorf("failed to watch %s: %w", s.getKeyName(s.path), ErrPerformanceData)
+	}
+
+	if s.changeEvent != "" {
+		named, err := openChangeEvent(s.changeEvent)
+		if err != nil {
+			return fmt.Errorf("watch failed: %w", err)
+		}
+		if err = s.watchPaths(signalChange(named, cb)); err != nil {
+			windows.Close(named)
+			return err
+		}
+		s.watches.closeOnStop(named)
+		return nil
+	}
+
+	return s.watchPaths(cb)
+}
+
+// watchPaths watches the provider's key or, if Paths are set, the keys of
+// all of them.
+func (s *WinReg) watchPaths(cb func(event interface{}, err error)) error {
+	if s.paths == nil {
+		return s.watch(cb)
+	}
+
+	for _, p := range s.paths {
+		// Stopped along with the provider's watches
+		c := *p
+		c.watches = s.watches
+		if err := c.watch(cb); err != nil {
+			s.Unwatch()
+			return err
+		}
+	}
 
-	k, err := registry.OpenKey(s.key, s.path, s.getAccess(registry.NOTIFY))
+	return nil
+}
+
+func (s *WinReg) watch(cb func(event interface{}, err error)) error {
+	if s.host != "" {
+		// RegNotifyChangeKeyValue can't be asynchronous for remote keys
+		return s.watchPoll(cb)
+	}
+
+	k, err := s.api.OpenKey(s.key, s.path, s.getAccess(registry.NOTIFY))
 	if err != nil {
-		return fmt.Errorf("failed to open registry key %s: %v", s.getKeyName(s.path), err)
+		if s.watchMissing && errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+			return s.watchMissingKey(cb)
+		}
+		return fmt.Errorf("failed to open registry key %w", s.keyError("open", s.path, "", err))
 	}
 
-	// We need this complication because the function starts the goroutine,
-	// but we cannot exit the function until the monitoring has actually started.
+	return s.watchKey(k, cb)
+}
+
+// watchKey starts watching the opened key k, which is closed when
+// watching stops.
+func (s *WinReg) watchKey(k registry.Key, cb func(event interface{}, err error)) error {
+	filter := s.notifyFilter
+
 	event, err := windows.CreateEvent(nil, 1, 0, nil)
 	if err != nil {
-		k.Close()
-		return fmt.Errorf("watch failed: %v", err)
+		s.api.CloseKey(k)
+		return fmt.Errorf("watch failed: %w", err)
 	}
-	err = regNotifyChangeKeyValue(syscall.Handle(k), (s.maxDepth != 1), filter, event, true)
+	stop, err := s.watches.start()
 	if err != nil {
-		k.Close()
+		s.api.CloseKey(k)
 		windows.Close(event)
-		return fmt.Errorf("watch failed: %v", err)
+		return fmt.Errorf("watch failed: %w", err)
+	}
+
+	// The initial state is taken before the notification is armed, a
+	// change in between shows up in the next comparison
+	var digest [sha256.Size]byte
+	if s.verifyData {
+		// An unreadable tree is not compared, all notifications pass
+		digest, _ = s.digest()
+	}
+	var tracker *changeTracker
+	if s.eventDetails {
+		// Without the initial state changes can't be described
+		tracker, _ = s.newChangeTracker()
 	}
 
+	// The notification is armed by the goroutine waiting for it, which
+	// keeps its thread if notifications aren't thread agnostic. We cannot
+	// exit the function until the monitoring has actually started.
+	armed := make(chan error)
 	go func() {
 		var (
 			waitResult uint32
 			err        error
 		)
 
-		defer k.Close()
+		defer s.watches.running.Done()
+		defer s.api.CloseKey(k)
 		defer windows.Close(event)
+		defer lockNotifyThread()()
+		err = s.api.NotifyChangeKeyValue(k, (s.maxDepth != 1), filter, event)
+		armed <- err
+		if err != nil {
+			return
+		}
 		for {
-			waitResult, err = windows.WaitForSingleObject(event, windows.INFINITE)
+			waitResult, err = windows.WaitForMultipleObjects([]windows.Handle{event, stop}, false, windows.INFINITE)
 			if err != nil {
-				// The  windows.WaitForSingleObject() wrapper will assign
+				// The  windows.WaitForMultipleObjects() wrapper will assign
 				// a non-nil value to err if the API function returns
 				// WAIT_FAILED.
-				cb(nil, fmt.Errorf("watch failed: %v", err))
+				cb(nil, fmt.Errorf("watch failed: %w", err))
 				return
 			}
 
 			switch waitResult {
 			case windows.WAIT_OBJECT_0:
 				if err = windows.ResetEvent(event); err != nil {
-					cb(nil, fmt.Errorf("watch failed: %v", err))
+					cb(nil, fmt.Errorf("watch failed: %w", err))
 					return
 				}
 				// RegNotifyChangeKeyValue is a one-time function, according
 				// to the documentation, we need to call it again to get the
 				// next event.
-				if err = regNotifyChangeKeyValue(syscall.Handle(k), (s.maxDepth != 1), filter, event, true); err != nil {
-					cb(nil, fmt.Errorf("watch failed: %v", err))
+				err = s.api.NotifyChangeKeyValue(k, (s.maxDepth != 1), filter, event)
+				if s.resubscribe && errors.Is(err, windows.ERROR_KEY_DELETED) {
+					s.rewatch(tracker, cb)
 					return
 				}
+				if err != nil {
+					cb(nil, fmt.Errorf("watch failed: %w", err))
+					return
+				}
+				if s.debounce > 0 {
+					stopped, err := s.settle(k, filter, event, stop)
+					if s.resubscribe && errors.Is(err, windows.ERROR_KEY_DELETED) {
+						s.rewatch(tracker, cb)
+						return
+					}
+					if err != nil {
+						cb(nil, err)
+						return
+					}
+					if stopped {
+						return
+					}
+				}
 
-				cb(nil, nil)
+				if s.verifyData {
+					current, err := s.digest()
+					if err == nil && current == digest {
+						// Only metadata was touched or identical data rewritten
+						continue
+					}
+					digest = current
+				}
+				notifyChanges(tracker, cb)
+			case windows.WAIT_OBJECT_0 + 1:
+				// Stopped by Unwatch()
+				return
 			case windows.WAIT_ABANDONED:
 				// The program was terminated.
 				return
 			}
 		}
 	}()
+	if err = <-armed; err != nil {
+		return fmt.Errorf("watch failed: %w", err)
+	}
 
 	return nil
 }
@@ -271,6 +1158,34 @@ func (s *WinReg) Watch(cb func(event interface{}, err error)) error {
 var (
 	advapi32                    = syscall.NewLazyDLL("Advapi32.dll")
 	procRegNotifyChangeKeyValue = advapi32.NewProc("RegNotifyChangeKeyValue")
+	procRegLoadAppKeyW          = advapi32.NewProc("RegLoadAppKeyW")
+	procRegSetValueExW          = advapi32.NewProc("RegSetValueExW")
+	procRegCreateKeyExW         = advapi32.NewProc("RegCreateKeyExW")
+	procRegFlushKey             = advapi32.NewProc("RegFlushKey")
+	procRegOpenKeyTransactedW   = advapi32.NewProc("RegOpenKeyTransactedW")
+	procRegCreateKeyTransacted  = advapi32.NewProc("RegCreateKeyTransactedW")
+	procRegDeleteKeyExW         = advapi32.NewProc("RegDeleteKeyExW")
+	procAccessCheck             = advapi32.NewProc("AccessCheck")
+
+	authz                              = syscall.NewLazyDLL("Authz.dll")
+	procAuthzInitializeResourceManager = authz.NewProc("AuthzInitializeResourceManager")
+	procAuthzFreeResourceManager       = authz.NewProc("AuthzFreeResourceManager")
+	procAuthzInitializeContextFromSid  = authz.NewProc("AuthzInitializeContextFromSid")
+	procAuthzFreeContext               = authz.NewProc("AuthzFreeContext")
+	procAuthzAccessCheck               = authz.NewProc("AuthzAccessCheck")
+
+	ntdll                   = syscall.NewLazyDLL("ntdll.dll")
+	procNtOpenKey           = ntdll.NewProc("NtOpenKey")
+	procNtEnumerateKey      = ntdll.NewProc("NtEnumerateKey")
+	procNtEnumerateValueKey = ntdll.NewProc("NtEnumerateValueKey")
+	procNtQueryValueKey     = ntdll.NewProc("NtQueryValueKey")
+	procRtlOpenCurrentUser  = ntdll.NewProc("RtlOpenCurrentUser")
+	procNtDeleteKey         = ntdll.NewProc("NtDeleteKey")
+
+	ktmw32                  = syscall.NewLazyDLL("ktmw32.dll")
+	procCreateTransaction   = ktmw32.NewProc("CreateTransaction")
+	procCommitTransaction   = ktmw32.NewProc("CommitTransaction")
+	procRollbackTransaction = ktmw32.NewProc("RollbackTransaction")
 )
 
 const (
@@ -281,6 +1196,17 @@ const (
 	REG_NOTIFY_THREAD_AGNOSTIC   = uint32(0x10000000)
 )
 
+const (
+	REG_OPTION_VOLATILE    = uint32(0x00000001)
+	REG_OPTION_CREATE_LINK = uint32(0x00000002)
+	REG_OPTION_OPEN_LINK   = uint32(0x00000008)
+
+	REG_CREATED_NEW_KEY     = uint32(0x00000001)
+	REG_OPENED_EXISTING_KEY = uint32(0x00000002)
+
+	KEY_CREATE_LINK = uint32(0x0020)
+)
+
 func regNotifyChangeKeyValue(key syscall.Handle, watchSubtree bool, notifyFilter uint32, event windows.Handle, asynchronous bool) (regerrno error) {
 	var _p0, _p1 uint32
 	if watchSubtree {
@@ -295,3 +1221,267 @@ func regNotifyChangeKeyValue(key syscall.Handle, watchSubtree bool, notifyFilter
 	}
 	return
 }
+
+func regLoadAppKey(file string, access uint32, options uint32) (key registry.Key, regerrno error) {
+	p, err := syscall.UTF16PtrFromString(file)
+	if err != nil {
+		return 0, err
+	}
+	var result syscall.Handle
+	r0, _, _ := syscall.Syscall6(procRegLoadAppKeyW.Addr(), 5, uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&result)), uintptr(access), uintptr(options), 0, 0)
+	if r0 != 0 {
+		return 0, syscall.Errno(r0)
+	}
+	return registry.Key(result), nil
+}
+
+func regSetValueEx(key registry.Key, name string, valtype uint32, data []byte) (regerrno error) {
+	p, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	var buf *byte
+	if len(data) > 0 {
+		buf = &data[0]
+	}
+	r0, _, _ := syscall.Syscall6(procRegSetValueExW.Addr(), 6, uintptr(key), uintptr(unsafe.Pointer(p)), 0, uintptr(valtype), uintptr(unsafe.Pointer(buf)), uintptr(len(data)))
+	if r0 != 0 {
+		regerrno = syscall.Errno(r0)
+	}
+	return
+}
+
+func regFlushKey(key registry.Key) (regerrno error) {
+	r0, _, _ := syscall.Syscall(procRegFlushKey.Addr(), 1, uintptr(key), 0, 0)
+	if r0 != 0 {
+		regerrno = syscall.Errno(r0)
+	}
+	return
+}
+
+type genericMapping struct {
+	GenericRead    uint32
+	GenericWrite   uint32
+	GenericExecute uint32
+	GenericAll     uint32
+}
+
+func accessCheck(sd *windows.SECURITY_DESCRIPTOR, token windows.Token, desiredAccess uint32, mapping *genericMapping) (granted uint32, err error) {
+	var (
+		privileges    [256]byte
+		privilegesLen = uint32(len(privileges))
+		status        int32
+	)
+	r1, _, e1 := syscall.Syscall9(procAccessCheck.Addr(), 8, uintptr(unsafe.Pointer(sd)), uintptr(token), uintptr(desiredAccess), uintptr(unsafe.Pointer(mapping)), uintptr(unsafe.Pointer(&privileges[0])), uintptr(unsafe.Pointer(&privilegesLen)), uintptr(unsafe.Pointer(&granted)), uintptr(unsafe.Pointer(&status)), 0)
+	if r1 == 0 {
+		return 0, e1
+	}
+	if status == 0 {
+		// Nothing is granted
+		return 0, nil
+	}
+	return granted, nil
+}
+
+const authzRMFlagNoAudit = 0x1
+
+type authzAccessRequest struct {
+	DesiredAccess        uint32
+	PrincipalSelfSid     *windows.SID
+	ObjectTypeList       uintptr
+	ObjectTypeListLength uint32
+	OptionalArguments    uintptr
+}
+
+type authzAccessReply struct {
+	ResultListLength      uint32
+	GrantedAccessMask     *uint32
+	SaclEvaluationResults *uint32
+	Error                 *uint32
+}
+
+func authzInitializeResourceManager() (rm windows.Handle, err error) {
+	r1, _, e1 := syscall.Syscall6(procAuthzInitializeResourceManager.Addr(), 6, authzRMFlagNoAudit, 0, 0, 0, 0, uintptr(unsafe.Pointer(&rm)))
+	if r1 == 0 {
+		return 0, e1
+	}
+	return rm, nil
+}
+
+func authzFreeResourceManager(rm windows.Handle) {
+	syscall.Syscall(procAuthzFreeResourceManager.Addr(), 1, uintptr(rm), 0, 0)
+}
+
+func authzInitializeContextFromSid(sid *windows.SID, rm windows.Handle) (ctx windows.Handle, err error) {
+	var r1 uintptr
+	var e1 syscall.Errno
+	// The zero LUID identifier is passed by value, it takes two arguments
+	// on 32-bit platforms.
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		r1, _, e1 = syscall.Syscall9(procAuthzInitializeContextFromSid.Addr(), 7, 0, uintptr(unsafe.Pointer(sid)), uintptr(rm), 0, 0, 0, uintptr(unsafe.Pointer(&ctx)), 0, 0)
+	} else {
+		r1, _, e1 = syscall.Syscall9(procAuthzInitializeContextFromSid.Addr(), 8, 0, uintptr(unsafe.Pointer(sid)), uintptr(rm), 0, 0, 0, 0, uintptr(unsafe.Pointer(&ctx)), 0)
+	}
+	if r1 == 0 {
+		return 0, e1
+	}
+	return ctx, nil
+}
+
+func authzFreeContext(ctx windows.Handle) {
+	syscall.Syscall(procAuthzFreeContext.Addr(), 1, uintptr(ctx), 0, 0)
+}
+
+func authzAccessCheck(ctx windows.Handle, sd *windows.SECURITY_DESCRIPTOR, desiredAccess uint32) (uint32, error) {
+	var granted, result uint32
+	request := authzAccessRequest{DesiredAccess: desiredAccess}
+	reply := authzAccessReply{ResultListLength: 1, GrantedAccessMask: &granted, Error: &result}
+	r1, _, e1 := syscall.Syscall9(procAuthzAccessCheck.Addr(), 9, 0, uintptr(ctx), uintptr(unsafe.Pointer(&request)), 0, uintptr(unsafe.Pointer(sd)), 0, 0, uintptr(unsafe.Pointer(&reply)), 0)
+	if r1 == 0 {
+		return 0, e1
+	}
+	if result != 0 {
+		// Nothing is granted
+		return 0, nil
+	}
+	return granted, nil
+}
+
+func ntOpenKey(root windows.Handle, name string, access uint32) (registry.Key, error) {
+	objectName, err := ntString(name)
+	if err != nil {
+		return 0, err
+	}
+	attrs := windows.OBJECT_ATTRIBUTES{RootDirectory: root, ObjectName: objectName, Attributes: windows.OBJ_CASE_INSENSITIVE}
+	attrs.Length = uint32(unsafe.Sizeof(attrs))
+	var result windows.Handle
+	r0, _, _ := syscall.Syscall(procNtOpenKey.Addr(), 3, uintptr(unsafe.Pointer(&result)), uintptr(access), uintptr(unsafe.Pointer(&attrs)))
+	if r0 != 0 {
+		// Same error codes as returned by the Win32 API
+		return 0, windows.NTStatus(r0).Errno()
+	}
+	return registry.Key(result), nil
+}
+
+func ntEnumerateKey(key registry.Key, index uint32, buf []byte) (uint32, windows.NTStatus) {
+	var result uint32
+	// KeyBasicInformation
+	r0, _, _ := syscall.Syscall6(procNtEnumerateKey.Addr(), 6, uintptr(key), uintptr(index), 0, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), uintptr(unsafe.Pointer(&result)))
+	return result, windows.NTStatus(r0)
+}
+
+func ntEnumerateValueKey(key registry.Key, index uint32, buf []byte) (uint32, windows.NTStatus) {
+	var result uint32
+	// KeyValueBasicInformation
+	r0, _, _ := syscall.Syscall6(procNtEnumerateValueKey.Addr(), 6, uintptr(key), uintptr(index), 0, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), uintptr(unsafe.Pointer(&result)))
+	return result, windows.NTStatus(r0)
+}
+
+func rtlOpenCurrentUser(access uint32) (registry.Key, error) {
+	var result windows.Handle
+	r0, _, _ := syscall.Syscall(procRtlOpenCurrentUser.Addr(), 2, uintptr(access), uintptr(unsafe.Pointer(&result)), 0)
+	if r0 != 0 {
+		return 0, windows.NTStatus(r0).Errno()
+	}
+	return registry.Key(result), nil
+}
+
+func ntQueryValueKey(key registry.Key, name *windows.NTUnicodeString, buf []byte) (uint32, windows.NTStatus) {
+	var result uint32
+	// KeyValuePartialInformation
+	r0, _, _ := syscall.Syscall6(procNtQueryValueKey.Addr(), 6, uintptr(key), uintptr(unsafe.Pointer(name)), 2, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), uintptr(unsafe.Pointer(&result)))
+	return result, windows.NTStatus(r0)
+}
+
+func regOpenKeyTransacted(key registry.Key, path string, access uint32, transaction windows.Handle) (registry.Key, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var result syscall.Handle
+	r0, _, _ := syscall.Syscall9(procRegOpenKeyTransactedW.Addr(), 7, uintptr(key), uintptr(unsafe.Pointer(p)), 0, uintptr(access), uintptr(unsafe.Pointer(&result)), uintptr(transaction), 0, 0, 0)
+	if r0 != 0 {
+		return 0, syscall.Errno(r0)
+	}
+	return registry.Key(result), nil
+}
+
+func createTransaction(description string) (windows.Handle, error) {
+	p, err := syscall.UTF16PtrFromString(description)
+	if err != nil {
+		return 0, err
+	}
+	r0, _, e1 := syscall.Syscall9(procCreateTransaction.Addr(), 7, 0, 0, 0, 0, 0, 0, uintptr(unsafe.Pointer(p)), 0, 0)
+	if windows.Handle(r0) == windows.InvalidHandle {
+		return 0, e1
+	}
+	return windows.Handle(r0), nil
+}
+
+func commitTransaction(transaction windows.Handle) error {
+	r0, _, e1 := syscall.Syscall(procCommitTransaction.Addr(), 1, uintptr(transaction), 0, 0)
+	if r0 == 0 {
+		return e1
+	}
+	return nil
+}
+
+func rollbackTransaction(transaction windows.Handle) error {
+	r0, _, e1 := syscall.Syscall(procRollbackTransaction.Addr(), 1, uintptr(transaction), 0, 0)
+	if r0 == 0 {
+		return e1
+	}
+	return nil
+}
+
+func regCreateKeyTransacted(key registry.Key, path string, options uint32, access uint32, transaction windows.Handle) (registry.Key, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		result      syscall.Handle
+		disposition uint32
+	)
+	r0, _, _ := syscall.Syscall12(procRegCreateKeyTransacted.Addr(), 11, uintptr(key), uintptr(unsafe.Pointer(p)), 0, 0, uintptr(options), uintptr(access), 0, uintptr(unsafe.Pointer(&result)), uintptr(unsafe.Pointer(&disposition)), uintptr(transaction), 0, 0)
+	if r0 != 0 {
+		return 0, syscall.Errno(r0)
+	}
+	return registry.Key(result), nil
+}
+
+func regCreateKeyEx(key registry.Key, path string, options uint32, access uint32) (registry.Key, uint32, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	var (
+		result      syscall.Handle
+		disposition uint32
+	)
+	r0, _, _ := syscall.Syscall9(procRegCreateKeyExW.Addr(), 9, uintptr(key), uintptr(unsafe.Pointer(p)), 0, 0, uintptr(options), uintptr(access), 0, uintptr(unsafe.Pointer(&result)), uintptr(unsafe.Pointer(&disposition)))
+	if r0 != 0 {
+		return 0, 0, syscall.Errno(r0)
+	}
+	return registry.Key(result), disposition, nil
+}
+
+func regDeleteKeyEx(key registry.Key, path string, access uint32) error {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	r0, _, _ := syscall.Syscall6(procRegDeleteKeyExW.Addr(), 4, uintptr(key), uintptr(unsafe.Pointer(p)), uintptr(access), 0, 0, 0)
+	if r0 != 0 {
+		return syscall.Errno(r0)
+	}
+	return nil
+}
+
+func ntDeleteKey(key registry.Key) error {
+	r0, _, _ := syscall.Syscall(procNtDeleteKey.Addr(), 1, uintptr(key), 0, 0)
+	if r0 != 0 {
+		return windows.NTStatus(r0).Errno()
+	}
+	return nil
+}
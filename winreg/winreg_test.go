@@ -3,10 +3,13 @@
 package winreg
 
 import (
+	"context"
 	"errors"
 	"io"
 	"os"
+	"reflect"
 	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
@@ -332,14 +335,116 @@ func TestFailParseRegistry(t *testing.T) {
 			if err = k.Load(Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey}), nil); err == nil {
 				t.Fatalf("\t%s\tNon-existent key was read.", failed)
 			}
-			if err.Error() != "unable to read registry, HKCU\\SOFTWARE\\"+testKey+": The system cannot find the file specified." {
-				t.Fatalf("\t%s\tInvalid error value, got \"%v\", expect \"%s\".", failed, err, "unable to read registry, HKCU\\SOFTWARE\\"+testKey+": The system cannot find the file specified.")
+			if err.Error() != "unable to read registry, open HKCU\\SOFTWARE\\"+testKey+": ERROR_FILE_NOT_FOUND" {
+				t.Fatalf("\t%s\tInvalid error value, got \"%v\", expect \"%s\".", failed, err, "unable to read registry, open HKCU\\SOFTWARE\\"+testKey+": ERROR_FILE_NOT_FOUND")
 			}
 			t.Logf("\t%s\tReading a non-existent key returned an error.", success)
 		}
 	}
 }
 
+func TestTornRead(t *testing.T) {
+	t.Log("Testing concurrent modification flag.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		testID := 0
+		t.Logf("\tTest %d:\tunmodified key.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey})
+			if _, err := p.Read(); err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if p.Torn() {
+				t.Fatalf("\t%s\tRead of an unmodified key is reported as torn.", failed)
+			}
+			t.Logf("\t%s\tRead is not torn.", success)
+		}
+	}
+}
+
+func TestPerformanceData(t *testing.T) {
+	t.Log("Testing performance data pseudo-keys.")
+	{
+		for testID, key := range []registry.Key{PERFORMANCE_DATA, PERFORMANCE_TEXT, PERFORMANCE_NLSTEXT} {
+			t.Logf("\tTest %d:\t%s.", testID, keyName(key, ""))
+			{
+				p := Provider(Config{Key: key})
+				if _, err := p.Read(); !errors.Is(err, ErrPerformanceData) {
+					t.Fatalf("\t%s\tRead() should fail with ErrPerformanceData, got: %v.", failed, err)
+				}
+				if err := p.Watch(func(event interface{}, err error) {}); !errors.Is(err, ErrPerformanceData) {
+					t.Fatalf("\t%s\tWatch() should fail with ErrPerformanceData, got: %v.", failed, err)
+				}
+				t.Logf("\t%s\tPseudo-key is rejected.", success)
+			}
+		}
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	t.Log("Testing flat read.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		testID := 0
+		t.Logf("\tTest %d:\tvalue paths as keys.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, DefaultValue: "Default", Flatten: true, FlatSeparator: "/"})
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if tree["SubKeyA/StrValue"] != "The quick brown fox jumps over the lazy dog" || tree["SubKeyB/Default"] != "default value" || tree["on"] != uint64(1) {
+				t.Fatalf("\t%s\tTree is not flat, got %v.", failed, tree)
+			}
+			for path, value := range tree {
+				if _, ok := value.(map[string]interface{}); ok {
+					t.Fatalf("\t%s\tUnexpected subtree %s.", failed, path)
+				}
+			}
+			t.Logf("\t%s\tTree is flat.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\twrites of a flat tree.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, Flatten: true})
+			if err := p.Set("SubKeyA\\StrValue", "other"); !errors.Is(err, ErrMappedNames) {
+				t.Fatalf("\t%s\tSet() should fail with ErrMappedNames, got: %v.", failed, err)
+			}
+			if _, err := p.Sync(map[string]interface{}{}); !errors.Is(err, ErrMappedNames) {
+				t.Fatalf("\t%s\tSync() should fail with ErrMappedNames, got: %v.", failed, err)
+			}
+			t.Logf("\t%s\tWrites are refused.", success)
+		}
+	}
+}
+
+func TestNoExpand(t *testing.T) {
+	t.Log("Testing unexpanded strings.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		testID := 0
+		t.Logf("\tTest %d:\tSubKeyA.Expand.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey + "\\SubKeyA", NoExpand: true})
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if tree["Expand"] != "Some %PATH%" {
+				t.Fatalf("\t%s\tSubKeyA.Expand is invalid, got \"%v\", expect \"Some %%PATH%%\".", failed, tree["Expand"])
+			}
+			t.Logf("\t%s\tSubKeyA.Expand is not expanded.", success)
+		}
+	}
+}
+
 func TestWatch(t *testing.T) {
 	t.Log("Testing provider's Watch method.")
 	{
@@ -660,3 +765,212 @@ func deleteTestData(t *testing.T) {
 		deleteSubKey(t, k, testKey)
 	}
 }
+
+func TestSentinelErrors(t *testing.T) {
+	t.Log("Testing sentinel errors.")
+	{
+		m := NewMemory()
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\Missing", Memory: m})
+
+		testID := 0
+		t.Logf("\tTest %d:\tmissing key.", testID)
+		{
+			_, err := p.Read()
+			if !errors.Is(err, ErrKeyNotFound) {
+				t.Fatalf("\t%s\tRead() should fail with ErrKeyNotFound, got: %v.", failed, err)
+			}
+			var errno syscall.Errno
+			if !errors.As(err, &errno) || errno != 2 {
+				t.Fatalf("\t%s\tWin32 error code is not wrapped, got: %v.", failed, err)
+			}
+			t.Logf("\t%s\tMissing key is reported.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tunsupported type.", testID)
+		{
+			if err := p.Set("Float", 1.5); !errors.Is(err, ErrUnsupportedType) {
+				t.Fatalf("\t%s\tSet() should fail with ErrUnsupportedType, got: %v.", failed, err)
+			}
+			t.Logf("\t%s\tUnsupported type is reported.", success)
+		}
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	t.Log("Testing validation of Config.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tinvalid fields.", testID)
+		{
+			for _, cfg := range []Config{{Mode: 3}, {Backend: -1}, {Transport: 5}, {BinaryFormat: 7}, {Links: 9}} {
+				if _, err := NewProvider(cfg); !errors.Is(err, ErrInvalidConfig) {
+					t.Fatalf("\t%s\tNewProvider(%+v) should fail with ErrInvalidConfig, got: %v.", failed, cfg, err)
+				}
+			}
+			t.Logf("\t%s\tInvalid fields are reported.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tvalid config.", testID)
+		{
+			p, err := NewProvider(Config{Key: CURRENT_USER, Mode: Reg64Bit, Memory: NewMemory(), Backend: -1})
+			if err != nil || p == nil {
+				t.Fatalf("\t%s\tUnable to create provider: %v.", failed, err)
+			}
+			t.Logf("\t%s\tProvider is created.", success)
+		}
+	}
+}
+
+// cancelAPI serves an in-memory registry, canceling a read when a subkey
+// of path is opened.
+type cancelAPI struct {
+	*Memory
+	path   string
+	cancel context.CancelFunc
+}
+
+func (a cancelAPI) OpenKey(k registry.Key, path string, access uint32) (registry.Key, error) {
+	if path != a.path {
+		a.cancel()
+	}
+	return a.Memory.OpenKey(k, path, access)
+}
+
+func TestReadContext(t *testing.T) {
+	t.Log("Testing canceled reads.")
+	{
+		m := NewMemory()
+		err := m.Load(CURRENT_USER, "SOFTWARE\\Vendor", map[string]interface{}{
+			"A": map[string]interface{}{"Name": "a"},
+			"B": map[string]interface{}{"Name": "b"},
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to load memory registry: %v", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tcontext done before the read.", testID)
+		{
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\Vendor", Memory: m})
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if _, err := p.ReadContext(ctx); !errors.Is(err, context.Canceled) {
+				t.Fatalf("\t%s\tReadContext() should fail with context.Canceled, got: %v.", failed, err)
+			}
+			t.Logf("\t%s\tRead is not started.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tcontext done during the read.", testID)
+		{
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\Vendor"})
+			p.api = cancelAPI{Memory: m, path: "SOFTWARE\\Vendor", cancel: cancel}
+			if _, err := p.ReadContext(ctx); !errors.Is(err, context.Canceled) {
+				t.Fatalf("\t%s\tReadContext() should fail with context.Canceled, got: %v.", failed, err)
+			}
+			if _, err := p.Read(); err != nil {
+				t.Fatalf("\t%s\tRead() should ignore the done context, got: %v.", failed, err)
+			}
+			t.Logf("\t%s\tTraversal is aborted.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tread during a canceled read.", testID)
+		{
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\Vendor"})
+			var nested error
+			started := false
+			p.api = cancelAPI{Memory: m, path: "SOFTWARE\\Vendor", cancel: func() {
+				cancel()
+				if !started {
+					started = true
+					_, nested = p.Read()
+				}
+			}}
+			if _, err := p.ReadContext(ctx); !errors.Is(err, context.Canceled) {
+				t.Fatalf("\t%s\tReadContext() should fail with context.Canceled, got: %v.", failed, err)
+			}
+			if nested != nil {
+				t.Fatalf("\t%s\tRead() should ignore the context of another read, got: %v.", failed, nested)
+			}
+			t.Logf("\t%s\tReads have their own contexts.", success)
+		}
+	}
+}
+
+// countAPI serves an in-memory registry, counting the calls reading values.
+type countAPI struct {
+	*Memory
+	calls *int
+}
+
+func (a countAPI) GetValue(k registry.Key, name string, buf []byte) (int, uint32, error) {
+	*a.calls++
+	return a.Memory.GetValue(k, name, buf)
+}
+
+func (a countAPI) GetStringValue(k registry.Key, name string) (string, uint32, error) {
+	*a.calls++
+	return a.Memory.GetStringValue(k, name)
+}
+
+func (a countAPI) GetStringsValue(k registry.Key, name string) ([]string, uint32, error) {
+	*a.calls++
+	return a.Memory.GetStringsValue(k, name)
+}
+
+func (a countAPI) GetIntegerValue(k registry.Key, name string) (uint64, uint32, error) {
+	*a.calls++
+	return a.Memory.GetIntegerValue(k, name)
+}
+
+func (a countAPI) GetBinaryValue(k registry.Key, name string) ([]byte, uint32, error) {
+	*a.calls++
+	return a.Memory.GetBinaryValue(k, name)
+}
+
+func TestValueCalls(t *testing.T) {
+	t.Log("Testing calls reading values.")
+	{
+		m := NewMemory()
+		blob := make([]byte, valueBufferSize+1)
+		blob[valueBufferSize] = 1
+		err := m.Load(CURRENT_USER, "SOFTWARE\\Vendor", map[string]interface{}{
+			"Name":  "vendor",
+			"List":  []string{"a", "", "b"},
+			"Count": uint32(42),
+			"Blob":  blob,
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tUnable to load memory registry: %v", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\ttype and data in one call.", testID)
+		{
+			calls := 0
+			p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\Vendor"})
+			p.api = countAPI{Memory: m, calls: &calls}
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			expected := map[string]interface{}{"Name": "vendor", "List": []string{"a", "", "b"}, "Count": uint64(42), "Blob": blob}
+			if !reflect.DeepEqual(tree, expected) {
+				t.Fatalf("\t%s\tTree is invalid, got %v.", failed, tree)
+			}
+			// A single call per value, the blob larger than the buffer
+			// is read again at its size
+			if calls != 5 {
+				t.Fatalf("\t%s\tValues should be read in 5 calls, got %d.", failed, calls)
+			}
+			t.Logf("\t%s\tValues are read in a single call.", success)
+		}
+	}
+}
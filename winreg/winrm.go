@@ -0,0 +1,447 @@
+//go:build windows
+
+package winreg
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf16"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// Transports of remote registry reads, see Config.Transport.
+const (
+	TransportRemoteRegistry = iota // Remote Registry service
+	TransportWinRM                 // PowerShell remoting over WinRM
+	TransportAuto                  // Remote Registry service, WinRM if the host can't be connected
+)
+
+// ErrWinRMRead is returned for operations which are not available when
+// the registry is read over WinRM, like writes and notifications.
+var ErrWinRMRead = errors.New("operation is not supported over WinRM")
+
+// winrmScript reads a key on the remote host and writes it as JSON, values
+// converted to strings which survive JSON: integers as decimal strings,
+// binary data as base64. Values of other types are left out.
+const winrmScript = `param($Hive, $Path, $View, $Depth)
+$ErrorActionPreference = 'Stop'
+function Read-Key($k, $level) {
+	$values = @(foreach ($n in $k.GetValueNames()) {
+		$kind = [string]$k.GetValueKind($n)
+		$d = $k.GetValue($n, $null, 'DoNotExpandEnvironmentNames')
+		if ($kind -eq 'DWord') { $d = ([long]$d -band 0xFFFFFFFFL).ToString() }
+		elseif ($kind -eq 'QWord') { $d = [BitConverter]::ToUInt64([BitConverter]::GetBytes([long]$d), 0).ToString() }
+		elseif ($kind -eq 'Binary') { $d = [Convert]::ToBase64String([byte[]]$d) }
+		elseif ($kind -eq 'MultiString') { $d = [string[]]$d }
+		elseif ($kind -ne 'String' -and $kind -ne 'ExpandString') { continue }
+		@{n = $n; k = $kind; d = $d}
+	})
+	$subs = @()
+	if ($Depth -eq 0 -or $level -lt $Depth) {
+		$subs = @(foreach ($n in $k.GetSubKeyNames()) {
+			$s = $k.OpenSubKey($n)
+			@{n = $n; k = (Read-Key $s ($level + 1))}
+			$s.Close()
+		})
+	}
+	@{v = $values; s = $subs}
+}
+$k = [Microsoft.Win32.RegistryKey]::OpenBaseKey($Hive, $View)
+if ($Path) { $k = $k.OpenSubKey($Path) }
+if ($k) { Read-Key $k 1 | ConvertTo-Json -Depth 100 -Compress } else { '{"missing":true}' }
+`
+
+// winrmHives are the RegistryHive names of the predefined keys.
+var winrmHives = map[registry.Key]string{
+	CLASSES_ROOT:   "ClassesRoot",
+	CURRENT_USER:   "CurrentUser",
+	LOCAL_MACHINE:  "LocalMachine",
+	USERS:          "Users",
+	CURRENT_CONFIG: "CurrentConfig",
+}
+
+// winrmKey is a key as written by winrmScript.
+type winrmKey struct {
+	Missing bool `json:"missing"`
+	Values  []struct {
+		Name string          `json:"n"`
+		Kind string          `json:"k"`
+		Data json.RawMessage `json:"d"`
+	} `json:"v"`
+	SubKeys []struct {
+		Name string   `json:"n"`
+		Key  winrmKey `json:"k"`
+	} `json:"s"`
+}
+
+// readRemote reads the provider's key on a remote host over the configured
+// transport.
+func (s *WinReg) readRemote(ctx context.Context) (map[string]interface{}, bool, error) {
+	if s.transport == TransportAuto {
+		if root, err := s.connect(); err == nil {
+			s.api.CloseKey(root)
+			return s.readTree(ctx)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, false, s.keyError("open", s.path, "", err)
+	}
+	api, err := s.fetchWinRM(ctx)
+	if err != nil {
+		return nil, false, s.keyError("open", s.path, "", err)
+	}
+	// The tree is processed as read by the Remote Registry service
+	p := *s
+	p.api = api
+
+	return p.readTree(ctx)
+}
+
+// fetchWinRM reads the provider's key on the remote host by a PowerShell
+// script run over WinRM, returning a registry API serving it.
+func (s *WinReg) fetchWinRM(ctx context.Context) (*memAPI, error) {
+	hive, ok := winrmHives[s.key]
+	if !ok {
+		return nil, fmt.Errorf("key %s can't be read over WinRM", keyName(s.key, ""))
+	}
+	view := "Default"
+	switch s.access & (registry.WOW64_32KEY | registry.WOW64_64KEY) {
+	case registry.WOW64_32KEY:
+		view = "Registry32"
+	case registry.WOW64_64KEY:
+		view = "Registry64"
+	}
+
+	s.limiter.Wait()
+	out, err := runPowerShellContext(ctx, fmt.Sprintf("Invoke-Command -ComputerName %s -ArgumentList %s, %s, %s, %d -ScriptBlock {%s}",
+		psQuote(s.host), psQuote(hive), psQuote(strings.Trim(s.path, "\\")), psQuote(view), s.maxDepth, winrmScript))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read over WinRM: %w", err)
+	}
+
+	var key winrmKey
+	if err = json.Unmarshal([]byte(out), &key); err != nil {
+		return nil, fmt.Errorf("unable to read over WinRM: %w", err)
+	}
+
+	return newMemAPI(s.key, s.path, key)
+}
+
+// memKey is a key of memAPI and Memory.
+type memKey struct {
+	values  map[string]memValue // By upper-cased names
+	names   []string
+	subKeys map[string]*memKey // By upper-cased names
+	subs    []string
+	parent  *memKey
+	deleted bool
+}
+
+type memValue struct {
+	typ  uint32
+	data []byte
+}
+
+// memAPI is a read-only registry held in memory, serving a tree fetched
+// as a whole, e.g. over WinRM. Only the predefined key the tree was read
+// from is available.
+type memAPI struct {
+	sysAPI
+	key  registry.Key
+	root *memKey
+
+	mu      sync.Mutex
+	handles map[registry.Key]*memKey
+	next    registry.Key
+}
+
+// newMemAPI builds the hive of the predefined key, where key is found at
+// path.
+func newMemAPI(predefined registry.Key, path string, key winrmKey) (*memAPI, error) {
+	retval := &memAPI{key: predefined, root: newMemKey(), handles: make(map[registry.Key]*memKey), next: 1}
+
+	parent := retval.root
+	parentPath, last := splitParent(path)
+	for _, name := range strings.Split(parentPath, "\\") {
+		if name != "" {
+			parent = parent.addSubKey(name, newMemKey())
+		}
+	}
+	if key.Missing {
+		return retval, nil
+	}
+
+	k, err := convertWinRMKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if last == "" {
+		retval.root = k
+	} else {
+		parent.addSubKey(last, k)
+	}
+
+	return retval, nil
+}
+
+func newMemKey() *memKey {
+	return &memKey{values: make(map[string]memValue), subKeys: make(map[string]*memKey)}
+}
+
+func (k *memKey) addSubKey(name string, sub *memKey) *memKey {
+	if prev, ok := k.subKeys[strings.ToUpper(name)]; ok {
+		return prev
+	}
+	k.subKeys[strings.ToUpper(name)] = sub
+	k.subs = append(k.subs, name)
+	sub.parent = k
+
+	return sub
+}
+
+func (k *memKey) removeSubKey(name string) {
+	delete(k.subKeys, strings.ToUpper(name))
+	for i, sub := range k.subs {
+		if strings.EqualFold(sub, name) {
+			k.subs = append(k.subs[:i], k.subs[i+1:]...)
+			break
+		}
+	}
+}
+
+func (k *memKey) setValue(name string, v memValue) {
+	if _, ok := k.values[strings.ToUpper(name)]; !ok {
+		k.names = append(k.names, name)
+	}
+	k.values[strings.ToUpper(name)] = v
+}
+
+// deleteValue deletes the value name, reporting whether it existed.
+func (k *memKey) deleteValue(name string) bool {
+	if _, ok := k.values[strings.ToUpper(name)]; !ok {
+		return false
+	}
+	delete(k.values, strings.ToUpper(name))
+	for i, item := range k.names {
+		if strings.EqualFold(item, name) {
+			k.names = append(k.names[:i], k.names[i+1:]...)
+			break
+		}
+	}
+
+	return true
+}
+
+// convertWinRMKey converts the values of key to their stored form.
+func convertWinRMKey(key winrmKey) (*memKey, error) {
+	retval := newMemKey()
+	for _, v := range key.Values {
+		var (
+			str  string
+			strs []string
+			val  memValue
+			err  error
+		)
+		switch v.Kind {
+		case "String", "ExpandString":
+			if err = json.Unmarshal(v.Data, &str); err == nil {
+				val.typ = registry.SZ
+				if v.Kind == "ExpandString" {
+					val.typ = registry.EXPAND_SZ
+				}
+				val.data = utf16Bytes(utf16.Encode(append([]rune(str), 0)))
+			}
+		case "MultiString":
+			if err = json.Unmarshal(v.Data, &strs); err == nil {
+				val.typ, val.data, err = encodeValue(strs)
+			}
+		case "DWord", "QWord":
+			var n uint64
+			if err = json.Unmarshal(v.Data, &str); err == nil {
+				n, err = strconv.ParseUint(str, 10, 64)
+			}
+			if v.Kind == "DWord" {
+				val.typ, val.data = registry.DWORD, dwordBytes(uint32(n))
+			} else {
+				val.typ, val.data = registry.QWORD, qwordBytes(n)
+			}
+		case "Binary":
+			if err = json.Unmarshal(v.Data, &str); err == nil {
+				val.typ = registry.BINARY
+				val.data, err = base64.StdEncoding.DecodeString(str)
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %s: %w", v.Name, err)
+		}
+		retval.setValue(v.Name, val)
+	}
+
+	for _, sub := range key.SubKeys {
+		k, err := convertWinRMKey(sub.Key)
+		if err != nil {
+			return nil, fmt.Errorf("%s\\%w", sub.Name, err)
+		}
+		retval.addSubKey(sub.Name, k)
+	}
+
+	return retval, nil
+}
+
+func (a *memAPI) lookup(k registry.Key) (*memKey, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if k == a.key {
+		return a.root, nil
+	}
+	if key, ok := a.handles[k]; ok {
+		return key, nil
+	}
+
+	return nil, windows.ERROR_INVALID_HANDLE
+}
+
+func (a *memAPI) OpenKey(k registry.Key, path string, access uint32) (registry.Key, error) {
+	key, err := a.lookup(k)
+	if err != nil {
+		return 0, err
+	}
+	for _, name := range strings.Split(strings.Trim(path, "\\"), "\\") {
+		if name == "" {
+			continue
+		}
+		if key = key.subKeys[strings.ToUpper(name)]; key == nil {
+			return 0, windows.ERROR_FILE_NOT_FOUND
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// Above predefined keys, which start at 0x80000000
+	retval := a.next
+	a.next++
+	a.handles[retval] = key
+
+	return retval, nil
+}
+
+func (a *memAPI) OpenRemoteKey(host string, k registry.Key) (registry.Key, error) {
+	if k != a.key {
+		return 0, fmt.Errorf("%s: %w", keyName(k, ""), ErrWinRMRead)
+	}
+	return k, nil
+}
+
+func (a *memAPI) CloseKey(k registry.Key) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.handles, k)
+
+	return nil
+}
+
+func (a *memAPI) ReadValueNames(k registry.Key) ([]string, error) {
+	key, err := a.lookup(k)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), key.names...), nil
+}
+
+func (a *memAPI) ReadSubKeyNames(k registry.Key) ([]string, error) {
+	key, err := a.lookup(k)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), key.subs...), nil
+}
+
+func (a *memAPI) GetValue(k registry.Key, name string, buf []byte) (int, uint32, error) {
+	key, err := a.lookup(k)
+	if err != nil {
+		return 0, 0, err
+	}
+	v, ok := key.values[strings.ToUpper(name)]
+	if !ok {
+		return 0, 0, windows.ERROR_FILE_NOT_FOUND
+	}
+	if len(buf) < len(v.data) {
+		return len(v.data), v.typ, registry.ErrShortBuffer
+	}
+
+	return copy(buf, v.data), v.typ, nil
+}
+
+func (a *memAPI) GetStringValue(k registry.Key, name string) (string, uint32, error) {
+	return storedString(a.GetValue, k, name)
+}
+
+func (a *memAPI) GetStringsValue(k registry.Key, name string) ([]string, uint32, error) {
+	return storedStrings(a.GetValue, k, name)
+}
+
+func (a *memAPI) GetIntegerValue(k registry.Key, name string) (uint64, uint32, error) {
+	return storedInteger(a.GetValue, k, name)
+}
+
+func (a *memAPI) GetBinaryValue(k registry.Key, name string) ([]byte, uint32, error) {
+	return storedData(a.GetValue, k, name, registry.BINARY)
+}
+
+func (a *memAPI) GetMUIStringValue(k registry.Key, name string) (string, error) {
+	return "", ErrWinRMRead
+}
+
+func (a *memAPI) GetKeyClass(k registry.Key) (string, error) {
+	// Not available from .NET, reported as no class
+	return "", nil
+}
+
+func (a *memAPI) NotifyChangeKeyValue(k registry.Key, watchSubtree bool, filter uint32, event windows.Handle) error {
+	return ErrWinRMRead
+}
+
+func (a *memAPI) CreateKey(k registry.Key, path string, options, access uint32) (registry.Key, error) {
+	return 0, ErrWinRMRead
+}
+
+func (a *memAPI) SetValue(k registry.Key, name string, valtype uint32, data []byte) error {
+	return ErrWinRMRead
+}
+
+func (a *memAPI) DeleteValue(k registry.Key, name string) error {
+	return ErrWinRMRead
+}
+
+func (a *memAPI) DeleteKey(k registry.Key, path string, access uint32) error {
+	return ErrWinRMRead
+}
+
+func (a *memAPI) FlushKey(k registry.Key) error {
+	return ErrWinRMRead
+}
+
+func (a *memAPI) CreateLinkKey(k registry.Key, path string, access uint32) (registry.Key, error) {
+	return 0, ErrWinRMRead
+}
+
+func (a *memAPI) DeleteLinkKey(k registry.Key, path string) error {
+	return ErrWinRMRead
+}
+
+func (a *memAPI) LinkTarget(k registry.Key, path string, access uint32) (string, bool, error) {
+	// Links are followed by the remote side
+	return "", false, nil
+}
@@ -0,0 +1,70 @@
+//go:build windows
+
+package winreg
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// winrmOutput is what winrmScript writes for a small key.
+const winrmOutput = `{"v":[{"n":"Name","k":"String","d":"server"},{"n":"Path","k":"ExpandString","d":"%WINDIR%"},
+{"n":"Count","k":"DWord","d":"4294967295"},{"n":"Size","k":"QWord","d":"18446744073709551615"},
+{"n":"List","k":"MultiString","d":["a","b"]},{"n":"Data","k":"Binary","d":"AQID"}],
+"s":[{"n":"Sub","k":{"v":[{"n":"Level","k":"DWord","d":"2"}],"s":[]}}]}`
+
+func TestWinRMRead(t *testing.T) {
+	t.Log("Testing reads of trees fetched over WinRM.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tconverted tree.", testID)
+		{
+			var key winrmKey
+			if err := json.Unmarshal([]byte(winrmOutput), &key); err != nil {
+				t.Fatalf("\t%s\tUnable to parse output: %v.", failed, err)
+			}
+			api, err := newMemAPI(LOCAL_MACHINE, "SOFTWARE\\Vendor", key)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to convert output: %v.", failed, err)
+			}
+
+			p := Provider(Config{Key: LOCAL_MACHINE, Path: "SOFTWARE\\Vendor", Host: "server"})
+			p.api = api
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if tree["Name"] != "server" || tree["Count"] != uint64(0xFFFFFFFF) || tree["Size"] != uint64(0xFFFFFFFFFFFFFFFF) {
+				t.Fatalf("\t%s\tValues are invalid, got %v.", failed, tree)
+			}
+			if !reflect.DeepEqual(tree["List"], []string{"a", "b"}) || !reflect.DeepEqual(tree["Data"], []byte{1, 2, 3}) {
+				t.Fatalf("\t%s\tValues are invalid, got %v.", failed, tree)
+			}
+			if sub, ok := tree["Sub"].(map[string]interface{}); !ok || sub["Level"] != uint64(2) {
+				t.Fatalf("\t%s\tSub is invalid, got %v.", failed, tree["Sub"])
+			}
+			t.Logf("\t%s\tTree is read.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tmissing key.", testID)
+		{
+			api, err := newMemAPI(LOCAL_MACHINE, "SOFTWARE\\Vendor", winrmKey{Missing: true})
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to convert output: %v.", failed, err)
+			}
+
+			p := Provider(Config{Key: LOCAL_MACHINE, Path: "SOFTWARE\\Vendor", Host: "server", Defaults: map[string]interface{}{"Name": "default"}})
+			p.api = api
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			if tree["Name"] != "default" {
+				t.Fatalf("\t%s\tDefaults are not used, got %v.", failed, tree)
+			}
+			t.Logf("\t%s\tMissing key is reported as missing.", success)
+		}
+	}
+}
@@ -0,0 +1,444 @@
+//go:build windows
+
+package winreg
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// Write is a single modification of a registry value.
+type Write struct {
+	Path  string      // Key path relative to the provider's path, e.g. "SubKey"
+	Name  string      // Value name, empty for the default value of the key
+	Value interface{} // New data, nil deletes the value
+}
+
+// rawValue is registry value data in its stored form.
+type rawValue struct {
+	Path   string
+	Name   string
+	Exists bool // If false the value is deleted
+	Type   uint32
+	Data   []byte
+}
+
+// Apply writes values in the given order, creating missing keys.
+func (s *WinReg) Apply(writes []Write) error {
+	raw, err := s.encodeWrites(writes)
+	if err != nil {
+		return err
+	}
+
+	return s.applyRaw(raw)
+}
+
+// Set writes a single value at the koanf path, e.g. "Window.Width",
+// creating missing keys. The last path component is the value name,
+// the name of Config.DefaultValue stands for the default key value.
+// With NameMapper, DelimEscape or Flatten, ErrMappedNames is returned.
+func (s *WinReg) Set(path string, value interface{}) error {
+	if value == nil {
+		return fmt.Errorf("%s: nil value, use Delete()", path)
+	}
+	if err := s.namesWritable(); err != nil {
+		return err
+	}
+
+	return s.Apply([]Write{s.pathWrite(path, value)})
+}
+
+// Delete removes the value at the koanf path. Missing values are ignored.
+// With NameMapper, DelimEscape or Flatten, ErrMappedNames is returned.
+func (s *WinReg) Delete(path string) error {
+	if err := s.namesWritable(); err != nil {
+		return err
+	}
+
+	return s.Apply([]Write{s.pathWrite(path, nil)})
+}
+
+// pathWrite converts a koanf path into a write of value.
+func (s *WinReg) pathWrite(path string, value interface{}) Write {
+	names := strings.Split(path, s.delimiter)
+	retval := Write{
+		Path:  strings.Join(names[:len(names)-1], "\\"),
+		Name:  names[len(names)-1],
+		Value: value,
+	}
+	if s.defaultValue != "" && retval.Name == s.defaultValue {
+		retval.Name = ""
+	}
+
+	return retval
+}
+
+// TypeRules control which registry types Go types are stored as by writes,
+// so they match the types already used by an application.
+type TypeRules struct {
+	ExpandVars bool              // Store strings containing %VAR% references as REG_EXPAND_SZ
+	IntAsDWORD bool              // Store int, int64 and uint64 values which fit in 32 bits as REG_DWORD
+	Paths      map[string]uint32 // Registry types by value path, e.g. "SubKey\Value", overriding the above
+}
+
+func (s *WinReg) encodeWrites(writes []Write) ([]rawValue, error) {
+	if err := s.singleKey(); err != nil {
+		return nil, err
+	}
+	// Existing values keep their types
+	root, connErr := s.connect()
+	if connErr == nil && root != s.key {
+		defer s.api.CloseKey(root)
+	}
+
+	retval := make([]rawValue, 0, len(writes))
+	for _, w := range writes {
+		v := rawValue{Path: w.Path, Name: w.Name}
+		if w.Value != nil {
+			var (
+				stored uint32
+				exists bool
+			)
+			if connErr == nil {
+				stored, exists = s.storedType(root, w.Path, w.Name)
+			}
+			var err error
+			if v.Type, v.Data, err = s.encodeWrite(w, stored, exists); err != nil {
+				return nil, fmt.Errorf("%s: %w", joinPath(w.Path, w.Name), err)
+			}
+			v.Exists = true
+		}
+		retval = append(retval, v)
+	}
+
+	return retval, nil
+}
+
+// encodeWrite converts the data of w as selected by the provider's type
+// rules. If the value exists with the type stored, it keeps the type when
+// the data fits, e.g. a REG_DWORD written with uint64 read by koanf.
+func (s *WinReg) encodeWrite(w Write, stored uint32, exists bool) (uint32, []byte, error) {
+	if typ, ok := s.typeRules.Paths[strings.ToUpper(joinPath(strings.Trim(w.Path, "\\"), w.Name))]; ok {
+		return encodeValueAs(typ, w.Value)
+	}
+	if exists && keepsType(stored, w.Value) {
+		return encodeValueAs(stored, w.Value)
+	}
+
+	typ, data, err := encodeValue(w.Value)
+	if err != nil {
+		return 0, nil, err
+	}
+	switch {
+	case typ == registry.SZ && s.typeRules.ExpandVars && hasEnvRefs(w.Value.(string)):
+		typ = registry.EXPAND_SZ
+	case typ == registry.QWORD && s.typeRules.IntAsDWORD:
+		if n := binary.LittleEndian.Uint64(data); n <= math.MaxUint32 {
+			if _, isUint64 := w.Value.(uint64); isUint64 || int64(n) >= 0 {
+				typ, data = registry.DWORD, dwordBytes(uint32(n))
+			}
+		}
+	}
+
+	return typ, data, nil
+}
+
+// keepsType reports whether value is written as the type stored of the
+// existing value: integers as REG_DWORD or REG_QWORD, strings as REG_SZ or
+// REG_EXPAND_SZ. Other values are written as their Go type selects.
+func keepsType(stored uint32, value interface{}) bool {
+	switch value.(type) {
+	case bool, uint32, uint64, int64, int, float64:
+		return stored == registry.DWORD || stored == registry.QWORD
+	case string:
+		return stored == registry.SZ || stored == registry.EXPAND_SZ
+	default:
+		return false
+	}
+}
+
+// storedType returns the type of the existing value name of the key path
+// relative to the provider's key.
+func (s *WinReg) storedType(root registry.Key, path, name string) (uint32, bool) {
+	s.limiter.Wait()
+	k, err := s.api.OpenKey(root, joinPath(s.path, path), s.getAccess(registry.QUERY_VALUE))
+	if err != nil {
+		return 0, false
+	}
+	defer s.api.CloseKey(k)
+
+	s.limiter.Wait()
+	_, typ, err := s.api.GetValue(k, name, nil)
+	if err != nil && !errors.Is(err, registry.ErrShortBuffer) {
+		return 0, false
+	}
+
+	return typ, true
+}
+
+// hasEnvRefs reports whether str contains a %VAR% reference.
+func hasEnvRefs(str string) bool {
+	if i := strings.IndexByte(str, '%'); i >= 0 {
+		if j := strings.IndexByte(str[i+1:], '%'); j > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeValueAs converts Go data into the stored form of the registry
+// type typ. Integers are range checked, strings are accepted for both
+// string types.
+func encodeValueAs(typ uint32, value interface{}) (uint32, []byte, error) {
+	switch typ {
+	case registry.SZ, registry.EXPAND_SZ:
+		if str, ok := value.(string); ok {
+			return typ, utf16Bytes(utf16.Encode(append([]rune(str), 0))), nil
+		}
+	case registry.MULTI_SZ:
+		if _, ok := value.([]string); ok {
+			return encodeValue(value)
+		}
+	case registry.DWORD, registry.QWORD:
+		var (
+			n   uint64
+			err error
+		)
+		switch v := value.(type) {
+		case bool:
+			if v {
+				n = 1
+			}
+		case uint32:
+			n = uint64(v)
+		case uint64:
+			n = v
+		case int64:
+			if n, err = signedBits(typ, v); err != nil {
+				return 0, nil, err
+			}
+		case int:
+			if n, err = signedBits(typ, int64(v)); err != nil {
+				return 0, nil, err
+			}
+		case float64:
+			// Numbers decoded from JSON or YAML
+			switch {
+			case v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxUint64:
+				return 0, nil, fmt.Errorf("%v doesn't fit into %s", v, valueTypeName(typ))
+			case v < 0:
+				if n, err = signedBits(typ, int64(v)); err != nil {
+					return 0, nil, err
+				}
+			default:
+				n = uint64(v)
+			}
+		default:
+			return 0, nil, fmt.Errorf("unable to store %T as %s", value, valueTypeName(typ))
+		}
+		if typ == registry.QWORD {
+			return typ, qwordBytes(n), nil
+		}
+		if n > math.MaxUint32 {
+			return 0, nil, fmt.Errorf("%d doesn't fit into %s", n, valueTypeName(typ))
+		}
+		return typ, dwordBytes(uint32(n)), nil
+	case registry.BINARY, registry.NONE, registry.RESOURCE_LIST, registry.FULL_RESOURCE_DESCRIPTOR, registry.RESOURCE_REQUIREMENTS_LIST:
+		if data, ok := value.([]byte); ok {
+			return typ, data, nil
+		}
+	default:
+		return 0, nil, fmt.Errorf("%w %s", ErrUnsupportedType, valueTypeName(typ))
+	}
+
+	return 0, nil, fmt.Errorf("unable to store %T as %s", value, valueTypeName(typ))
+}
+
+// signedBits returns the two's complement of v stored as typ. Negative
+// values are stored in REG_DWORD as int32, as read back by DecodeInt32().
+func signedBits(typ uint32, v int64) (uint64, error) {
+	if typ == registry.DWORD && v < 0 {
+		if v < math.MinInt32 {
+			return 0, fmt.Errorf("%d doesn't fit into %s", v, valueTypeName(typ))
+		}
+		return uint64(uint32(int32(v))), nil
+	}
+
+	return uint64(v), nil
+}
+
+// encodeValue converts Go data into a registry type and its stored form:
+// string to REG_SZ, []string to REG_MULTI_SZ, uint32 and bool to
+// REG_DWORD, uint64, int64, int and integral float64 to REG_QWORD, []byte
+// to REG_BINARY.
+func encodeValue(value interface{}) (uint32, []byte, error) {
+	switch v := value.(type) {
+	case string:
+		return registry.SZ, utf16Bytes(utf16.Encode(append([]rune(v), 0))), nil
+	case []string:
+		var buf []uint16
+		for _, str := range v {
+			buf = append(append(buf, utf16.Encode([]rune(str))...), 0)
+		}
+		return registry.MULTI_SZ, utf16Bytes(append(buf, 0)), nil
+	case uint32:
+		return registry.DWORD, dwordBytes(v), nil
+	case bool:
+		var dw uint32
+		if v {
+			dw = 1
+		}
+		return registry.DWORD, dwordBytes(dw), nil
+	case uint64:
+		return registry.QWORD, qwordBytes(v), nil
+	case int64:
+		return registry.QWORD, qwordBytes(uint64(v)), nil
+	case int:
+		return registry.QWORD, qwordBytes(uint64(v)), nil
+	case float64:
+		return encodeValueAs(registry.QWORD, v)
+	case []byte:
+		return registry.BINARY, v, nil
+	default:
+		return 0, nil, fmt.Errorf("%w %T", ErrUnsupportedType, value)
+	}
+}
+
+func utf16Bytes(s []uint16) []byte {
+	retval := make([]byte, len(s)*2)
+	for i, c := range s {
+		binary.LittleEndian.PutUint16(retval[i*2:], c)
+	}
+
+	return retval
+}
+
+func dwordBytes(v uint32) []byte {
+	retval := make([]byte, 4)
+	binary.LittleEndian.PutUint32(retval, v)
+
+	return retval
+}
+
+func qwordBytes(v uint64) []byte {
+	retval := make([]byte, 8)
+	binary.LittleEndian.PutUint64(retval, v)
+
+	return retval
+}
+
+// applyRaw writes values in their stored form.
+func (s *WinReg) applyRaw(values []rawValue) error {
+	root, err := s.connect()
+	if err != nil {
+		return err
+	}
+	if root != s.key {
+		defer s.api.CloseKey(root)
+	}
+
+	for _, v := range values {
+		if err := s.writeRaw(root, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *WinReg) writeRaw(root registry.Key, v rawValue) error {
+	path := joinPath(s.path, v.Path)
+
+	s.limiter.Wait()
+	var (
+		k   registry.Key
+		err error
+		op  = "create"
+	)
+	if v.Exists {
+		k, err = s.api.CreateKey(root, path, s.keyOptions, s.getAccess(registry.SET_VALUE))
+	} else {
+		op = "open"
+		k, err = s.api.OpenKey(root, path, s.getAccess(registry.SET_VALUE))
+		if errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+			// Nothing to delete
+			return nil
+		}
+	}
+	if err != nil {
+		return s.keyError(op, path, "", err)
+	}
+	defer s.api.CloseKey(k)
+
+	s.limiter.Wait()
+	op = "delete"
+	if v.Exists {
+		op = "write"
+		err = s.api.SetValue(k, v.Name, v.Type, v.Data)
+	} else if err = s.api.DeleteValue(k, v.Name); errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+		err = nil
+	}
+	if err != nil {
+		return s.keyError(op, path, v.Name, err)
+	}
+
+	return nil
+}
+
+// readRaw reads the current stored form of the value name of the key path.
+func (s *WinReg) readRaw(root registry.Key, path, name string) (rawValue, error) {
+	retval := rawValue{Path: path, Name: name}
+	full := joinPath(s.path, path)
+
+	s.limiter.Wait()
+	k, err := s.api.OpenKey(root, full, s.getAccess(registry.QUERY_VALUE))
+	if err != nil {
+		if errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+			return retval, nil
+		}
+		return retval, s.keyError("open", full, "", err)
+	}
+	defer s.api.CloseKey(k)
+
+	retval.Data, retval.Type, err = s.getValueBytes(k, name)
+	if errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+		return retval, nil
+	}
+	if err != nil {
+		return retval, s.keyError("read", full, name, err)
+	}
+	retval.Exists = true
+
+	return retval, nil
+}
+
+// getValueBytes returns the stored form of the value name of the key k.
+func (s *WinReg) getValueBytes(k registry.Key, name string) ([]byte, uint32, error) {
+	return s.getValueSized(k, name, 0)
+}
+
+// getValueSized returns the stored form of the value name of the key k
+// whose size is known to be about size, in a single call unless the value
+// has grown since.
+func (s *WinReg) getValueSized(k registry.Key, name string, size int) ([]byte, uint32, error) {
+	buf := make([]byte, size)
+	for {
+		s.limiter.Wait()
+		n, typ, err := s.api.GetValue(k, name, buf)
+		if err != nil && !errors.Is(err, registry.ErrShortBuffer) {
+			return nil, 0, err
+		}
+		if err == nil && n <= len(buf) {
+			return buf[:n], typ, nil
+		}
+		// The value may grow between calls
+		buf = make([]byte, n)
+	}
+}
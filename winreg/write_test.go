@@ -0,0 +1,214 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestSetDelete(t *testing.T) {
+	t.Log("Testing writes of single values.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, DefaultValue: "Default"})
+
+		testID := 0
+		t.Logf("\tTest %d:\tset values.", testID)
+		{
+			if err := p.Set("Window.Size.Width", uint32(800)); err != nil {
+				t.Fatalf("\t%s\tUnable to set value: %v.", failed, err)
+			}
+			if err := p.Set("Window.Default", "main"); err != nil {
+				t.Fatalf("\t%s\tUnable to set value: %v.", failed, err)
+			}
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			window, _ := tree["Window"].(map[string]interface{})
+			if window["Default"] != "main" {
+				t.Fatalf("\t%s\tWindow.Default is invalid, got %v.", failed, tree["Window"])
+			}
+			if size, _ := window["Size"].(map[string]interface{}); size["Width"] != uint64(800) {
+				t.Fatalf("\t%s\tWindow.Size.Width is invalid, got %v.", failed, window["Size"])
+			}
+			t.Logf("\t%s\tValues are written.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tdelete values.", testID)
+		{
+			if err := p.Delete("Window.Size.Width"); err != nil {
+				t.Fatalf("\t%s\tUnable to delete value: %v.", failed, err)
+			}
+			if err := p.Delete("Missing.Value"); err != nil {
+				t.Fatalf("\t%s\tDeleting a missing value should succeed, got: %v.", failed, err)
+			}
+			tree, err := p.Read()
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to read registry: %v.", failed, err)
+			}
+			size, _ := tree["Window"].(map[string]interface{})["Size"].(map[string]interface{})
+			if _, ok := size["Width"]; ok {
+				t.Fatalf("\t%s\tWindow.Size.Width was not deleted.", failed)
+			}
+			if err := p.Set("Value", nil); err == nil {
+				t.Fatalf("\t%s\tSet() of nil should fail.", failed)
+			}
+			t.Logf("\t%s\tValues are deleted.", success)
+		}
+	}
+}
+
+func TestTypeRules(t *testing.T) {
+	t.Log("Testing registry types of writes.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, TypeRules: TypeRules{
+			ExpandVars: true,
+			IntAsDWORD: true,
+			Paths:      map[string]uint32{"subkeya\\forced": registry.QWORD, "Text": registry.SZ},
+		}})
+
+		testID := 0
+		t.Logf("\tTest %d:\tinferred and forced types.", testID)
+		{
+			err := p.Apply([]Write{
+				{Name: "Path", Value: "%TEMP%\\app"},
+				{Name: "Text", Value: "100% %done%"},
+				{Name: "Plain", Value: "100%"},
+				{Name: "Small", Value: 5},
+				{Name: "Negative", Value: -1},
+				{Name: "Large", Value: uint64(5000000000)},
+				{Path: "SubKeyA", Name: "Forced", Value: uint32(1)},
+			})
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to apply writes: %v.", failed, err)
+			}
+
+			for _, c := range []struct {
+				path, name string
+				typ        uint32
+			}{
+				{"", "Path", registry.EXPAND_SZ},
+				{"", "Text", registry.SZ},
+				{"", "Plain", registry.SZ},
+				{"", "Small", registry.DWORD},
+				{"", "Negative", registry.QWORD},
+				{"", "Large", registry.QWORD},
+				{"SubKeyA", "Forced", registry.QWORD},
+			} {
+				k, err := registry.OpenKey(registry.CURRENT_USER, joinPath("SOFTWARE\\"+testKey, c.path), registry.QUERY_VALUE)
+				if err != nil {
+					t.Fatalf("\t%s\tUnable to open test key: %v.", failed, err)
+				}
+				_, typ, err := k.GetValue(c.name, nil)
+				k.Close()
+				if err != nil || typ != c.typ {
+					t.Fatalf("\t%s\t%s is %s, expect %s: %v.", failed, c.name, valueTypeName(typ), valueTypeName(c.typ), err)
+				}
+			}
+			t.Logf("\t%s\tTypes follow the rules.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tincompatible forced type.", testID)
+		{
+			if err := p.Set("Text", uint32(1)); err == nil {
+				t.Fatalf("\t%s\tWriting uint32 as REG_SZ should fail.", failed)
+			}
+			t.Logf("\t%s\tWrite is rejected.", success)
+		}
+	}
+}
+
+func TestVolatile(t *testing.T) {
+	t.Log("Testing writes to volatile keys.")
+	{
+		createTestData(t)
+		defer deleteTestData(t)
+
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\" + testKey, Volatile: true})
+
+		testID := 0
+		t.Logf("\tTest %d:\tcreate a volatile key.", testID)
+		{
+			if err := p.Set("Session.Token", "secret"); err != nil {
+				t.Fatalf("\t%s\tUnable to set value: %v.", failed, err)
+			}
+			// Only volatile keys can be created under a volatile key
+			_, _, err := registry.CreateKey(registry.CURRENT_USER, "SOFTWARE\\"+testKey+"\\Session\\Stable", registry.ALL_ACCESS)
+			if !errors.Is(err, windows.ERROR_CHILD_MUST_BE_VOLATILE) {
+				t.Fatalf("\t%s\tKey should be volatile, got: %v.", failed, err)
+			}
+			t.Logf("\t%s\tKey is volatile.", success)
+		}
+	}
+}
+
+func TestStoredType(t *testing.T) {
+	t.Log("Testing writes of existing values.")
+	{
+		m := NewMemory()
+		if err := m.Load(CURRENT_USER, "SOFTWARE\\Vendor", map[string]interface{}{"Count": uint32(1)}); err != nil {
+			t.Fatalf("\t%s\tUnable to load memory registry: %v", failed, err)
+		}
+		p := Provider(Config{Key: CURRENT_USER, Path: "SOFTWARE\\Vendor", Memory: m})
+		storedType := func(name string) uint32 {
+			k, err := m.OpenKey(CURRENT_USER, "SOFTWARE\\Vendor", registry.QUERY_VALUE)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to open memory key: %v.", failed, err)
+			}
+			defer m.CloseKey(k)
+			_, typ, err := m.GetValue(k, name, nil)
+			if err != nil && err != registry.ErrShortBuffer {
+				t.Fatalf("\t%s\tUnable to read %s: %v.", failed, name, err)
+			}
+			return typ
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tstored type is kept.", testID)
+		{
+			for _, value := range []interface{}{uint64(5), 6, float64(7)} {
+				if err := p.Set("Count", value); err != nil {
+					t.Fatalf("\t%s\tUnable to set %T: %v.", failed, value, err)
+				}
+				if typ := storedType("Count"); typ != registry.DWORD {
+					t.Fatalf("\t%s\t%T is stored as %s, expect REG_DWORD.", failed, value, valueTypeName(typ))
+				}
+			}
+			tree, err := p.Read()
+			if err != nil || tree["Count"] != uint64(7) {
+				t.Fatalf("\t%s\tCount is invalid, got %v: %v.", failed, tree["Count"], err)
+			}
+			if err := p.Set("Count", uint64(1)<<33); err == nil {
+				t.Fatalf("\t%s\tWriting a value larger than REG_DWORD should fail.", failed)
+			}
+			t.Logf("\t%s\tType of the existing value is kept.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tfloat64 of new values.", testID)
+		{
+			if err := p.Set("Size", float64(3)); err != nil {
+				t.Fatalf("\t%s\tUnable to set float64: %v.", failed, err)
+			}
+			if typ := storedType("Size"); typ != registry.QWORD {
+				t.Fatalf("\t%s\tfloat64 is stored as %s, expect REG_QWORD.", failed, valueTypeName(typ))
+			}
+			if err := p.Set("Ratio", 1.5); err == nil {
+				t.Fatalf("\t%s\tWriting a non-integral float64 should fail.", failed)
+			}
+			t.Logf("\t%s\tIntegral numbers are written.", success)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+//go:build windows
+
+package winreg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// XMLOptions controls how DecodeXML maps an XML document to a tree.
+//
+// The root element is mapped to the decoded value. An element without
+// attributes and children is mapped to its text, any other element to
+// a map of its children, with attributes prefixed by AttrPrefix and the
+// text stored as TextKey. Repeated children form a list.
+type XMLOptions struct {
+	AttrPrefix string // Prefix of attribute keys, "@" if empty
+	TextKey    string // Key of the text of an element with attributes or children, "#text" if empty
+
+	// If KeyAttr is set, a child element having such an attribute is mapped
+	// to an entry named by the attribute, with the value of the ValueAttr
+	// attribute, e.g. <add key="Name" value="Data"/> of .NET appSettings.
+	KeyAttr   string
+	ValueAttr string
+}
+
+// DecodeXML returns a Decoder parsing an XML document stored in a string
+// or binary value. Binary values may be UTF-8 or UTF-16 with a byte order
+// mark.
+func DecodeXML(opts XMLOptions) Decoder {
+	if opts.AttrPrefix == "" {
+		opts.AttrPrefix = "@"
+	}
+	if opts.TextKey == "" {
+		opts.TextKey = "#text"
+	}
+
+	return func(data interface{}) (interface{}, error) {
+		var doc string
+		switch v := data.(type) {
+		case string:
+			doc = v
+		case []byte:
+			doc = decodeTextBytes(v)
+		default:
+			return nil, fmt.Errorf("unable to decode XML from %T", data)
+		}
+
+		dec := xml.NewDecoder(strings.NewReader(doc))
+		// The document is already decoded, whatever encoding it declares
+		dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+			return input, nil
+		}
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					err = errors.New("no root element")
+				}
+				return nil, fmt.Errorf("invalid XML: %w", err)
+			}
+			if start, ok := tok.(xml.StartElement); ok {
+				retval, err := opts.element(dec, start)
+				if err != nil {
+					return nil, fmt.Errorf("invalid XML: %w", err)
+				}
+				return retval, nil
+			}
+		}
+	}
+}
+
+// element maps the element started by start, consuming its tokens.
+func (opts *XMLOptions) element(dec *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	var (
+		text     strings.Builder
+		children = make(map[string]interface{})
+	)
+	for _, attr := range start.Attr {
+		children[opts.AttrPrefix+attr.Name.Local] = attr.Value
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.CharData:
+			text.Write(tok)
+		case xml.StartElement:
+			name, value, err := opts.child(dec, tok)
+			if err != nil {
+				return nil, err
+			}
+			switch prev := children[name].(type) {
+			case nil:
+				children[name] = value
+			case []interface{}:
+				children[name] = append(prev, value)
+			default:
+				children[name] = []interface{}{prev, value}
+			}
+		case xml.EndElement:
+			str := strings.TrimSpace(text.String())
+			if len(children) == 0 {
+				return str, nil
+			}
+			if str != "" {
+				children[opts.TextKey] = str
+			}
+			return children, nil
+		}
+	}
+}
+
+// child maps a child element, returning the key it is stored with.
+func (opts *XMLOptions) child(dec *xml.Decoder, start xml.StartElement) (string, interface{}, error) {
+	if opts.KeyAttr != "" {
+		var key, value *string
+		for i := range start.Attr {
+			switch start.Attr[i].Name.Local {
+			case opts.KeyAttr:
+				key = &start.Attr[i].Value
+			case opts.ValueAttr:
+				value = &start.Attr[i].Value
+			}
+		}
+		if key != nil && value != nil {
+			return *key, *value, dec.Skip()
+		}
+		if key != nil {
+			content, err := opts.element(dec, xml.StartElement{Name: start.Name})
+			return *key, content, err
+		}
+	}
+
+	value, err := opts.element(dec, start)
+	return start.Name.Local, value, err
+}
+
+// decodeTextBytes converts binary data holding text to a string. UTF-16
+// is recognized by its byte order mark, anything else is taken as UTF-8.
+func decodeTextBytes(data []byte) string {
+	var order binary.ByteOrder
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		order = binary.LittleEndian
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		order = binary.BigEndian
+	default:
+		return string(bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF}))
+	}
+
+	units := make([]uint16, (len(data)-2)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2+i*2:])
+	}
+
+	return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+}
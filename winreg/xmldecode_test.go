@@ -0,0 +1,67 @@
+//go:build windows
+
+package winreg
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestDecodeXML(t *testing.T) {
+	t.Log("Testing XML decoder.")
+	{
+		const doc = `<?xml version="1.0" encoding="utf-16"?>
+<configuration>
+  <appSettings>
+    <add key="Timeout" value="30"/>
+    <add key="Mode" value="fast"/>
+  </appSettings>
+  <server host="a">text</server>
+  <item>1</item>
+  <item>2</item>
+</configuration>`
+		expect := map[string]interface{}{
+			"appSettings": map[string]interface{}{"Timeout": "30", "Mode": "fast"},
+			"server":      map[string]interface{}{"@host": "a", "#text": "text"},
+			"item":        []interface{}{"1", "2"},
+		}
+		decode := DecodeXML(XMLOptions{KeyAttr: "key", ValueAttr: "value"})
+
+		testID := 0
+		t.Logf("\tTest %d:\tstring value.", testID)
+		{
+			val, err := decode(doc)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to decode XML: %v.", failed, err)
+			}
+			if !reflect.DeepEqual(val, expect) {
+				t.Fatalf("\t%s\tInvalid tree, got %v, expect %v.", failed, val, expect)
+			}
+			t.Logf("\t%s\tDocument is decoded.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tUTF-16 binary value.", testID)
+		{
+			data := utf16Bytes(append([]uint16{0xFEFF}, utf16.Encode([]rune(doc))...))
+			val, err := decode(data)
+			if err != nil {
+				t.Fatalf("\t%s\tUnable to decode XML: %v.", failed, err)
+			}
+			if !reflect.DeepEqual(val, expect) {
+				t.Fatalf("\t%s\tInvalid tree, got %v, expect %v.", failed, val, expect)
+			}
+			t.Logf("\t%s\tDocument is decoded.", success)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tinvalid document.", testID)
+		{
+			if _, err := decode("<a><b></a>"); err == nil {
+				t.Fatalf("\t%s\tInvalid document should fail.", failed)
+			}
+			t.Logf("\t%s\tError is reported.", success)
+		}
+	}
+}